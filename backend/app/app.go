@@ -3,14 +3,18 @@ package app
 import (
 	"database/sql"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"backend/async"
 	"backend/config"
 	"backend/database"
 	"backend/handlers"
 	"backend/middleware"
 	"backend/routes"
+	"backend/services"
 	"backend/utils"
 
 	"github.com/gin-gonic/gin"
@@ -23,6 +27,21 @@ type App struct {
 	Config *config.Config
 	DB     *sql.DB
 	GormDB *gorm.DB
+
+	// TaskManager 后台任务管理器（打包、解压等耗时任务）
+	TaskManager *async.TaskManager
+
+	// ContentIndexer 文件内容全文索引后台服务
+	ContentIndexer *services.ContentIndexService
+
+	// VirusScanner 上传文件病毒扫描后台服务
+	VirusScanner *services.VirusScanService
+
+	// SFTPServer 内置 SFTP 服务，配置文件中未启用时不监听
+	SFTPServer *handlers.SFTPServer
+
+	// EventHub 向在线客户端推送任务进度、变更、存储空间和通知的后台服务
+	EventHub *services.EventHub
 }
 
 // NewApp 创建新的应用实例
@@ -59,6 +78,11 @@ func (app *App) Initialize() error {
 	}
 	app.Config = cfg
 
+	// 存储加密配置错误时拒绝启动，避免应当加密的文件以明文写入
+	if _, err := cfg.Encryption.LoadMasterKeys(); err != nil {
+		return fmt.Errorf("存储加密配置无效: %v", err)
+	}
+
 	// 使用安全的数据库初始化器
 	if err := app.safeInitializeDatabase(); err != nil {
 		return fmt.Errorf("安全数据库初始化失败: %v", err)
@@ -208,7 +232,7 @@ func (app *App) corsMiddleware() gin.HandlerFunc {
 			}
 		}
 
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, User-UUID")
 		c.Header("Access-Control-Expose-Headers", "Content-Length")
 		c.Header("Access-Control-Allow-Credentials", "true")
@@ -231,22 +255,102 @@ func (app *App) initializeHandlers(db *sql.DB, gormDB *gorm.DB) (*Handlers, data
 	folderRepo := database.NewGORMFolderRepository(gormDB)
 	docRepo := database.NewGORMDocumentRepository(gormDB)
 	urlFileRepo := database.NewGORMUrlFileRepository(gormDB)
+	versionRepo := database.NewGORMFileVersionRepository(gormDB)
+	shareRepo := database.NewGORMShareRepository(gormDB)
+	grantRepo := database.NewGORMGrantRepository(gormDB)
+	groupRepo := database.NewGORMUserGroupRepository(gormDB)
+	contentRepo := database.NewGORMFileContentRepository(gormDB)
+	nameRepo := database.NewGORMNamePinyinRepository(gormDB)
+	tagRepo := database.NewGORMTagRepository(gormDB)
+	activityRepo := database.NewGORMActivityRepository(gormDB)
+	scanRepo := database.NewGORMFileScanRepository(gormDB)
+	notificationRepo := database.NewGORMNotificationRepository(gormDB)
+	appTokenRepo := database.NewGORMAppTokenRepository(gormDB)
+	sshKeyRepo := database.NewGORMSSHKeyRepository(gormDB)
+	changeRepo := database.NewGORMChangeRepository(gormDB)
+	uploadTaskRepo := database.NewGORMUploadTaskRepository(gormDB)
+	userKeyRepo := database.NewGORMUserKeyRepository(gormDB)
+	vaultRepo := database.NewGORMVaultRepository(gormDB)
+
+	// 初始化存储加密：配置了主密钥时注册数据密钥来源，关闭加密后已加密的文件仍可读取
+	storageEncryption, err := services.NewStorageEncryptionService(app.Config.Encryption, userKeyRepo)
+	if err != nil {
+		log.Printf("⚠️ 存储加密配置无效: %v", err)
+	}
+	if storageEncryption.Configured() {
+		utils.SetStorageKeyring(storageEncryption)
+	}
 
-	// 初始化上传队列管理器
+	// 初始化上传队列管理器，任务状态写入数据库，重启或重连后仍可查询
 	uploadQueueManager := utils.NewUploadQueueManager()
+	uploadQueueManager.SetStore(uploadTaskRepo)
+
+	// 初始化并启动后台任务管理器
+	app.TaskManager = async.NewTaskManager(4, 100)
+	app.TaskManager.Start()
+
+	// 定期清理过期的异步打包结果，重启后生成时设置的删除定时器会丢失
+	go utils.SweepArchiveJobs(config.GetArchiveConfig().JobResultTTL, time.Hour)
+
+	// 初始化并启动文件内容与名称拼音索引服务
+	searchConfig := config.GetSearchConfig()
+	app.ContentIndexer = services.NewContentIndexService(contentRepo, nameRepo, searchConfig)
+	app.ContentIndexer.Start()
+
+	// 初始化并启动病毒扫描服务，未配置 clamd 地址时不扫描
+	scanConfig := config.GetScanConfig()
+	var scanner utils.VirusScanner
+	if scanConfig.Enabled() {
+		clamd, err := utils.NewClamdScanner(scanConfig.ClamdAddress, scanConfig.Timeout)
+		if err != nil {
+			log.Printf("⚠️ 病毒扫描配置无效，已禁用扫描: %v", err)
+		} else {
+			scanner = clamd
+		}
+	}
+	app.VirusScanner = services.NewVirusScanService(scanner, scanRepo, notificationRepo, userRepo, scanConfig)
+	app.VirusScanner.Start()
+
+	// 初始化并启动实时事件推送服务，上传队列的任务变化直接推送
+	app.EventHub = services.NewEventHub(changeRepo, notificationRepo, userRepo, fileRepo, urlFileRepo)
+	app.EventHub.Start()
+	uploadQueueManager.SetListener(app.EventHub.PublishUploadTask)
+
+	// 启动 SFTP 服务，启动失败不影响 HTTP 服务
+	app.SFTPServer = handlers.NewSFTPServer(app.Config.SFTP, userRepo, appTokenRepo, sshKeyRepo, fileRepo, folderRepo, versionRepo, grantRepo, app.ContentIndexer, app.VirusScanner)
+	if err := app.SFTPServer.Start(); err != nil {
+		log.Printf("⚠️ SFTP服务启动失败: %v", err)
+	}
 
 	// 初始化处理器层
 	handlers := &Handlers{
 		Auth:           handlers.NewAuthHandler(userRepo, fileRepo, urlFileRepo),
-		File:           handlers.NewFileHandler(fileRepo, userRepo, folderRepo),
-		Folder:         handlers.NewFolderHandler(folderRepo),
+		File:           handlers.NewFileHandler(fileRepo, userRepo, folderRepo, versionRepo, grantRepo, nameRepo, tagRepo, activityRepo, uploadQueueManager, app.ContentIndexer, app.VirusScanner, searchConfig),
+		FileVersion:    handlers.NewFileVersionHandler(fileRepo, userRepo, versionRepo, app.ContentIndexer, app.VirusScanner),
+		Folder:         handlers.NewFolderHandler(folderRepo, fileRepo, urlFileRepo, userRepo, versionRepo, grantRepo, tagRepo),
 		Storage:        handlers.NewStorageHandler(userRepo, fileRepo, urlFileRepo),
 		Profile:        handlers.NewProfileHandler(userRepo),
 		Document:       handlers.NewDocumentHandler(docRepo),
-		UrlFile:        handlers.NewUrlFileHandler(urlFileRepo, userRepo, folderRepo),
+		UrlFile:        handlers.NewUrlFileHandler(urlFileRepo, userRepo, folderRepo, activityRepo, fileRepo, grantRepo, versionRepo, uploadQueueManager, app.TaskManager, app.ContentIndexer, app.VirusScanner),
 		UploadProgress: handlers.NewUploadProgressHandler(uploadQueueManager),
 		UpdateLog:      handlers.NewUpdateLogHandler(db),
 		Health:         handlers.NewHealthHandler(db, gormDB),
+		Share:          handlers.NewShareHandler(shareRepo, fileRepo, folderRepo, grantRepo, userRepo, app.VirusScanner),
+		Grant:          handlers.NewGrantHandler(grantRepo, groupRepo, userRepo, fileRepo, folderRepo, urlFileRepo),
+		Archive:        handlers.NewArchiveHandler(fileRepo, folderRepo, grantRepo, userRepo, uploadQueueManager, app.TaskManager, app.VirusScanner),
+		Batch:          handlers.NewBatchHandler(database.NewGORMRepositorySet(gormDB), userRepo),
+		ContentSearch:  handlers.NewContentSearchHandler(contentRepo, folderRepo, grantRepo, app.ContentIndexer, searchConfig),
+		Tag:            handlers.NewTagHandler(tagRepo),
+		Activity:       handlers.NewActivityHandler(activityRepo, userRepo, grantRepo, urlFileRepo),
+		Scan:           handlers.NewScanHandler(scanRepo, app.VirusScanner, uploadQueueManager, app.TaskManager),
+		Notification:   handlers.NewNotificationHandler(notificationRepo),
+		AppToken:       handlers.NewAppTokenHandler(appTokenRepo),
+		WebDAV:         handlers.NewWebDAVHandler(userRepo, appTokenRepo, fileRepo, folderRepo, versionRepo, grantRepo, app.ContentIndexer, app.VirusScanner),
+		SSHKey:         handlers.NewSSHKeyHandler(sshKeyRepo),
+		Change:         handlers.NewChangeHandler(changeRepo),
+		Event:          handlers.NewEventHandler(app.EventHub),
+		Encryption:     handlers.NewEncryptionHandler(storageEncryption, uploadQueueManager, app.TaskManager),
+		Vault:          handlers.NewVaultHandler(vaultRepo, fileRepo, folderRepo, userRepo, versionRepo, grantRepo),
 	}
 
 	return handlers, userRepo, fileRepo, urlFileRepo
@@ -268,6 +372,23 @@ func (app *App) setupRoutes(handlers *Handlers, userRepo database.UserRepository
 		handlers.UrlFile,
 		handlers.UploadProgress,
 		handlers.UpdateLog,
+		handlers.FileVersion,
+		handlers.Share,
+		handlers.Grant,
+		handlers.Archive,
+		handlers.Batch,
+		handlers.ContentSearch,
+		handlers.Tag,
+		handlers.Activity,
+		handlers.Scan,
+		handlers.Notification,
+		handlers.AppToken,
+		handlers.WebDAV,
+		handlers.SSHKey,
+		handlers.Change,
+		handlers.Event,
+		handlers.Encryption,
+		handlers.Vault,
 	)
 
 	// 设置认证路由（/api/auth/*）
@@ -292,6 +413,23 @@ type Handlers struct {
 	UploadProgress *handlers.UploadProgressHandler
 	UpdateLog      *handlers.UpdateLogHandler
 	Health         *handlers.HealthHandler
+	FileVersion    *handlers.FileVersionHandler
+	Share          *handlers.ShareHandler
+	Grant          *handlers.GrantHandler
+	Archive        *handlers.ArchiveHandler
+	Batch          *handlers.BatchHandler
+	ContentSearch  *handlers.ContentSearchHandler
+	Tag            *handlers.TagHandler
+	Activity       *handlers.ActivityHandler
+	Scan           *handlers.ScanHandler
+	Notification   *handlers.NotificationHandler
+	AppToken       *handlers.AppTokenHandler
+	WebDAV         *handlers.WebDAVHandler
+	SSHKey         *handlers.SSHKeyHandler
+	Change         *handlers.ChangeHandler
+	Event          *handlers.EventHandler
+	Encryption     *handlers.EncryptionHandler
+	Vault          *handlers.VaultHandler
 }
 
 // Run 启动应用
@@ -309,6 +447,21 @@ func (app *App) Run() error {
 
 // Close 关闭应用
 func (app *App) Close() error {
+	if app.SFTPServer != nil {
+		app.SFTPServer.Stop()
+	}
+	if app.EventHub != nil {
+		app.EventHub.Stop()
+	}
+	if app.ContentIndexer != nil {
+		app.ContentIndexer.Stop()
+	}
+	if app.VirusScanner != nil {
+		app.VirusScanner.Stop()
+	}
+	if app.TaskManager != nil {
+		app.TaskManager.Stop()
+	}
 	if app.DB != nil {
 		return app.DB.Close()
 	}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// ArchiveConfig 打包下载与解压配置
+type ArchiveConfig struct {
+	// 直接流式下载的上限，超过后转为异步任务 (字节/条目数)
+	MaxStreamSize    int64
+	MaxStreamEntries int
+
+	// 单次打包允许的总大小上限 (字节)，超过直接拒绝
+	MaxArchiveSize int64
+
+	// 异步任务生成的压缩包保留时间
+	JobResultTTL time.Duration
+
+	// 解压限制：解压后总大小、条目数量、单个文件大小 (字节)
+	MaxExtractSize     int64
+	MaxExtractEntries  int
+	MaxExtractFileSize int64
+
+	// 解压后大小与压缩大小的最大比例，用于识别压缩炸弹
+	MaxCompressionRatio int64
+}
+
+// GetArchiveConfig 获取打包下载与解压配置，可通过环境变量覆盖默认值
+func GetArchiveConfig() *ArchiveConfig {
+	config := &ArchiveConfig{
+		MaxStreamSize:    500 * 1024 * 1024,      // 500MB
+		MaxStreamEntries: 2000,                   // 2000个条目
+		MaxArchiveSize:   5 * 1024 * 1024 * 1024, // 5GB
+		JobResultTTL:     24 * time.Hour,         // 24小时
+
+		MaxExtractSize:      2 * 1024 * 1024 * 1024, // 2GB
+		MaxExtractEntries:   10000,                  // 10000个条目
+		MaxExtractFileSize:  1024 * 1024 * 1024,     // 1GB
+		MaxCompressionRatio: 100,                    // 100倍
+	}
+
+	config.MaxStreamSize = getEnvInt64("ARCHIVE_MAX_STREAM_SIZE", config.MaxStreamSize)
+	config.MaxStreamEntries = int(getEnvInt64("ARCHIVE_MAX_STREAM_ENTRIES", int64(config.MaxStreamEntries)))
+	config.MaxArchiveSize = getEnvInt64("ARCHIVE_MAX_SIZE", config.MaxArchiveSize)
+	config.MaxExtractSize = getEnvInt64("EXTRACT_MAX_SIZE", config.MaxExtractSize)
+	config.MaxExtractEntries = int(getEnvInt64("EXTRACT_MAX_ENTRIES", int64(config.MaxExtractEntries)))
+	config.MaxExtractFileSize = getEnvInt64("EXTRACT_MAX_FILE_SIZE", config.MaxExtractFileSize)
+	config.MaxCompressionRatio = getEnvInt64("EXTRACT_MAX_COMPRESSION_RATIO", config.MaxCompressionRatio)
+	if hours := getEnvInt64("ARCHIVE_RESULT_TTL_HOURS", 0); hours > 0 {
+		config.JobResultTTL = time.Duration(hours) * time.Hour
+	}
+
+	return config
+}
+
+// getEnvInt64 读取整数环境变量，未设置或无效时返回默认值
+func getEnvInt64(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || parsed <= 0 {
+		return defaultValue
+	}
+	return parsed
+}
@@ -51,6 +51,10 @@ type Config struct {
 		StaticPath string `yaml:"static_path"`
 		UploadPath string `yaml:"upload_path"`
 	} `yaml:"deployment"`
+
+	SFTP SFTPConfig `yaml:"sftp"`
+
+	Encryption EncryptionConfig `yaml:"encryption"`
 }
 
 // DBConfig 数据库配置结构体（保持向后兼容）
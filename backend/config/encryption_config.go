@@ -0,0 +1,63 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EncryptionConfig 存储加密配置，对应配置文件中的 encryption 段，默认不启用。
+// 用户文件使用各自的数据密钥以 AES-256-GCM 加密，数据密钥由主密钥加密后保存在数据库中
+type EncryptionConfig struct {
+	// 是否加密新写入的文件；关闭后已加密的文件仍可读取，只要主密钥仍在配置中
+	Enabled bool `yaml:"enabled"`
+
+	// 当前主密钥，32字节的 base64 编码；与 master_key_file 二选一，优先使用 master_key_file
+	MasterKey string `yaml:"master_key"`
+
+	// 当前主密钥文件，内容为 base64 编码的32字节密钥
+	MasterKeyFile string `yaml:"master_key_file"`
+
+	// 轮换前使用过的主密钥，仍由其加密的数据密钥需要它们解开，轮换任务完成后可以移除
+	PreviousMasterKeys     []string `yaml:"previous_master_keys"`
+	PreviousMasterKeyFiles []string `yaml:"previous_master_key_files"`
+}
+
+// LoadMasterKeys 读取主密钥，第一个为当前主密钥，其余为轮换前的主密钥；没有配置主密钥时返回空
+func (c EncryptionConfig) LoadMasterKeys() ([][]byte, error) {
+	current := c.MasterKey
+	if c.MasterKeyFile != "" {
+		data, err := os.ReadFile(c.MasterKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取主密钥文件失败: %v", err)
+		}
+		current = string(data)
+	}
+	if strings.TrimSpace(current) == "" {
+		if c.Enabled {
+			return nil, fmt.Errorf("已启用存储加密但未配置主密钥")
+		}
+		return nil, nil
+	}
+
+	encoded := []string{current}
+	encoded = append(encoded, c.PreviousMasterKeys...)
+	for _, path := range c.PreviousMasterKeyFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取主密钥文件失败: %v", err)
+		}
+		encoded = append(encoded, string(data))
+	}
+
+	keys := make([][]byte, 0, len(encoded))
+	for _, value := range encoded {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("主密钥必须是 base64 编码的32字节密钥")
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
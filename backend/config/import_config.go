@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// ImportConfig 从远程URL导入文件的配置
+type ImportConfig struct {
+	// 单个导入文件的大小上限 (字节)
+	MaxFileSize int64
+
+	// 单次导入（连接、下载）的总超时时间
+	Timeout time.Duration
+
+	// 允许跟随的重定向次数
+	MaxRedirects int
+
+	// 允许的URL协议（小写）
+	AllowedSchemes []string
+}
+
+// GetImportConfig 获取URL导入配置，可通过环境变量覆盖默认值
+func GetImportConfig() *ImportConfig {
+	config := &ImportConfig{
+		MaxFileSize:    100 * 1024 * 1024, // 100MB
+		Timeout:        10 * time.Minute,
+		MaxRedirects:   5,
+		AllowedSchemes: []string{"http", "https"},
+	}
+
+	config.MaxFileSize = getEnvInt64("IMPORT_MAX_FILE_SIZE", config.MaxFileSize)
+	if seconds := getEnvInt64("IMPORT_TIMEOUT_SECONDS", 0); seconds > 0 {
+		config.Timeout = time.Duration(seconds) * time.Second
+	}
+	config.MaxRedirects = int(getEnvInt64("IMPORT_MAX_REDIRECTS", int64(config.MaxRedirects)))
+	if schemes := os.Getenv("IMPORT_ALLOWED_SCHEMES"); schemes != "" {
+		config.AllowedSchemes = nil
+		for _, scheme := range strings.Split(schemes, ",") {
+			if scheme = strings.ToLower(strings.TrimSpace(scheme)); scheme != "" {
+				config.AllowedSchemes = append(config.AllowedSchemes, scheme)
+			}
+		}
+	}
+
+	return config
+}
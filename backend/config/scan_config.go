@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// ScanConfig 上传文件病毒扫描配置
+type ScanConfig struct {
+	// clamd 地址：tcp://host:3310、host:3310 或 unix:///var/run/clamav/clamd.ctl，为空时不扫描
+	ClamdAddress string
+
+	// 单个文件扫描的超时时间
+	Timeout time.Duration
+
+	// 不超过该大小的文件在上传请求中同步扫描，更大的文件由后台服务异步扫描 (字节)
+	SyncMaxSize int64
+
+	// 超过该大小的文件不扫描，应与 clamd 的 StreamMaxLength 保持一致 (字节)
+	MaxScanSize int64
+
+	// 后台扫描的间隔，以及每轮处理的文件数量
+	Interval  time.Duration
+	BatchSize int
+}
+
+// Enabled 是否配置了扫描服务
+func (c *ScanConfig) Enabled() bool {
+	return c.ClamdAddress != ""
+}
+
+// GetScanConfig 获取病毒扫描配置，可通过环境变量覆盖默认值
+func GetScanConfig() *ScanConfig {
+	config := &ScanConfig{
+		ClamdAddress: strings.TrimSpace(os.Getenv("SCAN_CLAMD_ADDRESS")),
+		Timeout:      60 * time.Second,
+		SyncMaxSize:  10 * 1024 * 1024, // 10MB
+		MaxScanSize:  25 * 1024 * 1024, // 25MB，clamd 默认的 StreamMaxLength
+		Interval:     30 * time.Second,
+		BatchSize:    20,
+	}
+
+	if seconds := getEnvInt64("SCAN_TIMEOUT_SECONDS", 0); seconds > 0 {
+		config.Timeout = time.Duration(seconds) * time.Second
+	}
+	config.SyncMaxSize = getEnvInt64("SCAN_SYNC_MAX_SIZE", config.SyncMaxSize)
+	config.MaxScanSize = getEnvInt64("SCAN_MAX_FILE_SIZE", config.MaxScanSize)
+	if seconds := getEnvInt64("SCAN_INTERVAL_SECONDS", 0); seconds > 0 {
+		config.Interval = time.Duration(seconds) * time.Second
+	}
+	config.BatchSize = int(getEnvInt64("SCAN_BATCH_SIZE", int64(config.BatchSize)))
+
+	return config
+}
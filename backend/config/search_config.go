@@ -0,0 +1,54 @@
+package config
+
+import "time"
+
+// SearchConfig 文件内容全文检索与名称模糊搜索配置
+type SearchConfig struct {
+	// 后台索引任务的扫描间隔，以及每轮处理的文件数量
+	IndexInterval  time.Duration
+	IndexBatchSize int
+
+	// 超过该大小的文件不提取内容 (字节)
+	MaxIndexFileSize int64
+
+	// 单个文件写入索引的最大字符数
+	MaxContentLength int
+
+	// 每条结果返回的高亮片段数量及片段上下文字符数
+	MaxSnippets    int
+	SnippetContext int
+
+	// 每批生成拼音索引的名称数量
+	NameIndexBatchSize int
+
+	// 模糊搜索单次最多参与匹配的候选记录数
+	MaxFuzzyCandidates int
+}
+
+// GetSearchConfig 获取全文检索配置，可通过环境变量覆盖默认值
+func GetSearchConfig() *SearchConfig {
+	config := &SearchConfig{
+		IndexInterval:    30 * time.Second,
+		IndexBatchSize:   50,
+		MaxIndexFileSize: 50 * 1024 * 1024, // 50MB
+		MaxContentLength: 1000000,          // 100万字符
+		MaxSnippets:      3,
+		SnippetContext:   60,
+
+		NameIndexBatchSize: 500,
+		MaxFuzzyCandidates: 20000,
+	}
+
+	if seconds := getEnvInt64("SEARCH_INDEX_INTERVAL_SECONDS", 0); seconds > 0 {
+		config.IndexInterval = time.Duration(seconds) * time.Second
+	}
+	config.IndexBatchSize = int(getEnvInt64("SEARCH_INDEX_BATCH_SIZE", int64(config.IndexBatchSize)))
+	config.MaxIndexFileSize = getEnvInt64("SEARCH_MAX_INDEX_FILE_SIZE", config.MaxIndexFileSize)
+	config.MaxContentLength = int(getEnvInt64("SEARCH_MAX_CONTENT_LENGTH", int64(config.MaxContentLength)))
+	config.MaxSnippets = int(getEnvInt64("SEARCH_MAX_SNIPPETS", int64(config.MaxSnippets)))
+	config.SnippetContext = int(getEnvInt64("SEARCH_SNIPPET_CONTEXT", int64(config.SnippetContext)))
+	config.NameIndexBatchSize = int(getEnvInt64("SEARCH_NAME_INDEX_BATCH_SIZE", int64(config.NameIndexBatchSize)))
+	config.MaxFuzzyCandidates = int(getEnvInt64("SEARCH_MAX_FUZZY_CANDIDATES", int64(config.MaxFuzzyCandidates)))
+
+	return config
+}
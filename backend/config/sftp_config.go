@@ -0,0 +1,57 @@
+package config
+
+import "time"
+
+// SFTPConfig 内置 SFTP 服务配置，对应配置文件中的 sftp 段，默认不启用
+type SFTPConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// 监听地址，默认 :2022
+	Listen string `yaml:"listen"`
+
+	// 主机私钥文件（OpenSSH 格式），不存在时自动生成 ed25519 密钥并保存
+	HostKeyFile string `yaml:"host_key_file"`
+
+	// 是否允许使用账号密码或应用专用密码登录，默认允许；关闭后只能使用登记的SSH公钥
+	PasswordAuth *bool `yaml:"password_auth"`
+
+	// 同一IP在时间窗口内允许的密码认证失败次数，超过后暂时拒绝认证
+	MaxAuthFailures   int           `yaml:"max_auth_failures"`
+	AuthFailureWindow time.Duration `yaml:"auth_failure_window"`
+
+	// 连接空闲超时，超时后断开
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+
+	// 同时保持的连接数上限
+	MaxConnections int `yaml:"max_connections"`
+
+	// 审计日志文件，为空时写入标准日志
+	AuditLogFile string `yaml:"audit_log_file"`
+}
+
+// WithDefaults 返回补全默认值后的配置
+func (c SFTPConfig) WithDefaults() SFTPConfig {
+	if c.Listen == "" {
+		c.Listen = ":2022"
+	}
+	if c.HostKeyFile == "" {
+		c.HostKeyFile = "config/sftp_host_ed25519_key"
+	}
+	if c.PasswordAuth == nil {
+		enabled := true
+		c.PasswordAuth = &enabled
+	}
+	if c.MaxAuthFailures <= 0 {
+		c.MaxAuthFailures = 10
+	}
+	if c.AuthFailureWindow <= 0 {
+		c.AuthFailureWindow = 15 * time.Minute
+	}
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = 15 * time.Minute
+	}
+	if c.MaxConnections <= 0 {
+		c.MaxConnections = 100
+	}
+	return c
+}
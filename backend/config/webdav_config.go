@@ -0,0 +1,30 @@
+package config
+
+import "time"
+
+// WebDAVConfig WebDAV 访问配置
+type WebDAVConfig struct {
+	// 通过 WebDAV 写入的单个文件大小上限 (字节)，同时受存储配额限制
+	MaxFileSize int64
+
+	// 同一IP在时间窗口内允许的认证失败次数，超过后暂时拒绝认证
+	MaxAuthFailures   int
+	AuthFailureWindow time.Duration
+}
+
+// GetWebDAVConfig 获取 WebDAV 配置，可通过环境变量覆盖默认值
+func GetWebDAVConfig() *WebDAVConfig {
+	config := &WebDAVConfig{
+		MaxFileSize:       1024 * 1024 * 1024, // 1GB
+		MaxAuthFailures:   10,
+		AuthFailureWindow: 15 * time.Minute,
+	}
+
+	config.MaxFileSize = getEnvInt64("WEBDAV_MAX_FILE_SIZE", config.MaxFileSize)
+	config.MaxAuthFailures = int(getEnvInt64("WEBDAV_MAX_AUTH_FAILURES", int64(config.MaxAuthFailures)))
+	if minutes := getEnvInt64("WEBDAV_AUTH_FAILURE_WINDOW_MINUTES", 0); minutes > 0 {
+		config.AuthFailureWindow = time.Duration(minutes) * time.Minute
+	}
+
+	return config
+}
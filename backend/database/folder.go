@@ -106,6 +106,207 @@ func (r *FolderRepository) DeleteFolder(folderID uint, userID string) error {
 	return err
 }
 
+// MoveFolder 在事务中修改文件夹的父文件夹（parentID 为 nil 表示移动到根目录），name 非空时同时重命名。
+// 从目标父文件夹向上查找祖先，遇到文件夹自身时返回 ErrFolderCycle；查找过程加行锁，避免并发移动形成环
+func (r *FolderRepository) MoveFolder(folderID uint, userID string, parentID *uint, name string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id uint
+	if err := tx.QueryRow(`SELECT id FROM folders WHERE id = ? AND user_id = ? FOR UPDATE`, folderID, userID).Scan(&id); err != nil {
+		return err
+	}
+
+	err = checkFolderMove(folderID, parentID, func(id uint) (*uint, error) {
+		var ancestorParentID *uint
+		err := tx.QueryRow(`SELECT parent_id FROM folders WHERE id = ? AND user_id = ? FOR UPDATE`, id, userID).Scan(&ancestorParentID)
+		return ancestorParentID, err
+	})
+	if err != nil {
+		return err
+	}
+
+	if name != "" {
+		_, err = tx.Exec(`UPDATE folders SET parent_id = ?, name = ?, updated_at = ? WHERE id = ? AND user_id = ?`,
+			parentID, name, time.Now(), folderID, userID)
+	} else {
+		_, err = tx.Exec(`UPDATE folders SET parent_id = ?, updated_at = ? WHERE id = ? AND user_id = ?`,
+			parentID, time.Now(), folderID, userID)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// getDescendantFolderIDs 在事务中获取文件夹及其所有子孙文件夹的ID（包含自身）
+func (r *FolderRepository) getDescendantFolderIDs(tx *sql.Tx, folderID uint, userID string) ([]uint, error) {
+	rows, err := tx.Query(`SELECT id, parent_id FROM folders WHERE user_id = ? AND parent_id IS NOT NULL`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	children := make(map[uint][]uint)
+	for rows.Next() {
+		var id, parentID uint
+		if err := rows.Scan(&id, &parentID); err != nil {
+			return nil, err
+		}
+		children[parentID] = append(children[parentID], id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return collectDescendantIDs(folderID, children), nil
+}
+
+// deleteShareLinksTx 在事务中删除指向已删除资源的分享链接及其访问日志
+func deleteShareLinksTx(tx *sql.Tx, resourceType, placeholders string, args []interface{}) error {
+	resourceArgs := append([]interface{}{resourceType}, args...)
+	if _, err := tx.Exec(`DELETE FROM share_access_logs WHERE share_id IN (SELECT id FROM share_links WHERE resource_type = ? AND resource_id IN (`+placeholders+`))`,
+		resourceArgs...); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM share_links WHERE resource_type = ? AND resource_id IN (`+placeholders+`)`, resourceArgs...)
+	return err
+}
+
+// DeleteFolderRecursive 在事务中删除文件夹及其所有子孙文件夹、文件、历史版本、URL文件、共享授权和分享链接，
+// 返回删除统计和需要清理的物理文件路径
+func (r *FolderRepository) DeleteFolderRecursive(folderID uint, userID string) (*models.FolderDeleteResult, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id uint
+	if err := tx.QueryRow(`SELECT id FROM folders WHERE id = ? AND user_id = ? FOR UPDATE`, folderID, userID).Scan(&id); err != nil {
+		return nil, err
+	}
+
+	folderIDs, err := r.getDescendantFolderIDs(tx, folderID, userID)
+	if err != nil {
+		return nil, err
+	}
+	folderPlaceholders, folderArgs := inPlaceholders(folderIDs)
+	result := &models.FolderDeleteResult{FolderCount: len(folderIDs)}
+
+	// 收集文件夹树中的文件
+	rows, err := tx.Query(`SELECT id, path, size FROM files WHERE user_id = ? AND folder_id IN (`+folderPlaceholders+`)`,
+		append([]interface{}{userID}, folderArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	var fileIDs []uint
+	for rows.Next() {
+		var fileID uint
+		var path string
+		var size int64
+		if err := rows.Scan(&fileID, &path, &size); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		fileIDs = append(fileIDs, fileID)
+		result.StoredPaths = append(result.StoredPaths, path)
+		result.FreedSize += size
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	result.FileCount = len(fileIDs)
+
+	if len(fileIDs) > 0 {
+		filePlaceholders, fileArgs := inPlaceholders(fileIDs)
+
+		// 收集并删除历史版本
+		versionRows, err := tx.Query(`SELECT path, size FROM file_versions WHERE file_id IN (`+filePlaceholders+`)`, fileArgs...)
+		if err != nil {
+			return nil, err
+		}
+		for versionRows.Next() {
+			var path string
+			var size int64
+			if err := versionRows.Scan(&path, &size); err != nil {
+				versionRows.Close()
+				return nil, err
+			}
+			result.StoredPaths = append(result.StoredPaths, path)
+			result.FreedSize += size
+			result.VersionCount++
+		}
+		versionRows.Close()
+		if err := versionRows.Err(); err != nil {
+			return nil, err
+		}
+
+		if _, err := tx.Exec(`DELETE FROM file_versions WHERE file_id IN (`+filePlaceholders+`)`, fileArgs...); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`DELETE FROM share_grants WHERE resource_type = ? AND resource_id IN (`+filePlaceholders+`)`,
+			append([]interface{}{models.ShareResourceFile}, fileArgs...)...); err != nil {
+			return nil, err
+		}
+		if err := deleteShareLinksTx(tx, models.ShareResourceFile, filePlaceholders, fileArgs); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`DELETE FROM file_contents WHERE file_id IN (`+filePlaceholders+`)`, fileArgs...); err != nil {
+			return nil, err
+		}
+		for _, table := range itemLinkTables {
+			if _, err := tx.Exec(`DELETE FROM `+table+` WHERE item_type = ? AND item_id IN (`+filePlaceholders+`)`,
+				append([]interface{}{models.SearchItemFile}, fileArgs...)...); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := tx.Exec(`DELETE FROM files WHERE user_id = ? AND id IN (`+filePlaceholders+`)`,
+			append([]interface{}{userID}, fileArgs...)...); err != nil {
+			return nil, err
+		}
+	}
+
+	// 删除URL文件、文件夹授权、标签关联、收藏、最近访问和文件夹本身
+	for _, table := range itemLinkTables {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE item_type = ? AND item_id IN (SELECT id FROM url_files WHERE user_id = ? AND folder_id IN (`+folderPlaceholders+`))`,
+			append([]interface{}{models.SearchItemUrlFile, userID}, folderArgs...)...); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE item_type = ? AND item_id IN (`+folderPlaceholders+`)`,
+			append([]interface{}{models.SearchItemFolder}, folderArgs...)...); err != nil {
+			return nil, err
+		}
+	}
+	urlResult, err := tx.Exec(`DELETE FROM url_files WHERE user_id = ? AND folder_id IN (`+folderPlaceholders+`)`,
+		append([]interface{}{userID}, folderArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	urlCount, _ := urlResult.RowsAffected()
+	result.UrlFileCount = int(urlCount)
+
+	if _, err := tx.Exec(`DELETE FROM share_grants WHERE resource_type = ? AND resource_id IN (`+folderPlaceholders+`)`,
+		append([]interface{}{models.ShareResourceFolder}, folderArgs...)...); err != nil {
+		return nil, err
+	}
+	if err := deleteShareLinksTx(tx, models.ShareResourceFolder, folderPlaceholders, folderArgs); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM folders WHERE user_id = ? AND id IN (`+folderPlaceholders+`)`,
+		append([]interface{}{userID}, folderArgs...)...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // CheckFolderExists 检查文件夹是否存在
 func (r *FolderRepository) CheckFolderExists(folderID uint, userID string) (bool, error) {
 	var exists bool
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrFolderCycle 移动文件夹会形成环（目标是自身或其子孙文件夹）
+var ErrFolderCycle = errors.New("不能将文件夹移动到自身或其子文件夹")
+
+// checkFolderMove 从目标父文件夹 parentID 开始沿 parentOf 向上查找祖先，遇到 folderID 自身时返回 ErrFolderCycle。
+// 最多查找 maxFolderDepth 层，遇到脏数据中已有的环时停止
+func checkFolderMove(folderID uint, parentID *uint, parentOf func(id uint) (*uint, error)) error {
+	current := parentID
+	visited := make(map[uint]bool)
+	for depth := 0; current != nil && depth < maxFolderDepth; depth++ {
+		if *current == folderID {
+			return ErrFolderCycle
+		}
+		if visited[*current] {
+			return nil
+		}
+		visited[*current] = true
+
+		next, err := parentOf(*current)
+		if err != nil {
+			return err
+		}
+		current = next
+	}
+	return nil
+}
+
+// collectDescendantIDs 根据父子关系广度优先收集 rootID 及其所有子孙文件夹ID，visited 防止脏数据中的环导致死循环
+func collectDescendantIDs(rootID uint, children map[uint][]uint) []uint {
+	ids := []uint{rootID}
+	visited := map[uint]bool{rootID: true}
+	for i := 0; i < len(ids); i++ {
+		for _, childID := range children[ids[i]] {
+			if !visited[childID] {
+				visited[childID] = true
+				ids = append(ids, childID)
+			}
+		}
+	}
+	return ids
+}
+
+// inPlaceholders 生成原生SQL IN 子句的占位符和参数，如 "?,?,?"
+func inPlaceholders(ids []uint) (string, []interface{}) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", len(ids)), ","), args
+}
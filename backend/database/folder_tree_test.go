@@ -0,0 +1,109 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// testFolderParents 根据 子ID->父ID 的映射模拟查询父文件夹，0 表示根目录
+func testFolderParents(parents map[uint]uint) func(id uint) (*uint, error) {
+	return func(id uint) (*uint, error) {
+		parent, ok := parents[id]
+		if !ok {
+			return nil, errors.New("文件夹不存在")
+		}
+		if parent == 0 {
+			return nil, nil
+		}
+		return &parent, nil
+	}
+}
+
+func TestCheckFolderMove(t *testing.T) {
+	// 1 ─┬─ 2 ── 3 ── 4
+	//    └─ 5
+	// 6（根目录）
+	tree := map[uint]uint{1: 0, 2: 1, 3: 2, 4: 3, 5: 1, 6: 0}
+	id := func(v uint) *uint { return &v }
+
+	tests := []struct {
+		name     string
+		folderID uint
+		parentID *uint
+		parents  map[uint]uint
+		wantErr  error
+	}{
+		{name: "移动到根目录", folderID: 3, parentID: nil, parents: tree},
+		{name: "移动到兄弟文件夹", folderID: 2, parentID: id(5), parents: tree},
+		{name: "移动到其他根文件夹", folderID: 1, parentID: id(6), parents: tree},
+		{name: "子文件夹移动到祖先", folderID: 4, parentID: id(1), parents: tree},
+		{name: "移动到自身", folderID: 2, parentID: id(2), parents: tree, wantErr: ErrFolderCycle},
+		{name: "移动到子文件夹", folderID: 2, parentID: id(3), parents: tree, wantErr: ErrFolderCycle},
+		{name: "移动到深层子孙", folderID: 1, parentID: id(4), parents: tree, wantErr: ErrFolderCycle},
+		{
+			name:     "脏数据中已有的环不导致死循环",
+			folderID: 9,
+			parentID: id(7),
+			parents:  map[uint]uint{7: 8, 8: 7, 9: 0},
+		},
+		{
+			name:     "超过最大层数时停止查找",
+			folderID: 1,
+			parentID: id(maxFolderDepth + 10),
+			parents: func() map[uint]uint {
+				chain := map[uint]uint{1: 0}
+				for i := uint(2); i <= maxFolderDepth+10; i++ {
+					chain[i] = i - 1
+				}
+				return chain
+			}(),
+		},
+		{name: "目标不存在", folderID: 2, parentID: id(99), parents: tree, wantErr: errors.New("文件夹不存在")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkFolderMove(tt.folderID, tt.parentID, testFolderParents(tt.parents))
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Errorf("不应出错: %v", err)
+			case tt.wantErr == ErrFolderCycle && !errors.Is(err, ErrFolderCycle):
+				t.Errorf("期望 ErrFolderCycle，实际为 %v", err)
+			case tt.wantErr != nil && err == nil:
+				t.Errorf("期望错误 %v", tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCollectDescendantIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		rootID   uint
+		children map[uint][]uint
+		want     []uint
+	}{
+		{name: "没有子文件夹", rootID: 1, children: map[uint][]uint{}, want: []uint{1}},
+		{
+			name:     "按层级收集",
+			rootID:   1,
+			children: map[uint][]uint{1: {2, 3}, 2: {4}, 3: {5}, 4: {6}, 7: {8}},
+			want:     []uint{1, 2, 3, 4, 5, 6},
+		},
+		{
+			name:     "脏数据中的环只收集一次",
+			rootID:   1,
+			children: map[uint][]uint{1: {2}, 2: {3}, 3: {1, 2}},
+			want:     []uint{1, 2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := collectDescendantIDs(tt.rootID, tt.children); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("collectDescendantIDs = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"strings"
+	"time"
+
+	"backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GORMActivityRepository 收藏与最近访问仓库
+type GORMActivityRepository struct {
+	db *gorm.DB
+}
+
+// NewGORMActivityRepository 创建收藏与最近访问仓库
+func NewGORMActivityRepository(db *gorm.DB) *GORMActivityRepository {
+	return &GORMActivityRepository{db: db}
+}
+
+// activityRow 收藏或最近访问列表的一条记录
+type activityRow struct {
+	ItemType    string
+	ItemID      uint
+	ActivityAt  time.Time
+	AccessCount int
+	OwnerID     string
+}
+
+// AddFavorite 收藏对象，已收藏时保持原收藏时间
+func (r *GORMActivityRepository) AddFavorite(userID, itemType string, itemID uint) error {
+	favorite := models.Favorite{UserID: userID, ItemType: itemType, ItemID: itemID}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&favorite).Error
+}
+
+// RemoveFavorite 取消收藏，返回是否确实删除了记录
+func (r *GORMActivityRepository) RemoveFavorite(userID, itemType string, itemID uint) (bool, error) {
+	result := r.db.Where("user_id = ? AND item_type = ? AND item_id = ?", userID, itemType, itemID).Delete(&models.Favorite{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ListFavorites 按收藏时间倒序分页列出收藏的对象
+func (r *GORMActivityRepository) ListFavorites(query *models.ActivityQuery) ([]models.ActivityItem, string, error) {
+	return r.listActivity("favorites", "created_at", "0", query)
+}
+
+// RecordAccess 记录一次访问：同一对象只保留一条记录并更新访问时间，
+// 用户关闭最近访问记录时不写入；新增记录后删除超出上限的最早记录
+func (r *GORMActivityRepository) RecordAccess(userID, itemType string, itemID uint) error {
+	result := r.db.Exec(`INSERT INTO recent_items (user_id, item_type, item_id, accessed_at, access_count)
+		SELECT uuid, ?, ?, CURRENT_TIMESTAMP(3), 1 FROM user WHERE uuid = ? AND recent_history_enabled
+		ON DUPLICATE KEY UPDATE accessed_at = CURRENT_TIMESTAMP(3), access_count = access_count + 1`,
+		itemType, itemID, userID)
+	// 插入新记录时影响行数为1，更新已有记录时为2
+	if result.Error != nil || result.RowsAffected != 1 {
+		return result.Error
+	}
+	return r.db.Exec(`DELETE r FROM recent_items r JOIN (
+			SELECT item_type, item_id FROM recent_items WHERE user_id = ?
+			ORDER BY accessed_at DESC, item_type DESC, item_id DESC LIMIT 18446744073709551615 OFFSET ?
+		) old ON old.item_type = r.item_type AND old.item_id = r.item_id
+		WHERE r.user_id = ?`, userID, models.MaxRecentItems, userID).Error
+}
+
+// ListRecent 按访问时间倒序分页列出最近访问的对象
+func (r *GORMActivityRepository) ListRecent(query *models.ActivityQuery) ([]models.ActivityItem, string, error) {
+	return r.listActivity("recent_items", "accessed_at", "a.access_count", query)
+}
+
+// RemoveRecent 从最近访问中移除单个对象
+func (r *GORMActivityRepository) RemoveRecent(userID, itemType string, itemID uint) error {
+	return r.db.Where("user_id = ? AND item_type = ? AND item_id = ?", userID, itemType, itemID).Delete(&models.RecentItem{}).Error
+}
+
+// ClearRecent 清空用户的最近访问记录
+func (r *GORMActivityRepository) ClearRecent(userID string) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.RecentItem{}).Error
+}
+
+// SetRecentHistoryEnabled 开启或关闭最近访问记录，关闭时同时清空已有记录
+func (r *GORMActivityRepository) SetRecentHistoryEnabled(userID string, enabled bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("uuid = ?", userID).Update("recent_history_enabled", enabled).Error; err != nil {
+			return err
+		}
+		if enabled {
+			return nil
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecentItem{}).Error
+	})
+}
+
+// listActivity 分页列出收藏或最近访问记录，每类对象分别关联，只返回仍然存在的对象。
+// 以 (时间, 类型, ID) 作为游标键倒序排列，countExpr 为访问次数的取值表达式
+func (r *GORMActivityRepository) listActivity(table, timeColumn, countExpr string, query *models.ActivityQuery) ([]models.ActivityItem, string, error) {
+	var selects []string
+	var args []interface{}
+	for _, source := range tagItemTables {
+		if query.ItemType != "" && query.ItemType != source.itemType {
+			continue
+		}
+		selects = append(selects, `SELECT a.item_type, a.item_id, a.`+timeColumn+` AS activity_at, `+countExpr+` AS access_count, t.user_id AS owner_id
+			FROM `+table+` a JOIN `+source.table+` t ON t.id = a.item_id
+			WHERE a.user_id = ? AND a.item_type = ?`)
+		args = append(args, query.UserID, source.itemType)
+	}
+	if len(selects) == 0 {
+		return []models.ActivityItem{}, "", nil
+	}
+
+	sql := `SELECT x.item_type, x.item_id, x.activity_at, x.access_count, x.owner_id FROM (` + strings.Join(selects, " UNION ALL ") + `) x`
+	if query.Cursor != nil {
+		at, err := time.Parse(time.RFC3339Nano, query.Cursor.Value)
+		if err != nil {
+			return nil, "", models.ErrInvalidCursor
+		}
+		sql += ` WHERE (x.activity_at, x.item_type, x.item_id) < (?, ?, ?)`
+		args = append(args, at, query.Cursor.ItemType, query.Cursor.ID)
+	}
+	sql += ` ORDER BY x.activity_at DESC, x.item_type DESC, x.item_id DESC LIMIT ?`
+	args = append(args, query.Limit+1)
+
+	var rows []activityRow
+	if err := r.db.Raw(sql, args...).Scan(&rows).Error; err != nil {
+		return nil, "", err
+	}
+	var nextCursor string
+	if len(rows) > query.Limit {
+		rows = rows[:query.Limit]
+		last := rows[len(rows)-1]
+		nextCursor = (&models.SearchCursor{
+			Sort:     models.ActivitySortTime,
+			Desc:     true,
+			Value:    last.ActivityAt.Format(time.RFC3339Nano),
+			ItemType: last.ItemType,
+			ID:       last.ItemID,
+		}).Encode()
+	}
+
+	searchRows := make([]searchRow, 0, len(rows))
+	byKey := make(map[searchRow]activityRow, len(rows))
+	for _, row := range rows {
+		key := searchRow{ItemType: row.ItemType, ID: row.ItemID}
+		searchRows = append(searchRows, key)
+		byKey[key] = row
+	}
+	loaded, err := loadSearchItems(r.db, searchRows)
+	if err != nil {
+		return nil, "", err
+	}
+
+	items := make([]models.ActivityItem, 0, len(loaded))
+	for _, item := range loaded {
+		row := byKey[searchRow{ItemType: item.ItemType, ID: item.RecordID()}]
+		at := row.ActivityAt
+		entry := models.ActivityItem{SearchItem: item, OwnerID: row.OwnerID}
+		if table == "favorites" {
+			entry.FavoritedAt = &at
+		} else {
+			entry.AccessedAt = &at
+			entry.AccessCount = row.AccessCount
+		}
+		items = append(items, entry)
+	}
+	return items, nextCursor, nil
+}
+
+// itemLinkTables 按 (item_type, item_id) 引用文件、文件夹、URL文件的关联表，对象删除时需要同步清理
+var itemLinkTables = []string{"item_tags", "favorites", "recent_items"}
+
+// deleteItemLinks 删除对象被删除后残留的标签关联、收藏和最近访问记录，ids 可以是ID列表或子查询
+func deleteItemLinks(tx *gorm.DB, itemType string, ids interface{}) error {
+	for _, table := range itemLinkTables {
+		if err := tx.Exec("DELETE FROM "+table+" WHERE item_type = ? AND item_id IN (?)", itemType, ids).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
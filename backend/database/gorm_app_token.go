@@ -0,0 +1,62 @@
+package database
+
+import (
+	"time"
+
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GORMAppTokenRepository 应用专用密码仓库
+type GORMAppTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewGORMAppTokenRepository 创建应用专用密码仓库
+func NewGORMAppTokenRepository(db *gorm.DB) *GORMAppTokenRepository {
+	return &GORMAppTokenRepository{db: db}
+}
+
+// CreateAppToken 创建应用专用密码
+func (r *GORMAppTokenRepository) CreateAppToken(token *models.AppToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetAppTokensByUser 获取用户的全部应用专用密码，按创建时间倒序
+func (r *GORMAppTokenRepository) GetAppTokensByUser(userID string) ([]models.AppToken, error) {
+	var tokens []models.AppToken
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// CountAppTokens 统计用户的应用专用密码数量
+func (r *GORMAppTokenRepository) CountAppTokens(userID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.AppToken{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// GetAppTokenByHash 按摘要查找应用专用密码，不存在时返回 nil
+func (r *GORMAppTokenRepository) GetAppTokenByHash(tokenHash string) (*models.AppToken, error) {
+	var token models.AppToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// DeleteAppToken 删除用户的应用专用密码，返回是否删除了记录
+func (r *GORMAppTokenRepository) DeleteAppToken(tokenID uint, userID string) (bool, error) {
+	result := r.db.Where("id = ? AND user_id = ?", tokenID, userID).Delete(&models.AppToken{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// TouchAppToken 记录应用专用密码的最近使用时间
+func (r *GORMAppTokenRepository) TouchAppToken(tokenID uint) error {
+	return r.db.Model(&models.AppToken{}).Where("id = ?", tokenID).Update("last_used_at", time.Now()).Error
+}
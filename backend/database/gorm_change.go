@@ -0,0 +1,148 @@
+package database
+
+import (
+	"time"
+
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// changePruneInterval 每写入多少条变更清理一次该用户过期的变更记录
+const changePruneInterval = 200
+
+// GORMChangeRepository 变更日志仓库
+type GORMChangeRepository struct {
+	db *gorm.DB
+}
+
+// NewGORMChangeRepository 创建变更日志仓库
+func NewGORMChangeRepository(db *gorm.DB) *GORMChangeRepository {
+	return &GORMChangeRepository{db: db}
+}
+
+// GetLatestChangeSeq 获取用户最新的变更序号，还没有变更时为0
+func (r *GORMChangeRepository) GetLatestChangeSeq(userID string) (int64, error) {
+	var seq int64
+	err := r.db.Table("change_sequences").Select("seq").Where("user_id = ?", userID).Scan(&seq).Error
+	return seq, err
+}
+
+// GetLatestChangeSeqs 批量获取用户最新的变更序号，没有变更的用户不在结果中
+func (r *GORMChangeRepository) GetLatestChangeSeqs(userIDs []string) (map[string]int64, error) {
+	seqs := make(map[string]int64, len(userIDs))
+	if len(userIDs) == 0 {
+		return seqs, nil
+	}
+	var rows []struct {
+		UserID string
+		Seq    int64
+	}
+	if err := r.db.Table("change_sequences").Select("user_id", "seq").Where("user_id IN ?", userIDs).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		seqs[row.UserID] = row.Seq
+	}
+	return seqs, nil
+}
+
+// GetChangesSince 按序号升序获取 cursor 之后的变更，最多 limit 条，同时返回用户最新的变更序号。
+// cursor 超过最新序号或之后的变更已被清理时返回 models.ErrChangeCursorExpired
+func (r *GORMChangeRepository) GetChangesSince(userID string, cursor int64, limit int) ([]models.Change, int64, error) {
+	latest, err := r.GetLatestChangeSeq(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if cursor < 0 || cursor > latest {
+		return nil, latest, models.ErrChangeCursorExpired
+	}
+	changes := []models.Change{}
+	if cursor == latest {
+		return changes, latest, nil
+	}
+
+	if err := r.db.Where("user_id = ? AND seq > ?", userID, cursor).Order("seq").Limit(limit).Find(&changes).Error; err != nil {
+		return nil, 0, err
+	}
+	// 同一用户的序号连续，第一条不是 cursor+1 说明中间的记录已被清理
+	if len(changes) == 0 || changes[0].Seq != cursor+1 {
+		return nil, latest, models.ErrChangeCursorExpired
+	}
+	return changes, latest, nil
+}
+
+// recordChange 为用户分配下一个变更序号并写入变更，必须在修改数据的事务中调用：
+// 序号随事务一起回滚，序号行在提交前保持锁定，保证同一用户的变更按序号顺序提交、没有空缺
+func recordChange(tx *gorm.DB, userID string, change models.Change) error {
+	if err := tx.Exec(`INSERT INTO change_sequences (user_id, seq) VALUES (?, 1)
+		ON DUPLICATE KEY UPDATE seq = seq + 1`, userID).Error; err != nil {
+		return err
+	}
+	if err := tx.Table("change_sequences").Select("seq").Where("user_id = ?", userID).Scan(&change.Seq).Error; err != nil {
+		return err
+	}
+
+	change.UserID = userID
+	if err := tx.Create(&change).Error; err != nil {
+		return err
+	}
+	if change.Seq%changePruneInterval != 0 {
+		return nil
+	}
+	return tx.Where("user_id = ? AND created_at < ?", userID, time.Now().Add(-models.ChangeRetention)).Delete(&models.Change{}).Error
+}
+
+// fileChange 根据文件当前的状态生成变更记录
+func fileChange(action string, file *models.File) models.Change {
+	return models.Change{
+		ItemType: models.SearchItemFile,
+		ItemID:   file.ID,
+		Action:   action,
+		Name:     file.Name,
+		ParentID: file.FolderID,
+		Size:     file.Size,
+		Checksum: file.Checksum,
+	}
+}
+
+// folderChange 根据文件夹当前的状态生成变更记录
+func folderChange(action string, folder *models.Folder) models.Change {
+	return models.Change{
+		ItemType: models.SearchItemFolder,
+		ItemID:   folder.ID,
+		Action:   action,
+		Name:     folder.Name,
+		ParentID: folder.ParentID,
+	}
+}
+
+// urlFileChange 根据URL文件当前的状态生成变更记录
+func urlFileChange(action string, urlFile *models.UrlFile) models.Change {
+	return models.Change{
+		ItemType: models.SearchItemUrlFile,
+		ItemID:   urlFile.ID,
+		Action:   action,
+		Name:     urlFile.Title,
+		ParentID: urlFile.FolderID,
+	}
+}
+
+// movedAction 根据修改前后所在文件夹和名称判断变更类型
+func movedAction(oldParent, newParent *uint, oldName, newName string) string {
+	switch {
+	case !sameParent(oldParent, newParent):
+		return models.ChangeMove
+	case oldName != newName:
+		return models.ChangeRename
+	}
+	return models.ChangeUpdate
+}
+
+// sameParent 判断两个可空的文件夹ID是否相同
+func sameParent(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,329 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/models"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// changeStore 内存中的 change_sequences 与 changes 表，只支持变更日志仓库用到的语句，
+// 事务开始时保存快照，回滚时恢复
+type changeStore struct {
+	mu       sync.Mutex
+	seqs     map[string]int64
+	changes  []map[string]driver.Value
+	nextID   int64
+	snapshot *changeStore
+}
+
+// changeColumns changes 表的列，顺序与 models.Change 一致
+var changeColumns = []string{"id", "user_id", "seq", "item_type", "item_id", "action", "name", "parent_id", "size", "checksum", "created_at"}
+
+var insertColumnsPattern = regexp.MustCompile("^INSERT INTO `changes` \\((.+)\\) VALUES")
+
+func (s *changeStore) Connect(context.Context) (driver.Conn, error) {
+	return &changeConn{store: s}, nil
+}
+
+func (s *changeStore) Driver() driver.Driver { return nil }
+
+// newTestChangeRepository 创建使用内存表的变更日志仓库
+func newTestChangeRepository(t *testing.T) (*GORMChangeRepository, *changeStore) {
+	t.Helper()
+	store := &changeStore{seqs: map[string]int64{}}
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sql.OpenDB(store), SkipInitializeWithVersion: true}),
+		&gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	return NewGORMChangeRepository(db), store
+}
+
+type changeConn struct {
+	store *changeStore
+}
+
+func (c *changeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("不支持预处理语句")
+}
+
+func (c *changeConn) Close() error { return nil }
+
+func (c *changeConn) Begin() (driver.Tx, error) {
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := &changeStore{seqs: make(map[string]int64, len(s.seqs)), changes: append([]map[string]driver.Value(nil), s.changes...), nextID: s.nextID}
+	for userID, seq := range s.seqs {
+		snapshot.seqs[userID] = seq
+	}
+	s.snapshot = snapshot
+	return c, nil
+}
+
+func (c *changeConn) Commit() error {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	c.store.snapshot = nil
+	return nil
+}
+
+func (c *changeConn) Rollback() error {
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seqs, s.changes, s.nextID = s.snapshot.seqs, s.snapshot.changes, s.snapshot.nextID
+	s.snapshot = nil
+	return nil
+}
+
+func (c *changeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "INSERT INTO change_sequences"):
+		s.seqs[args[0].Value.(string)]++
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(query, "INSERT INTO `changes`"):
+		s.nextID++
+		row := map[string]driver.Value{"id": s.nextID, "created_at": time.Now()}
+		columns := insertColumnsPattern.FindStringSubmatch(query)[1]
+		for i, column := range strings.Split(columns, ",") {
+			row[strings.Trim(column, "`")] = args[i].Value
+		}
+		s.changes = append(s.changes, row)
+		return changeInsertResult(s.nextID), nil
+	case query == "DELETE FROM `changes` WHERE user_id = ? AND created_at < ?":
+		kept := s.changes[:0:0]
+		for _, row := range s.changes {
+			if row["user_id"] != args[0].Value || !row["created_at"].(time.Time).Before(args[1].Value.(time.Time)) {
+				kept = append(kept, row)
+			}
+		}
+		affected := len(s.changes) - len(kept)
+		s.changes = kept
+		return driver.RowsAffected(affected), nil
+	}
+	return nil, fmt.Errorf("不支持的语句: %s", query)
+}
+
+func (c *changeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := &changeRows{}
+	switch query {
+	case "SELECT seq FROM `change_sequences` WHERE user_id = ?":
+		rows.columns = []string{"seq"}
+		if seq, ok := s.seqs[args[0].Value.(string)]; ok {
+			rows.values = append(rows.values, []driver.Value{seq})
+		}
+	case "SELECT user_id,seq FROM `change_sequences` WHERE user_id IN (" + strings.TrimSuffix(strings.Repeat("?,", len(args)), ",") + ")":
+		rows.columns = []string{"user_id", "seq"}
+		for _, arg := range args {
+			if seq, ok := s.seqs[arg.Value.(string)]; ok {
+				rows.values = append(rows.values, []driver.Value{arg.Value, seq})
+			}
+		}
+	case "SELECT * FROM `changes` WHERE user_id = ? AND seq > ? ORDER BY seq LIMIT ?":
+		// 写入顺序即序号顺序
+		rows.columns = changeColumns
+		for _, row := range s.changes {
+			if len(rows.values) == int(args[2].Value.(int64)) {
+				break
+			}
+			if row["user_id"] == args[0].Value && row["seq"].(int64) > args[1].Value.(int64) {
+				values := make([]driver.Value, len(changeColumns))
+				for i, column := range changeColumns {
+					values[i] = row[column]
+				}
+				rows.values = append(rows.values, values)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("不支持的查询: %s", query)
+	}
+	return rows, nil
+}
+
+// changeInsertResult 插入结果，返回自增ID
+type changeInsertResult int64
+
+func (r changeInsertResult) LastInsertId() (int64, error) { return int64(r), nil }
+func (r changeInsertResult) RowsAffected() (int64, error) { return 1, nil }
+
+type changeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *changeRows) Columns() []string { return r.columns }
+func (r *changeRows) Close() error      { return nil }
+
+func (r *changeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// recordTestChange 在单独的事务中为用户写入一条变更
+func recordTestChange(t *testing.T, repo *GORMChangeRepository, userID string, itemID uint) {
+	t.Helper()
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		return recordChange(tx, userID, models.Change{ItemType: models.SearchItemFile, ItemID: itemID, Action: models.ChangeCreate, Name: fmt.Sprintf("文件%d", itemID)})
+	})
+	if err != nil {
+		t.Fatalf("写入变更失败: %v", err)
+	}
+}
+
+// changeSeqs 取出变更的序号
+func changeSeqs(changes []models.Change) []int64 {
+	seqs := make([]int64, 0, len(changes))
+	for _, change := range changes {
+		seqs = append(seqs, change.Seq)
+	}
+	return seqs
+}
+
+func TestRecordChangeAllocatesSequencePerUser(t *testing.T) {
+	repo, _ := newTestChangeRepository(t)
+
+	// 两个用户交替写入，各自的序号从1开始连续递增
+	for i, userID := range []string{"alice", "bob", "alice", "alice", "bob"} {
+		recordTestChange(t, repo, userID, uint(i+1))
+	}
+
+	for userID, want := range map[string][]int64{"alice": {1, 2, 3}, "bob": {1, 2}} {
+		changes, latest, err := repo.GetChangesSince(userID, 0, 10)
+		if err != nil {
+			t.Fatalf("%s: 读取变更失败: %v", userID, err)
+		}
+		if got := changeSeqs(changes); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: 序号 = %v，期望 %v", userID, got, want)
+		}
+		if latest != want[len(want)-1] {
+			t.Errorf("%s: 最新序号 = %d，期望 %d", userID, latest, want[len(want)-1])
+		}
+		for _, change := range changes {
+			if change.UserID != userID {
+				t.Errorf("%s: 读到了 %s 的变更", userID, change.UserID)
+			}
+		}
+	}
+
+	seqs, err := repo.GetLatestChangeSeqs([]string{"alice", "bob", "carol"})
+	if err != nil {
+		t.Fatalf("批量读取序号失败: %v", err)
+	}
+	if want := map[string]int64{"alice": 3, "bob": 2}; !reflect.DeepEqual(seqs, want) {
+		t.Errorf("GetLatestChangeSeqs = %v，期望 %v", seqs, want)
+	}
+	if seq, err := repo.GetLatestChangeSeq("carol"); err != nil || seq != 0 {
+		t.Errorf("没有变更的用户: 序号 = %d，错误 %v，期望 0", seq, err)
+	}
+}
+
+func TestRecordChangeRollsBackWithTransaction(t *testing.T) {
+	repo, _ := newTestChangeRepository(t)
+	recordTestChange(t, repo, "alice", 1)
+
+	errAbort := errors.New("中止")
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := recordChange(tx, "alice", models.Change{ItemType: models.SearchItemFile, ItemID: 2, Action: models.ChangeCreate, Name: "文件2"}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("事务错误 = %v，期望 %v", err, errAbort)
+	}
+
+	// 回滚的事务不占用序号，后续变更仍然连续
+	recordTestChange(t, repo, "alice", 3)
+	changes, latest, err := repo.GetChangesSince("alice", 0, 10)
+	if err != nil {
+		t.Fatalf("读取变更失败: %v", err)
+	}
+	if got := changeSeqs(changes); !reflect.DeepEqual(got, []int64{1, 2}) || latest != 2 {
+		t.Errorf("序号 = %v，最新序号 %d，期望 [1 2] 和 2", got, latest)
+	}
+	if changes[1].ItemID != 3 {
+		t.Errorf("序号2对应对象 %d，期望回滚后写入的对象 3", changes[1].ItemID)
+	}
+}
+
+func TestGetChangesSince(t *testing.T) {
+	tests := []struct {
+		name    string
+		pruned  int64 // 清理掉序号不超过该值的变更
+		cursor  int64
+		limit   int
+		want    []int64
+		wantErr error
+	}{
+		{name: "从头读取", cursor: 0, limit: 10, want: []int64{1, 2, 3, 4, 5}},
+		{name: "从指定序号之后读取", cursor: 2, limit: 10, want: []int64{3, 4, 5}},
+		{name: "按数量分页", cursor: 1, limit: 2, want: []int64{2, 3}},
+		{name: "已是最新", cursor: 5, limit: 10, want: []int64{}},
+		{name: "游标超过最新序号", cursor: 6, limit: 10, wantErr: models.ErrChangeCursorExpired},
+		{name: "负数游标", cursor: -1, limit: 10, wantErr: models.ErrChangeCursorExpired},
+		{name: "之后的变更已被清理", pruned: 2, cursor: 1, limit: 10, wantErr: models.ErrChangeCursorExpired},
+		{name: "清理点之后的游标仍然有效", pruned: 2, cursor: 2, limit: 10, want: []int64{3, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, store := newTestChangeRepository(t)
+			for i := uint(1); i <= 5; i++ {
+				recordTestChange(t, repo, "alice", i)
+				recordTestChange(t, repo, "bob", i)
+			}
+			kept := store.changes[:0:0]
+			for _, row := range store.changes {
+				if row["seq"].(int64) > tt.pruned {
+					kept = append(kept, row)
+				}
+			}
+			store.changes = kept
+
+			changes, latest, err := repo.GetChangesSince("alice", tt.cursor, tt.limit)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("错误 = %v，期望 %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("读取变更失败: %v", err)
+			}
+			if got := changeSeqs(changes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("序号 = %v，期望 %v", got, tt.want)
+			}
+			if latest != 5 {
+				t.Errorf("最新序号 = %d，期望 5", latest)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"strings"
+
+	"backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GORMFileContentRepository 文件内容全文索引仓库
+type GORMFileContentRepository struct {
+	db *gorm.DB
+}
+
+// NewGORMFileContentRepository 创建文件内容索引仓库
+func NewGORMFileContentRepository(db *gorm.DB) *GORMFileContentRepository {
+	return &GORMFileContentRepository{db: db}
+}
+
+// staleContentCondition 没有索引记录，或文件存储路径、校验和与建立索引时不一致
+const staleContentCondition = `(c.file_id IS NULL OR c.source_path <> f.path OR c.source_checksum <> COALESCE(f.checksum, ''))`
+
+// GetFilesToIndex 获取需要建立或重建内容索引的文件，不读取缩略图数据。
+// 保险库文件为客户端加密的密文，不建立索引
+func (r *GORMFileContentRepository) GetFilesToIndex(limit int) ([]models.File, error) {
+	var files []models.File
+	err := r.db.Table("files f").
+		Select("f.id, f.name, f.size, f.type, f.path, f.user_id, f.folder_id, f.checksum, f.encryption_key_id").
+		Joins("LEFT JOIN file_contents c ON c.file_id = f.id").
+		Where("f.vault_id IS NULL").
+		Where(staleContentCondition).
+		Order("f.id").
+		Limit(limit).
+		Scan(&files).Error
+	return files, err
+}
+
+// UpsertContent 写入或覆盖文件的内容索引
+func (r *GORMFileContentRepository) UpsertContent(content *models.FileContent) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(content).Error
+}
+
+// DeleteContent 删除文件的内容索引
+func (r *GORMFileContentRepository) DeleteContent(fileID uint) error {
+	return r.db.Where("file_id = ?", fileID).Delete(&models.FileContent{}).Error
+}
+
+// ResetUserContents 删除用户全部文件的内容索引，后台索引任务会重新建立
+func (r *GORMFileContentRepository) ResetUserContents(userID string) (int64, error) {
+	result := r.db.Where("user_id = ?", userID).Delete(&models.FileContent{})
+	return result.RowsAffected, result.Error
+}
+
+// GetIndexStatus 统计用户文件的内容索引状态，不包括保险库文件
+func (r *GORMFileContentRepository) GetIndexStatus(userID string) (*models.ContentIndexStatus, error) {
+	status := &models.ContentIndexStatus{}
+	err := r.db.Raw(`SELECT COUNT(*) AS total_files,
+			COALESCE(SUM(CASE WHEN `+staleContentCondition+` THEN 1 ELSE 0 END), 0) AS pending,
+			COALESCE(SUM(CASE WHEN NOT `+staleContentCondition+` AND c.status = ? THEN 1 ELSE 0 END), 0) AS indexed,
+			COALESCE(SUM(CASE WHEN NOT `+staleContentCondition+` AND c.status = ? THEN 1 ELSE 0 END), 0) AS unsupported,
+			COALESCE(SUM(CASE WHEN NOT `+staleContentCondition+` AND c.status = ? THEN 1 ELSE 0 END), 0) AS failed
+		FROM files f LEFT JOIN file_contents c ON c.file_id = f.id
+		WHERE f.user_id = ? AND f.vault_id IS NULL`,
+		models.ContentIndexed, models.ContentUnsupported, models.ContentFailed, userID).Scan(status).Error
+	return status, err
+}
+
+// SearchContent 在范围内的文件内容中按短语全文检索，按相关度排序。
+// 已删除或内容已变化但尚未重建索引的文件不会返回
+func (r *GORMFileContentRepository) SearchContent(phrase string, scope models.ContentSearchScope, limit, offset int) ([]models.ContentSearchHit, int64, error) {
+	if scope.IsEmpty() {
+		return nil, 0, nil
+	}
+
+	// 布尔模式下按完整短语匹配，去掉会破坏短语语法的双引号
+	against := `"` + strings.ReplaceAll(phrase, `"`, " ") + `"`
+
+	var scopeConds []string
+	var scopeArgs []interface{}
+	if scope.OwnerID != "" {
+		scopeConds = append(scopeConds, "f.user_id = ?")
+		scopeArgs = append(scopeArgs, scope.OwnerID)
+	}
+	if len(scope.FolderIDs) > 0 {
+		scopeConds = append(scopeConds, "f.folder_id IN ?")
+		scopeArgs = append(scopeArgs, scope.FolderIDs)
+	}
+	if len(scope.FileIDs) > 0 {
+		scopeConds = append(scopeConds, "f.id IN ?")
+		scopeArgs = append(scopeArgs, scope.FileIDs)
+	}
+
+	query := r.db.Table("file_contents c").
+		Joins("JOIN files f ON f.id = c.file_id AND f.path = c.source_path").
+		Where("c.status = ?", models.ContentIndexed).
+		Where("MATCH(c.content) AGAINST(? IN BOOLEAN MODE)", against).
+		Where("("+strings.Join(scopeConds, " OR ")+")", scopeArgs...).
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var hits []models.ContentSearchHit
+	err := query.
+		Select("f.id, f.name, f.size, f.type, f.path, f.user_id, f.folder_id, f.checksum, f.uploaded_by, f.created_at, f.updated_at, "+
+			"MATCH(c.content) AGAINST(? IN BOOLEAN MODE) AS score, c.content", against).
+		Order("score DESC, f.id DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&hits).Error
+	return hits, total, err
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"time"
+
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GORMFileScanRepository 文件病毒扫描状态仓库
+type GORMFileScanRepository struct {
+	db *gorm.DB
+}
+
+// NewGORMFileScanRepository 创建文件病毒扫描状态仓库
+func NewGORMFileScanRepository(db *gorm.DB) *GORMFileScanRepository {
+	return &GORMFileScanRepository{db: db}
+}
+
+// GetFilesToScan 获取等待后台扫描的文件，按ID顺序最多返回 limit 个
+func (r *GORMFileScanRepository) GetFilesToScan(limit int) ([]models.File, error) {
+	var files []models.File
+	err := r.db.Select(fileListColumnsWithoutThumbnail).
+		Where("scan_status = ?", models.ScanStatusPending).
+		Order("id").Limit(limit).Find(&files).Error
+	return files, err
+}
+
+// GetFilesAfterID 按ID顺序获取 afterID 之后的文件，用于全量重新扫描
+func (r *GORMFileScanRepository) GetFilesAfterID(afterID uint, limit int) ([]models.File, error) {
+	var files []models.File
+	err := r.db.Select(fileListColumnsWithoutThumbnail).
+		Where("id > ?", afterID).
+		Order("id").Limit(limit).Find(&files).Error
+	return files, err
+}
+
+// CountFiles 统计全部文件数量
+func (r *GORMFileScanRepository) CountFiles() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.File{}).Count(&count).Error
+	return count, err
+}
+
+// UpdateScanResult 写入扫描结果。只有文件仍指向被扫描的内容（path 未变）时才更新，
+// 扫描期间内容被替换或重命名时返回 false，由后续扫描处理新内容。
+// 文件被隔离后无法下载，记录一条修改变更通知同步客户端
+func (r *GORMFileScanRepository) UpdateScanResult(fileID uint, path, status, result string) (bool, error) {
+	updated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		db := tx.Model(&models.File{}).Where("id = ? AND path = ?", fileID, path).
+			UpdateColumns(map[string]interface{}{
+				"scan_status": status,
+				"scan_result": result,
+				"scanned_at":  time.Now(),
+			})
+		if db.Error != nil || db.RowsAffected == 0 || status != models.ScanStatusInfected {
+			updated = db.RowsAffected > 0
+			return db.Error
+		}
+		updated = true
+
+		var file models.File
+		if err := tx.Select("id", "name", "size", "checksum", "folder_id", "user_id").Where("id = ?", fileID).Take(&file).Error; err != nil {
+			return err
+		}
+		return recordChange(tx, file.UserID, fileChange(models.ChangeUpdate, &file))
+	})
+	return updated, err
+}
+
+// GetScanStatusCounts 按扫描状态统计文件数量
+func (r *GORMFileScanRepository) GetScanStatusCounts() ([]models.ScanStatusCount, error) {
+	var counts []models.ScanStatusCount
+	err := r.db.Model(&models.File{}).
+		Select("COALESCE(scan_status, '') AS scan_status, COUNT(*) AS count").
+		Group("COALESCE(scan_status, '')").Order("scan_status").Scan(&counts).Error
+	return counts, err
+}
+
+// GetInfectedFiles 按扫描时间倒序分页获取被隔离的文件
+func (r *GORMFileScanRepository) GetInfectedFiles(limit, offset int) ([]models.File, int64, error) {
+	query := r.db.Model(&models.File{}).Where("scan_status = ?", models.ScanStatusInfected)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var files []models.File
+	err := query.Select(fileListColumnsWithoutThumbnail).
+		Order("scanned_at DESC, id DESC").Limit(limit).Offset(offset).Find(&files).Error
+	return files, total, err
+}
@@ -0,0 +1,344 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// searchSortColumns 排序字段对应的查询列
+var searchSortColumns = map[string]string{
+	models.SearchSortName:      "name",
+	models.SearchSortSize:      "size",
+	models.SearchSortCreatedAt: "created_at",
+	models.SearchSortUpdatedAt: "updated_at",
+}
+
+// searchRow 分页查询返回的轻量记录，完整数据按ID二次加载
+type searchRow struct {
+	ItemType  string
+	ID        uint
+	Name      string
+	Size      int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// SearchItems 按结构化条件搜索文件、URL文件，以及按需包含的文件夹。
+// 两类记录通过 UNION ALL 合并后统一排序，使用 (排序值, 类型, ID) 作为游标键进行分页
+func (r *GORMFileRepository) SearchItems(query *models.FileSearchQuery) (*models.FileSearchPage, error) {
+	page := &models.FileSearchPage{Items: []models.SearchItem{}}
+	column, ok := searchSortColumns[query.Sort]
+	if !ok {
+		return nil, fmt.Errorf("不支持的排序字段: %s", query.Sort)
+	}
+
+	var selects []string
+	var args []interface{}
+	if query.IncludeFiles {
+		sql, selectArgs := fileSearchSelect(query)
+		selects = append(selects, sql)
+		args = append(args, selectArgs...)
+	}
+	if query.IncludeFolders {
+		sql, selectArgs := folderSearchSelect(query)
+		selects = append(selects, sql)
+		args = append(args, selectArgs...)
+	}
+	if query.IncludeUrls {
+		sql, selectArgs := urlFileSearchSelect(query)
+		selects = append(selects, sql)
+		args = append(args, selectArgs...)
+	}
+	if len(selects) == 0 {
+		return page, nil
+	}
+
+	direction, comparison := "ASC", ">"
+	if query.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	sql := `SELECT t.item_type, t.id, t.name, t.size, t.created_at, t.updated_at FROM (` +
+		strings.Join(selects, " UNION ALL ") + `) t`
+	if query.Cursor != nil {
+		value, err := searchCursorValue(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		sql += fmt.Sprintf(` WHERE (t.%s, t.item_type, t.id) %s (?, ?, ?)`, column, comparison)
+		args = append(args, value, query.Cursor.ItemType, query.Cursor.ID)
+	}
+	sql += fmt.Sprintf(` ORDER BY t.%[1]s %[2]s, t.item_type %[2]s, t.id %[2]s LIMIT ?`, column, direction)
+	args = append(args, query.Limit+1)
+
+	var rows []searchRow
+	if err := r.db.Raw(sql, args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) > query.Limit {
+		rows = rows[:query.Limit]
+		page.HasMore = true
+	}
+	if len(rows) == 0 {
+		return page, nil
+	}
+
+	items, err := loadSearchItems(r.db, rows)
+	if err != nil {
+		return nil, err
+	}
+	page.Items = items
+
+	if page.HasMore {
+		page.NextCursor = newSearchCursor(query, rows[len(rows)-1]).Encode()
+	}
+	return page, nil
+}
+
+// fileSearchSelect 构造普通文件的查询语句，保险库文件的名称为密文，不参与搜索
+func fileSearchSelect(query *models.FileSearchQuery) (string, []interface{}) {
+	conds := []string{"user_id = ?", "vault_id IS NULL"}
+	args := []interface{}{query.OwnerID}
+
+	if query.Keyword != "" {
+		pattern := "%" + escapeLike(query.Keyword) + "%"
+		conds = append(conds, "(name LIKE ? OR description LIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+	var types []string
+	for _, fileType := range query.Types {
+		if fileType != models.SearchTypeUrl {
+			types = append(types, fileType)
+		}
+	}
+	if len(types) > 0 {
+		conds = append(conds, "type IN ?")
+		args = append(args, types)
+	}
+	if len(query.Extensions) > 0 {
+		var extConds []string
+		for _, ext := range query.Extensions {
+			extConds = append(extConds, "LOWER(name) LIKE ?")
+			args = append(args, "%."+escapeLike(ext))
+		}
+		conds = append(conds, "("+strings.Join(extConds, " OR ")+")")
+	}
+	if query.MinSize != nil {
+		conds = append(conds, "size >= ?")
+		args = append(args, *query.MinSize)
+	}
+	if query.MaxSize != nil {
+		conds = append(conds, "size <= ?")
+		args = append(args, *query.MaxSize)
+	}
+	conds, args = appendSearchCommonConds(query, conds, args, models.SearchItemFile, "folder_id")
+	conds, args = appendMetadataConds(query, conds, args)
+
+	return `SELECT 'file' AS item_type, id, name, size, created_at, updated_at FROM files WHERE ` +
+		strings.Join(conds, " AND "), args
+}
+
+// urlFileSearchSelect 构造URL文件的查询语句，关键词同时匹配标题、链接和描述
+func urlFileSearchSelect(query *models.FileSearchQuery) (string, []interface{}) {
+	conds := []string{"user_id = ?"}
+	args := []interface{}{query.OwnerID}
+
+	if query.Keyword != "" {
+		pattern := "%" + escapeLike(query.Keyword) + "%"
+		conds = append(conds, "(title LIKE ? OR url LIKE ? OR description LIKE ?)")
+		args = append(args, pattern, pattern, pattern)
+	}
+	conds, args = appendSearchCommonConds(query, conds, args, models.SearchItemUrlFile, "folder_id")
+
+	return `SELECT 'url_file' AS item_type, id, title AS name, 0 AS size, created_at, updated_at FROM url_files WHERE ` +
+		strings.Join(conds, " AND "), args
+}
+
+// folderSearchSelect 构造文件夹的查询语句，所在文件夹对应 parent_id，不包括保险库文件夹
+func folderSearchSelect(query *models.FileSearchQuery) (string, []interface{}) {
+	conds := []string{"user_id = ?", "vault_id IS NULL"}
+	args := []interface{}{query.OwnerID}
+
+	if query.Keyword != "" {
+		pattern := "%" + escapeLike(query.Keyword) + "%"
+		conds = append(conds, "(name LIKE ? OR description LIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+	conds, args = appendSearchCommonConds(query, conds, args, models.SearchItemFolder, "parent_id")
+	conds, args = appendMetadataConds(query, conds, args)
+
+	return `SELECT 'folder' AS item_type, id, name, 0 AS size, created_at, updated_at FROM folders WHERE ` +
+		strings.Join(conds, " AND "), args
+}
+
+// appendSearchCommonConds 追加各类记录共有的时间、所在文件夹和标签条件，
+// itemType 为记录在标签关联表中的类型，parentColumn 为所在文件夹的列名
+func appendSearchCommonConds(query *models.FileSearchQuery, conds []string, args []interface{}, itemType, parentColumn string) ([]string, []interface{}) {
+	if query.CreatedFrom != nil {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, *query.CreatedFrom)
+	}
+	if query.CreatedTo != nil {
+		conds = append(conds, "created_at < ?")
+		args = append(args, *query.CreatedTo)
+	}
+	if query.UpdatedFrom != nil {
+		conds = append(conds, "updated_at >= ?")
+		args = append(args, *query.UpdatedFrom)
+	}
+	if query.UpdatedTo != nil {
+		conds = append(conds, "updated_at < ?")
+		args = append(args, *query.UpdatedTo)
+	}
+	if query.RootOnly {
+		conds = append(conds, parentColumn+" IS NULL")
+	} else if query.FolderIDs != nil {
+		conds = append(conds, parentColumn+" IN ?")
+		args = append(args, query.FolderIDs)
+	}
+	if len(query.TagIDs) > 0 {
+		tagSQL := "SELECT item_id FROM item_tags WHERE item_type = ? AND tag_id IN ?"
+		if query.TagMatchAll && len(query.TagIDs) > 1 {
+			tagSQL += " GROUP BY item_id HAVING COUNT(DISTINCT tag_id) = ?"
+			args = append(args, itemType, query.TagIDs, len(query.TagIDs))
+		} else {
+			args = append(args, itemType, query.TagIDs)
+		}
+		conds = append(conds, "id IN ("+tagSQL+")")
+	}
+	return conds, args
+}
+
+// appendMetadataConds 追加自定义元数据条件：只给出键时要求存在该键，给出值时匹配其中任一类型的值
+func appendMetadataConds(query *models.FileSearchQuery, conds []string, args []interface{}) ([]string, []interface{}) {
+	for _, filter := range query.Metadata {
+		if len(filter.Values) == 0 {
+			conds = append(conds, "JSON_CONTAINS_PATH(metadata, 'one', ?)")
+			args = append(args, `$."`+filter.Key+`"`)
+			continue
+		}
+		valueConds := make([]string, 0, len(filter.Values))
+		for _, value := range filter.Values {
+			candidate, _ := json.Marshal(map[string]interface{}{filter.Key: value})
+			valueConds = append(valueConds, "JSON_CONTAINS(metadata, ?)")
+			args = append(args, string(candidate))
+		}
+		conds = append(conds, "("+strings.Join(valueConds, " OR ")+")")
+	}
+	return conds, args
+}
+
+// escapeLike 转义 LIKE 模式中的通配符
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// searchCursorValue 将游标中的排序值转换为查询参数
+func searchCursorValue(cursor *models.SearchCursor) (interface{}, error) {
+	switch cursor.Sort {
+	case models.SearchSortName:
+		return cursor.Value, nil
+	case models.SearchSortSize, models.ListSortOrder:
+		number, err := strconv.ParseInt(cursor.Value, 10, 64)
+		if err != nil {
+			return nil, models.ErrInvalidCursor
+		}
+		return number, nil
+	default:
+		value, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, models.ErrInvalidCursor
+		}
+		return value, nil
+	}
+}
+
+// newSearchCursor 根据本页最后一条记录生成下一页游标
+func newSearchCursor(query *models.FileSearchQuery, last searchRow) *models.SearchCursor {
+	cursor := &models.SearchCursor{Sort: query.Sort, Desc: query.Desc, ItemType: last.ItemType, ID: last.ID}
+	switch query.Sort {
+	case models.SearchSortName:
+		cursor.Value = last.Name
+	case models.SearchSortSize:
+		cursor.Value = strconv.FormatInt(last.Size, 10)
+	case models.SearchSortCreatedAt:
+		cursor.Value = last.CreatedAt.Format(time.RFC3339Nano)
+	default:
+		cursor.Value = last.UpdatedAt.Format(time.RFC3339Nano)
+	}
+	return cursor
+}
+
+// loadSearchItems 按ID加载完整记录并保持分页查询的顺序
+func loadSearchItems(db *gorm.DB, rows []searchRow) ([]models.SearchItem, error) {
+	var fileIDs, folderIDs, urlFileIDs []uint
+	for _, row := range rows {
+		switch row.ItemType {
+		case models.SearchItemFile:
+			fileIDs = append(fileIDs, row.ID)
+		case models.SearchItemFolder:
+			folderIDs = append(folderIDs, row.ID)
+		default:
+			urlFileIDs = append(urlFileIDs, row.ID)
+		}
+	}
+
+	files := make(map[uint]*models.File, len(fileIDs))
+	if len(fileIDs) > 0 {
+		var records []models.File
+		if err := db.Where("id IN ?", fileIDs).Find(&records).Error; err != nil {
+			return nil, err
+		}
+		for i := range records {
+			files[records[i].ID] = &records[i]
+		}
+	}
+
+	folders := make(map[uint]*models.Folder, len(folderIDs))
+	if len(folderIDs) > 0 {
+		var records []models.Folder
+		if err := db.Where("id IN ?", folderIDs).Find(&records).Error; err != nil {
+			return nil, err
+		}
+		for i := range records {
+			folders[records[i].ID] = &records[i]
+		}
+	}
+
+	urlFiles := make(map[uint]*models.UrlFile, len(urlFileIDs))
+	if len(urlFileIDs) > 0 {
+		var records []models.UrlFile
+		if err := db.Where("id IN ?", urlFileIDs).Find(&records).Error; err != nil {
+			return nil, err
+		}
+		for i := range records {
+			urlFiles[records[i].ID] = &records[i]
+		}
+	}
+
+	items := make([]models.SearchItem, 0, len(rows))
+	for _, row := range rows {
+		item := models.SearchItem{ItemType: row.ItemType}
+		switch row.ItemType {
+		case models.SearchItemFile:
+			item.File = files[row.ID]
+		case models.SearchItemFolder:
+			item.Folder = folders[row.ID]
+		default:
+			item.UrlFile = urlFiles[row.ID]
+		}
+		// 两次查询之间被删除的记录直接跳过
+		if item.File == nil && item.Folder == nil && item.UrlFile == nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
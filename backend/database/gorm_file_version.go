@@ -0,0 +1,59 @@
+package database
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GORMFileVersionRepository GORM 文件版本仓库
+type GORMFileVersionRepository struct {
+	db *gorm.DB
+}
+
+// NewGORMFileVersionRepository 创建 GORM 文件版本仓库
+func NewGORMFileVersionRepository(db *gorm.DB) *GORMFileVersionRepository {
+	return &GORMFileVersionRepository{db: db}
+}
+
+func (r *GORMFileVersionRepository) CreateVersion(version *models.FileVersion) error {
+	return r.db.Create(version).Error
+}
+
+// GetVersionsByFileID 获取文件的所有历史版本（按版本号倒序）
+func (r *GORMFileVersionRepository) GetVersionsByFileID(fileID uint, userID string) ([]models.FileVersion, error) {
+	var versions []models.FileVersion
+	err := r.db.Where("file_id = ? AND user_id = ?", fileID, userID).Order("version DESC").Find(&versions).Error
+	return versions, err
+}
+
+func (r *GORMFileVersionRepository) GetVersionByID(versionID, fileID uint, userID string) (*models.FileVersion, error) {
+	var version models.FileVersion
+	err := r.db.Where("id = ? AND file_id = ? AND user_id = ?", versionID, fileID, userID).First(&version).Error
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// GetNextVersionNumber 获取文件下一个可用的版本号
+func (r *GORMFileVersionRepository) GetNextVersionNumber(fileID uint) (int, error) {
+	var maxVersion int
+	err := r.db.Model(&models.FileVersion{}).Where("file_id = ?", fileID).Select("COALESCE(MAX(version), 0)").Scan(&maxVersion).Error
+	return maxVersion + 1, err
+}
+
+func (r *GORMFileVersionRepository) DeleteVersion(versionID uint, userID string) error {
+	return r.db.Where("id = ? AND user_id = ?", versionID, userID).Delete(&models.FileVersion{}).Error
+}
+
+func (r *GORMFileVersionRepository) DeleteVersionsByFileID(fileID uint, userID string) error {
+	return r.db.Where("file_id = ? AND user_id = ?", fileID, userID).Delete(&models.FileVersion{}).Error
+}
+
+// GetUserVersionStorage 获取用户历史版本占用的存储空间
+func (r *GORMFileVersionRepository) GetUserVersionStorage(userID string) (int64, error) {
+	var totalSize int64
+	err := r.db.Model(&models.FileVersion{}).Where("user_id = ?", userID).Select("COALESCE(SUM(size), 0)").Scan(&totalSize).Error
+	return totalSize, err
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"backend/models"
+)
+
+// folderStatsJoins 按文件夹汇总直接包含的文件数量、字节数和URL文件数量
+const folderStatsJoins = `
+	LEFT JOIN (SELECT folder_id, COUNT(*) AS file_count, COALESCE(SUM(size), 0) AS size
+		FROM files WHERE user_id = ? AND folder_id IS NOT NULL GROUP BY folder_id) fs ON fs.folder_id = t.id
+	LEFT JOIN (SELECT folder_id, COUNT(*) AS url_file_count
+		FROM url_files WHERE user_id = ? AND folder_id IS NOT NULL GROUP BY folder_id) us ON us.folder_id = t.id`
+
+// folderStatsColumns 文件夹树节点的查询列
+const folderStatsColumns = `t.id, t.name, t.category, t.parent_id, t.created_at, t.updated_at,
+	COALESCE(fs.file_count, 0) AS file_count, COALESCE(fs.size, 0) AS size, COALESCE(us.url_file_count, 0) AS url_file_count`
+
+// GetFolderTree 获取文件夹树的扁平节点列表及每个文件夹直接包含的统计。
+// rootID 为 nil 时返回用户的全部文件夹；否则使用递归CTE只查询该文件夹及其子孙
+func (r *GORMFolderRepository) GetFolderTree(userID string, rootID *uint) ([]*models.FolderTreeNode, error) {
+	var nodes []*models.FolderTreeNode
+	if rootID == nil {
+		err := r.db.Raw(`SELECT `+folderStatsColumns+` FROM folders t`+folderStatsJoins+` WHERE t.user_id = ?`,
+			userID, userID, userID).Scan(&nodes).Error
+		return nodes, err
+	}
+
+	err := r.db.Raw(`WITH RECURSIVE t AS (
+			SELECT id, name, category, parent_id, created_at, updated_at, 0 AS depth
+			FROM folders WHERE id = ? AND user_id = ?
+			UNION ALL
+			SELECT f.id, f.name, f.category, f.parent_id, f.created_at, f.updated_at, t.depth + 1
+			FROM folders f JOIN t ON f.parent_id = t.id
+			WHERE f.user_id = ? AND t.depth < ?
+		)
+		SELECT `+folderStatsColumns+` FROM t`+folderStatsJoins,
+		*rootID, userID, userID, maxFolderDepth, userID, userID).Scan(&nodes).Error
+	return nodes, err
+}
+
+// GetFolderPath 使用递归CTE获取文件夹的祖先链，按从最上层到当前文件夹的顺序返回
+func (r *GORMFolderRepository) GetFolderPath(folderID uint, userID string) ([]models.Folder, error) {
+	var path []models.Folder
+	err := r.db.Raw(`WITH RECURSIVE ancestors AS (
+			SELECT id, name, user_id, category, parent_id, created_at, updated_at, 0 AS depth
+			FROM folders WHERE id = ? AND user_id = ?
+			UNION ALL
+			SELECT f.id, f.name, f.user_id, f.category, f.parent_id, f.created_at, f.updated_at, a.depth + 1
+			FROM folders f JOIN ancestors a ON f.id = a.parent_id
+			WHERE f.user_id = ? AND a.depth < ?
+		)
+		SELECT id, name, user_id, category, parent_id, created_at, updated_at
+		FROM ancestors ORDER BY depth DESC`,
+		folderID, userID, userID, maxFolderDepth).Scan(&path).Error
+	return path, err
+}
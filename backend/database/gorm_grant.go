@@ -0,0 +1,263 @@
+package database
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// maxFolderDepth 向上查找祖先文件夹的最大层数，防止异常数据导致死循环
+const maxFolderDepth = 128
+
+// GORMGrantRepository GORM 共享授权仓库
+type GORMGrantRepository struct {
+	db *gorm.DB
+}
+
+// NewGORMGrantRepository 创建 GORM 共享授权仓库
+func NewGORMGrantRepository(db *gorm.DB) *GORMGrantRepository {
+	return &GORMGrantRepository{db: db}
+}
+
+func (r *GORMGrantRepository) CreateGrant(grant *models.ShareGrant) error {
+	return r.db.Create(grant).Error
+}
+
+func (r *GORMGrantRepository) GetGrantByID(grantID uint) (*models.ShareGrant, error) {
+	var grant models.ShareGrant
+	err := r.db.Where("id = ?", grantID).First(&grant).Error
+	if err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// GetExistingGrant 查找同一资源对同一用户或用户组的已有授权
+func (r *GORMGrantRepository) GetExistingGrant(resourceType string, resourceID uint, granteeUserID string, granteeGroupID *uint) (*models.ShareGrant, error) {
+	var grant models.ShareGrant
+	query := r.db.Where("resource_type = ? AND resource_id = ?", resourceType, resourceID)
+	if granteeGroupID != nil {
+		query = query.Where("grantee_type = ? AND grantee_group_id = ?", models.GranteeTypeGroup, *granteeGroupID)
+	} else {
+		query = query.Where("grantee_type = ? AND grantee_user_id = ?", models.GranteeTypeUser, granteeUserID)
+	}
+	if err := query.First(&grant).Error; err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+func (r *GORMGrantRepository) UpdateGrantPermission(grantID uint, permission string) error {
+	return r.db.Model(&models.ShareGrant{}).Where("id = ?", grantID).Update("permission", permission).Error
+}
+
+func (r *GORMGrantRepository) DeleteGrant(grantID uint) error {
+	return r.db.Where("id = ?", grantID).Delete(&models.ShareGrant{}).Error
+}
+
+func (r *GORMGrantRepository) DeleteGrantsByResource(resourceType string, resourceID uint) error {
+	return r.db.Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).Delete(&models.ShareGrant{}).Error
+}
+
+func (r *GORMGrantRepository) GetGrantsByResource(resourceType string, resourceID uint) ([]models.ShareGrant, error) {
+	var grants []models.ShareGrant
+	err := r.db.Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).Order("created_at ASC").Find(&grants).Error
+	return grants, err
+}
+
+func (r *GORMGrantRepository) GetGrantsByOwner(ownerID string) ([]models.ShareGrant, error) {
+	var grants []models.ShareGrant
+	err := r.db.Where("owner_id = ?", ownerID).Order("created_at DESC").Find(&grants).Error
+	return grants, err
+}
+
+// granteeScope 限定授权对象为指定用户本人或其所在的用户组
+func (r *GORMGrantRepository) granteeScope(userID string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		memberGroups := r.db.Model(&models.UserGroupMember{}).Select("group_id").Where("user_id = ?", userID)
+		return db.Where("((grantee_type = ? AND grantee_user_id = ?) OR (grantee_type = ? AND grantee_group_id IN (?)))",
+			models.GranteeTypeUser, userID, models.GranteeTypeGroup, memberGroups)
+	}
+}
+
+// GetGrantsForUser 获取直接授予用户或通过用户组授予用户的所有授权
+func (r *GORMGrantRepository) GetGrantsForUser(userID string) ([]models.ShareGrant, error) {
+	var grants []models.ShareGrant
+	err := r.db.Scopes(r.granteeScope(userID)).
+		Where("owner_id <> ?", userID).
+		Order("created_at DESC").
+		Find(&grants).Error
+	return grants, err
+}
+
+// getAncestorFolderIDs 获取文件夹自身及其所有祖先文件夹ID
+func (r *GORMGrantRepository) getAncestorFolderIDs(folderID uint) ([]uint, error) {
+	ids := []uint{}
+	visited := make(map[uint]bool)
+	current := &folderID
+
+	for depth := 0; current != nil && depth < maxFolderDepth; depth++ {
+		if visited[*current] {
+			break
+		}
+		visited[*current] = true
+		ids = append(ids, *current)
+
+		var folder models.Folder
+		if err := r.db.Select("id", "parent_id").Where("id = ?", *current).First(&folder).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				break
+			}
+			return nil, err
+		}
+		current = folder.ParentID
+	}
+
+	return ids, nil
+}
+
+// resolvePermission 计算用户在所有者资源上的最高授权级别
+func (r *GORMGrantRepository) resolvePermission(ownerID, userID string, fileID *uint, folderIDs []uint) (string, error) {
+	if ownerID == userID {
+		return models.PermissionOwner, nil
+	}
+
+	query := r.db.Model(&models.ShareGrant{}).Scopes(r.granteeScope(userID)).Where("owner_id = ?", ownerID)
+	if fileID != nil && len(folderIDs) > 0 {
+		query = query.Where("((resource_type = ? AND resource_id = ?) OR (resource_type = ? AND resource_id IN ?))",
+			models.ShareResourceFile, *fileID, models.ShareResourceFolder, folderIDs)
+	} else if fileID != nil {
+		query = query.Where("resource_type = ? AND resource_id = ?", models.ShareResourceFile, *fileID)
+	} else if len(folderIDs) > 0 {
+		query = query.Where("resource_type = ? AND resource_id IN ?", models.ShareResourceFolder, folderIDs)
+	} else {
+		return "", nil
+	}
+
+	var permissions []string
+	if err := query.Pluck("permission", &permissions).Error; err != nil {
+		return "", err
+	}
+
+	best := ""
+	for _, permission := range permissions {
+		if models.PermissionLevel(permission) > models.PermissionLevel(best) {
+			best = permission
+		}
+	}
+	return best, nil
+}
+
+// GetFilePermission 获取文件（不限所有者）及用户对其的有效权限，无权限时返回空字符串
+func (r *GORMGrantRepository) GetFilePermission(fileID uint, userID string) (*models.File, string, error) {
+	var file models.File
+	if err := r.db.Where("id = ?", fileID).First(&file).Error; err != nil {
+		return nil, "", err
+	}
+
+	var folderIDs []uint
+	if file.FolderID != nil && file.UserID != userID {
+		ids, err := r.getAncestorFolderIDs(*file.FolderID)
+		if err != nil {
+			return nil, "", err
+		}
+		folderIDs = ids
+	}
+
+	permission, err := r.resolvePermission(file.UserID, userID, &file.ID, folderIDs)
+	if err != nil {
+		return nil, "", err
+	}
+	return &file, permission, nil
+}
+
+// GetFolderPermission 获取文件夹（不限所有者）及用户对其的有效权限，授权对整个子树生效
+func (r *GORMGrantRepository) GetFolderPermission(folderID uint, userID string) (*models.Folder, string, error) {
+	var folder models.Folder
+	if err := r.db.Where("id = ?", folderID).First(&folder).Error; err != nil {
+		return nil, "", err
+	}
+
+	var folderIDs []uint
+	if folder.UserID != userID {
+		ids, err := r.getAncestorFolderIDs(folder.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		folderIDs = ids
+	}
+
+	permission, err := r.resolvePermission(folder.UserID, userID, nil, folderIDs)
+	if err != nil {
+		return nil, "", err
+	}
+	return &folder, permission, nil
+}
+
+// GORMUserGroupRepository GORM 用户组仓库
+type GORMUserGroupRepository struct {
+	db *gorm.DB
+}
+
+// NewGORMUserGroupRepository 创建 GORM 用户组仓库
+func NewGORMUserGroupRepository(db *gorm.DB) *GORMUserGroupRepository {
+	return &GORMUserGroupRepository{db: db}
+}
+
+func (r *GORMUserGroupRepository) CreateGroup(group *models.UserGroup) error {
+	return r.db.Create(group).Error
+}
+
+func (r *GORMUserGroupRepository) GetGroupByID(groupID uint) (*models.UserGroup, error) {
+	var group models.UserGroup
+	err := r.db.Where("id = ?", groupID).First(&group).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetGroupsForUser 获取用户创建的或所属的用户组
+func (r *GORMUserGroupRepository) GetGroupsForUser(userID string) ([]models.UserGroup, error) {
+	var groups []models.UserGroup
+	memberGroups := r.db.Model(&models.UserGroupMember{}).Select("group_id").Where("user_id = ?", userID)
+	err := r.db.Where("(owner_id = ? OR id IN (?))", userID, memberGroups).Order("created_at ASC").Find(&groups).Error
+	return groups, err
+}
+
+// DeleteGroup 删除用户组及其成员和相关授权
+func (r *GORMUserGroupRepository) DeleteGroup(groupID uint, ownerID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND owner_id = ?", groupID, ownerID).Delete(&models.UserGroup{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Where("group_id = ?", groupID).Delete(&models.UserGroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("grantee_type = ? AND grantee_group_id = ?", models.GranteeTypeGroup, groupID).Delete(&models.ShareGrant{}).Error
+	})
+}
+
+func (r *GORMUserGroupRepository) AddMember(groupID uint, userID string) error {
+	return r.db.Create(&models.UserGroupMember{GroupID: groupID, UserID: userID}).Error
+}
+
+func (r *GORMUserGroupRepository) RemoveMember(groupID uint, userID string) error {
+	return r.db.Where("group_id = ? AND user_id = ?", groupID, userID).Delete(&models.UserGroupMember{}).Error
+}
+
+func (r *GORMUserGroupRepository) GetMembers(groupID uint) ([]models.UserGroupMember, error) {
+	var members []models.UserGroupMember
+	err := r.db.Where("group_id = ?", groupID).Order("created_at ASC").Find(&members).Error
+	return members, err
+}
+
+func (r *GORMUserGroupRepository) IsMember(groupID uint, userID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.UserGroupMember{}).Where("group_id = ? AND user_id = ?", groupID, userID).Count(&count).Error
+	return count > 0, err
+}
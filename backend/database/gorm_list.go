@@ -0,0 +1,206 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// 各列表支持的排序字段及对应的列
+var (
+	fileListColumns = map[string]string{
+		models.SearchSortName:      "name",
+		models.SearchSortSize:      "size",
+		models.SearchSortCreatedAt: "created_at",
+		models.SearchSortUpdatedAt: "updated_at",
+	}
+	folderListColumns = map[string]string{
+		models.SearchSortName:      "name",
+		models.SearchSortCreatedAt: "created_at",
+		models.SearchSortUpdatedAt: "updated_at",
+	}
+	urlFileListColumns = map[string]string{
+		models.SearchSortName:      "title",
+		models.SearchSortCreatedAt: "created_at",
+		models.SearchSortUpdatedAt: "updated_at",
+	}
+	documentListColumns = map[string]string{
+		models.ListSortOrder:       "`order`",
+		models.SearchSortName:      "title",
+		models.SearchSortCreatedAt: "created_at",
+		models.SearchSortUpdatedAt: "updated_at",
+	}
+)
+
+// fileListColumnsWithoutThumbnail 省略缩略图数据的文件列，改为返回是否有缩略图
+const fileListColumnsWithoutThumbnail = "id, name, size, type, path, user_id, folder_id, checksum, uploaded_by, description, metadata, scan_status, scan_result, scanned_at, vault_id, encrypted_key, encryption_key_id, created_at, updated_at, " +
+	"(thumbnail_data IS NOT NULL AND thumbnail_data <> '') AS has_thumbnail"
+
+// applyListQuery 追加游标条件和排序，以 (排序列, id) 作为游标键；分页时多取一条用于判断是否还有下一页
+func applyListQuery(db *gorm.DB, query *models.ListQuery, columns map[string]string) (*gorm.DB, error) {
+	column, ok := columns[query.Sort]
+	if !ok {
+		return nil, fmt.Errorf("不支持的排序字段: %s", query.Sort)
+	}
+
+	direction, comparison := "ASC", ">"
+	if query.Desc {
+		direction, comparison = "DESC", "<"
+	}
+	if query.Cursor != nil {
+		value, err := searchCursorValue(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(fmt.Sprintf("(%s, id) %s (?, ?)", column, comparison), value, query.Cursor.ID)
+	}
+	db = db.Order(fmt.Sprintf("%[1]s %[2]s, id %[2]s", column, direction))
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit + 1)
+	}
+	return db, nil
+}
+
+// listPageSize 返回本页应保留的记录数；多取到记录时根据本页最后一条生成下一页游标
+func listPageSize(query *models.ListQuery, count int, itemType string, last func(i int) (interface{}, uint)) (int, string) {
+	if query.Limit <= 0 || count <= query.Limit {
+		return count, ""
+	}
+	value, id := last(query.Limit - 1)
+	cursor := &models.SearchCursor{Sort: query.Sort, Desc: query.Desc, ItemType: itemType, ID: id}
+	switch v := value.(type) {
+	case string:
+		cursor.Value = v
+	case int:
+		cursor.Value = strconv.Itoa(v)
+	case int64:
+		cursor.Value = strconv.FormatInt(v, 10)
+	case time.Time:
+		cursor.Value = v.Format(time.RFC3339Nano)
+	}
+	return query.Limit, cursor.Encode()
+}
+
+// ListFiles 分页列出文件夹（nil 为根目录）中的文件，默认不读取缩略图数据
+func (r *GORMFileRepository) ListFiles(query *models.ListQuery) ([]models.File, string, error) {
+	db := r.db.Model(&models.File{}).Where("user_id = ?", query.OwnerID)
+	if query.FolderID != nil {
+		db = db.Where("folder_id = ?", *query.FolderID)
+	} else {
+		db = db.Where("folder_id IS NULL")
+	}
+	if !query.WithThumbnail {
+		db = db.Select(fileListColumnsWithoutThumbnail)
+	}
+	db, err := applyListQuery(db, query, fileListColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var files []models.File
+	if err := db.Find(&files).Error; err != nil {
+		return nil, "", err
+	}
+	if query.WithThumbnail {
+		for i := range files {
+			files[i].HasThumbnail = files[i].ThumbnailData != ""
+		}
+	}
+	n, next := listPageSize(query, len(files), models.SearchItemFile, func(i int) (interface{}, uint) {
+		file := files[i]
+		switch query.Sort {
+		case models.SearchSortName:
+			return file.Name, file.ID
+		case models.SearchSortSize:
+			return file.Size, file.ID
+		case models.SearchSortCreatedAt:
+			return file.CreatedAt, file.ID
+		}
+		return file.UpdatedAt, file.ID
+	})
+	return files[:n], next, nil
+}
+
+// ListFolders 分页列出用户的全部文件夹
+func (r *GORMFolderRepository) ListFolders(query *models.ListQuery) ([]models.Folder, string, error) {
+	db, err := applyListQuery(r.db.Where("user_id = ?", query.OwnerID), query, folderListColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var folders []models.Folder
+	if err := db.Find(&folders).Error; err != nil {
+		return nil, "", err
+	}
+	n, next := listPageSize(query, len(folders), models.SearchItemFolder, func(i int) (interface{}, uint) {
+		folder := folders[i]
+		switch query.Sort {
+		case models.SearchSortName:
+			return folder.Name, folder.ID
+		case models.SearchSortCreatedAt:
+			return folder.CreatedAt, folder.ID
+		}
+		return folder.UpdatedAt, folder.ID
+	})
+	return folders[:n], next, nil
+}
+
+// ListUrlFiles 分页列出文件夹（nil 为根目录）中的URL文件，名称排序使用标题
+func (r *GORMUrlFileRepository) ListUrlFiles(query *models.ListQuery) ([]models.UrlFile, string, error) {
+	db := r.db.Where("user_id = ?", query.OwnerID)
+	if query.FolderID != nil {
+		db = db.Where("folder_id = ?", *query.FolderID)
+	} else {
+		db = db.Where("folder_id IS NULL")
+	}
+	db, err := applyListQuery(db, query, urlFileListColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var files []models.UrlFile
+	if err := db.Find(&files).Error; err != nil {
+		return nil, "", err
+	}
+	n, next := listPageSize(query, len(files), models.SearchItemUrlFile, func(i int) (interface{}, uint) {
+		file := files[i]
+		switch query.Sort {
+		case models.SearchSortName:
+			return file.Title, file.ID
+		case models.SearchSortCreatedAt:
+			return file.CreatedAt, file.ID
+		}
+		return file.UpdatedAt, file.ID
+	})
+	return files[:n], next, nil
+}
+
+// ListDocuments 分页列出文档，默认按展示顺序排列
+func (r *GORMDocumentRepository) ListDocuments(query *models.ListQuery) ([]models.Document, string, error) {
+	db, err := applyListQuery(r.db, query, documentListColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var docs []models.Document
+	if err := db.Find(&docs).Error; err != nil {
+		return nil, "", err
+	}
+	n, next := listPageSize(query, len(docs), models.ListItemDocument, func(i int) (interface{}, uint) {
+		doc := docs[i]
+		switch query.Sort {
+		case models.ListSortOrder:
+			return doc.Order, doc.ID
+		case models.SearchSortName:
+			return doc.Title, doc.ID
+		case models.SearchSortCreatedAt:
+			return doc.CreatedAt, doc.ID
+		}
+		return doc.UpdatedAt, doc.ID
+	})
+	return docs[:n], next, nil
+}
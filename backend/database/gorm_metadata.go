@@ -0,0 +1,71 @@
+package database
+
+import (
+	"encoding/json"
+
+	"backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// itemMetadata 文件或文件夹当前的描述和自定义元数据
+type itemMetadata struct {
+	Description string
+	Metadata    models.Metadata
+}
+
+// UpdateFileMetadata 修改文件描述（description 为 nil 时不变）并合并自定义元数据
+func (r *GORMFileRepository) UpdateFileMetadata(fileID uint, userID string, description *string, patch map[string]json.RawMessage) error {
+	return updateItemMetadata(r.db, &models.File{}, fileID, userID, description, patch, func(tx *gorm.DB) error {
+		var file models.File
+		if err := tx.Select("id", "name", "size", "checksum", "folder_id").Where("id = ?", fileID).Take(&file).Error; err != nil {
+			return err
+		}
+		return recordChange(tx, userID, fileChange(models.ChangeUpdate, &file))
+	})
+}
+
+// UpdateFolderMetadata 修改文件夹描述（description 为 nil 时不变）并合并自定义元数据
+func (r *GORMFolderRepository) UpdateFolderMetadata(folderID uint, userID string, description *string, patch map[string]json.RawMessage) error {
+	return updateItemMetadata(r.db, &models.Folder{}, folderID, userID, description, patch, func(tx *gorm.DB) error {
+		var folder models.Folder
+		if err := tx.Select("id", "name", "parent_id").Where("id = ?", folderID).Take(&folder).Error; err != nil {
+			return err
+		}
+		return recordChange(tx, userID, folderChange(models.ChangeUpdate, &folder))
+	})
+}
+
+// updateItemMetadata 在事务中锁定记录后合并元数据，避免并发修改不同的键时互相覆盖。
+// 记录不存在时返回 gorm.ErrRecordNotFound，合并后键数量超限时返回 models.ErrTooManyMetadataKeys；
+// 有修改时在同一事务中调用 recordUpdate 记录变更
+func updateItemMetadata(db *gorm.DB, model interface{}, id uint, userID string, description *string, patch map[string]json.RawMessage, recordUpdate func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var current itemMetadata
+		if err := tx.Model(model).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("COALESCE(description, '') AS description", "metadata").
+			Where("id = ? AND user_id = ?", id, userID).Take(&current).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if description != nil {
+			updates["description"] = *description
+		}
+		if len(patch) > 0 {
+			metadata, err := current.Metadata.ApplyPatch(patch)
+			if err != nil {
+				return err
+			}
+			updates["metadata"] = metadata
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		if err := tx.Model(model).Where("id = ? AND user_id = ?", id, userID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return recordUpdate(tx)
+	})
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GORMNamePinyinRepository 名称拼音索引仓库
+type GORMNamePinyinRepository struct {
+	db *gorm.DB
+}
+
+// NewGORMNamePinyinRepository 创建名称拼音索引仓库
+func NewGORMNamePinyinRepository(db *gorm.DB) *GORMNamePinyinRepository {
+	return &GORMNamePinyinRepository{db: db}
+}
+
+// nameIndexTables 各类对象对应的表和名称列，URL文件使用标题；
+// scope 为需要建立索引的记录条件，保险库中的名称为密文，不建立索引
+var nameIndexTables = []struct {
+	itemType string
+	table    string
+	column   string
+	scope    string
+}{
+	{models.SearchItemFile, "files", "name", "t.vault_id IS NULL"},
+	{models.SearchItemFolder, "folders", "name", "t.vault_id IS NULL"},
+	{models.SearchItemUrlFile, "url_files", "title", "TRUE"},
+}
+
+// GetNamesToIndex 获取没有拼音索引或重命名后索引已过期的对象。
+// 每类对象分别关联索引表，使类型常量按索引表的排序规则比较并走主键
+func (r *GORMNamePinyinRepository) GetNamesToIndex(limit int) ([]models.NameIndexSource, error) {
+	var selects []string
+	var args []interface{}
+	for _, source := range nameIndexTables {
+		selects = append(selects, fmt.Sprintf(`SELECT ? AS item_type, t.id AS item_id, t.user_id, t.%[2]s AS name
+			FROM %[1]s t LEFT JOIN name_pinyin p ON p.item_type = ? AND p.item_id = t.id
+			WHERE %[3]s AND (p.item_id IS NULL OR BINARY p.name <> BINARY t.%[2]s)`, source.table, source.column, source.scope))
+		args = append(args, source.itemType, source.itemType)
+	}
+
+	var sources []models.NameIndexSource
+	err := r.db.Raw(strings.Join(selects, " UNION ALL ")+` ORDER BY item_type, item_id LIMIT ?`,
+		append(args, limit)...).Scan(&sources).Error
+	return sources, err
+}
+
+// UpsertNames 写入或覆盖一批名称的拼音索引
+func (r *GORMNamePinyinRepository) UpsertNames(names []models.NamePinyin) error {
+	if len(names) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&names).Error
+}
+
+// DeleteOrphanNames 清理对象已被删除的拼音索引，返回删除的记录数
+func (r *GORMNamePinyinRepository) DeleteOrphanNames() (int64, error) {
+	var total int64
+	for _, source := range nameIndexTables {
+		result := r.db.Exec(`DELETE p FROM name_pinyin p LEFT JOIN `+source.table+` t ON t.id = p.item_id
+			WHERE p.item_type = ? AND t.id IS NULL`, source.itemType)
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+	}
+	return total, nil
+}
+
+// GetFuzzyCandidates 按结构化条件（不含关键词）获取模糊搜索的候选记录及其拼音索引，
+// 最近更新的优先，最多返回 limit 条
+func (r *GORMNamePinyinRepository) GetFuzzyCandidates(query *models.FileSearchQuery, limit int) ([]models.FuzzyCandidate, error) {
+	// 关键词由调用方在内存中做模糊匹配，这里只按其他条件筛选
+	scoped := *query
+	scoped.Keyword = ""
+
+	var selects []string
+	var args []interface{}
+	for _, part := range []struct {
+		include  bool
+		itemType string
+		build    func(*models.FileSearchQuery) (string, []interface{})
+	}{
+		{query.IncludeFiles, models.SearchItemFile, fileSearchSelect},
+		{query.IncludeFolders, models.SearchItemFolder, folderSearchSelect},
+		{query.IncludeUrls, models.SearchItemUrlFile, urlFileSearchSelect},
+	} {
+		if !part.include {
+			continue
+		}
+		sql, selectArgs := part.build(&scoped)
+		selects = append(selects, `SELECT t.item_type, t.id, t.name, t.updated_at, p.name AS indexed_name,
+				COALESCE(p.full_pinyin, '') AS full_pinyin, COALESCE(p.initials, '') AS initials
+			FROM (`+sql+`) t LEFT JOIN name_pinyin p ON p.item_type = ? AND p.item_id = t.id`)
+		args = append(args, selectArgs...)
+		args = append(args, part.itemType)
+	}
+	if len(selects) == 0 {
+		return nil, nil
+	}
+
+	var candidates []models.FuzzyCandidate
+	err := r.db.Raw(strings.Join(selects, " UNION ALL ")+` ORDER BY updated_at DESC, id DESC LIMIT ?`,
+		append(args, limit)...).Scan(&candidates).Error
+	return candidates, err
+}
+
+// LoadItems 按候选记录加载完整的文件、文件夹和URL文件，保持传入顺序
+func (r *GORMNamePinyinRepository) LoadItems(candidates []models.FuzzyCandidate) ([]models.SearchItem, error) {
+	rows := make([]searchRow, 0, len(candidates))
+	for _, candidate := range candidates {
+		rows = append(rows, searchRow{ItemType: candidate.ItemType, ID: candidate.ID})
+	}
+	return loadSearchItems(r.db, rows)
+}
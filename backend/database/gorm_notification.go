@@ -0,0 +1,88 @@
+package database
+
+import (
+	"time"
+
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GORMNotificationRepository 站内通知仓库
+type GORMNotificationRepository struct {
+	db *gorm.DB
+}
+
+// NewGORMNotificationRepository 创建站内通知仓库
+func NewGORMNotificationRepository(db *gorm.DB) *GORMNotificationRepository {
+	return &GORMNotificationRepository{db: db}
+}
+
+// CreateNotifications 批量创建通知
+func (r *GORMNotificationRepository) CreateNotifications(notifications []models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	return r.db.Create(&notifications).Error
+}
+
+// GetNotifications 按时间倒序分页获取用户的通知，unreadOnly 为 true 时只返回未读通知
+func (r *GORMNotificationRepository) GetNotifications(userID string, unreadOnly bool, limit, offset int) ([]models.Notification, int64, error) {
+	query := r.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var notifications []models.Notification
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&notifications).Error
+	return notifications, total, err
+}
+
+// CountUnread 统计用户的未读通知数量
+func (r *GORMNotificationRepository) CountUnread(userID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// MarkRead 将通知标记为已读，通知不存在或不属于该用户时返回 false
+func (r *GORMNotificationRepository) MarkRead(userID string, notificationID uint) (bool, error) {
+	var notification models.Notification
+	if err := r.db.Where("id = ? AND user_id = ?", notificationID, userID).Take(&notification).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	if notification.ReadAt != nil {
+		return true, nil
+	}
+	err := r.db.Model(&notification).Update("read_at", time.Now()).Error
+	return err == nil, err
+}
+
+// MarkAllRead 将用户的全部未读通知标记为已读，返回标记的数量
+func (r *GORMNotificationRepository) MarkAllRead(userID string) (int64, error) {
+	result := r.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// GetLatestNotificationID 获取当前最大的通知ID，没有通知时为0
+func (r *GORMNotificationRepository) GetLatestNotificationID() (uint, error) {
+	var id uint
+	err := r.db.Model(&models.Notification{}).Select("COALESCE(MAX(id), 0)").Scan(&id).Error
+	return id, err
+}
+
+// GetNotificationsAfter 按ID升序获取指定用户ID大于 afterID 的通知，最多 limit 条
+func (r *GORMNotificationRepository) GetNotificationsAfter(afterID uint, userIDs []string, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+	if len(userIDs) == 0 {
+		return notifications, nil
+	}
+	err := r.db.Where("id > ? AND user_id IN ?", afterID, userIDs).Order("id").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
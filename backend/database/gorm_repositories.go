@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ===== GORM Repository 实现 =====
@@ -100,14 +101,25 @@ func (r *GORMUserRepository) GetUserStorageInfo(userID string) (int64, int64, er
 	if err != nil {
 		return 0, user.StorageLimit, err
 	}
-	
-	return usedStorage, user.StorageLimit, nil
+
+	// 历史版本同样计入存储配额
+	var versionStorage int64
+	err = r.db.Model(&models.FileVersion{}).Where("user_id = ?", userID).Select("COALESCE(SUM(size), 0)").Scan(&versionStorage).Error
+	if err != nil {
+		return 0, user.StorageLimit, err
+	}
+
+	return usedStorage + versionStorage, user.StorageLimit, nil
 }
 
 func (r *GORMUserRepository) UpdateUserStorage(userID string, storageLimit int64) error {
 	return r.db.Model(&models.User{}).Where("uuid = ?", userID).Update("storage_limit", storageLimit).Error
 }
 
+func (r *GORMUserRepository) UpdateUserMaxFileVersions(userID string, maxVersions int) error {
+	return r.db.Model(&models.User{}).Where("uuid = ?", userID).Update("max_file_versions", maxVersions).Error
+}
+
 func (r *GORMUserRepository) UpdateLastLoginTime(uuid string) error {
 	return r.db.Model(&models.User{}).Where("uuid = ?", uuid).Updates(map[string]interface{}{
 		"last_login_time": time.Now(),
@@ -175,21 +187,86 @@ func (r *GORMFileRepository) GetFileByName(fileName, userID string) (*models.Fil
 	return &file, nil
 }
 
+// GetFileByNameAndUser 按名称查找用户的文件，用于上传时检查同名文件；保险库文件的名称为密文，不参与匹配
 func (r *GORMFileRepository) GetFileByNameAndUser(fileName, userID string) (*models.File, error) {
 	var file models.File
-	err := r.db.Where("name = ? AND user_id = ?", fileName, userID).First(&file).Error
+	err := r.db.Where("name = ? AND user_id = ? AND vault_id IS NULL", fileName, userID).First(&file).Error
 	if err != nil {
 		return nil, err
 	}
 	return &file, nil
 }
 
+// GetFileByNameInFolder 获取指定文件夹中的同名文件（folderID 为 nil 表示根目录）
+func (r *GORMFileRepository) GetFileByNameInFolder(userID, name string, folderID *uint) (*models.File, error) {
+	var file models.File
+	query := r.db.Where("user_id = ? AND name = ?", userID, name)
+	if folderID != nil {
+		query = query.Where("folder_id = ?", *folderID)
+	} else {
+		query = query.Where("folder_id IS NULL")
+	}
+	if err := query.First(&file).Error; err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// GetFileByPath 按存储路径（/uploads/...）获取文件，不限定所有者，由调用方检查访问权限
+func (r *GORMFileRepository) GetFileByPath(path string) (*models.File, error) {
+	var file models.File
+	if err := r.db.Where("path = ?", path).First(&file).Error; err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
 func (r *GORMFileRepository) CreateFile(file *models.File) error {
-	return r.db.Create(file).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(file).Error; err != nil {
+			return err
+		}
+		return recordChange(tx, file.UserID, fileChange(models.ChangeCreate, file))
+	})
+}
+
+// UpdateFile 保存文件记录，根据修改前的文件夹和名称记录为移动、重命名或修改
+func (r *GORMFileRepository) UpdateFile(file *models.File) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var old models.File
+		if err := tx.Select("id", "name", "folder_id").Where("id = ?", file.ID).Take(&old).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(file).Error; err != nil {
+			return err
+		}
+		action := movedAction(old.FolderID, file.FolderID, old.Name, file.Name)
+		return recordChange(tx, file.UserID, fileChange(action, file))
+	})
 }
 
 func (r *GORMFileRepository) DeleteFile(fileID uint, userID string) error {
-	return r.db.Where("id = ? AND user_id = ?", fileID, userID).Delete(&models.File{}).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var file models.File
+		err := tx.Select("id", "name", "size", "checksum", "folder_id").Where("id = ? AND user_id = ?", fileID, userID).Take(&file).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.File{}, fileID).Error; err != nil {
+			return err
+		}
+		// 同步清理内容索引、标签关联、收藏和最近访问，避免已删除文件出现在搜索结果和统计中
+		if err := tx.Where("file_id = ?", fileID).Delete(&models.FileContent{}).Error; err != nil {
+			return err
+		}
+		if err := deleteItemLinks(tx, models.SearchItemFile, []uint{fileID}); err != nil {
+			return err
+		}
+		return recordChange(tx, userID, fileChange(models.ChangeDelete, &file))
+	})
 }
 
 func (r *GORMFileRepository) MoveFile(fileID uint, userID string, folderID *uint) error {
@@ -199,13 +276,35 @@ func (r *GORMFileRepository) MoveFile(fileID uint, userID string, folderID *uint
 	} else {
 		updates["folder_id"] = nil
 	}
-	return r.db.Model(&models.File{}).Where("id = ? AND user_id = ?", fileID, userID).Updates(updates).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var file models.File
+		err := tx.Select("id", "name", "size", "checksum", "folder_id").Where("id = ? AND user_id = ?", fileID, userID).Take(&file).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.File{}).Where("id = ?", fileID).Updates(updates).Error; err != nil {
+			return err
+		}
+		action := movedAction(file.FolderID, folderID, file.Name, file.Name)
+		file.FolderID = folderID
+		return recordChange(tx, userID, fileChange(action, &file))
+	})
 }
 
 func (r *GORMFileRepository) GetUserTotalStorage(userID string) (int64, error) {
 	var totalSize int64
 	err := r.db.Model(&models.File{}).Where("user_id = ?", userID).Select("COALESCE(SUM(size), 0)").Scan(&totalSize).Error
-	return totalSize, err
+	if err != nil {
+		return 0, err
+	}
+
+	// 历史版本同样计入存储配额
+	var versionSize int64
+	err = r.db.Model(&models.FileVersion{}).Where("user_id = ?", userID).Select("COALESCE(SUM(size), 0)").Scan(&versionSize).Error
+	return totalSize + versionSize, err
 }
 
 func (r *GORMFileRepository) GetUserFileCount(userID string) (int, error) {
@@ -226,18 +325,6 @@ func (r *GORMFileRepository) GetTotalFileCount() (int, error) {
 	return int(count), err
 }
 
-func (r *GORMFileRepository) SearchFilesByUserID(userID, query string, folderID *uint) ([]models.File, error) {
-	var files []models.File
-	dbQuery := r.db.Where("user_id = ? AND name LIKE ?", userID, "%"+query+"%")
-	if folderID != nil {
-		dbQuery = dbQuery.Where("folder_id = ?", *folderID)
-	} else {
-		dbQuery = dbQuery.Where("folder_id IS NULL")
-	}
-	err := dbQuery.Find(&files).Error
-	return files, err
-}
-
 // ===== GORM Folder Repository 方法 =====
 
 func (r *GORMFolderRepository) GetFoldersByUserID(userID string) ([]models.Folder, error) {
@@ -256,18 +343,98 @@ func (r *GORMFolderRepository) GetFolderByID(folderID uint, userID string) (*mod
 }
 
 func (r *GORMFolderRepository) CreateFolder(folder *models.Folder) error {
-	return r.db.Create(folder).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(folder).Error; err != nil {
+			return err
+		}
+		return recordChange(tx, folder.UserID, folderChange(models.ChangeCreate, folder))
+	})
 }
 
 func (r *GORMFolderRepository) UpdateFolder(folderID uint, userID, name, category string) error {
-	return r.db.Model(&models.Folder{}).Where("id = ? AND user_id = ?", folderID, userID).Updates(map[string]interface{}{
-		"name":     name,
-		"category": category,
-	}).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var folder models.Folder
+		err := tx.Select("id", "name", "parent_id").Where("id = ? AND user_id = ?", folderID, userID).Take(&folder).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Folder{}).Where("id = ?", folderID).Updates(map[string]interface{}{
+			"name":     name,
+			"category": category,
+		}).Error; err != nil {
+			return err
+		}
+		action := movedAction(folder.ParentID, folder.ParentID, folder.Name, name)
+		folder.Name = name
+		return recordChange(tx, userID, folderChange(action, &folder))
+	})
+}
+
+// MoveFolder 在事务中修改文件夹的父文件夹（parentID 为 nil 表示移动到根目录），name 非空时同时重命名。
+// 从目标父文件夹向上查找祖先，遇到文件夹自身时返回 ErrFolderCycle；查找过程加行锁，避免并发移动形成环
+func (r *GORMFolderRepository) MoveFolder(folderID uint, userID string, parentID *uint, name string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		locking := clause.Locking{Strength: "UPDATE"}
+		var folder models.Folder
+		if err := tx.Clauses(locking).Select("id", "name", "parent_id").Where("id = ? AND user_id = ?", folderID, userID).First(&folder).Error; err != nil {
+			return err
+		}
+
+		err := checkFolderMove(folderID, parentID, func(id uint) (*uint, error) {
+			var ancestor models.Folder
+			if err := tx.Clauses(locking).Select("id", "parent_id").Where("id = ? AND user_id = ?", id, userID).First(&ancestor).Error; err != nil {
+				return nil, err
+			}
+			return ancestor.ParentID, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if parentID != nil {
+			updates["parent_id"] = *parentID
+		} else {
+			updates["parent_id"] = nil
+		}
+		if name != "" {
+			updates["name"] = name
+		}
+		if err := tx.Model(&models.Folder{}).Where("id = ? AND user_id = ?", folderID, userID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		moved := folder
+		moved.ParentID = parentID
+		if name != "" {
+			moved.Name = name
+		}
+		action := movedAction(folder.ParentID, moved.ParentID, folder.Name, moved.Name)
+		return recordChange(tx, userID, folderChange(action, &moved))
+	})
 }
 
 func (r *GORMFolderRepository) DeleteFolder(folderID uint, userID string) error {
-	return r.db.Where("id = ? AND user_id = ?", folderID, userID).Delete(&models.Folder{}).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var folder models.Folder
+		err := tx.Select("id", "name", "parent_id").Where("id = ? AND user_id = ?", folderID, userID).Take(&folder).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Folder{}, folderID).Error; err != nil {
+			return err
+		}
+		if err := deleteItemLinks(tx, models.SearchItemFolder, []uint{folderID}); err != nil {
+			return err
+		}
+		return recordChange(tx, userID, folderChange(models.ChangeDelete, &folder))
+	})
 }
 
 func (r *GORMFolderRepository) CheckFolderExists(folderID uint, userID string) (bool, error) {
@@ -298,6 +465,133 @@ func (r *GORMFolderRepository) GetFolderUrlFileCount(folderID uint, userID strin
 	return int(count), err
 }
 
+// GetFolderByNameInParent 获取指定父文件夹下的同名文件夹（parentID 为 nil 表示根目录）
+func (r *GORMFolderRepository) GetFolderByNameInParent(userID, name string, parentID *uint) (*models.Folder, error) {
+	var folder models.Folder
+	query := r.db.Where("user_id = ? AND name = ?", userID, name)
+	if parentID != nil {
+		query = query.Where("parent_id = ?", *parentID)
+	} else {
+		query = query.Where("parent_id IS NULL")
+	}
+	if err := query.First(&folder).Error; err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// GetSubFolders 获取指定文件夹的直接子文件夹
+func (r *GORMFolderRepository) GetSubFolders(parentID uint, userID string) ([]models.Folder, error) {
+	var folders []models.Folder
+	err := r.db.Where("parent_id = ? AND user_id = ?", parentID, userID).Find(&folders).Error
+	return folders, err
+}
+
+// GetDescendantFolderIDs 获取文件夹及其所有子孙文件夹的ID（包含自身）
+func (r *GORMFolderRepository) GetDescendantFolderIDs(folderID uint, userID string) ([]uint, error) {
+	var folders []models.Folder
+	if err := r.db.Select("id", "parent_id").Where("user_id = ?", userID).Find(&folders).Error; err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]uint)
+	for _, folder := range folders {
+		if folder.ParentID != nil {
+			children[*folder.ParentID] = append(children[*folder.ParentID], folder.ID)
+		}
+	}
+	return collectDescendantIDs(folderID, children), nil
+}
+
+// DeleteFolderRecursive 在事务中删除文件夹及其所有子孙文件夹、文件、历史版本、URL文件、共享授权和分享链接，
+// 返回删除统计和需要清理的物理文件路径
+func (r *GORMFolderRepository) DeleteFolderRecursive(folderID uint, userID string) (*models.FolderDeleteResult, error) {
+	result := &models.FolderDeleteResult{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var root models.Folder
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND user_id = ?", folderID, userID).First(&root).Error; err != nil {
+			return err
+		}
+
+		folderIDs, err := NewGORMFolderRepository(tx).GetDescendantFolderIDs(folderID, userID)
+		if err != nil {
+			return err
+		}
+
+		var files []models.File
+		if err := tx.Select("id", "path", "size").Where("user_id = ? AND folder_id IN ?", userID, folderIDs).Find(&files).Error; err != nil {
+			return err
+		}
+		fileIDs := make([]uint, 0, len(files))
+		for _, file := range files {
+			fileIDs = append(fileIDs, file.ID)
+			result.StoredPaths = append(result.StoredPaths, file.Path)
+			result.FreedSize += file.Size
+		}
+
+		if len(fileIDs) > 0 {
+			var versions []models.FileVersion
+			if err := tx.Select("id", "path", "size").Where("file_id IN ?", fileIDs).Find(&versions).Error; err != nil {
+				return err
+			}
+			for _, version := range versions {
+				result.StoredPaths = append(result.StoredPaths, version.Path)
+				result.FreedSize += version.Size
+			}
+			result.VersionCount = len(versions)
+
+			if err := tx.Where("file_id IN ?", fileIDs).Delete(&models.FileVersion{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("resource_type = ? AND resource_id IN ?", models.ShareResourceFile, fileIDs).Delete(&models.ShareGrant{}).Error; err != nil {
+				return err
+			}
+			if err := deleteShareLinks(tx, models.ShareResourceFile, fileIDs); err != nil {
+				return err
+			}
+			if err := tx.Where("file_id IN ?", fileIDs).Delete(&models.FileContent{}).Error; err != nil {
+				return err
+			}
+			if err := deleteItemLinks(tx, models.SearchItemFile, fileIDs); err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ? AND user_id = ?", fileIDs, userID).Delete(&models.File{}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := deleteItemLinks(tx, models.SearchItemUrlFile,
+			tx.Model(&models.UrlFile{}).Select("id").Where("user_id = ? AND folder_id IN ?", userID, folderIDs)); err != nil {
+			return err
+		}
+		urlFiles := tx.Where("user_id = ? AND folder_id IN ?", userID, folderIDs).Delete(&models.UrlFile{})
+		if urlFiles.Error != nil {
+			return urlFiles.Error
+		}
+		if err := tx.Where("resource_type = ? AND resource_id IN ?", models.ShareResourceFolder, folderIDs).Delete(&models.ShareGrant{}).Error; err != nil {
+			return err
+		}
+		if err := deleteShareLinks(tx, models.ShareResourceFolder, folderIDs); err != nil {
+			return err
+		}
+		if err := deleteItemLinks(tx, models.SearchItemFolder, folderIDs); err != nil {
+			return err
+		}
+		if err := tx.Where("id IN ? AND user_id = ?", folderIDs, userID).Delete(&models.Folder{}).Error; err != nil {
+			return err
+		}
+
+		result.FolderCount = len(folderIDs)
+		result.FileCount = len(fileIDs)
+		result.UrlFileCount = int(urlFiles.RowsAffected)
+		return recordChange(tx, userID, folderChange(models.ChangeDelete, &root))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // ===== GORM Document Repository 方法 =====
 
 func (r *GORMDocumentRepository) CreateDocument(doc *models.Document) error {
@@ -357,11 +651,32 @@ func (r *GORMUrlFileRepository) GetUrlFileByID(fileID uint, userID string) (*mod
 }
 
 func (r *GORMUrlFileRepository) CreateUrlFile(file *models.UrlFile) error {
-	return r.db.Create(file).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(file).Error; err != nil {
+			return err
+		}
+		return recordChange(tx, file.UserID, urlFileChange(models.ChangeCreate, file))
+	})
 }
 
 func (r *GORMUrlFileRepository) DeleteUrlFile(fileID uint, userID string) error {
-	return r.db.Where("id = ? AND user_id = ?", fileID, userID).Delete(&models.UrlFile{}).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var urlFile models.UrlFile
+		err := tx.Select("id", "title", "folder_id").Where("id = ? AND user_id = ?", fileID, userID).Take(&urlFile).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.UrlFile{}, fileID).Error; err != nil {
+			return err
+		}
+		if err := deleteItemLinks(tx, models.SearchItemUrlFile, []uint{fileID}); err != nil {
+			return err
+		}
+		return recordChange(tx, userID, urlFileChange(models.ChangeDelete, &urlFile))
+	})
 }
 
 func (r *GORMUrlFileRepository) MoveUrlFile(fileID uint, userID string, folderID *uint) error {
@@ -371,7 +686,22 @@ func (r *GORMUrlFileRepository) MoveUrlFile(fileID uint, userID string, folderID
 	} else {
 		updates["folder_id"] = nil
 	}
-	return r.db.Model(&models.UrlFile{}).Where("id = ? AND user_id = ?", fileID, userID).Updates(updates).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var urlFile models.UrlFile
+		err := tx.Select("id", "title", "folder_id").Where("id = ? AND user_id = ?", fileID, userID).Take(&urlFile).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.UrlFile{}).Where("id = ?", fileID).Updates(updates).Error; err != nil {
+			return err
+		}
+		action := movedAction(urlFile.FolderID, folderID, urlFile.Title, urlFile.Title)
+		urlFile.FolderID = folderID
+		return recordChange(tx, userID, urlFileChange(action, &urlFile))
+	})
 }
 
 func (r *GORMUrlFileRepository) GetUserTotalUrlFileCount(userID string) (int, error) {
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GORMShareRepository GORM 分享链接仓库
+type GORMShareRepository struct {
+	db *gorm.DB
+}
+
+// NewGORMShareRepository 创建 GORM 分享链接仓库
+func NewGORMShareRepository(db *gorm.DB) *GORMShareRepository {
+	return &GORMShareRepository{db: db}
+}
+
+func (r *GORMShareRepository) CreateShare(share *models.ShareLink) error {
+	return r.db.Create(share).Error
+}
+
+func (r *GORMShareRepository) GetShareBySlug(slug string) (*models.ShareLink, error) {
+	var share models.ShareLink
+	err := r.db.Where("slug = ?", slug).First(&share).Error
+	if err != nil {
+		return nil, err
+	}
+	share.HasPassword = share.PasswordHash != ""
+	return &share, nil
+}
+
+func (r *GORMShareRepository) GetShareByID(shareID uint, userID string) (*models.ShareLink, error) {
+	var share models.ShareLink
+	err := r.db.Where("id = ? AND user_id = ?", shareID, userID).First(&share).Error
+	if err != nil {
+		return nil, err
+	}
+	share.HasPassword = share.PasswordHash != ""
+	return &share, nil
+}
+
+func (r *GORMShareRepository) GetSharesByUserID(userID string) ([]models.ShareLink, error) {
+	var shares []models.ShareLink
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&shares).Error
+	for i := range shares {
+		shares[i].HasPassword = shares[i].PasswordHash != ""
+	}
+	return shares, err
+}
+
+func (r *GORMShareRepository) RevokeShare(shareID uint, userID string) error {
+	return r.db.Model(&models.ShareLink{}).Where("id = ? AND user_id = ?", shareID, userID).Update("revoked", true).Error
+}
+
+func (r *GORMShareRepository) IncrementAccessCount(shareID uint) error {
+	return r.db.Model(&models.ShareLink{}).Where("id = ?", shareID).
+		UpdateColumn("access_count", gorm.Expr("access_count + 1")).Error
+}
+
+// TryIncrementDownloadCount 在未超过下载上限时原子地增加下载次数（同时计入访问次数），返回是否成功
+func (r *GORMShareRepository) TryIncrementDownloadCount(shareID uint) (bool, error) {
+	result := r.db.Model(&models.ShareLink{}).
+		Where("id = ? AND (max_downloads = 0 OR download_count < max_downloads)", shareID).
+		UpdateColumns(map[string]interface{}{
+			"download_count": gorm.Expr("download_count + 1"),
+			"access_count":   gorm.Expr("access_count + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *GORMShareRepository) CreateAccessLog(log *models.ShareAccessLog) error {
+	return r.db.Create(log).Error
+}
+
+func (r *GORMShareRepository) GetAccessLogs(shareID uint, limit int) ([]models.ShareAccessLog, error) {
+	var logs []models.ShareAccessLog
+	err := r.db.Where("share_id = ?", shareID).Order("created_at DESC").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+// deleteShareLinks 删除指向已删除资源的分享链接及其访问日志
+func deleteShareLinks(tx *gorm.DB, resourceType string, resourceIDs []uint) error {
+	links := tx.Model(&models.ShareLink{}).Select("id").Where("resource_type = ? AND resource_id IN ?", resourceType, resourceIDs)
+	if err := tx.Where("share_id IN (?)", links).Delete(&models.ShareAccessLog{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("resource_type = ? AND resource_id IN ?", resourceType, resourceIDs).Delete(&models.ShareLink{}).Error
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"time"
+
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GORMSSHKeyRepository SSH公钥仓库
+type GORMSSHKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewGORMSSHKeyRepository 创建SSH公钥仓库
+func NewGORMSSHKeyRepository(db *gorm.DB) *GORMSSHKeyRepository {
+	return &GORMSSHKeyRepository{db: db}
+}
+
+// CreateSSHKey 登记SSH公钥
+func (r *GORMSSHKeyRepository) CreateSSHKey(key *models.SSHKey) error {
+	return r.db.Create(key).Error
+}
+
+// GetSSHKeysByUser 获取用户的全部SSH公钥，按创建时间倒序
+func (r *GORMSSHKeyRepository) GetSSHKeysByUser(userID string) ([]models.SSHKey, error) {
+	var keys []models.SSHKey
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// CountSSHKeys 统计用户的SSH公钥数量
+func (r *GORMSSHKeyRepository) CountSSHKeys(userID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.SSHKey{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// GetSSHKeyByFingerprint 按指纹查找SSH公钥，不存在时返回 nil
+func (r *GORMSSHKeyRepository) GetSSHKeyByFingerprint(fingerprint string) (*models.SSHKey, error) {
+	var key models.SSHKey
+	err := r.db.Where("fingerprint = ?", fingerprint).First(&key).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// DeleteSSHKey 删除用户的SSH公钥，返回是否删除了记录
+func (r *GORMSSHKeyRepository) DeleteSSHKey(keyID uint, userID string) (bool, error) {
+	result := r.db.Where("id = ? AND user_id = ?", keyID, userID).Delete(&models.SSHKey{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// TouchSSHKey 记录SSH公钥的最近使用时间
+func (r *GORMSSHKeyRepository) TouchSSHKey(keyID uint) error {
+	return r.db.Model(&models.SSHKey{}).Where("id = ?", keyID).Update("last_used_at", time.Now()).Error
+}
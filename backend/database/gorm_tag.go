@@ -0,0 +1,208 @@
+package database
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GORMTagRepository 标签仓库
+type GORMTagRepository struct {
+	db *gorm.DB
+}
+
+// NewGORMTagRepository 创建标签仓库
+func NewGORMTagRepository(db *gorm.DB) *GORMTagRepository {
+	return &GORMTagRepository{db: db}
+}
+
+// tagItemTables 各类对象对应的表和所在文件夹列
+var tagItemTables = []struct {
+	itemType     string
+	table        string
+	parentColumn string
+}{
+	{models.SearchItemFile, "files", "folder_id"},
+	{models.SearchItemFolder, "folders", "parent_id"},
+	{models.SearchItemUrlFile, "url_files", "folder_id"},
+}
+
+// GetTagsByUserID 获取用户的全部标签及各标签关联的对象数量，按名称排序
+func (r *GORMTagRepository) GetTagsByUserID(userID string) ([]models.Tag, error) {
+	var tags []models.Tag
+	err := r.db.Model(&models.Tag{}).
+		Select("tags.*, (SELECT COUNT(*) FROM item_tags it WHERE it.tag_id = tags.id) AS item_count").
+		Where("user_id = ?", userID).
+		Order("name").
+		Find(&tags).Error
+	return tags, err
+}
+
+// GetTagByID 获取用户的单个标签
+func (r *GORMTagRepository) GetTagByID(tagID uint, userID string) (*models.Tag, error) {
+	var tag models.Tag
+	if err := r.db.Where("id = ? AND user_id = ?", tagID, userID).First(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// GetTagByName 按名称获取用户的标签（名称比较不区分大小写）
+func (r *GORMTagRepository) GetTagByName(userID, name string) (*models.Tag, error) {
+	var tag models.Tag
+	if err := r.db.Where("user_id = ? AND name = ?", userID, name).First(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// GetTagsByIDs 获取用户拥有的指定标签，不存在或不属于该用户的ID会被忽略
+func (r *GORMTagRepository) GetTagsByIDs(userID string, tagIDs []uint) ([]models.Tag, error) {
+	var tags []models.Tag
+	if len(tagIDs) == 0 {
+		return tags, nil
+	}
+	err := r.db.Where("user_id = ? AND id IN ?", userID, tagIDs).Find(&tags).Error
+	return tags, err
+}
+
+// GetTagsByNames 按名称获取用户的标签，不存在的名称会被忽略
+func (r *GORMTagRepository) GetTagsByNames(userID string, names []string) ([]models.Tag, error) {
+	var tags []models.Tag
+	if len(names) == 0 {
+		return tags, nil
+	}
+	err := r.db.Where("user_id = ? AND name IN ?", userID, names).Find(&tags).Error
+	return tags, err
+}
+
+// CreateTag 创建标签
+func (r *GORMTagRepository) CreateTag(tag *models.Tag) error {
+	return r.db.Create(tag).Error
+}
+
+// UpdateTag 修改标签名称和颜色
+func (r *GORMTagRepository) UpdateTag(tag *models.Tag) error {
+	return r.db.Model(&models.Tag{}).Where("id = ? AND user_id = ?", tag.ID, tag.UserID).
+		Updates(map[string]interface{}{"name": tag.Name, "color": tag.Color}).Error
+}
+
+// DeleteTag 删除标签及其全部关联
+func (r *GORMTagRepository) DeleteTag(tagID uint, userID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", tagID, userID).Delete(&models.Tag{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("tag_id = ?", tagID).Delete(&models.ItemTag{}).Error
+	})
+}
+
+// FilterOwnedItems 返回 items 中确实存在且属于该用户的对象，保持传入顺序
+func (r *GORMTagRepository) FilterOwnedItems(userID string, items []models.TagItemRef) ([]models.TagItemRef, error) {
+	idsByType := make(map[string][]uint)
+	for _, item := range items {
+		idsByType[item.ItemType] = append(idsByType[item.ItemType], item.ID)
+	}
+
+	owned := make(map[models.TagItemRef]bool, len(items))
+	for _, source := range tagItemTables {
+		ids := idsByType[source.itemType]
+		if len(ids) == 0 {
+			continue
+		}
+		var found []uint
+		if err := r.db.Table(source.table).Where("user_id = ? AND id IN ?", userID, ids).Pluck("id", &found).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range found {
+			owned[models.TagItemRef{ItemType: source.itemType, ID: id}] = true
+		}
+	}
+
+	result := make([]models.TagItemRef, 0, len(owned))
+	for _, item := range items {
+		if owned[item] {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// AddItemTags 为对象打上标签，已存在的关联保持不变，返回新增的关联数量
+func (r *GORMTagRepository) AddItemTags(userID string, tagIDs []uint, items []models.TagItemRef) (int64, error) {
+	links := make([]models.ItemTag, 0, len(tagIDs)*len(items))
+	for _, tagID := range tagIDs {
+		for _, item := range items {
+			links = append(links, models.ItemTag{TagID: tagID, ItemType: item.ItemType, ItemID: item.ID, UserID: userID})
+		}
+	}
+	if len(links) == 0 {
+		return 0, nil
+	}
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(links, 500)
+	return result.RowsAffected, result.Error
+}
+
+// RemoveItemTags 取消对象上的标签，返回删除的关联数量
+func (r *GORMTagRepository) RemoveItemTags(userID string, tagIDs []uint, items []models.TagItemRef) (int64, error) {
+	if len(tagIDs) == 0 || len(items) == 0 {
+		return 0, nil
+	}
+	idsByType := make(map[string][]uint)
+	for _, item := range items {
+		idsByType[item.ItemType] = append(idsByType[item.ItemType], item.ID)
+	}
+
+	var affected int64
+	for itemType, ids := range idsByType {
+		result := r.db.Where("user_id = ? AND tag_id IN ? AND item_type = ? AND item_id IN ?", userID, tagIDs, itemType, ids).
+			Delete(&models.ItemTag{})
+		if result.Error != nil {
+			return affected, result.Error
+		}
+		affected += result.RowsAffected
+	}
+	return affected, nil
+}
+
+// GetItemTags 获取指定对象上该用户的标签关联
+func (r *GORMTagRepository) GetItemTags(userID, itemType string, itemIDs []uint) ([]models.ItemTag, error) {
+	var links []models.ItemTag
+	if len(itemIDs) == 0 {
+		return links, nil
+	}
+	err := r.db.Where("user_id = ? AND item_type = ? AND item_id IN ?", userID, itemType, itemIDs).
+		Order("item_id, tag_id").Find(&links).Error
+	return links, err
+}
+
+// GetFolderTagCounts 统计每个文件夹中直接包含的文件、子文件夹、URL文件上各标签的数量。
+// 每类对象分别关联，只统计仍然存在的对象
+func (r *GORMTagRepository) GetFolderTagCounts(userID string) ([]models.FolderTagCount, error) {
+	counts := make(map[[2]uint]int)
+	for _, source := range tagItemTables {
+		var rows []models.FolderTagCount
+		err := r.db.Raw(`SELECT t.`+source.parentColumn+` AS folder_id, it.tag_id, COUNT(*) AS count
+			FROM item_tags it JOIN `+source.table+` t ON t.id = it.item_id
+			WHERE it.user_id = ? AND it.item_type = ? AND t.user_id = ? AND t.`+source.parentColumn+` IS NOT NULL
+			GROUP BY t.`+source.parentColumn+`, it.tag_id`,
+			userID, source.itemType, userID).Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			counts[[2]uint{row.FolderID, row.TagID}] += row.Count
+		}
+	}
+
+	result := make([]models.FolderTagCount, 0, len(counts))
+	for key, count := range counts {
+		result = append(result, models.FolderTagCount{FolderID: key[0], TagID: key[1], Count: count})
+	}
+	return result, nil
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// RepositorySet 共享同一个数据库会话的仓库集合，用于在事务中组合多个仓库操作
+type RepositorySet struct {
+	Files    FileRepositoryInterface
+	Folders  FolderRepositoryInterface
+	UrlFiles UrlFileRepositoryInterface
+	Versions FileVersionRepositoryInterface
+	Grants   GrantRepositoryInterface
+	Tags     TagRepositoryInterface
+
+	db *gorm.DB
+}
+
+// NewGORMRepositorySet 基于 GORM 会话创建仓库集合
+func NewGORMRepositorySet(db *gorm.DB) *RepositorySet {
+	return &RepositorySet{
+		Files:    NewGORMFileRepository(db),
+		Folders:  NewGORMFolderRepository(db),
+		UrlFiles: NewGORMUrlFileRepository(db),
+		Versions: NewGORMFileVersionRepository(db),
+		Grants:   NewGORMGrantRepository(db),
+		Tags:     NewGORMTagRepository(db),
+		db:       db,
+	}
+}
+
+// Transaction 在事务中执行 fn，fn 返回错误时回滚；已处于事务中时使用保存点实现嵌套事务
+func (s *RepositorySet) Transaction(fn func(repos *RepositorySet) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(NewGORMRepositorySet(tx))
+	})
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"time"
+
+	"backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GORMUploadTaskRepository 上传任务仓库
+type GORMUploadTaskRepository struct {
+	db *gorm.DB
+}
+
+// NewGORMUploadTaskRepository 创建上传任务仓库
+func NewGORMUploadTaskRepository(db *gorm.DB) *GORMUploadTaskRepository {
+	return &GORMUploadTaskRepository{db: db}
+}
+
+// SaveUploadTask 保存任务的当前状态，任务不存在时创建
+func (r *GORMUploadTaskRepository) SaveUploadTask(task *models.UploadTask) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(task).Error
+}
+
+// GetUploadTask 获取任务，不存在时返回 nil
+func (r *GORMUploadTaskRepository) GetUploadTask(taskID string) (*models.UploadTask, error) {
+	var task models.UploadTask
+	err := r.db.Where("id = ?", taskID).First(&task).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// GetUploadTasksByUser 获取用户最近的任务，按创建时间倒序
+func (r *GORMUploadTaskRepository) GetUploadTasksByUser(userID string, limit int) ([]models.UploadTask, error) {
+	var tasks []models.UploadTask
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Find(&tasks).Error
+	return tasks, err
+}
+
+// DeleteUploadTasksBefore 删除在指定时间之后没有更新过的任务
+func (r *GORMUploadTaskRepository) DeleteUploadTasksBefore(before time.Time) error {
+	return r.db.Where("updated_at < ?", before).Delete(&models.UploadTask{}).Error
+}
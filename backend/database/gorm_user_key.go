@@ -0,0 +1,145 @@
+package database
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GORMUserKeyRepository 用户数据密钥仓库
+type GORMUserKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewGORMUserKeyRepository 创建用户数据密钥仓库
+func NewGORMUserKeyRepository(db *gorm.DB) *GORMUserKeyRepository {
+	return &GORMUserKeyRepository{db: db}
+}
+
+// GetUserKey 按ID获取数据密钥，不存在时返回 nil
+func (r *GORMUserKeyRepository) GetUserKey(id uint64) (*models.UserKey, error) {
+	var key models.UserKey
+	err := r.db.Where("id = ?", id).First(&key).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetActiveUserKey 获取用户使用中的数据密钥，不存在时返回 nil
+func (r *GORMUserKeyRepository) GetActiveUserKey(userID string) (*models.UserKey, error) {
+	var key models.UserKey
+	err := r.db.Where("user_id = ? AND status = ?", userID, models.UserKeyActive).Order("id DESC").First(&key).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// CreateUserKey 创建数据密钥
+func (r *GORMUserKeyRepository) CreateUserKey(key *models.UserKey) error {
+	return r.db.Create(key).Error
+}
+
+// ReplaceActiveUserKey 在同一事务中停用用户原有的数据密钥并登记新密钥
+func (r *GORMUserKeyRepository) ReplaceActiveUserKey(key *models.UserKey) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserKey{}).
+			Where("user_id = ? AND status = ?", key.UserID, models.UserKeyActive).
+			Update("status", models.UserKeyRetired).Error; err != nil {
+			return err
+		}
+		return tx.Create(key).Error
+	})
+}
+
+// UpdateWrappedKey 保存以新主密钥重新加密的数据密钥
+func (r *GORMUserKeyRepository) UpdateWrappedKey(id uint64, wrappedKey []byte, masterKeyID string) error {
+	return r.db.Model(&models.UserKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"wrapped_key":   wrappedKey,
+		"master_key_id": masterKeyID,
+	}).Error
+}
+
+// GetUserKeysAfterID 按ID顺序分页获取数据密钥
+func (r *GORMUserKeyRepository) GetUserKeysAfterID(afterID uint64, limit int) ([]models.UserKey, error) {
+	var keys []models.UserKey
+	err := r.db.Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&keys).Error
+	return keys, err
+}
+
+// CountUserKeys 统计各状态的数据密钥数量，以及不是由指定主密钥加密的数量
+func (r *GORMUserKeyRepository) CountUserKeys(masterKeyID string) (active, retired, toRewrap int64, err error) {
+	if err = r.db.Model(&models.UserKey{}).Where("status = ?", models.UserKeyActive).Count(&active).Error; err != nil {
+		return
+	}
+	if err = r.db.Model(&models.UserKey{}).Where("status = ?", models.UserKeyRetired).Count(&retired).Error; err != nil {
+		return
+	}
+	err = r.db.Model(&models.UserKey{}).Where("master_key_id <> ?", masterKeyID).Count(&toRewrap).Error
+	return
+}
+
+// CountStoredContent 统计当前文件和历史版本的数量
+func (r *GORMUserKeyRepository) CountStoredContent() (int64, error) {
+	var files, versions int64
+	if err := r.db.Model(&models.File{}).Count(&files).Error; err != nil {
+		return 0, err
+	}
+	if err := r.db.Model(&models.FileVersion{}).Count(&versions).Error; err != nil {
+		return 0, err
+	}
+	return files + versions, nil
+}
+
+// GetFileContentAfterID 按ID顺序分页获取当前文件的存储位置
+func (r *GORMUserKeyRepository) GetFileContentAfterID(afterID uint, limit int) ([]models.StoredContentRef, error) {
+	var files []models.File
+	err := r.db.Select("id", "user_id", "path", "encryption_key_id", "scan_status").
+		Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&files).Error
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]models.StoredContentRef, len(files))
+	for i := range files {
+		refs[i] = models.StoredContentRef{
+			ID:          files[i].ID,
+			UserID:      files[i].UserID,
+			Path:        files[i].Path,
+			KeyID:       files[i].EncryptionKeyID,
+			Quarantined: files[i].IsInfected(),
+		}
+	}
+	return refs, nil
+}
+
+// GetVersionContentAfterID 按ID顺序分页获取历史版本的存储位置
+func (r *GORMUserKeyRepository) GetVersionContentAfterID(afterID uint, limit int) ([]models.StoredContentRef, error) {
+	var refs []models.StoredContentRef
+	err := r.db.Model(&models.FileVersion{}).Select("id", "user_id", "path", "encryption_key_id AS key_id").
+		Where("id > ?", afterID).Order("id ASC").Limit(limit).Scan(&refs).Error
+	return refs, err
+}
+
+// UpdateFileContentKey 文件内容以新的数据密钥重写到 path 后更新记录。只有记录仍指向重写前的内容
+// （路径和密钥ID均未变）时才更新，期间内容被替换、重命名或删除时返回 false，由调用方删除新写入的内容
+func (r *GORMUserKeyRepository) UpdateFileContentKey(ref models.StoredContentRef, path string, keyID uint64) (bool, error) {
+	db := r.db.Model(&models.File{}).
+		Where("id = ? AND path = ? AND encryption_key_id = ?", ref.ID, ref.Path, ref.KeyID).
+		UpdateColumns(map[string]interface{}{"path": path, "encryption_key_id": keyID})
+	return db.RowsAffected > 0, db.Error
+}
+
+// UpdateVersionContentKey 历史版本内容以新的数据密钥重写到 path 后更新记录，条件与 UpdateFileContentKey 相同
+func (r *GORMUserKeyRepository) UpdateVersionContentKey(ref models.StoredContentRef, path string, keyID uint64) (bool, error) {
+	db := r.db.Model(&models.FileVersion{}).
+		Where("id = ? AND path = ? AND encryption_key_id = ?", ref.ID, ref.Path, ref.KeyID).
+		UpdateColumns(map[string]interface{}{"path": path, "encryption_key_id": keyID})
+	return db.RowsAffected > 0, db.Error
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GORMVaultRepository 保险库设备和密钥信封仓库
+type GORMVaultRepository struct {
+	db *gorm.DB
+}
+
+// NewGORMVaultRepository 创建保险库仓库
+func NewGORMVaultRepository(db *gorm.DB) *GORMVaultRepository {
+	return &GORMVaultRepository{db: db}
+}
+
+// CreateVaultDevice 登记保险库设备
+func (r *GORMVaultRepository) CreateVaultDevice(device *models.VaultDevice) error {
+	return r.db.Create(device).Error
+}
+
+// GetVaultDevices 获取用户登记的全部设备，按创建时间排序
+func (r *GORMVaultRepository) GetVaultDevices(userID string) ([]models.VaultDevice, error) {
+	var devices []models.VaultDevice
+	err := r.db.Where("user_id = ?", userID).Order("created_at, id").Find(&devices).Error
+	return devices, err
+}
+
+// GetVaultDevice 获取用户的设备，不存在时返回 nil
+func (r *GORMVaultRepository) GetVaultDevice(deviceID uint, userID string) (*models.VaultDevice, error) {
+	var device models.VaultDevice
+	err := r.db.Where("id = ? AND user_id = ?", deviceID, userID).First(&device).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// GetVaultDeviceByFingerprint 按公钥指纹查找用户的设备，不存在时返回 nil
+func (r *GORMVaultRepository) GetVaultDeviceByFingerprint(userID, fingerprint string) (*models.VaultDevice, error) {
+	var device models.VaultDevice
+	err := r.db.Where("user_id = ? AND fingerprint = ?", userID, fingerprint).First(&device).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// CountVaultDevices 统计用户的设备数量
+func (r *GORMVaultRepository) CountVaultDevices(userID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.VaultDevice{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// DeleteVaultDevice 在事务中删除设备及为其包装的全部密钥信封，返回是否删除了设备
+func (r *GORMVaultRepository) DeleteVaultDevice(deviceID uint, userID string) (bool, error) {
+	found := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", deviceID, userID).Delete(&models.VaultDevice{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		found = true
+		return tx.Where("device_id = ? AND user_id = ?", deviceID, userID).Delete(&models.VaultKeyEnvelope{}).Error
+	})
+	return found, err
+}
+
+// CreateVault 在事务中创建保险库根文件夹（vault_id 指向自身）并写入初始密钥信封
+func (r *GORMVaultRepository) CreateVault(folder *models.Folder, envelopes []models.VaultKeyEnvelope) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		folder.VaultID = nil
+		if err := tx.Create(folder).Error; err != nil {
+			return err
+		}
+		vaultID := folder.ID
+		if err := tx.Model(&models.Folder{}).Where("id = ?", folder.ID).Update("vault_id", vaultID).Error; err != nil {
+			return err
+		}
+		folder.VaultID = &vaultID
+
+		for i := range envelopes {
+			envelopes[i].VaultID = vaultID
+		}
+		if len(envelopes) > 0 {
+			if err := tx.Create(&envelopes).Error; err != nil {
+				return err
+			}
+		}
+		return recordChange(tx, folder.UserID, folderChange(models.ChangeCreate, folder))
+	})
+}
+
+// GetVaults 获取用户的全部保险库根文件夹
+func (r *GORMVaultRepository) GetVaults(userID string) ([]models.Folder, error) {
+	var folders []models.Folder
+	err := r.db.Where("user_id = ? AND vault_id = id", userID).Order("created_at, id").Find(&folders).Error
+	return folders, err
+}
+
+// PutVaultKeyEnvelopes 写入密钥信封，同一保险库和设备已有信封时覆盖
+func (r *GORMVaultRepository) PutVaultKeyEnvelopes(envelopes []models.VaultKeyEnvelope) error {
+	if len(envelopes) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "vault_id"}, {Name: "device_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"algorithm", "wrapped_key", "updated_at"}),
+	}).Create(&envelopes).Error
+}
+
+// GetVaultKeyEnvelopes 获取保险库的密钥信封，deviceID 非空时只返回该设备的信封
+func (r *GORMVaultRepository) GetVaultKeyEnvelopes(vaultID uint, userID string, deviceID *uint) ([]models.VaultKeyEnvelope, error) {
+	var envelopes []models.VaultKeyEnvelope
+	query := r.db.Where("vault_id = ? AND user_id = ?", vaultID, userID)
+	if deviceID != nil {
+		query = query.Where("device_id = ?", *deviceID)
+	}
+	err := query.Order("device_id").Find(&envelopes).Error
+	return envelopes, err
+}
+
+// DeleteVaultKeyEnvelope 撤销某台设备对保险库的访问，返回是否删除了信封
+func (r *GORMVaultRepository) DeleteVaultKeyEnvelope(vaultID, deviceID uint, userID string) (bool, error) {
+	result := r.db.Where("vault_id = ? AND device_id = ? AND user_id = ?", vaultID, deviceID, userID).Delete(&models.VaultKeyEnvelope{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteVaultKeyEnvelopes 删除保险库的全部密钥信封，在保险库根文件夹删除后调用
+func (r *GORMVaultRepository) DeleteVaultKeyEnvelopes(vaultID uint, userID string) error {
+	return r.db.Where("vault_id = ? AND user_id = ?", vaultID, userID).Delete(&models.VaultKeyEnvelope{}).Error
+}
@@ -1,6 +1,9 @@
 package database
 
 import (
+	"encoding/json"
+	"time"
+
 	"backend/models"
 )
 
@@ -19,6 +22,7 @@ type UserRepositoryInterface interface {
 	CheckUsernameExists(username string) (bool, error)
 	GetUserStorageInfo(userID string) (int64, int64, error)
 	UpdateUserStorage(userID string, storageLimit int64) error
+	UpdateUserMaxFileVersions(userID string, maxVersions int) error
 	UpdateLastLoginTime(uuid string) error
 	SetUserOffline(uuid string) error
 }
@@ -26,30 +30,44 @@ type UserRepositoryInterface interface {
 // FileRepositoryInterface 文件仓库接口
 type FileRepositoryInterface interface {
 	GetFilesByUserID(userID string, folderID *uint) ([]models.File, error)
+	ListFiles(query *models.ListQuery) ([]models.File, string, error)
 	GetFileByID(fileID uint, userID string) (*models.File, error)
 	GetFileByName(fileName, userID string) (*models.File, error)
 	GetFileByNameAndUser(fileName, userID string) (*models.File, error)
+	GetFileByNameInFolder(userID, name string, folderID *uint) (*models.File, error)
+	GetFileByPath(path string) (*models.File, error)
 	CreateFile(file *models.File) error
+	UpdateFile(file *models.File) error
 	DeleteFile(fileID uint, userID string) error
 	MoveFile(fileID uint, userID string, folderID *uint) error
+	UpdateFileMetadata(fileID uint, userID string, description *string, patch map[string]json.RawMessage) error
 	GetUserTotalStorage(userID string) (int64, error)
 	GetUserFileCount(userID string) (int, error)
 	GetUserTotalFileCount(userID string) (int, error)
 	GetTotalFileCount() (int, error)
-	SearchFilesByUserID(userID, query string, folderID *uint) ([]models.File, error)
+	SearchItems(query *models.FileSearchQuery) (*models.FileSearchPage, error)
 }
 
 // FolderRepositoryInterface 文件夹仓库接口
 type FolderRepositoryInterface interface {
 	GetFoldersByUserID(userID string) ([]models.Folder, error)
+	ListFolders(query *models.ListQuery) ([]models.Folder, string, error)
 	GetFolderByID(folderID uint, userID string) (*models.Folder, error)
 	CreateFolder(folder *models.Folder) error
 	UpdateFolder(folderID uint, userID, name, category string) error
+	UpdateFolderMetadata(folderID uint, userID string, description *string, patch map[string]json.RawMessage) error
+	MoveFolder(folderID uint, userID string, parentID *uint, name string) error
 	DeleteFolder(folderID uint, userID string) error
+	DeleteFolderRecursive(folderID uint, userID string) (*models.FolderDeleteResult, error)
 	CheckFolderExists(folderID uint, userID string) (bool, error)
 	CheckFolderNameExists(userID, name, category string, excludeID uint) (bool, error)
 	GetFolderFileCount(folderID uint, userID string) (int, error)
 	GetFolderUrlFileCount(folderID uint, userID string) (int, error)
+	GetFolderByNameInParent(userID, name string, parentID *uint) (*models.Folder, error)
+	GetSubFolders(parentID uint, userID string) ([]models.Folder, error)
+	GetDescendantFolderIDs(folderID uint, userID string) ([]uint, error)
+	GetFolderTree(userID string, rootID *uint) ([]*models.FolderTreeNode, error)
+	GetFolderPath(folderID uint, userID string) ([]models.Folder, error)
 }
 
 // DocumentRepositoryInterface 文档仓库接口
@@ -57,6 +75,7 @@ type DocumentRepositoryInterface interface {
 	CreateDocument(doc *models.Document) error
 	GetDocumentsByCategory(category string) ([]models.Document, error)
 	GetDocuments() ([]models.Document, error)
+	ListDocuments(query *models.ListQuery) ([]models.Document, string, error)
 	GetDocumentByID(id uint) (*models.Document, error)
 	UpdateDocument(doc *models.Document) error
 	DeleteDocument(id uint) error
@@ -65,6 +84,7 @@ type DocumentRepositoryInterface interface {
 // UrlFileRepositoryInterface URL文件仓库接口
 type UrlFileRepositoryInterface interface {
 	GetUrlFilesByUserID(userID string, folderID *uint) ([]models.UrlFile, error)
+	ListUrlFiles(query *models.ListQuery) ([]models.UrlFile, string, error)
 	GetUrlFileByID(fileID uint, userID string) (*models.UrlFile, error)
 	CreateUrlFile(file *models.UrlFile) error
 	DeleteUrlFile(fileID uint, userID string) error
@@ -72,3 +92,190 @@ type UrlFileRepositoryInterface interface {
 	GetUserTotalUrlFileCount(userID string) (int, error)
 	GetFolderUrlFileCount(folderID uint, userID string) (int, error)
 }
+
+// FileVersionRepositoryInterface 文件版本仓库接口
+type FileVersionRepositoryInterface interface {
+	CreateVersion(version *models.FileVersion) error
+	GetVersionsByFileID(fileID uint, userID string) ([]models.FileVersion, error)
+	GetVersionByID(versionID, fileID uint, userID string) (*models.FileVersion, error)
+	GetNextVersionNumber(fileID uint) (int, error)
+	DeleteVersion(versionID uint, userID string) error
+	DeleteVersionsByFileID(fileID uint, userID string) error
+	GetUserVersionStorage(userID string) (int64, error)
+}
+
+// ShareRepositoryInterface 分享链接仓库接口
+type ShareRepositoryInterface interface {
+	CreateShare(share *models.ShareLink) error
+	GetShareBySlug(slug string) (*models.ShareLink, error)
+	GetShareByID(shareID uint, userID string) (*models.ShareLink, error)
+	GetSharesByUserID(userID string) ([]models.ShareLink, error)
+	RevokeShare(shareID uint, userID string) error
+	IncrementAccessCount(shareID uint) error
+	TryIncrementDownloadCount(shareID uint) (bool, error)
+	CreateAccessLog(log *models.ShareAccessLog) error
+	GetAccessLogs(shareID uint, limit int) ([]models.ShareAccessLog, error)
+}
+
+// GrantRepositoryInterface 用户间共享授权仓库接口
+type GrantRepositoryInterface interface {
+	CreateGrant(grant *models.ShareGrant) error
+	GetGrantByID(grantID uint) (*models.ShareGrant, error)
+	GetExistingGrant(resourceType string, resourceID uint, granteeUserID string, granteeGroupID *uint) (*models.ShareGrant, error)
+	UpdateGrantPermission(grantID uint, permission string) error
+	DeleteGrant(grantID uint) error
+	DeleteGrantsByResource(resourceType string, resourceID uint) error
+	GetGrantsByResource(resourceType string, resourceID uint) ([]models.ShareGrant, error)
+	GetGrantsByOwner(ownerID string) ([]models.ShareGrant, error)
+	GetGrantsForUser(userID string) ([]models.ShareGrant, error)
+	GetFilePermission(fileID uint, userID string) (*models.File, string, error)
+	GetFolderPermission(folderID uint, userID string) (*models.Folder, string, error)
+}
+
+// UserGroupRepositoryInterface 用户组仓库接口
+type UserGroupRepositoryInterface interface {
+	CreateGroup(group *models.UserGroup) error
+	GetGroupByID(groupID uint) (*models.UserGroup, error)
+	GetGroupsForUser(userID string) ([]models.UserGroup, error)
+	DeleteGroup(groupID uint, ownerID string) error
+	AddMember(groupID uint, userID string) error
+	RemoveMember(groupID uint, userID string) error
+	GetMembers(groupID uint) ([]models.UserGroupMember, error)
+	IsMember(groupID uint, userID string) (bool, error)
+}
+
+// FileContentRepositoryInterface 文件内容全文索引仓库接口
+type FileContentRepositoryInterface interface {
+	GetFilesToIndex(limit int) ([]models.File, error)
+	UpsertContent(content *models.FileContent) error
+	DeleteContent(fileID uint) error
+	ResetUserContents(userID string) (int64, error)
+	GetIndexStatus(userID string) (*models.ContentIndexStatus, error)
+	SearchContent(phrase string, scope models.ContentSearchScope, limit, offset int) ([]models.ContentSearchHit, int64, error)
+}
+
+// NamePinyinRepositoryInterface 名称拼音索引仓库接口
+type NamePinyinRepositoryInterface interface {
+	GetNamesToIndex(limit int) ([]models.NameIndexSource, error)
+	UpsertNames(names []models.NamePinyin) error
+	DeleteOrphanNames() (int64, error)
+	GetFuzzyCandidates(query *models.FileSearchQuery, limit int) ([]models.FuzzyCandidate, error)
+	LoadItems(candidates []models.FuzzyCandidate) ([]models.SearchItem, error)
+}
+
+// TagRepositoryInterface 标签仓库接口
+type TagRepositoryInterface interface {
+	GetTagsByUserID(userID string) ([]models.Tag, error)
+	GetTagByID(tagID uint, userID string) (*models.Tag, error)
+	GetTagByName(userID, name string) (*models.Tag, error)
+	GetTagsByIDs(userID string, tagIDs []uint) ([]models.Tag, error)
+	GetTagsByNames(userID string, names []string) ([]models.Tag, error)
+	CreateTag(tag *models.Tag) error
+	UpdateTag(tag *models.Tag) error
+	DeleteTag(tagID uint, userID string) error
+	FilterOwnedItems(userID string, items []models.TagItemRef) ([]models.TagItemRef, error)
+	AddItemTags(userID string, tagIDs []uint, items []models.TagItemRef) (int64, error)
+	RemoveItemTags(userID string, tagIDs []uint, items []models.TagItemRef) (int64, error)
+	GetItemTags(userID, itemType string, itemIDs []uint) ([]models.ItemTag, error)
+	GetFolderTagCounts(userID string) ([]models.FolderTagCount, error)
+}
+
+// ActivityRepositoryInterface 收藏与最近访问仓库接口
+type ActivityRepositoryInterface interface {
+	AddFavorite(userID, itemType string, itemID uint) error
+	RemoveFavorite(userID, itemType string, itemID uint) (bool, error)
+	ListFavorites(query *models.ActivityQuery) ([]models.ActivityItem, string, error)
+	RecordAccess(userID, itemType string, itemID uint) error
+	ListRecent(query *models.ActivityQuery) ([]models.ActivityItem, string, error)
+	RemoveRecent(userID, itemType string, itemID uint) error
+	ClearRecent(userID string) error
+	SetRecentHistoryEnabled(userID string, enabled bool) error
+}
+
+// FileScanRepositoryInterface 文件病毒扫描状态仓库接口
+type FileScanRepositoryInterface interface {
+	GetFilesToScan(limit int) ([]models.File, error)
+	GetFilesAfterID(afterID uint, limit int) ([]models.File, error)
+	CountFiles() (int64, error)
+	UpdateScanResult(fileID uint, path, status, result string) (bool, error)
+	GetScanStatusCounts() ([]models.ScanStatusCount, error)
+	GetInfectedFiles(limit, offset int) ([]models.File, int64, error)
+}
+
+// NotificationRepositoryInterface 站内通知仓库接口
+type NotificationRepositoryInterface interface {
+	CreateNotifications(notifications []models.Notification) error
+	GetNotifications(userID string, unreadOnly bool, limit, offset int) ([]models.Notification, int64, error)
+	CountUnread(userID string) (int64, error)
+	MarkRead(userID string, notificationID uint) (bool, error)
+	MarkAllRead(userID string) (int64, error)
+	GetLatestNotificationID() (uint, error)
+	GetNotificationsAfter(afterID uint, userIDs []string, limit int) ([]models.Notification, error)
+}
+
+// AppTokenRepositoryInterface 应用专用密码仓库接口
+type AppTokenRepositoryInterface interface {
+	CreateAppToken(token *models.AppToken) error
+	GetAppTokensByUser(userID string) ([]models.AppToken, error)
+	CountAppTokens(userID string) (int64, error)
+	GetAppTokenByHash(tokenHash string) (*models.AppToken, error)
+	DeleteAppToken(tokenID uint, userID string) (bool, error)
+	TouchAppToken(tokenID uint) error
+}
+
+// SSHKeyRepositoryInterface SSH公钥仓库接口
+type SSHKeyRepositoryInterface interface {
+	CreateSSHKey(key *models.SSHKey) error
+	GetSSHKeysByUser(userID string) ([]models.SSHKey, error)
+	CountSSHKeys(userID string) (int64, error)
+	GetSSHKeyByFingerprint(fingerprint string) (*models.SSHKey, error)
+	DeleteSSHKey(keyID uint, userID string) (bool, error)
+	TouchSSHKey(keyID uint) error
+}
+
+// ChangeRepositoryInterface 变更日志仓库接口，变更记录由文件、文件夹和URL文件仓库在修改时写入
+type ChangeRepositoryInterface interface {
+	GetLatestChangeSeq(userID string) (int64, error)
+	GetLatestChangeSeqs(userIDs []string) (map[string]int64, error)
+	GetChangesSince(userID string, cursor int64, limit int) ([]models.Change, int64, error)
+}
+
+// UploadTaskRepositoryInterface 上传任务仓库接口，实现 utils.UploadTaskStore
+type UploadTaskRepositoryInterface interface {
+	SaveUploadTask(task *models.UploadTask) error
+	GetUploadTask(taskID string) (*models.UploadTask, error)
+	GetUploadTasksByUser(userID string, limit int) ([]models.UploadTask, error)
+	DeleteUploadTasksBefore(before time.Time) error
+}
+
+// UserKeyRepositoryInterface 用户数据密钥仓库接口，同时提供加密迁移和密钥轮换需要遍历的文件内容
+type UserKeyRepositoryInterface interface {
+	GetUserKey(id uint64) (*models.UserKey, error)
+	GetActiveUserKey(userID string) (*models.UserKey, error)
+	CreateUserKey(key *models.UserKey) error
+	ReplaceActiveUserKey(key *models.UserKey) error
+	UpdateWrappedKey(id uint64, wrappedKey []byte, masterKeyID string) error
+	GetUserKeysAfterID(afterID uint64, limit int) ([]models.UserKey, error)
+	CountUserKeys(masterKeyID string) (active, retired, toRewrap int64, err error)
+	CountStoredContent() (int64, error)
+	GetFileContentAfterID(afterID uint, limit int) ([]models.StoredContentRef, error)
+	GetVersionContentAfterID(afterID uint, limit int) ([]models.StoredContentRef, error)
+	UpdateFileContentKey(ref models.StoredContentRef, path string, keyID uint64) (bool, error)
+	UpdateVersionContentKey(ref models.StoredContentRef, path string, keyID uint64) (bool, error)
+}
+
+// VaultRepositoryInterface 保险库设备和密钥信封仓库接口，保险库中的文件和文件夹由文件、文件夹仓库保存
+type VaultRepositoryInterface interface {
+	CreateVaultDevice(device *models.VaultDevice) error
+	GetVaultDevices(userID string) ([]models.VaultDevice, error)
+	GetVaultDevice(deviceID uint, userID string) (*models.VaultDevice, error)
+	GetVaultDeviceByFingerprint(userID, fingerprint string) (*models.VaultDevice, error)
+	CountVaultDevices(userID string) (int64, error)
+	DeleteVaultDevice(deviceID uint, userID string) (bool, error)
+	CreateVault(folder *models.Folder, envelopes []models.VaultKeyEnvelope) error
+	GetVaults(userID string) ([]models.Folder, error)
+	PutVaultKeyEnvelopes(envelopes []models.VaultKeyEnvelope) error
+	GetVaultKeyEnvelopes(vaultID uint, userID string, deviceID *uint) ([]models.VaultKeyEnvelope, error)
+	DeleteVaultKeyEnvelope(vaultID, deviceID uint, userID string) (bool, error)
+	DeleteVaultKeyEnvelopes(vaultID uint, userID string) error
+}
@@ -97,6 +97,8 @@ func (s *SafeDatabaseInitializer) safeCreateTables() error {
 				storage_limit BIGINT DEFAULT 1073741824,
 				last_login_time TIMESTAMP NULL,
 				is_online BOOLEAN DEFAULT FALSE,
+				max_file_versions INT DEFAULT 10,
+				recent_history_enabled BOOLEAN DEFAULT TRUE,
 				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 			)`,
@@ -110,10 +112,22 @@ func (s *SafeDatabaseInitializer) safeCreateTables() error {
 				user_id VARCHAR(50) NOT NULL,
 				folder_id INT,
 				thumbnail_data LONGTEXT,
+				checksum VARCHAR(64),
+				uploaded_by VARCHAR(50),
+				description TEXT,
+				metadata JSON,
+				scan_status VARCHAR(20) DEFAULT '',
+				scan_result VARCHAR(255),
+				scanned_at TIMESTAMP NULL,
+				vault_id INT NULL,
+				encrypted_key TEXT,
+				encryption_key_id BIGINT UNSIGNED NOT NULL DEFAULT 0,
 				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 				INDEX idx_user_id (user_id),
-				INDEX idx_created_at (created_at)
+				INDEX idx_created_at (created_at),
+				INDEX idx_scan_status (scan_status),
+				INDEX idx_vault_id (vault_id)
 			)`,
 		"folders": `
 			CREATE TABLE IF NOT EXISTS folders (
@@ -122,11 +136,15 @@ func (s *SafeDatabaseInitializer) safeCreateTables() error {
 				user_id VARCHAR(50) NOT NULL,
 				category VARCHAR(50) DEFAULT 'all',
 				parent_id INT,
+				description TEXT,
+				metadata JSON,
+				vault_id INT NULL,
 				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 				INDEX idx_user_id (user_id),
 				INDEX idx_parent_id (parent_id),
-				INDEX idx_category (category)
+				INDEX idx_category (category),
+				INDEX idx_vault_id (vault_id)
 			)`,
 		"documents": `
 			CREATE TABLE IF NOT EXISTS documents (
@@ -168,6 +186,268 @@ func (s *SafeDatabaseInitializer) safeCreateTables() error {
 				INDEX idx_user_id (user_id),
 				INDEX idx_folder_id (folder_id)
 			)`,
+		"file_versions": `
+			CREATE TABLE IF NOT EXISTS file_versions (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				file_id INT NOT NULL,
+				user_id VARCHAR(50) NOT NULL,
+				version INT NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				size BIGINT NOT NULL,
+				checksum VARCHAR(64),
+				path VARCHAR(500) NOT NULL,
+				uploaded_by VARCHAR(50),
+				encryption_key_id BIGINT UNSIGNED NOT NULL DEFAULT 0,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				INDEX idx_file_id (file_id),
+				INDEX idx_user_id (user_id),
+				UNIQUE KEY uk_file_version (file_id, version)
+			)`,
+		"share_links": `
+			CREATE TABLE IF NOT EXISTS share_links (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				slug VARCHAR(32) NOT NULL,
+				user_id VARCHAR(50) NOT NULL,
+				resource_type VARCHAR(20) NOT NULL,
+				resource_id INT NOT NULL,
+				password_hash VARCHAR(255),
+				mode VARCHAR(20) DEFAULT 'read_only',
+				expires_at TIMESTAMP NULL,
+				max_downloads INT DEFAULT 0,
+				download_count INT DEFAULT 0,
+				access_count INT DEFAULT 0,
+				revoked BOOLEAN DEFAULT FALSE,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				UNIQUE KEY uk_slug (slug),
+				INDEX idx_user_id (user_id)
+			)`,
+		"share_access_logs": `
+			CREATE TABLE IF NOT EXISTS share_access_logs (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				share_id INT NOT NULL,
+				action VARCHAR(20) NOT NULL,
+				file_id INT,
+				ip VARCHAR(64),
+				user_agent VARCHAR(255),
+				detail VARCHAR(255),
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				INDEX idx_share_id (share_id)
+			)`,
+		"share_grants": `
+			CREATE TABLE IF NOT EXISTS share_grants (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				owner_id VARCHAR(50) NOT NULL,
+				resource_type VARCHAR(20) NOT NULL,
+				resource_id INT NOT NULL,
+				grantee_type VARCHAR(20) NOT NULL,
+				grantee_user_id VARCHAR(50),
+				grantee_group_id INT,
+				permission VARCHAR(20) NOT NULL,
+				created_by VARCHAR(50),
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_owner_id (owner_id),
+				INDEX idx_resource (resource_type, resource_id),
+				INDEX idx_grantee_user_id (grantee_user_id),
+				INDEX idx_grantee_group_id (grantee_group_id)
+			)`,
+		"user_groups": `
+			CREATE TABLE IF NOT EXISTS user_groups (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				name VARCHAR(100) NOT NULL,
+				owner_id VARCHAR(50) NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_owner_id (owner_id)
+			)`,
+		"user_group_members": `
+			CREATE TABLE IF NOT EXISTS user_group_members (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				group_id INT NOT NULL,
+				user_id VARCHAR(50) NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE KEY uk_group_user (group_id, user_id),
+				INDEX idx_user_id (user_id)
+			)`,
+		"file_contents": `
+			CREATE TABLE IF NOT EXISTS file_contents (
+				file_id INT PRIMARY KEY,
+				user_id VARCHAR(50) NOT NULL,
+				source_path VARCHAR(500) NOT NULL,
+				source_checksum VARCHAR(64) NOT NULL DEFAULT '',
+				status VARCHAR(20) NOT NULL DEFAULT 'indexed',
+				content LONGTEXT,
+				error VARCHAR(500),
+				indexed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				INDEX idx_user_id (user_id),
+				FULLTEXT INDEX ft_content (content) WITH PARSER ngram
+			)`,
+		"name_pinyin": `
+			CREATE TABLE IF NOT EXISTS name_pinyin (
+				item_type VARCHAR(20) NOT NULL,
+				item_id INT NOT NULL,
+				user_id VARCHAR(50) NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				full_pinyin TEXT,
+				initials TEXT,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				PRIMARY KEY (item_type, item_id),
+				INDEX idx_user_id (user_id)
+			)`,
+		"tags": `
+			CREATE TABLE IF NOT EXISTS tags (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				user_id VARCHAR(50) NOT NULL,
+				name VARCHAR(50) NOT NULL,
+				color VARCHAR(20) NOT NULL DEFAULT '#6b7280',
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				UNIQUE KEY uk_user_name (user_id, name)
+			)`,
+		"item_tags": `
+			CREATE TABLE IF NOT EXISTS item_tags (
+				tag_id INT NOT NULL,
+				item_type VARCHAR(20) NOT NULL,
+				item_id INT NOT NULL,
+				user_id VARCHAR(50) NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (tag_id, item_type, item_id),
+				INDEX idx_item (item_type, item_id),
+				INDEX idx_user_id (user_id)
+			)`,
+		"favorites": `
+			CREATE TABLE IF NOT EXISTS favorites (
+				user_id VARCHAR(50) NOT NULL,
+				item_type VARCHAR(20) NOT NULL,
+				item_id INT NOT NULL,
+				created_at TIMESTAMP(3) DEFAULT CURRENT_TIMESTAMP(3),
+				PRIMARY KEY (user_id, item_type, item_id),
+				INDEX idx_user_created (user_id, created_at)
+			)`,
+		"recent_items": `
+			CREATE TABLE IF NOT EXISTS recent_items (
+				user_id VARCHAR(50) NOT NULL,
+				item_type VARCHAR(20) NOT NULL,
+				item_id INT NOT NULL,
+				accessed_at TIMESTAMP(3) DEFAULT CURRENT_TIMESTAMP(3),
+				access_count INT DEFAULT 1,
+				PRIMARY KEY (user_id, item_type, item_id),
+				INDEX idx_user_accessed (user_id, accessed_at)
+			)`,
+		"notifications": `
+			CREATE TABLE IF NOT EXISTS notifications (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				user_id VARCHAR(50) NOT NULL,
+				type VARCHAR(50) NOT NULL,
+				title VARCHAR(255) NOT NULL,
+				content TEXT,
+				item_type VARCHAR(20),
+				item_id INT,
+				read_at TIMESTAMP NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				INDEX idx_user_created (user_id, created_at)
+			)`,
+		"app_tokens": `
+			CREATE TABLE IF NOT EXISTS app_tokens (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				user_id VARCHAR(50) NOT NULL,
+				name VARCHAR(100) NOT NULL,
+				token_hash CHAR(64) NOT NULL,
+				hint VARCHAR(16) NOT NULL,
+				last_used_at TIMESTAMP NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE KEY uk_token_hash (token_hash),
+				INDEX idx_user (user_id)
+			)`,
+		"ssh_keys": `
+			CREATE TABLE IF NOT EXISTS ssh_keys (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				user_id VARCHAR(50) NOT NULL,
+				name VARCHAR(100) NOT NULL,
+				key_type VARCHAR(50) NOT NULL,
+				public_key TEXT NOT NULL,
+				fingerprint VARCHAR(64) NOT NULL,
+				last_used_at TIMESTAMP NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE KEY uk_fingerprint (fingerprint),
+				INDEX idx_user (user_id)
+			)`,
+		"change_sequences": `
+			CREATE TABLE IF NOT EXISTS change_sequences (
+				user_id VARCHAR(50) PRIMARY KEY,
+				seq BIGINT NOT NULL DEFAULT 0
+			)`,
+		"changes": `
+			CREATE TABLE IF NOT EXISTS changes (
+				id BIGINT AUTO_INCREMENT PRIMARY KEY,
+				user_id VARCHAR(50) NOT NULL,
+				seq BIGINT NOT NULL,
+				item_type VARCHAR(20) NOT NULL,
+				item_id INT NOT NULL,
+				action VARCHAR(20) NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				parent_id INT NULL,
+				size BIGINT NOT NULL DEFAULT 0,
+				checksum VARCHAR(64),
+				created_at TIMESTAMP(3) DEFAULT CURRENT_TIMESTAMP(3),
+				UNIQUE KEY uk_user_seq (user_id, seq),
+				INDEX idx_user_created (user_id, created_at)
+			)`,
+		"upload_tasks": `
+			CREATE TABLE IF NOT EXISTS upload_tasks (
+				id VARCHAR(100) PRIMARY KEY,
+				user_id VARCHAR(50) NOT NULL,
+				file_name VARCHAR(500) NOT NULL,
+				file_size BIGINT NOT NULL DEFAULT 0,
+				bytes_done BIGINT NOT NULL DEFAULT 0,
+				speed BIGINT NOT NULL DEFAULT 0,
+				progress INT NOT NULL DEFAULT 0,
+				status VARCHAR(20) NOT NULL,
+				error TEXT,
+				type VARCHAR(20),
+				result_url VARCHAR(500),
+				created_at TIMESTAMP(3) DEFAULT CURRENT_TIMESTAMP(3),
+				updated_at TIMESTAMP(3) DEFAULT CURRENT_TIMESTAMP(3),
+				INDEX idx_user_created (user_id, created_at),
+				INDEX idx_updated (updated_at)
+			)`,
+		"user_keys": `
+			CREATE TABLE IF NOT EXISTS user_keys (
+				id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+				user_id VARCHAR(50) NOT NULL,
+				wrapped_key VARBINARY(128) NOT NULL,
+				master_key_id VARCHAR(16) NOT NULL,
+				status VARCHAR(20) NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				INDEX idx_user_status (user_id, status),
+				INDEX idx_master_key (master_key_id)
+			)`,
+		"vault_devices": `
+			CREATE TABLE IF NOT EXISTS vault_devices (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				user_id VARCHAR(50) NOT NULL,
+				name VARCHAR(100) NOT NULL,
+				algorithm VARCHAR(50) NOT NULL,
+				public_key TEXT NOT NULL,
+				fingerprint VARCHAR(64) NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE KEY uk_user_fingerprint (user_id, fingerprint)
+			)`,
+		"vault_key_envelopes": `
+			CREATE TABLE IF NOT EXISTS vault_key_envelopes (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				vault_id INT NOT NULL,
+				device_id INT NOT NULL,
+				user_id VARCHAR(50) NOT NULL,
+				algorithm VARCHAR(50) NOT NULL,
+				wrapped_key TEXT NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				UNIQUE KEY uk_vault_device (vault_id, device_id),
+				INDEX idx_device (device_id),
+				INDEX idx_user (user_id)
+			)`,
 	}
 
 	// 只创建不存在的表
@@ -211,6 +491,86 @@ func (s *SafeDatabaseInitializer) safeFieldMigration() error {
 			columnName: "thumbnail_data",
 			sql:        "ALTER TABLE files ADD COLUMN IF NOT EXISTS thumbnail_data LONGTEXT",
 		},
+		{
+			tableName:  "files",
+			columnName: "checksum",
+			sql:        "ALTER TABLE files ADD COLUMN IF NOT EXISTS checksum VARCHAR(64)",
+		},
+		{
+			tableName:  "files",
+			columnName: "uploaded_by",
+			sql:        "ALTER TABLE files ADD COLUMN IF NOT EXISTS uploaded_by VARCHAR(50)",
+		},
+		{
+			tableName:  "user",
+			columnName: "max_file_versions",
+			sql:        "ALTER TABLE user ADD COLUMN IF NOT EXISTS max_file_versions INT DEFAULT 10",
+		},
+		{
+			tableName:  "user",
+			columnName: "recent_history_enabled",
+			sql:        "ALTER TABLE user ADD COLUMN IF NOT EXISTS recent_history_enabled BOOLEAN DEFAULT TRUE",
+		},
+		{
+			tableName:  "files",
+			columnName: "description",
+			sql:        "ALTER TABLE files ADD COLUMN IF NOT EXISTS description TEXT",
+		},
+		{
+			tableName:  "files",
+			columnName: "metadata",
+			sql:        "ALTER TABLE files ADD COLUMN IF NOT EXISTS metadata JSON",
+		},
+		{
+			tableName:  "folders",
+			columnName: "description",
+			sql:        "ALTER TABLE folders ADD COLUMN IF NOT EXISTS description TEXT",
+		},
+		{
+			tableName:  "folders",
+			columnName: "metadata",
+			sql:        "ALTER TABLE folders ADD COLUMN IF NOT EXISTS metadata JSON",
+		},
+		{
+			tableName:  "files",
+			columnName: "scan_status",
+			sql:        "ALTER TABLE files ADD COLUMN IF NOT EXISTS scan_status VARCHAR(20) DEFAULT ''",
+		},
+		{
+			tableName:  "files",
+			columnName: "scan_result",
+			sql:        "ALTER TABLE files ADD COLUMN IF NOT EXISTS scan_result VARCHAR(255)",
+		},
+		{
+			tableName:  "files",
+			columnName: "scanned_at",
+			sql:        "ALTER TABLE files ADD COLUMN IF NOT EXISTS scanned_at TIMESTAMP NULL",
+		},
+		{
+			tableName:  "files",
+			columnName: "vault_id",
+			sql:        "ALTER TABLE files ADD COLUMN IF NOT EXISTS vault_id INT NULL",
+		},
+		{
+			tableName:  "files",
+			columnName: "encrypted_key",
+			sql:        "ALTER TABLE files ADD COLUMN IF NOT EXISTS encrypted_key TEXT",
+		},
+		{
+			tableName:  "folders",
+			columnName: "vault_id",
+			sql:        "ALTER TABLE folders ADD COLUMN IF NOT EXISTS vault_id INT NULL",
+		},
+		{
+			tableName:  "files",
+			columnName: "encryption_key_id",
+			sql:        "ALTER TABLE files ADD COLUMN IF NOT EXISTS encryption_key_id BIGINT UNSIGNED NOT NULL DEFAULT 0",
+		},
+		{
+			tableName:  "file_versions",
+			columnName: "encryption_key_id",
+			sql:        "ALTER TABLE file_versions ADD COLUMN IF NOT EXISTS encryption_key_id BIGINT UNSIGNED NOT NULL DEFAULT 0",
+		},
 	}
 
 	// 安全添加字段
@@ -268,7 +628,7 @@ func (s *SafeDatabaseInitializer) validateDatabaseIntegrity() error {
 	log.Println("🔧 验证数据库完整性...")
 
 	// 验证所有必需的表都存在
-	requiredTables := []string{"user", "files", "folders", "documents", "update_logs", "url_files"}
+	requiredTables := []string{"user", "files", "folders", "documents", "update_logs", "url_files", "file_versions", "share_links", "share_access_logs", "share_grants", "user_groups", "user_group_members", "file_contents", "name_pinyin", "tags", "item_tags", "favorites", "recent_items", "notifications", "app_tokens", "ssh_keys", "change_sequences", "changes", "upload_tasks", "user_keys", "vault_devices", "vault_key_envelopes"}
 	existingTables, err := s.getExistingTables()
 	if err != nil {
 		return fmt.Errorf("获取现有表失败: %v", err)
@@ -315,7 +675,7 @@ func (s *SafeDatabaseInitializer) PerformReadOnlyCheck() error {
 	}
 
 	// 2. 检测必需的表是否存在
-	requiredTables := []string{"user", "files", "folders", "documents", "update_logs", "url_files"}
+	requiredTables := []string{"user", "files", "folders", "documents", "update_logs", "url_files", "file_versions", "share_links", "share_access_logs", "share_grants", "user_groups", "user_group_members", "file_contents", "name_pinyin", "tags", "item_tags", "favorites", "recent_items", "notifications", "app_tokens", "ssh_keys", "change_sequences", "changes", "upload_tasks", "user_keys", "vault_devices", "vault_key_envelopes"}
 	existingTables, err := s.getExistingTables()
 	if err != nil {
 		return fmt.Errorf("无法获取表信息: %v", err)
@@ -347,6 +707,15 @@ func (s *SafeDatabaseInitializer) PerformReadOnlyCheck() error {
 		{"folders", "id", "文件夹ID字段"},
 		{"folders", "name", "文件夹名字段"},
 		{"folders", "user_id", "文件夹用户ID字段"},
+		{"files", "checksum", "文件校验和字段"},
+		{"files", "uploaded_by", "文件上传者字段"},
+		{"user", "max_file_versions", "用户版本保留数量字段"},
+		{"user", "recent_history_enabled", "用户最近访问记录开关字段"},
+		{"files", "description", "文件描述字段"},
+		{"files", "metadata", "文件自定义元数据字段"},
+		{"folders", "description", "文件夹描述字段"},
+		{"folders", "metadata", "文件夹自定义元数据字段"},
+		{"files", "scan_status", "文件病毒扫描状态字段"},
 	}
 
 	missingFields := []string{}
@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActivityHandler 收藏与最近访问处理器
+type ActivityHandler struct {
+	activityRepo database.ActivityRepositoryInterface
+	userRepo     database.UserRepositoryInterface
+	grantRepo    database.GrantRepositoryInterface
+	urlFileRepo  database.UrlFileRepositoryInterface
+}
+
+// NewActivityHandler 创建收藏与最近访问处理器实例
+func NewActivityHandler(activityRepo database.ActivityRepositoryInterface, userRepo database.UserRepositoryInterface, grantRepo database.GrantRepositoryInterface, urlFileRepo database.UrlFileRepositoryInterface) *ActivityHandler {
+	return &ActivityHandler{
+		activityRepo: activityRepo,
+		userRepo:     userRepo,
+		grantRepo:    grantRepo,
+		urlFileRepo:  urlFileRepo,
+	}
+}
+
+// recordRecentAccess 记录用户对文件、文件夹或URL文件的访问，失败只记录日志，不影响本次请求
+func recordRecentAccess(activityRepo database.ActivityRepositoryInterface, userID, itemType string, itemID uint) {
+	if err := activityRepo.RecordAccess(userID, itemType, itemID); err != nil {
+		log.Printf("记录最近访问失败: %v", err)
+	}
+}
+
+// GetFavorites 按收藏时间倒序分页获取收藏的文件、文件夹和URL文件
+func (h *ActivityHandler) GetFavorites(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	query, ok := parseActivityQuery(c, userID)
+	if !ok {
+		return
+	}
+	items, nextCursor, err := h.activityRepo.ListFavorites(query)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取收藏列表失败"})
+		return
+	}
+	items, err = h.filterAccessible(items, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取收藏列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ActivityListResponse{
+		Success:    true,
+		Items:      items,
+		NextCursor: nextCursor,
+		HasMore:    nextCursor != "",
+	})
+}
+
+// AddFavorite 收藏文件、文件夹（包括共享给我的）或自己的URL文件，重复收藏不报错
+func (h *ActivityHandler) AddFavorite(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.FavoriteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	switch request.ItemType {
+	case models.SearchItemFile:
+		if _, ok := resolveFileAccess(c, h.grantRepo, request.ID, userID, models.PermissionViewer); !ok {
+			return
+		}
+	case models.SearchItemFolder:
+		if _, _, ok := resolveFolderAccess(c, h.grantRepo, request.ID, userID, models.PermissionViewer); !ok {
+			return
+		}
+	case models.SearchItemUrlFile:
+		if _, err := h.urlFileRepo.GetUrlFileByID(request.ID, userID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "URL文件不存在"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type 只支持 file、folder、url_file"})
+		return
+	}
+
+	if err := h.activityRepo.AddFavorite(userID, request.ItemType, request.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "收藏失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "收藏成功"})
+}
+
+// RemoveFavorite 取消收藏
+func (h *ActivityHandler) RemoveFavorite(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	itemType, itemID, ok := parseActivityItem(c)
+	if !ok {
+		return
+	}
+	removed, err := h.activityRepo.RemoveFavorite(userID, itemType, itemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "取消收藏失败"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未收藏该对象"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "已取消收藏"})
+}
+
+// GetRecent 按访问时间倒序分页获取最近访问的文件、文件夹和URL文件
+func (h *ActivityHandler) GetRecent(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userRepo.GetUserByUUID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+		return
+	}
+	query, ok := parseActivityQuery(c, userID)
+	if !ok {
+		return
+	}
+	items, nextCursor, err := h.activityRepo.ListRecent(query)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取最近访问失败"})
+		return
+	}
+	items, err = h.filterAccessible(items, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取最近访问失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RecentListResponse{
+		ActivityListResponse: models.ActivityListResponse{
+			Success:    true,
+			Items:      items,
+			NextCursor: nextCursor,
+			HasMore:    nextCursor != "",
+		},
+		Enabled: user.RecentHistoryEnabled,
+	})
+}
+
+// RemoveRecent 从最近访问中移除单个对象
+func (h *ActivityHandler) RemoveRecent(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	itemType, itemID, ok := parseActivityItem(c)
+	if !ok {
+		return
+	}
+	if err := h.activityRepo.RemoveRecent(userID, itemType, itemID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除最近访问失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "已从最近访问中移除"})
+}
+
+// ClearRecent 清空最近访问
+func (h *ActivityHandler) ClearRecent(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.activityRepo.ClearRecent(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "清空最近访问失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "最近访问已清空"})
+}
+
+// GetRecentSettings 获取最近访问记录开关
+func (h *ActivityHandler) GetRecentSettings(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userRepo.GetUserByUUID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"enabled":     user.RecentHistoryEnabled,
+		"max_records": models.MaxRecentItems,
+	})
+}
+
+// UpdateRecentSettings 开启或关闭最近访问记录，关闭时清空已有记录
+func (h *ActivityHandler) UpdateRecentSettings(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.RecentSettingsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	if err := h.activityRepo.SetRecentHistoryEnabled(userID, *request.Enabled); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新最近访问设置失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "最近访问设置更新成功",
+		"enabled": *request.Enabled,
+	})
+}
+
+// filterAccessible 过滤掉共享已被取消的他人文件和文件夹
+func (h *ActivityHandler) filterAccessible(items []models.ActivityItem, userID string) ([]models.ActivityItem, error) {
+	accessible := items[:0]
+	for _, item := range items {
+		if item.OwnerID != userID {
+			var permission string
+			var err error
+			switch item.ItemType {
+			case models.SearchItemFile:
+				_, permission, err = h.grantRepo.GetFilePermission(item.RecordID(), userID)
+			case models.SearchItemFolder:
+				_, permission, err = h.grantRepo.GetFolderPermission(item.RecordID(), userID)
+			}
+			if err != nil && !isRecordNotFound(err) {
+				return nil, err
+			}
+			if permission == "" {
+				continue
+			}
+		}
+		accessible = append(accessible, item)
+	}
+	return accessible, nil
+}
+
+// parseActivityQuery 解析 type、limit、cursor 参数，失败时直接写入响应
+func parseActivityQuery(c *gin.Context, userID string) (*models.ActivityQuery, bool) {
+	query := &models.ActivityQuery{UserID: userID, ItemType: c.Query("type")}
+	switch query.ItemType {
+	case "", models.SearchItemFile, models.SearchItemFolder, models.SearchItemUrlFile:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type 只支持 file、folder、url_file"})
+		return nil, false
+	}
+
+	limit, _, ok := parseLimitOffset(c, models.DefaultRecentLimit, models.MaxRecentLimit)
+	if !ok {
+		return nil, false
+	}
+	query.Limit = limit
+
+	if encoded := c.Query("cursor"); encoded != "" {
+		cursor, err := models.DecodeActivityCursor(encoded)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		query.Cursor = cursor
+	}
+	return query, true
+}
+
+// parseActivityItem 解析路径中的对象类型和ID，失败时直接写入响应
+func parseActivityItem(c *gin.Context) (string, uint, bool) {
+	itemType := c.Param("type")
+	switch itemType {
+	case models.SearchItemFile, models.SearchItemFolder, models.SearchItemUrlFile:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type 只支持 file、folder、url_file"})
+		return "", 0, false
+	}
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的对象ID"})
+		return "", 0, false
+	}
+	return itemType, uint(itemID), true
+}
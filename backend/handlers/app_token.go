@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AppTokenHandler 应用专用密码处理器
+type AppTokenHandler struct {
+	appTokenRepo database.AppTokenRepositoryInterface
+}
+
+// NewAppTokenHandler 创建应用专用密码处理器实例
+func NewAppTokenHandler(appTokenRepo database.AppTokenRepositoryInterface) *AppTokenHandler {
+	return &AppTokenHandler{appTokenRepo: appTokenRepo}
+}
+
+// credentialUser 应用专用密码、SSH公钥等登录凭据等同于账号密码，只允许为已登录的用户本人管理；
+// 事件流同样只推送给登录用户本人。不使用 user_id 参数
+func credentialUser(c *gin.Context) (*models.User, bool) {
+	currentUser, _ := c.Get("currentUser")
+	user, _ := currentUser.(*models.User)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+		return nil, false
+	}
+	return user, true
+}
+
+// GetAppTokens 获取当前用户的应用专用密码列表（不含明文）
+func (h *AppTokenHandler) GetAppTokens(c *gin.Context) {
+	user, ok := credentialUser(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.appTokenRepo.GetAppTokensByUser(user.UUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取应用专用密码失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"tokens":  tokens,
+	})
+}
+
+// CreateAppToken 创建应用专用密码，明文只在响应中返回一次
+func (h *AppTokenHandler) CreateAppToken(c *gin.Context) {
+	user, ok := credentialUser(c)
+	if !ok {
+		return
+	}
+
+	var request models.CreateAppTokenRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	name := strings.TrimSpace(request.Name)
+	if name == "" || len(name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "名称不能为空且不能超过100个字符"})
+		return
+	}
+
+	count, err := h.appTokenRepo.CountAppTokens(user.UUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取应用专用密码失败"})
+		return
+	}
+	if count >= models.MaxAppTokensPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "应用专用密码数量已达上限"})
+		return
+	}
+
+	random, err := utils.GenerateRandomSlug(40)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成应用专用密码失败"})
+		return
+	}
+	plain := models.AppTokenPrefix + random
+
+	token := models.AppToken{
+		UserID:    user.UUID,
+		Name:      name,
+		TokenHash: utils.HashAppToken(plain),
+		Hint:      plain[len(plain)-4:],
+	}
+	if err := h.appTokenRepo.CreateAppToken(&token); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存应用专用密码失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CreateAppTokenResponse{
+		Success:  true,
+		Token:    plain,
+		AppToken: token,
+	})
+}
+
+// DeleteAppToken 吊销应用专用密码
+func (h *AppTokenHandler) DeleteAppToken(c *gin.Context) {
+	user, ok := credentialUser(c)
+	if !ok {
+		return
+	}
+
+	tokenID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的应用专用密码ID"})
+		return
+	}
+
+	found, err := h.appTokenRepo.DeleteAppToken(uint(tokenID), user.UUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除应用专用密码失败"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "应用专用密码不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "应用专用密码已吊销"})
+}
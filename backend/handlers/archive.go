@@ -0,0 +1,742 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/async"
+	"backend/config"
+	"backend/database"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ArchiveHandler 打包下载与解压处理器
+type ArchiveHandler struct {
+	fileRepo     database.FileRepositoryInterface
+	folderRepo   database.FolderRepositoryInterface
+	grantRepo    database.GrantRepositoryInterface
+	userRepo     database.UserRepositoryInterface
+	queueManager *utils.UploadQueueManager
+	taskManager  *async.TaskManager
+	scanner      *services.VirusScanService
+}
+
+// NewArchiveHandler 创建打包下载与解压处理器实例
+func NewArchiveHandler(fileRepo database.FileRepositoryInterface, folderRepo database.FolderRepositoryInterface, grantRepo database.GrantRepositoryInterface, userRepo database.UserRepositoryInterface, queueManager *utils.UploadQueueManager, taskManager *async.TaskManager, scanner *services.VirusScanService) *ArchiveHandler {
+	return &ArchiveHandler{
+		fileRepo:     fileRepo,
+		folderRepo:   folderRepo,
+		grantRepo:    grantRepo,
+		userRepo:     userRepo,
+		queueManager: queueManager,
+		taskManager:  taskManager,
+		scanner:      scanner,
+	}
+}
+
+// archiveCollector 收集待打包的条目，处理同名冲突和重复选择
+type archiveCollector struct {
+	handler   *ArchiveHandler
+	entries   []utils.ArchiveEntry
+	names     *utils.ArchiveNameSet
+	seenFiles map[uint]bool
+	totalSize int64
+
+	// 需要导出描述和元数据时记录有描述或元数据的条目
+	includeMetadata bool
+	metadata        []models.ArchiveMetadataEntry
+
+	// 按所有者缓存的文件夹子节点，用于还原层级结构
+	children map[string]map[uint][]models.Folder
+}
+
+// folderChildren 获取所有者文件夹的子文件夹映射（按 ParentID 分组）
+func (ac *archiveCollector) folderChildren(ownerID string) (map[uint][]models.Folder, error) {
+	if children, exists := ac.children[ownerID]; exists {
+		return children, nil
+	}
+
+	folders, err := ac.handler.folderRepo.GetFoldersByUserID(ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]models.Folder)
+	for _, folder := range folders {
+		if folder.ParentID != nil {
+			children[*folder.ParentID] = append(children[*folder.ParentID], folder)
+		}
+	}
+	ac.children[ownerID] = children
+	return children, nil
+}
+
+// addFile 将文件加入压缩包的指定目录下
+func (ac *archiveCollector) addFile(file models.File, dirPath string) {
+	// 被隔离的文件不打包
+	if ac.seenFiles[file.ID] || file.IsInfected() {
+		return
+	}
+	ac.seenFiles[file.ID] = true
+
+	name := ac.names.Reserve(dirPath + utils.SanitizeArchiveName(file.Name))
+	ac.entries = append(ac.entries, utils.ArchiveEntry{
+		Name:         name,
+		AbsolutePath: utils.GetFileAbsolutePath(file.Path),
+		KeyID:        file.EncryptionKeyID,
+		Size:         file.Size,
+		Modified:     file.UpdatedAt,
+	})
+	ac.totalSize += file.Size
+	ac.addMetadata(name, models.SearchItemFile, file.Description, file.Metadata)
+}
+
+// addMetadata 记录条目的描述和自定义元数据，两者都为空时不记录
+func (ac *archiveCollector) addMetadata(name, itemType, description string, metadata models.Metadata) {
+	if !ac.includeMetadata || (description == "" && len(metadata) == 0) {
+		return
+	}
+	ac.metadata = append(ac.metadata, models.ArchiveMetadataEntry{
+		Path:        name,
+		Type:        itemType,
+		Description: description,
+		Metadata:    metadata,
+	})
+}
+
+// addMetadataFile 在压缩包根目录加入 metadata.json
+func (ac *archiveCollector) addMetadataFile() error {
+	if ac.metadata == nil {
+		ac.metadata = []models.ArchiveMetadataEntry{}
+	}
+	data, err := json.MarshalIndent(gin.H{"items": ac.metadata}, "", "  ")
+	if err != nil {
+		return err
+	}
+	ac.entries = append(ac.entries, utils.ArchiveEntry{
+		Name:     models.ArchiveMetadataFileName,
+		Data:     data,
+		Size:     int64(len(data)),
+		Modified: time.Now(),
+	})
+	return nil
+}
+
+// addFolder 递归加入文件夹及其所有子文件夹和文件，visited 防止异常数据导致的循环
+func (ac *archiveCollector) addFolder(folder models.Folder, prefix string, visited map[uint]bool) error {
+	if visited[folder.ID] {
+		return nil
+	}
+	visited[folder.ID] = true
+
+	dirPath := ac.names.Reserve(prefix + utils.SanitizeArchiveName(folder.Name) + "/")
+	ac.entries = append(ac.entries, utils.ArchiveEntry{Name: dirPath, Modified: folder.UpdatedAt})
+	ac.addMetadata(dirPath, models.SearchItemFolder, folder.Description, folder.Metadata)
+
+	files, err := ac.handler.fileRepo.GetFilesByUserID(folder.UserID, &folder.ID)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		ac.addFile(file, dirPath)
+	}
+
+	children, err := ac.folderChildren(folder.UserID)
+	if err != nil {
+		return err
+	}
+	for _, child := range children[folder.ID] {
+		if err := ac.addFolder(child, dirPath, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectEntries 校验权限并收集所选文件和文件夹，失败时直接写入响应
+func (h *ArchiveHandler) collectEntries(c *gin.Context, userID string, request *models.CreateArchiveRequest) (*archiveCollector, bool) {
+	collector := &archiveCollector{
+		handler:   h,
+		names:     utils.NewArchiveNameSet(),
+		seenFiles: make(map[uint]bool),
+		children:  make(map[string]map[uint][]models.Folder),
+
+		includeMetadata: request.IncludeMetadata,
+	}
+	if request.IncludeMetadata {
+		// 先占用根目录的 metadata.json，同名的用户文件会被自动重命名
+		collector.names.Reserve(models.ArchiveMetadataFileName)
+	}
+
+	for _, folderID := range request.FolderIDs {
+		folder, _, ok := resolveFolderAccess(c, h.grantRepo, folderID, userID, models.PermissionViewer)
+		if !ok || rejectVaultFolder(c, folder) {
+			return nil, false
+		}
+		if err := collector.addFolder(*folder, "", make(map[uint]bool)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件夹内容失败"})
+			return nil, false
+		}
+	}
+
+	for _, fileID := range request.FileIDs {
+		file, ok := resolveFileAccess(c, h.grantRepo, fileID, userID, models.PermissionViewer)
+		if !ok || rejectInfectedFile(c, file) || rejectVaultFile(c, file) {
+			return nil, false
+		}
+		collector.addFile(*file, "")
+	}
+
+	if request.IncludeMetadata {
+		if err := collector.addMetadataFile(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "导出元数据失败"})
+			return nil, false
+		}
+	}
+	return collector, true
+}
+
+// archiveFileName 生成压缩包文件名
+func (h *ArchiveHandler) archiveFileName(request *models.CreateArchiveRequest, collector *archiveCollector) string {
+	name := strings.TrimSpace(request.Name)
+	if name == "" && len(request.FolderIDs) == 1 && len(request.FileIDs) == 0 && len(collector.entries) > 0 {
+		name = strings.TrimSuffix(collector.entries[0].Name, "/")
+	}
+	if name == "" {
+		name = "archive-" + time.Now().Format("20060102-150405")
+	}
+
+	name = utils.SanitizeArchiveName(name)
+	if !strings.HasSuffix(strings.ToLower(name), ".zip") {
+		name += ".zip"
+	}
+	return name
+}
+
+// CreateArchive 将所选文件和文件夹打包为zip；较小的选择直接流式返回，较大的选择转为异步任务
+func (h *ArchiveHandler) CreateArchive(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.CreateArchiveRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	if len(request.FileIDs) == 0 && len(request.FolderIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请选择要打包的文件或文件夹"})
+		return
+	}
+
+	collector, ok := h.collectEntries(c, userID, &request)
+	if !ok {
+		return
+	}
+
+	archiveConfig := config.GetArchiveConfig()
+	if collector.totalSize > archiveConfig.MaxArchiveSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("打包内容总大小不能超过%s，当前: %s",
+				utils.FormatStorageSize(archiveConfig.MaxArchiveSize), utils.FormatStorageSize(collector.totalSize)),
+		})
+		return
+	}
+
+	archiveName := h.archiveFileName(&request, collector)
+
+	if request.Async || collector.totalSize > archiveConfig.MaxStreamSize || len(collector.entries) > archiveConfig.MaxStreamEntries {
+		h.startArchiveJob(c, userID, archiveName, collector, archiveConfig)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", "attachment; filename=\""+archiveName+"\"")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+
+	// 响应已开始发送，出错时只能中断连接
+	if err := utils.WriteZipArchive(c.Writer, collector.entries, nil); err != nil {
+		log.Printf("流式打包失败: %v", err)
+		c.Abort()
+	}
+}
+
+// startArchiveJob 提交异步打包任务，完成后通过任务的 result_url 下载
+func (h *ArchiveHandler) startArchiveJob(c *gin.Context, userID, archiveName string, collector *archiveCollector, archiveConfig *config.ArchiveConfig) {
+	task := h.queueManager.CreateTask(userID, "archive", archiveName, collector.totalSize)
+	taskID := task.ID
+	entries := collector.entries
+	totalSize := collector.totalSize
+
+	job := func() error {
+		if !h.queueManager.StartTask(taskID) {
+			return nil
+		}
+
+		jobDir := utils.GetArchiveJobDir(userID)
+		if err := os.MkdirAll(jobDir, 0700); err != nil {
+			h.queueManager.UpdateTaskError(taskID, "创建打包目录失败")
+			return err
+		}
+
+		// 先写入临时文件，完成后按加密密钥ID重命名，下载时据此解密
+		outputPath := filepath.Join(jobDir, taskID+".zip.tmp")
+		output, err := os.Create(outputPath)
+		if err != nil {
+			h.queueManager.UpdateTaskError(taskID, "创建压缩包失败")
+			return err
+		}
+
+		writer, err := utils.NewStoredContentWriter(output, userID)
+		if err != nil {
+			output.Close()
+			os.Remove(outputPath)
+			h.queueManager.UpdateTaskError(taskID, "创建压缩包失败")
+			return err
+		}
+
+		lastProgress := -1
+		err = utils.WriteZipArchive(writer, entries, func(written int64) {
+			if totalSize <= 0 {
+				return
+			}
+			progress := int(written * 100 / totalSize)
+			if progress >= 100 {
+				progress = 99
+			}
+			if progress != lastProgress {
+				lastProgress = progress
+				h.queueManager.UpdateTaskProgress(taskID, progress)
+			}
+		})
+		if err == nil {
+			err = writer.Close()
+		}
+		closeErr := output.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(outputPath)
+			h.queueManager.UpdateTaskError(taskID, "生成压缩包失败")
+			return err
+		}
+
+		resultPath := archiveJobResultPath(jobDir, taskID, writer.KeyID())
+		if err := os.Rename(outputPath, resultPath); err != nil {
+			os.Remove(outputPath)
+			h.queueManager.UpdateTaskError(taskID, "生成压缩包失败")
+			return err
+		}
+		outputPath = resultPath
+
+		h.queueManager.UpdateTaskResult(taskID, "/api/files/archive/"+taskID+"/download")
+
+		// 过期后自动删除生成的压缩包
+		time.AfterFunc(archiveConfig.JobResultTTL, func() {
+			os.Remove(outputPath)
+		})
+		return nil
+	}
+
+	if err := h.taskManager.SubmitTask(async.NewBaseTask(taskID, 1, 0, job)); err != nil {
+		h.queueManager.UpdateTaskError(taskID, "任务队列繁忙")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "任务队列繁忙，请稍后重试"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success":     true,
+		"message":     "打包内容较大，已转为后台任务",
+		"task_id":     taskID,
+		"total_size":  totalSize,
+		"entry_count": len(entries),
+	})
+}
+
+// DownloadArchive 下载异步打包任务生成的压缩包
+func (h *ArchiveHandler) DownloadArchive(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	taskID := c.Param("task_id")
+
+	if taskID == "" || filepath.Base(taskID) != taskID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	archiveName := "archive.zip"
+	if task := h.queueManager.GetTask(taskID); task != nil {
+		if task.UserID != userID || task.Type != "archive" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+			return
+		}
+		if task.Status != "completed" {
+			c.JSON(http.StatusConflict, gin.H{"error": "压缩包尚未生成完成", "task": task})
+			return
+		}
+		archiveName = task.FileName
+	}
+
+	// 结果文件按用户隔离存放，任务记录被清理后仍可在保留期内下载
+	outputPath, keyID, ok := findArchiveJobResult(utils.GetArchiveJobDir(userID), taskID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "压缩包不存在或已过期"})
+		return
+	}
+
+	serveFileAttachment(c, outputPath, keyID, archiveName)
+}
+
+// archiveJobResultPath 打包结果文件路径，文件名中记录加密密钥ID（0 表示明文）
+func archiveJobResultPath(jobDir, taskID string, keyID uint64) string {
+	return filepath.Join(jobDir, taskID+"."+strconv.FormatUint(keyID, 10)+".zip")
+}
+
+// findArchiveJobResult 查找任务的打包结果文件，返回路径和加密密钥ID
+func findArchiveJobResult(jobDir, taskID string) (string, uint64, bool) {
+	entries, err := os.ReadDir(jobDir)
+	if err != nil {
+		return "", 0, false
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !strings.HasPrefix(name, taskID+".") || !strings.HasSuffix(name, ".zip") {
+			continue
+		}
+		keyID, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, taskID+"."), ".zip"), 10, 64)
+		if err != nil {
+			continue
+		}
+		return filepath.Join(jobDir, name), keyID, true
+	}
+	return "", 0, false
+}
+
+// archiveLimits 根据配置生成解压限制
+func archiveLimits(archiveConfig *config.ArchiveConfig) utils.ArchiveLimits {
+	return utils.ArchiveLimits{
+		MaxTotalSize: archiveConfig.MaxExtractSize,
+		MaxEntries:   archiveConfig.MaxExtractEntries,
+		MaxFileSize:  archiveConfig.MaxExtractFileSize,
+		MaxRatio:     archiveConfig.MaxCompressionRatio,
+	}
+}
+
+// ExtractArchive 将已上传的压缩包（zip/tar/tar.gz）解压到文件夹中，作为后台任务执行
+func (h *ArchiveHandler) ExtractArchive(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	fileIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件ID"})
+		return
+	}
+
+	var request models.ExtractArchiveRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+			return
+		}
+	}
+
+	archiveFile, ok := resolveFileAccess(c, h.grantRepo, uint(fileIDInt), userID, models.PermissionViewer)
+	if !ok || rejectInfectedFile(c, archiveFile) || rejectVaultFile(c, archiveFile) {
+		return
+	}
+
+	format := utils.DetectArchiveFormat(archiveFile.Name)
+	if format == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的压缩格式，仅支持 zip、tar、tar.gz"})
+		return
+	}
+
+	// 解压目标：指定文件夹（需要编辑者权限，文件归文件夹所有者）或当前用户根目录
+	ownerID := userID
+	var parent *models.Folder
+	if request.FolderID != nil {
+		folder, _, ok := resolveFolderAccess(c, h.grantRepo, *request.FolderID, userID, models.PermissionEditor)
+		if !ok {
+			return
+		}
+		parent = folder
+		ownerID = folder.UserID
+	}
+
+	archiveConfig := config.GetArchiveConfig()
+	limits := archiveLimits(archiveConfig)
+	archivePath := utils.GetFileAbsolutePath(archiveFile.Path)
+
+	// 写入前扫描压缩包：拒绝非法路径、压缩炸弹，并统计解压后的大小
+	summary, err := utils.ScanArchive(archivePath, archiveFile.EncryptionKeyID, format, limits)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "压缩包校验失败: " + err.Error()})
+		return
+	}
+
+	usedSpace, storageLimit, err := h.userRepo.GetUserStorageInfo(ownerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取存储信息失败"})
+		return
+	}
+	if !utils.ValidateFileSize(summary.TotalSize, storageLimit, usedSpace) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("存储空间不足，解压需要%s", utils.FormatStorageSize(summary.TotalSize))})
+		return
+	}
+
+	createFolder := request.CreateFolder == nil || *request.CreateFolder
+
+	task := h.queueManager.CreateTask(userID, "extract", archiveFile.Name, summary.TotalSize)
+	taskID := task.ID
+
+	extraction := &archiveExtraction{
+		handler:      h,
+		taskID:       taskID,
+		userID:       userID,
+		ownerID:      ownerID,
+		parent:       parent,
+		archiveName:  archiveFile.Name,
+		archivePath:  archivePath,
+		archiveKeyID: archiveFile.EncryptionKeyID,
+		format:       format,
+		limits:       limits,
+		totalSize:    summary.TotalSize,
+		createFolder: createFolder,
+	}
+
+	if err := h.taskManager.SubmitTask(async.NewBaseTask(taskID, 1, 0, extraction.run)); err != nil {
+		h.queueManager.UpdateTaskError(taskID, "任务队列繁忙")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "任务队列繁忙，请稍后重试"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "解压任务已提交",
+		"task_id": taskID,
+		"summary": summary,
+	})
+}
+
+// archiveExtraction 一次解压任务的执行状态
+type archiveExtraction struct {
+	handler      *ArchiveHandler
+	taskID       string
+	userID       string
+	ownerID      string
+	parent       *models.Folder
+	archiveName  string
+	archivePath  string
+	archiveKeyID uint64
+	format       string
+	limits       utils.ArchiveLimits
+	totalSize    int64
+	createFolder bool
+
+	rootFolderID   *uint
+	folders        map[string]uint // 压缩包内目录路径 -> 文件夹ID
+	createdFolders []uint
+	createdFiles   []models.File
+	storedPaths    []string
+	written        int64
+	lastProgress   int
+}
+
+// category 新建文件夹使用的分类，与父文件夹保持一致
+func (e *archiveExtraction) category() string {
+	if e.parent != nil && e.parent.Category != "" {
+		return e.parent.Category
+	}
+	return "all"
+}
+
+// createRootFolder 以压缩包名称创建解压根文件夹，同名时追加序号
+func (e *archiveExtraction) createRootFolder() error {
+	baseName := utils.SanitizeArchiveName(utils.TrimArchiveExt(e.archiveName))
+	name := baseName
+	for counter := 1; ; counter++ {
+		exists, err := e.handler.folderRepo.CheckFolderNameExists(e.ownerID, name, e.category(), 0)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			break
+		}
+		name = fmt.Sprintf("%s (%d)", baseName, counter)
+	}
+
+	folder := &models.Folder{Name: name, UserID: e.ownerID, Category: e.category()}
+	if e.parent != nil {
+		folder.ParentID = &e.parent.ID
+	}
+	if err := e.handler.folderRepo.CreateFolder(folder); err != nil {
+		return err
+	}
+	e.createdFolders = append(e.createdFolders, folder.ID)
+	e.rootFolderID = &folder.ID
+	return nil
+}
+
+// ensureFolder 确保压缩包内的目录路径在文件夹树中存在，返回对应文件夹ID（根目录返回nil）
+func (e *archiveExtraction) ensureFolder(dirPath string) (*uint, error) {
+	if dirPath == "" || dirPath == "." {
+		return e.rootFolderID, nil
+	}
+	if id, exists := e.folders[dirPath]; exists {
+		return &id, nil
+	}
+
+	parentID, err := e.ensureFolder(path.Dir(dirPath))
+	if err != nil {
+		return nil, err
+	}
+
+	folder := &models.Folder{
+		Name:     utils.SanitizeArchiveName(path.Base(dirPath)),
+		UserID:   e.ownerID,
+		Category: e.category(),
+		ParentID: parentID,
+	}
+	if err := e.handler.folderRepo.CreateFolder(folder); err != nil {
+		return nil, err
+	}
+	e.createdFolders = append(e.createdFolders, folder.ID)
+	e.folders[dirPath] = folder.ID
+	return &folder.ID, nil
+}
+
+// extractProgressReader 统计已解压字节数并更新任务进度
+type extractProgressReader struct {
+	reader     io.Reader
+	extraction *archiveExtraction
+}
+
+func (r *extractProgressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.extraction.addProgress(int64(n))
+	return n, err
+}
+
+func (e *archiveExtraction) addProgress(n int64) {
+	e.written += n
+	if e.totalSize <= 0 {
+		return
+	}
+	progress := int(e.written * 100 / e.totalSize)
+	if progress >= 100 {
+		progress = 99
+	}
+	if progress != e.lastProgress {
+		e.lastProgress = progress
+		e.handler.queueManager.UpdateTaskProgress(e.taskID, progress)
+	}
+}
+
+// handleEntry 处理单个条目：目录创建文件夹，文件写入存储并登记为 File
+func (e *archiveExtraction) handleEntry(entry utils.ExtractedEntry, reader io.Reader) error {
+	if entry.IsDir {
+		_, err := e.ensureFolder(entry.Path)
+		return err
+	}
+
+	folderID, err := e.ensureFolder(path.Dir(entry.Path))
+	if err != nil {
+		return err
+	}
+
+	fileName := utils.SanitizeArchiveName(path.Base(entry.Path))
+	stored, err := utils.StoreFileContent(&extractProgressReader{reader: reader, extraction: e}, fileName, e.ownerID)
+	if err != nil {
+		return err
+	}
+	e.storedPaths = append(e.storedPaths, stored.AbsolutePath)
+
+	file := models.File{
+		Name:            fileName,
+		Size:            stored.Size,
+		Type:            stored.FileType,
+		Path:            stored.Path,
+		UserID:          e.ownerID,
+		FolderID:        folderID,
+		Checksum:        stored.Checksum,
+		UploadedBy:      e.userID,
+		EncryptionKeyID: stored.KeyID,
+	}
+	e.handler.scanner.ResetScanState(&file)
+	if err := e.handler.fileRepo.CreateFile(&file); err != nil {
+		return err
+	}
+	e.createdFiles = append(e.createdFiles, file)
+	return nil
+}
+
+// rollback 解压失败时删除已写入的文件、文件记录和文件夹
+func (e *archiveExtraction) rollback() {
+	for _, file := range e.createdFiles {
+		e.handler.fileRepo.DeleteFile(file.ID, e.ownerID)
+	}
+	for _, storedPath := range e.storedPaths {
+		os.Remove(storedPath)
+	}
+	for i := len(e.createdFolders) - 1; i >= 0; i-- {
+		e.handler.folderRepo.DeleteFolder(e.createdFolders[i], e.ownerID)
+	}
+}
+
+// run 执行解压任务
+func (e *archiveExtraction) run() error {
+	queueManager := e.handler.queueManager
+	if !queueManager.StartTask(e.taskID) {
+		return nil
+	}
+
+	e.folders = make(map[string]uint)
+	e.lastProgress = -1
+	if e.parent != nil {
+		e.rootFolderID = &e.parent.ID
+	}
+
+	if e.createFolder {
+		if err := e.createRootFolder(); err != nil {
+			queueManager.UpdateTaskError(e.taskID, "创建文件夹失败")
+			return err
+		}
+	}
+
+	if err := utils.ExtractArchiveEntries(e.archivePath, e.archiveKeyID, e.format, e.limits, e.handleEntry); err != nil {
+		e.rollback()
+		queueManager.UpdateTaskError(e.taskID, "解压失败: "+err.Error())
+		return err
+	}
+
+	resultURL := "/api/files"
+	if e.rootFolderID != nil {
+		resultURL = fmt.Sprintf("/api/files?folder_id=%d", *e.rootFolderID)
+	}
+	queueManager.UpdateTaskResult(e.taskID, resultURL)
+	// 解压出的文件由后台扫描
+	e.handler.scanner.Notify()
+	return nil
+}
@@ -0,0 +1,749 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BatchHandler 批量操作处理器
+type BatchHandler struct {
+	repos    *database.RepositorySet
+	userRepo database.UserRepositoryInterface
+}
+
+// NewBatchHandler 创建批量操作处理器实例
+func NewBatchHandler(repos *database.RepositorySet, userRepo database.UserRepositoryInterface) *BatchHandler {
+	return &BatchHandler{repos: repos, userRepo: userRepo}
+}
+
+// batchError 批量操作中单个操作的业务错误
+type batchError struct {
+	code    string
+	message string
+}
+
+func (e *batchError) Error() string {
+	return e.message
+}
+
+func newBatchError(code, message string) *batchError {
+	return &batchError{code: code, message: message}
+}
+
+// errBatchRollback 用于主动回滚批量事务（试运行或原子模式下存在失败）
+var errBatchRollback = errors.New("batch rollback")
+
+// batchRun 一次批量请求的执行上下文
+type batchRun struct {
+	handler *BatchHandler
+	userID  string
+	dryRun  bool
+	repos   *database.RepositorySet
+
+	usage        map[string]int64 // 本批次中各所有者新增的存储占用
+	createdPaths []string         // 已写入的物理文件，事务回滚时删除
+	removedPaths []string         // 事务提交后需要删除的物理文件
+}
+
+// batchItem 单个操作的执行上下文，运行在独立的保存点中
+type batchItem struct {
+	run          *batchRun
+	repos        *database.RepositorySet
+	usage        map[string]int64
+	createdPaths []string
+	removedPaths []string
+}
+
+// ExecuteBatch 批量执行移动、删除、复制和标签操作
+func (h *BatchHandler) ExecuteBatch(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.BatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	if len(request.Operations) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "操作列表不能为空"})
+		return
+	}
+	if len(request.Operations) > models.MaxBatchOperations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("单次最多执行%d个操作", models.MaxBatchOperations)})
+		return
+	}
+
+	run := &batchRun{handler: h, userID: userID, dryRun: request.DryRun, usage: make(map[string]int64)}
+	results := make([]models.BatchItemResult, 0, len(request.Operations))
+	failed := 0
+
+	// 所有操作在同一个事务中执行，每个操作使用独立的保存点，失败时只回滚该操作
+	err := h.repos.Transaction(func(tx *database.RepositorySet) error {
+		run.repos = tx
+		for index, operation := range request.Operations {
+			result := models.BatchItemResult{Index: index, Op: operation.Op, ItemType: operation.ItemType, ID: operation.ID}
+			if request.Atomic && failed > 0 {
+				result.Code = "skipped"
+				result.Error = "前序操作失败，已跳过"
+				results = append(results, result)
+				continue
+			}
+
+			output, err := run.execute(operation)
+			if err != nil {
+				failed++
+				result.Code, result.Error = batchErrorDetail(err)
+			} else {
+				result.Success = true
+				result.Result = output
+			}
+			results = append(results, result)
+		}
+
+		if request.DryRun || (request.Atomic && failed > 0) {
+			return errBatchRollback
+		}
+		return nil
+	})
+
+	committed := err == nil
+	if !committed {
+		removeStoredPaths(run.createdPaths)
+		if !errors.Is(err, errBatchRollback) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "批量操作提交失败"})
+			return
+		}
+		// 原子模式下已成功的操作随事务一起回滚
+		if request.Atomic && !request.DryRun {
+			for i := range results {
+				if results[i].Success {
+					results[i].Success = false
+					results[i].Code = "rolled_back"
+					results[i].Error = "其他操作失败，已回滚"
+					failed++
+				}
+			}
+		}
+	} else {
+		removeStoredPaths(run.removedPaths)
+	}
+
+	c.JSON(http.StatusOK, models.BatchResponse{
+		Success:   failed == 0,
+		DryRun:    request.DryRun,
+		Atomic:    request.Atomic,
+		Committed: committed,
+		Total:     len(results),
+		Succeeded: len(results) - failed,
+		Failed:    failed,
+		Results:   results,
+	})
+}
+
+// batchErrorDetail 将错误转换为结果中的错误码和说明，内部错误不暴露细节
+func batchErrorDetail(err error) (string, string) {
+	var be *batchError
+	if errors.As(err, &be) {
+		return be.code, be.message
+	}
+	return "internal", "操作失败"
+}
+
+// execute 在独立保存点中执行单个操作，成功后合并该操作的物理文件变更与配额占用
+func (r *batchRun) execute(operation models.BatchOperation) (interface{}, error) {
+	if operation.ID == 0 {
+		return nil, newBatchError("invalid", "缺少操作对象ID")
+	}
+
+	var output interface{}
+	var item *batchItem
+	err := r.repos.Transaction(func(tx *database.RepositorySet) error {
+		item = &batchItem{run: r, repos: tx, usage: make(map[string]int64)}
+		var err error
+		output, err = item.dispatch(operation)
+		return err
+	})
+	if err != nil {
+		if item != nil {
+			removeStoredPaths(item.createdPaths)
+		}
+		return nil, err
+	}
+
+	r.createdPaths = append(r.createdPaths, item.createdPaths...)
+	r.removedPaths = append(r.removedPaths, item.removedPaths...)
+	for ownerID, size := range item.usage {
+		r.usage[ownerID] += size
+	}
+	return output, nil
+}
+
+// dispatch 根据操作类型和对象类型分派
+func (b *batchItem) dispatch(operation models.BatchOperation) (interface{}, error) {
+	strategy, ok := models.NormalizeConflictStrategy(operation.OnConflict)
+	if !ok {
+		return nil, newBatchError("invalid", "无效的冲突处理策略，可选值: fail、rename、overwrite")
+	}
+
+	switch operation.Op {
+	case models.BatchOpMove:
+		if operation.TargetFolderID == nil {
+			return nil, newBatchError("invalid", "移动操作缺少目标文件夹")
+		}
+		switch operation.ItemType {
+		case models.BatchItemFile:
+			return b.moveFile(operation.ID, *operation.TargetFolderID, strategy)
+		case models.BatchItemUrlFile:
+			return b.moveUrlFile(operation.ID, *operation.TargetFolderID)
+		case models.BatchItemFolder:
+			return b.moveFolder(operation.ID, *operation.TargetFolderID, strategy)
+		}
+	case models.BatchOpDelete:
+		switch operation.ItemType {
+		case models.BatchItemFile:
+			return b.deleteFile(operation.ID)
+		case models.BatchItemUrlFile:
+			return b.deleteUrlFile(operation.ID)
+		case models.BatchItemFolder:
+			return b.deleteFolder(operation.ID)
+		}
+	case models.BatchOpCopy:
+		switch operation.ItemType {
+		case models.BatchItemFile:
+			return b.copyFile(operation, strategy)
+		case models.BatchItemUrlFile:
+			return b.copyUrlFile(operation)
+		case models.BatchItemFolder:
+			return b.copyFolder(operation, strategy)
+		}
+	case models.BatchOpTag, models.BatchOpUntag:
+		switch operation.ItemType {
+		case models.BatchItemFile, models.BatchItemUrlFile, models.BatchItemFolder:
+			return b.changeTags(operation)
+		}
+	default:
+		return nil, newBatchError("invalid", "不支持的操作类型: "+operation.Op)
+	}
+	return nil, newBatchError("invalid", "不支持的对象类型: "+operation.ItemType)
+}
+
+// filePermission 检查当前用户对文件的权限，保险库文件只允许查看
+func (b *batchItem) filePermission(fileID uint, required string) (*models.File, error) {
+	file, permission, err := b.repos.Grants.GetFilePermission(fileID, b.run.userID)
+	if err != nil {
+		if isRecordNotFound(err) {
+			return nil, newBatchError("not_found", "文件不存在")
+		}
+		return nil, err
+	}
+	if permission == "" {
+		return nil, newBatchError("not_found", "文件不存在")
+	}
+	if !models.HasPermission(permission, required) {
+		return nil, newBatchError("forbidden", "没有操作该文件的权限")
+	}
+	if required != models.PermissionViewer && file.IsVault() {
+		return nil, newBatchError("forbidden", models.ErrVaultItem.Error())
+	}
+	return file, nil
+}
+
+// folderPermission 检查当前用户对文件夹的权限，保险库文件夹只允许查看
+func (b *batchItem) folderPermission(folderID uint, required string) (*models.Folder, error) {
+	folder, permission, err := b.repos.Grants.GetFolderPermission(folderID, b.run.userID)
+	if err != nil {
+		if isRecordNotFound(err) {
+			return nil, newBatchError("not_found", "文件夹不存在")
+		}
+		return nil, err
+	}
+	if permission == "" {
+		return nil, newBatchError("not_found", "文件夹不存在")
+	}
+	if !models.HasPermission(permission, required) {
+		return nil, newBatchError("forbidden", "没有操作该文件夹的权限")
+	}
+	if required != models.PermissionViewer && folder.IsVault() {
+		return nil, newBatchError("forbidden", models.ErrVaultItem.Error())
+	}
+	return folder, nil
+}
+
+// urlFile 获取当前用户自己的URL文件
+func (b *batchItem) urlFile(urlFileID uint) (*models.UrlFile, error) {
+	urlFile, err := b.repos.UrlFiles.GetUrlFileByID(urlFileID, b.run.userID)
+	if err != nil {
+		if isRecordNotFound(err) {
+			return nil, newBatchError("not_found", "URL文件不存在")
+		}
+		return nil, err
+	}
+	return urlFile, nil
+}
+
+// moveTarget 解析移动目标：0 表示根目录（只有所有者可以移动到根目录），其他文件夹需要编辑者权限且与对象属于同一所有者
+func (b *batchItem) moveTarget(targetID uint, ownerID string) (*uint, error) {
+	if targetID == 0 {
+		if ownerID != b.run.userID {
+			return nil, newBatchError("forbidden", "只有所有者可以移动到根目录")
+		}
+		return nil, nil
+	}
+
+	folder, err := b.folderPermission(targetID, models.PermissionEditor)
+	if err != nil {
+		return nil, err
+	}
+	if folder.UserID != ownerID {
+		return nil, newBatchError("invalid", "不能移动到其他用户的文件夹")
+	}
+	return &folder.ID, nil
+}
+
+// copyTarget 解析复制目标：不传时使用 fallback，0 表示当前用户的根目录，返回目标文件夹ID和副本所有者
+func (b *batchItem) copyTarget(requested, fallback *uint) (*uint, string, error) {
+	target := requested
+	if target == nil {
+		target = fallback
+	}
+	if target == nil || *target == 0 {
+		return nil, b.run.userID, nil
+	}
+
+	folder, err := b.folderPermission(*target, models.PermissionEditor)
+	if err != nil {
+		return nil, "", err
+	}
+	return &folder.ID, folder.UserID, nil
+}
+
+// reserveQuota 检查所有者的剩余空间（包含本批次中已占用的部分）并记录新增占用
+func (b *batchItem) reserveQuota(ownerID string, size int64) error {
+	usedSpace, storageLimit, err := b.run.handler.userRepo.GetUserStorageInfo(ownerID)
+	if err != nil {
+		return err
+	}
+	pending := b.run.usage[ownerID] + b.usage[ownerID]
+	if !utils.ValidateFileSize(size, storageLimit, usedSpace+pending) {
+		return newBatchError("quota_exceeded", "存储空间不足")
+	}
+	b.usage[ownerID] += size
+	return nil
+}
+
+// purgeFile 删除文件记录，物理文件在事务提交后删除
+func (b *batchItem) purgeFile(file *models.File) error {
+	paths, err := purgeFileRecords(b.repos.Files, b.repos.Versions, b.repos.Grants, file)
+	if err != nil {
+		return err
+	}
+	b.removedPaths = append(b.removedPaths, paths...)
+	return nil
+}
+
+// fileConflict 按冲突策略检查目标文件夹中的同名文件
+func (b *batchItem) fileConflict(ownerID, name string, folderID *uint, strategy string, excludeID uint) (string, *models.File, error) {
+	finalName, replaced, conflict, err := findFileNameConflict(b.repos.Files, ownerID, name, folderID, strategy, excludeID)
+	if err != nil {
+		if errors.Is(err, errNoUniqueName) {
+			return "", nil, newBatchError("conflict", err.Error())
+		}
+		return "", nil, err
+	}
+	if conflict != nil {
+		return "", nil, newBatchError("conflict", "目标位置已存在同名文件")
+	}
+	return finalName, replaced, nil
+}
+
+// folderConflict 按冲突策略检查目标父文件夹下的同名文件夹
+func (b *batchItem) folderConflict(ownerID, name string, parentID *uint, strategy string, excluded map[uint]bool) (string, bool, error) {
+	finalName, merge, conflict, err := resolveFolderNameConflict(b.repos.Folders, ownerID, name, parentID, strategy, excluded)
+	if err != nil {
+		if errors.Is(err, errMergeIntoSelf) {
+			return "", false, newBatchError("invalid", err.Error())
+		}
+		if errors.Is(err, errNoUniqueName) {
+			return "", false, newBatchError("conflict", err.Error())
+		}
+		return "", false, err
+	}
+	if conflict != nil {
+		return "", false, newBatchError("conflict", "目标位置已存在同名文件夹")
+	}
+	return finalName, merge, nil
+}
+
+// moveFile 移动文件，rename 策略下会同时修改文件名
+func (b *batchItem) moveFile(fileID, targetID uint, strategy string) (interface{}, error) {
+	file, err := b.filePermission(fileID, models.PermissionEditor)
+	if err != nil {
+		return nil, err
+	}
+	folderID, err := b.moveTarget(targetID, file.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	finalName, replaced, err := b.fileConflict(file.UserID, file.Name, folderID, strategy, file.ID)
+	if err != nil {
+		return nil, err
+	}
+	result := gin.H{"folder_id": folderID, "name": finalName, "overwritten": replaced != nil}
+	if b.run.dryRun {
+		return result, nil
+	}
+
+	if replaced != nil {
+		if err := b.purgeFile(replaced); err != nil {
+			return nil, err
+		}
+	}
+	file.Name = finalName
+	file.FolderID = folderID
+	if err := b.repos.Files.UpdateFile(file); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// moveUrlFile 移动URL文件（仅限自己的URL文件和文件夹）
+func (b *batchItem) moveUrlFile(urlFileID, targetID uint) (interface{}, error) {
+	urlFile, err := b.urlFile(urlFileID)
+	if err != nil {
+		return nil, err
+	}
+	folderID, err := b.moveTarget(targetID, urlFile.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := gin.H{"folder_id": folderID}
+	if b.run.dryRun {
+		return result, nil
+	}
+	if err := b.repos.UrlFiles.MoveUrlFile(urlFile.ID, urlFile.UserID, folderID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// moveFolder 修改文件夹的父文件夹，拒绝移动到自身或子孙文件夹
+func (b *batchItem) moveFolder(folderID, targetID uint, strategy string) (interface{}, error) {
+	if strategy == models.ConflictOverwrite {
+		return nil, newBatchError("invalid", "移动文件夹不支持覆盖策略")
+	}
+
+	folder, err := b.folderPermission(folderID, models.PermissionEditor)
+	if err != nil {
+		return nil, err
+	}
+	parentID, err := b.moveTarget(targetID, folder.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if sameFolderID(folder.ParentID, parentID) {
+		return gin.H{"parent_id": parentID, "name": folder.Name}, nil
+	}
+
+	finalName, _, err := b.folderConflict(folder.UserID, folder.Name, parentID, strategy, map[uint]bool{folder.ID: true})
+	if err != nil {
+		return nil, err
+	}
+	result := gin.H{"parent_id": parentID, "name": finalName}
+	if b.run.dryRun {
+		return result, nil
+	}
+
+	newName := ""
+	if finalName != folder.Name {
+		newName = finalName
+	}
+	if err := b.repos.Folders.MoveFolder(folder.ID, folder.UserID, parentID, newName); err != nil {
+		if errors.Is(err, database.ErrFolderCycle) {
+			return nil, newBatchError("invalid", err.Error())
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// deleteFile 删除文件及其历史版本和共享授权
+func (b *batchItem) deleteFile(fileID uint) (interface{}, error) {
+	file, err := b.filePermission(fileID, models.PermissionEditor)
+	if err != nil {
+		return nil, err
+	}
+	if b.run.dryRun {
+		return nil, nil
+	}
+	return nil, b.purgeFile(file)
+}
+
+// deleteUrlFile 删除自己的URL文件
+func (b *batchItem) deleteUrlFile(urlFileID uint) (interface{}, error) {
+	urlFile, err := b.urlFile(urlFileID)
+	if err != nil {
+		return nil, err
+	}
+	if b.run.dryRun {
+		return nil, nil
+	}
+	return nil, b.repos.UrlFiles.DeleteUrlFile(urlFile.ID, urlFile.UserID)
+}
+
+// deleteFolder 递归删除文件夹（需要共同所有者权限），物理文件在事务提交后删除
+func (b *batchItem) deleteFolder(folderID uint) (interface{}, error) {
+	folder, err := b.folderPermission(folderID, models.PermissionCoOwner)
+	if err != nil {
+		return nil, err
+	}
+	if b.run.dryRun {
+		return nil, nil
+	}
+	result, err := b.repos.Folders.DeleteFolderRecursive(folder.ID, folder.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, storedPath := range result.StoredPaths {
+		// 被隔离的文件内容位于隔离目录
+		b.removedPaths = append(b.removedPaths, utils.GetFileAbsolutePath(storedPath), utils.GetQuarantinePath(storedPath))
+	}
+	return result, nil
+}
+
+// changeTags 为自己的文件、URL文件、文件夹打上或取消标签，标签必须属于当前用户
+func (b *batchItem) changeTags(operation models.BatchOperation) (interface{}, error) {
+	if len(operation.TagIDs) == 0 {
+		return nil, newBatchError("invalid", "缺少标签ID")
+	}
+	if len(operation.TagIDs) > models.MaxTagsPerRequest {
+		return nil, newBatchError("invalid", "标签数量过多")
+	}
+
+	var ownerID string
+	switch operation.ItemType {
+	case models.BatchItemFile:
+		file, err := b.filePermission(operation.ID, models.PermissionViewer)
+		if err != nil {
+			return nil, err
+		}
+		ownerID = file.UserID
+	case models.BatchItemFolder:
+		folder, err := b.folderPermission(operation.ID, models.PermissionViewer)
+		if err != nil {
+			return nil, err
+		}
+		ownerID = folder.UserID
+	default:
+		urlFile, err := b.urlFile(operation.ID)
+		if err != nil {
+			return nil, err
+		}
+		ownerID = urlFile.UserID
+	}
+	if ownerID != b.run.userID {
+		return nil, newBatchError("forbidden", "只能给自己的文件和文件夹设置标签")
+	}
+
+	tagIDs := make([]uint, 0, len(operation.TagIDs))
+	for _, tagID := range operation.TagIDs {
+		if !containsUint(tagIDs, tagID) {
+			tagIDs = append(tagIDs, tagID)
+		}
+	}
+	tags, err := b.repos.Tags.GetTagsByIDs(b.run.userID, tagIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) != len(tagIDs) {
+		return nil, newBatchError("not_found", "标签不存在")
+	}
+	if b.run.dryRun {
+		return nil, nil
+	}
+
+	items := []models.TagItemRef{{ItemType: operation.ItemType, ID: operation.ID}}
+	var affected int64
+	if operation.Op == models.BatchOpTag {
+		affected, err = b.repos.Tags.AddItemTags(b.run.userID, tagIDs, items)
+	} else {
+		affected, err = b.repos.Tags.RemoveItemTags(b.run.userID, tagIDs, items)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{"affected": affected}, nil
+}
+
+// copyFile 复制文件，副本拥有独立的物理文件
+func (b *batchItem) copyFile(operation models.BatchOperation, strategy string) (interface{}, error) {
+	source, err := b.filePermission(operation.ID, models.PermissionViewer)
+	if err != nil {
+		return nil, err
+	}
+	if source.IsInfected() {
+		return nil, newBatchError("forbidden", models.ErrFileInfected.Error())
+	}
+	if source.IsVault() {
+		return nil, newBatchError("forbidden", models.ErrVaultItem.Error())
+	}
+	name := strings.TrimSpace(operation.Name)
+	if name == "" {
+		name = source.Name
+	}
+	if !validateItemName(name) {
+		return nil, newBatchError("invalid", "无效的文件名")
+	}
+
+	folderID, ownerID, err := b.copyTarget(operation.TargetFolderID, source.FolderID)
+	if err != nil {
+		return nil, err
+	}
+	finalName, replaced, err := b.fileConflict(ownerID, name, folderID, strategy, 0)
+	if err != nil {
+		return nil, err
+	}
+	if replaced != nil && replaced.ID == source.ID {
+		return nil, newBatchError("invalid", "不能用文件副本覆盖文件自身")
+	}
+	if err := b.reserveQuota(ownerID, source.Size); err != nil {
+		return nil, err
+	}
+	if b.run.dryRun {
+		return gin.H{"folder_id": folderID, "name": finalName, "overwritten": replaced != nil}, nil
+	}
+
+	stored, err := utils.CopyStoredFile(source.Path, source.EncryptionKeyID, finalName, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	b.createdPaths = append(b.createdPaths, stored.AbsolutePath)
+
+	newFile := &models.File{
+		Name:            finalName,
+		Size:            stored.Size,
+		Type:            stored.FileType,
+		Path:            stored.Path,
+		UserID:          ownerID,
+		FolderID:        folderID,
+		ThumbnailData:   source.ThumbnailData,
+		Checksum:        stored.Checksum,
+		EncryptionKeyID: stored.KeyID,
+		UploadedBy:      b.run.userID,
+		Description:     source.Description,
+		Metadata:        source.Metadata,
+		// 副本内容相同，沿用源文件的扫描结果
+		ScanStatus: source.ScanStatus,
+		ScanResult: source.ScanResult,
+		ScannedAt:  source.ScannedAt,
+	}
+	if err := b.repos.Files.CreateFile(newFile); err != nil {
+		return nil, err
+	}
+	if replaced != nil {
+		if err := b.purgeFile(replaced); err != nil {
+			return nil, err
+		}
+	}
+	return gin.H{"file": newFile, "overwritten": replaced != nil}, nil
+}
+
+// copyUrlFile 复制自己的URL文件
+func (b *batchItem) copyUrlFile(operation models.BatchOperation) (interface{}, error) {
+	source, err := b.urlFile(operation.ID)
+	if err != nil {
+		return nil, err
+	}
+	folderID, ownerID, err := b.copyTarget(operation.TargetFolderID, source.FolderID)
+	if err != nil {
+		return nil, err
+	}
+	title := strings.TrimSpace(operation.Name)
+	if title == "" {
+		title = source.Title
+	}
+	if b.run.dryRun {
+		return gin.H{"folder_id": folderID, "title": title}, nil
+	}
+
+	newUrlFile := &models.UrlFile{
+		Title:       title,
+		URL:         source.URL,
+		Description: source.Description,
+		UserID:      ownerID,
+		FolderID:    folderID,
+	}
+	if err := b.repos.UrlFiles.CreateUrlFile(newUrlFile); err != nil {
+		return nil, err
+	}
+	return gin.H{"url_file": newUrlFile}, nil
+}
+
+// copyFolder 递归复制文件夹
+func (b *batchItem) copyFolder(operation models.BatchOperation, strategy string) (interface{}, error) {
+	source, err := b.folderPermission(operation.ID, models.PermissionViewer)
+	if err != nil {
+		return nil, err
+	}
+	if source.IsVault() {
+		return nil, newBatchError("forbidden", models.ErrVaultItem.Error())
+	}
+	name := strings.TrimSpace(operation.Name)
+	if name == "" {
+		name = source.Name
+	}
+	if !validateItemName(name) {
+		return nil, newBatchError("invalid", "无效的文件夹名称")
+	}
+
+	parentID, ownerID, err := b.copyTarget(operation.TargetFolderID, source.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
+	copier := newFolderCopy(b.repos.Files, b.repos.Folders, b.repos.UrlFiles, b.run.userID, ownerID)
+	snapshot, err := copier.snapshot(*source)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.reserveQuota(ownerID, copier.result.TotalSize); err != nil {
+		return nil, err
+	}
+	finalName, merge, err := b.folderConflict(ownerID, name, parentID, strategy, copier.visited)
+	if err != nil {
+		return nil, err
+	}
+	if b.run.dryRun {
+		return gin.H{"parent_id": parentID, "name": finalName, "merge": merge, "result": copier.result}, nil
+	}
+
+	copier.merge = merge
+	rootID, err := copier.run(snapshot, finalName, parentID)
+	b.createdPaths = append(b.createdPaths, copier.storedPaths()...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range copier.replaced {
+		if err := b.purgeFile(&copier.replaced[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	result := copier.result
+	if root, err := b.repos.Folders.GetFolderByID(rootID, ownerID); err == nil {
+		result.Folder = *root
+	}
+	return result, nil
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// changePollInterval 长轮询期间检查新变更的间隔
+const changePollInterval = time.Second
+
+// ChangeHandler 变更日志处理器，供同步客户端增量获取文件、文件夹和URL文件的变化
+type ChangeHandler struct {
+	changeRepo database.ChangeRepositoryInterface
+}
+
+// NewChangeHandler 创建变更日志处理器实例
+func NewChangeHandler(changeRepo database.ChangeRepositoryInterface) *ChangeHandler {
+	return &ChangeHandler{changeRepo: changeRepo}
+}
+
+// GetChanges 获取游标之后的变更。不带 cursor 时只返回当前游标，客户端在全量列出前记录，之后从该游标增量同步；
+// wait 为长轮询的秒数，没有新变更时最多等待这么久，期间出现变更立即返回
+func (h *ChangeHandler) GetChanges(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	encoded := c.Query("cursor")
+	if encoded == "" {
+		latest, err := h.changeRepo.GetLatestChangeSeq(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取变更游标失败"})
+			return
+		}
+		c.JSON(http.StatusOK, models.ChangeListResponse{
+			Success: true,
+			Changes: []models.Change{},
+			Cursor:  strconv.FormatInt(latest, 10),
+		})
+		return
+	}
+	cursor, err := strconv.ParseInt(encoded, 10, 64)
+	if err != nil || cursor < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的游标"})
+		return
+	}
+
+	limit, _, ok := parseLimitOffset(c, models.DefaultChangeLimit, models.MaxChangeLimit)
+	if !ok {
+		return
+	}
+	var wait time.Duration
+	if waitStr := c.Query("wait"); waitStr != "" {
+		seconds, err := strconv.Atoi(waitStr)
+		if err != nil || seconds < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的wait参数"})
+			return
+		}
+		wait = min(time.Duration(seconds)*time.Second, models.MaxChangeWait)
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		changes, latest, err := h.changeRepo.GetChangesSince(userID, cursor, limit)
+		if errors.Is(err, models.ErrChangeCursorExpired) {
+			c.JSON(http.StatusGone, gin.H{"error": "变更游标已失效，请重新全量同步", "reset": true})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取变更失败"})
+			return
+		}
+
+		if len(changes) > 0 || !time.Now().Before(deadline) {
+			next := cursor
+			if len(changes) > 0 {
+				next = changes[len(changes)-1].Seq
+			}
+			c.JSON(http.StatusOK, models.ChangeListResponse{
+				Success: true,
+				Changes: changes,
+				Cursor:  strconv.FormatInt(next, 10),
+				HasMore: next < latest,
+			})
+			return
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-time.After(min(changePollInterval, time.Until(deadline))):
+		}
+	}
+}
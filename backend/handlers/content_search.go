@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"backend/config"
+	"backend/database"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 内容搜索关键词长度限制；MySQL ngram 分词的最小单位为2个字符
+const (
+	minContentQueryLength = 2
+	maxContentQueryLength = 200
+	defaultContentLimit   = 20
+	maxContentLimit       = 100
+)
+
+// ContentSearchHandler 文件内容全文搜索处理器
+type ContentSearchHandler struct {
+	contentRepo database.FileContentRepositoryInterface
+	folderRepo  database.FolderRepositoryInterface
+	grantRepo   database.GrantRepositoryInterface
+	indexer     *services.ContentIndexService
+	config      *config.SearchConfig
+}
+
+// NewContentSearchHandler 创建文件内容全文搜索处理器实例
+func NewContentSearchHandler(contentRepo database.FileContentRepositoryInterface, folderRepo database.FolderRepositoryInterface, grantRepo database.GrantRepositoryInterface, indexer *services.ContentIndexService, cfg *config.SearchConfig) *ContentSearchHandler {
+	return &ContentSearchHandler{
+		contentRepo: contentRepo,
+		folderRepo:  folderRepo,
+		grantRepo:   grantRepo,
+		indexer:     indexer,
+		config:      cfg,
+	}
+}
+
+// SearchContent 按短语搜索文件内容，返回带高亮片段的结果。
+// 指定 folder_id 时只搜索该文件夹（recursive=false 时不含子文件夹），否则搜索自己的文件和共享给我的文件
+func (h *ContentSearchHandler) SearchContent(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	query := strings.TrimSpace(c.Query("q"))
+	if utf8.RuneCountInString(query) < minContentQueryLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "搜索关键词至少需要2个字符"})
+		return
+	}
+	if utf8.RuneCountInString(query) > maxContentQueryLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "搜索关键词过长"})
+		return
+	}
+
+	limit, offset, ok := parseLimitOffset(c, defaultContentLimit, maxContentLimit)
+	if !ok {
+		return
+	}
+
+	scope, ok := h.resolveSearchScope(c, userID)
+	if !ok {
+		return
+	}
+
+	hits, total, err := h.contentRepo.SearchContent(query, scope, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索文件内容失败"})
+		return
+	}
+
+	results := make([]models.ContentSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		results = append(results, models.ContentSearchResult{
+			File:     hit.File,
+			Score:    hit.Score,
+			Snippets: utils.HighlightSnippets(hit.Content, query, h.config.MaxSnippets, h.config.SnippetContext),
+		})
+	}
+
+	c.JSON(http.StatusOK, models.ContentSearchResponse{
+		Success: true,
+		Query:   query,
+		Total:   total,
+		Results: results,
+	})
+}
+
+// resolveSearchScope 根据 folder_id 参数确定搜索范围，失败时直接写入响应
+func (h *ContentSearchHandler) resolveSearchScope(c *gin.Context, userID string) (models.ContentSearchScope, bool) {
+	folderIDStr := c.Query("folder_id")
+	if folderIDStr == "" || folderIDStr == "0" {
+		return h.accessibleScope(c, userID)
+	}
+
+	folderIDInt, err := strconv.Atoi(folderIDStr)
+	if err != nil || folderIDInt < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件夹ID"})
+		return models.ContentSearchScope{}, false
+	}
+
+	folder, _, ok := resolveFolderAccess(c, h.grantRepo, uint(folderIDInt), userID, models.PermissionViewer)
+	if !ok || rejectVaultFolder(c, folder) {
+		return models.ContentSearchScope{}, false
+	}
+
+	if c.DefaultQuery("recursive", "true") == "false" {
+		return models.ContentSearchScope{FolderIDs: []uint{folder.ID}}, true
+	}
+
+	folderIDs, err := h.folderRepo.GetDescendantFolderIDs(folder.ID, folder.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取子文件夹失败"})
+		return models.ContentSearchScope{}, false
+	}
+	return models.ContentSearchScope{FolderIDs: folderIDs}, true
+}
+
+// accessibleScope 自己的全部文件，加上共享给我的文件和文件夹（含子文件夹）中的文件
+func (h *ContentSearchHandler) accessibleScope(c *gin.Context, userID string) (models.ContentSearchScope, bool) {
+	scope := models.ContentSearchScope{OwnerID: userID}
+
+	grants, err := h.grantRepo.GetGrantsForUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取共享信息失败"})
+		return scope, false
+	}
+
+	seenFolders := make(map[uint]bool)
+	for _, grant := range grants {
+		switch grant.ResourceType {
+		case models.ShareResourceFile:
+			scope.FileIDs = append(scope.FileIDs, grant.ResourceID)
+		case models.ShareResourceFolder:
+			if seenFolders[grant.ResourceID] {
+				continue
+			}
+			folderIDs, err := h.folderRepo.GetDescendantFolderIDs(grant.ResourceID, grant.OwnerID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "获取子文件夹失败"})
+				return scope, false
+			}
+			for _, id := range folderIDs {
+				if !seenFolders[id] {
+					seenFolders[id] = true
+					scope.FolderIDs = append(scope.FolderIDs, id)
+				}
+			}
+		}
+	}
+	return scope, true
+}
+
+// GetIndexStatus 获取当前用户文件的内容索引进度
+func (h *ContentSearchHandler) GetIndexStatus(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.contentRepo.GetIndexStatus(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取索引状态失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"status":  status,
+	})
+}
+
+// RebuildIndex 清空当前用户的内容索引并由后台任务重新建立
+func (h *ContentSearchHandler) RebuildIndex(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	cleared, err := h.contentRepo.ResetUserContents(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重建索引失败"})
+		return
+	}
+	h.indexer.Notify()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "已开始重建内容索引",
+		"cleared": cleared,
+	})
+}
+
+// parseLimitOffset 解析分页参数，失败时直接写入响应
+func parseLimitOffset(c *gin.Context, defaultLimit, maxLimit int) (int, int, bool) {
+	limit := defaultLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的limit参数"})
+			return 0, 0, false
+		}
+		limit = min(parsed, maxLimit)
+	}
+
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		parsed, err := strconv.Atoi(offsetStr)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的offset参数"})
+			return 0, 0, false
+		}
+		offset = parsed
+	}
+	return limit, offset, true
+}
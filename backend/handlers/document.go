@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"io"
 	"net/http"
 	"os"
@@ -27,17 +28,31 @@ func NewDocumentHandler(docRepo database.DocumentRepositoryInterface) *DocumentH
 	}
 }
 
-// GetDocuments 获取所有文档
+// documentListSorts 文档列表支持的排序字段，name 按标题排序
+var documentListSorts = []string{models.ListSortOrder, models.SearchSortName, models.SearchSortCreatedAt, models.SearchSortUpdatedAt}
+
+// GetDocuments 获取所有文档，默认按展示顺序排列，支持游标分页
 func (h *DocumentHandler) GetDocuments(c *gin.Context) {
-	documents, err := h.docRepo.GetDocuments()
+	query, ok := parseListQuery(c, models.ListItemDocument, documentListSorts, models.ListSortOrder)
+	if !ok {
+		return
+	}
+
+	documents, nextCursor, err := h.docRepo.ListDocuments(query)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文档列表失败"})
 		return
 	}
 
 	response := models.DocumentListResponse{
-		Success:   true,
-		Documents: documents,
+		Success:    true,
+		Documents:  documents,
+		NextCursor: nextCursor,
+		HasMore:    nextCursor != "",
 	}
 	c.JSON(http.StatusOK, response)
 }
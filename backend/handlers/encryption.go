@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend/async"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EncryptionHandler 存储加密管理处理器（管理员）
+type EncryptionHandler struct {
+	encryption   *services.StorageEncryptionService
+	queueManager *utils.UploadQueueManager
+	taskManager  *async.TaskManager
+}
+
+// NewEncryptionHandler 创建存储加密管理处理器实例
+func NewEncryptionHandler(encryption *services.StorageEncryptionService, queueManager *utils.UploadQueueManager, taskManager *async.TaskManager) *EncryptionHandler {
+	return &EncryptionHandler{
+		encryption:   encryption,
+		queueManager: queueManager,
+		taskManager:  taskManager,
+	}
+}
+
+// GetEncryptionStatus 获取存储加密配置、数据密钥统计和正在进行的后台任务
+func (h *EncryptionHandler) GetEncryptionStatus(c *gin.Context) {
+	status, err := h.encryption.Status()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取加密状态失败"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// MigrateStorage 在后台将已有的明文文件原地加密，进度通过上传任务接口查询
+func (h *EncryptionHandler) MigrateStorage(c *gin.Context) {
+	if !h.encryption.Enabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未启用存储加密"})
+		return
+	}
+	h.startJob(c, models.EncryptionJobMigrate, "加密已有文件", h.encryption.RunMigration)
+}
+
+// RotateKeys 在后台执行密钥轮换：以当前主密钥重新加密全部数据密钥；
+// reencrypt=true 时同时为每个用户生成新的数据密钥并重新加密全部文件
+func (h *EncryptionHandler) RotateKeys(c *gin.Context) {
+	if !h.encryption.Configured() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrEncryptionNotConfigured.Error()})
+		return
+	}
+	reencrypt := c.Query("reencrypt") == "true"
+	if reencrypt && !h.encryption.Enabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未启用存储加密，无法生成新的数据密钥"})
+		return
+	}
+
+	name := "重新加密数据密钥"
+	if reencrypt {
+		name = "轮换数据密钥并重新加密文件"
+	}
+	h.startJob(c, models.EncryptionJobRotate, name, func(onProgress func(models.EncryptionJobProgress)) error {
+		return h.encryption.RunRotation(reencrypt, onProgress)
+	})
+}
+
+// startJob 登记并提交存储加密后台任务，同一时间只允许一个任务
+func (h *EncryptionHandler) startJob(c *gin.Context, jobType, name string, run func(onProgress func(models.EncryptionJobProgress)) error) {
+	currentUser, _ := c.Get("currentUser")
+	admin, _ := currentUser.(*models.User)
+	if admin == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+		return
+	}
+
+	if progress := h.encryption.JobProgress(); progress != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": services.ErrEncryptionJobRunning.Error(), "job": progress})
+		return
+	}
+
+	task := h.queueManager.CreateTask(admin.UUID, jobType, name, 0)
+	taskID := task.ID
+	if !h.encryption.BeginJob(taskID, jobType) {
+		h.queueManager.UpdateTaskError(taskID, services.ErrEncryptionJobRunning.Error())
+		c.JSON(http.StatusConflict, gin.H{"error": services.ErrEncryptionJobRunning.Error(), "job": h.encryption.JobProgress()})
+		return
+	}
+
+	job := func() error {
+		if !h.queueManager.StartTask(taskID) {
+			h.encryption.EndJob()
+			return nil
+		}
+
+		lastProgress := -1
+		err := run(func(progress models.EncryptionJobProgress) {
+			if progress.Total <= 0 {
+				return
+			}
+			percent := progress.Processed * 100 / progress.Total
+			if percent >= 100 {
+				percent = 99
+			}
+			if percent != lastProgress {
+				lastProgress = percent
+				h.queueManager.UpdateTaskProgress(taskID, percent)
+			}
+		})
+		if err != nil {
+			h.queueManager.UpdateTaskError(taskID, name+"失败: "+err.Error())
+			return err
+		}
+
+		h.queueManager.UpdateTaskResult(taskID, "/api/admin/encryption/status")
+		return nil
+	}
+
+	if err := h.taskManager.SubmitTask(async.NewBaseTask(taskID, 1, 0, job)); err != nil {
+		h.encryption.EndJob()
+		h.queueManager.UpdateTaskError(taskID, "任务队列繁忙")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "任务队列繁忙，请稍后重试"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "已开始" + name,
+		"task_id": taskID,
+	})
+}
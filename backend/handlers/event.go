@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"backend/models"
+	"backend/services"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	eventHeartbeatInterval = 25 * time.Second // 心跳间隔，需小于反向代理的读取超时
+	eventStreamLifetime    = 30 * time.Minute // 单个连接的最长时间，到期后客户端重连并重新验证登录状态
+	eventRetryDelay        = 3 * time.Second  // 建议 EventSource 断线后的重连间隔
+)
+
+// EventHandler 实时事件流处理器
+type EventHandler struct {
+	hub *services.EventHub
+}
+
+// NewEventHandler 创建实时事件流处理器实例
+func NewEventHandler(hub *services.EventHub) *EventHandler {
+	return &EventHandler{hub: hub}
+}
+
+// StreamEvents 以 Server-Sent Events 推送当前用户的事件。
+// 断线重连时 EventSource 自动携带 Last-Event-ID 请求头，刷新页面后可通过 last_event_id 参数传入
+func (h *EventHandler) StreamEvents(c *gin.Context) {
+	user, ok := credentialUser(c)
+	if !ok {
+		return
+	}
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	sub, initial, err := h.hub.Subscribe(user.UUID, lastEventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "订阅事件失败"})
+		return
+	}
+	defer sub.Unsubscribe()
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", eventRetryDelay.Milliseconds())
+	for _, event := range initial {
+		if err := writeSSEEvent(c.Writer, event); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+	lifetime := time.NewTimer(eventStreamLifetime)
+	defer lifetime.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-lifetime.C:
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if err := writeSSEEvent(c.Writer, event); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeSSEEvent 按 SSE 格式写入一个事件，data 为事件数据的 JSON
+func writeSSEEvent(w gin.ResponseWriter, event models.Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+// StreamEventsWebSocket 以 WebSocket 推送当前用户的事件，每条消息为 {"id", "type", "data"} 的 JSON，
+// 心跳为 ping 类型的消息；重连时通过 last_event_id 参数补发
+func (h *EventHandler) StreamEventsWebSocket(c *gin.Context) {
+	user, ok := credentialUser(c)
+	if !ok {
+		return
+	}
+	lastEventID := c.Query("last_event_id")
+
+	server := websocket.Server{
+		// 浏览器发起的 WebSocket 连接会自动携带 Cookie，只接受同源页面的连接，防止其他网站借用登录状态
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			return checkSameOrigin(r)
+		},
+		Handler: func(ws *websocket.Conn) {
+			h.serveWebSocket(ws, user.UUID, lastEventID)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// serveWebSocket 向 WebSocket 连接推送事件，客户端发送的消息被忽略，读取失败即视为连接关闭
+func (h *EventHandler) serveWebSocket(ws *websocket.Conn, userID, lastEventID string) {
+	defer ws.Close()
+
+	sub, initial, err := h.hub.Subscribe(userID, lastEventID)
+	if err != nil {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		var discard []byte
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+
+	for _, event := range initial {
+		if err := websocket.JSON.Send(ws, event); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+	lifetime := time.NewTimer(eventStreamLifetime)
+	defer lifetime.Stop()
+
+	for {
+		var err error
+		select {
+		case <-closed:
+			return
+		case <-lifetime.C:
+			return
+		case <-heartbeat.C:
+			err = websocket.JSON.Send(ws, models.Event{Type: models.EventPing})
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			err = websocket.JSON.Send(ws, event)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// checkSameOrigin 检查 Origin 与请求的 Host 一致；没有 Origin 的请求不是浏览器发起的，直接允许
+func checkSameOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Host != r.Host {
+		return fmt.Errorf("不允许的来源: %s", origin)
+	}
+	return nil
+}
@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -15,29 +20,35 @@ import (
 	"backend/config"
 	"backend/database"
 	"backend/models"
+	"backend/services"
 	"backend/utils"
 
 	"runtime/debug"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// ProgressReader 进度跟踪读取器
+// ProgressReader 进度跟踪读取器，Context 取消后读取返回错误，用于中止写入
 type ProgressReader struct {
 	Reader     io.Reader
-	TotalSize  int64
-	OnProgress func(progress int)
+	Context    context.Context
+	OnProgress func(read int64)
 	read       int64
 }
 
 // Read 实现 io.Reader 接口
 func (pr *ProgressReader) Read(p []byte) (n int, err error) {
+	if pr.Context != nil {
+		if err := pr.Context.Err(); err != nil {
+			return 0, err
+		}
+	}
 	n, err = pr.Reader.Read(p)
 	if n > 0 {
 		pr.read += int64(n)
-		progress := int((pr.read * 100) / pr.TotalSize)
 		if pr.OnProgress != nil {
-			pr.OnProgress(progress)
+			pr.OnProgress(pr.read)
 		}
 	}
 	return n, err
@@ -78,40 +89,83 @@ func (rlr *RateLimitedReader) Read(p []byte) (n int, err error) {
 
 // FileHandler 文件处理器
 type FileHandler struct {
-	fileRepo   database.FileRepositoryInterface
-	userRepo   database.UserRepositoryInterface
-	folderRepo database.FolderRepositoryInterface
+	fileRepo     database.FileRepositoryInterface
+	userRepo     database.UserRepositoryInterface
+	folderRepo   database.FolderRepositoryInterface
+	versionRepo  database.FileVersionRepositoryInterface
+	grantRepo    database.GrantRepositoryInterface
+	nameRepo     database.NamePinyinRepositoryInterface
+	tagRepo      database.TagRepositoryInterface
+	activityRepo database.ActivityRepositoryInterface
+	queueManager *utils.UploadQueueManager
+	indexer      *services.ContentIndexService
+	scanner      *services.VirusScanService
+	searchCfg    *config.SearchConfig
 }
 
 // NewFileHandler 创建文件处理器实例
-func NewFileHandler(fileRepo database.FileRepositoryInterface, userRepo database.UserRepositoryInterface, folderRepo database.FolderRepositoryInterface) *FileHandler {
+func NewFileHandler(fileRepo database.FileRepositoryInterface, userRepo database.UserRepositoryInterface, folderRepo database.FolderRepositoryInterface, versionRepo database.FileVersionRepositoryInterface, grantRepo database.GrantRepositoryInterface, nameRepo database.NamePinyinRepositoryInterface, tagRepo database.TagRepositoryInterface, activityRepo database.ActivityRepositoryInterface, queueManager *utils.UploadQueueManager, indexer *services.ContentIndexService, scanner *services.VirusScanService, searchCfg *config.SearchConfig) *FileHandler {
 	return &FileHandler{
-		fileRepo:   fileRepo,
-		userRepo:   userRepo,
-		folderRepo: folderRepo,
+		fileRepo:     fileRepo,
+		userRepo:     userRepo,
+		folderRepo:   folderRepo,
+		versionRepo:  versionRepo,
+		grantRepo:    grantRepo,
+		nameRepo:     nameRepo,
+		tagRepo:      tagRepo,
+		activityRepo: activityRepo,
+		queueManager: queueManager,
+		indexer:      indexer,
+		scanner:      scanner,
+		searchCfg:    searchCfg,
 	}
 }
 
-// GetFiles 获取用户文件列表
-func (h *FileHandler) GetFiles(c *gin.Context) {
-	userID := c.Query("user_id")
-	folderIDStr := c.Query("folder_id")
+// fileListSorts 文件列表支持的排序字段
+var fileListSorts = []string{models.SearchSortName, models.SearchSortSize, models.SearchSortCreatedAt, models.SearchSortUpdatedAt}
 
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少用户ID"})
+// GetFiles 获取用户文件列表，支持游标分页和排序，默认不返回缩略图数据
+func (h *FileHandler) GetFiles(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
 		return
 	}
+	folderIDStr := c.Query("folder_id")
 
 	var folderID *uint
+	ownerID := userID
 	if folderIDStr != "" {
 		if id, err := strconv.Atoi(folderIDStr); err == nil {
 			folderIDUint := uint(id)
 			folderID = &folderIDUint
+
+			// 共享给我的文件夹按所有者列出文件
+			folder, _, ok := resolveFolderAccess(c, h.grantRepo, folderIDUint, userID, models.PermissionViewer)
+			if !ok {
+				return
+			}
+			ownerID = folder.UserID
 		}
 	}
 
-	files, err := h.fileRepo.GetFilesByUserID(userID, folderID)
+	query, ok := parseListQuery(c, models.SearchItemFile, fileListSorts, models.SearchSortCreatedAt)
+	if !ok {
+		return
+	}
+	fields, ok := parseListFields(c, models.ListFieldThumbnailData)
+	if !ok {
+		return
+	}
+	query.OwnerID = ownerID
+	query.FolderID = folderID
+	query.WithThumbnail = fields[models.ListFieldThumbnailData]
+
+	files, nextCursor, err := h.fileRepo.ListFiles(query)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件列表失败"})
 		return
 	}
@@ -120,10 +174,16 @@ func (h *FileHandler) GetFiles(c *gin.Context) {
 	if files == nil {
 		files = []models.File{}
 	}
+	// 打开文件夹（第一页）计为一次访问
+	if folderID != nil && query.Cursor == nil {
+		recordRecentAccess(h.activityRepo, userID, models.SearchItemFolder, *folderID)
+	}
 
 	response := models.FileListResponse{
-		Success: true,
-		Files:   files,
+		Success:    true,
+		Files:      files,
+		NextCursor: nextCursor,
+		HasMore:    nextCursor != "",
 	}
 	c.JSON(http.StatusOK, response)
 }
@@ -131,10 +191,8 @@ func (h *FileHandler) GetFiles(c *gin.Context) {
 // GetFile 获取单个文件信息
 func (h *FileHandler) GetFile(c *gin.Context) {
 	fileIDStr := c.Param("id")
-	userID := c.Query("user_id")
-
-	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+	userID, ok := sessionUserID(c)
+	if !ok {
 		return
 	}
 
@@ -145,15 +203,11 @@ func (h *FileHandler) GetFile(c *gin.Context) {
 	}
 	fileID := uint(fileIDInt)
 
-	file, err := h.fileRepo.GetFileByID(fileID, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件信息失败"})
-		}
+	file, ok := resolveFileAccess(c, h.grantRepo, fileID, userID, models.PermissionViewer)
+	if !ok {
 		return
 	}
+	recordRecentAccess(h.activityRepo, userID, models.SearchItemFile, file.ID)
 
 	response := models.FileResponse{
 		Success: true,
@@ -165,16 +219,14 @@ func (h *FileHandler) GetFile(c *gin.Context) {
 // DownloadFile 下载文件
 func (h *FileHandler) DownloadFile(c *gin.Context) {
 	fileIDStr := c.Param("id")
-	userID := c.Query("user_id")
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
 
 	// 添加调试日志
 	// 下载请求
 
-	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
-		return
-	}
-
 	fileIDInt, err := strconv.Atoi(fileIDStr)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件ID"})
@@ -182,38 +234,65 @@ func (h *FileHandler) DownloadFile(c *gin.Context) {
 	}
 	fileID := uint(fileIDInt)
 
-	// 查询文件信息
-	file, err := h.fileRepo.GetFileByID(fileID, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件信息失败"})
-		}
+	// 查询文件信息（包括共享给我的文件）
+	file, ok := resolveFileAccess(c, h.grantRepo, fileID, userID, models.PermissionViewer)
+	if !ok || rejectInfectedFile(c, file) {
 		return
 	}
+	recordRecentAccess(h.activityRepo, userID, models.SearchItemFile, file.ID)
 
 	// 构建绝对路径 - 使用统一的路径处理
 	absolutePath := utils.GetFileAbsolutePath(file.Path)
+	serveFileAttachment(c, absolutePath, file.EncryptionKeyID, file.Name)
+}
 
-	// 检查文件是否存在
-	if _, err := os.Stat(absolutePath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
+// ServeUpload 通过存储路径（/uploads/...）预览文件，只允许有查看权限的用户访问
+func (h *FileHandler) ServeUpload(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
 		return
 	}
 
-	// 获取文件实际大小
-	fileInfo, err := os.Stat(absolutePath)
+	// 清理路径，防止跳出上传目录
+	storagePath := "/uploads" + path.Clean("/"+c.Param("filepath"))
+	c.Header("Cache-Control", "private, no-cache")
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	// 头像不属于文件记录，始终明文存储
+	if strings.HasPrefix(storagePath, "/uploads/avatars/") {
+		serveStoredContent(c, utils.GetFileAbsolutePath(storagePath), 0, path.Base(storagePath), "inline")
+		return
+	}
+
+	file, err := h.fileRepo.GetFileByPath(storagePath)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件信息失败"})
+		if isRecordNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件信息失败"})
+		}
+		return
+	}
+	file, ok = resolveFileAccess(c, h.grantRepo, file.ID, userID, models.PermissionViewer)
+	if !ok || rejectInfectedFile(c, file) {
 		return
 	}
 
-	// 根据文件类型设置正确的Content-Type
+	// 可执行脚本的类型不在本站内联打开
+	disposition := "inline"
+	switch getContentType(file.Name) {
+	case "text/html", "image/svg+xml", "application/javascript", "application/xml":
+		disposition = "attachment"
+	}
+	serveStoredContent(c, utils.GetFileAbsolutePath(file.Path), file.EncryptionKeyID, file.Name, disposition)
+}
+
+// getContentType 根据文件扩展名获取Content-Type
+func getContentType(fileName string) string {
 	contentType := "application/octet-stream"
 
 	// 检查文件扩展名，设置正确的MIME类型
-	ext := strings.ToLower(filepath.Ext(file.Name))
+	ext := strings.ToLower(filepath.Ext(fileName))
 	switch ext {
 	case ".jpg", ".jpeg":
 		contentType = "image/jpeg"
@@ -254,34 +333,39 @@ func (h *FileHandler) DownloadFile(c *gin.Context) {
 	case ".ppt", ".pptx":
 		contentType = "application/vnd.ms-powerpoint"
 	}
+	return contentType
+}
 
-	// 设置Content-Disposition头，强制下载
-	// 使用简单的filename参数，避免编码问题
-	disposition := "attachment; filename=\"" + file.Name + "\""
-	c.Header("Content-Disposition", disposition)
-	c.Header("Content-Type", contentType)
-	c.Header("Content-Length", strconv.FormatInt(fileInfo.Size(), 10))
-
-	// 如果是HEAD请求，只返回响应头，不返回文件内容
-	if c.Request.Method == "HEAD" {
-		c.Status(http.StatusOK)
+// serveFileAttachment 以附件形式发送磁盘上的文件，keyID 非0的加密文件解密后发送，支持 Range 断点续传
+func serveFileAttachment(c *gin.Context, absolutePath string, keyID uint64, fileName string) {
+	serveStoredContent(c, absolutePath, keyID, fileName, "attachment")
+}
+
+// serveStoredContent 发送磁盘上的文件，disposition 为 attachment（下载）或 inline（预览）
+func serveStoredContent(c *gin.Context, absolutePath string, keyID uint64, fileName, disposition string) {
+	content, err := utils.OpenStoredContent(absolutePath, keyID)
+	if os.IsNotExist(err) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
 		return
 	}
-
-	// 打开文件
-	fileHandle, err := os.Open(absolutePath)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "打开文件失败"})
 		return
 	}
-	defer fileHandle.Close()
+	defer content.Close()
 
-	// 发送文件
-	_, err = io.Copy(c.Writer, fileHandle)
+	fileInfo, err := os.Stat(absolutePath)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "发送文件失败"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件信息失败"})
 		return
 	}
+
+	// 使用简单的filename参数，避免编码问题
+	c.Header("Content-Disposition", disposition+"; filename=\""+fileName+"\"")
+	c.Header("Content-Type", getContentType(fileName))
+
+	// ServeContent 按明文大小处理 HEAD、Range 和 If-Modified-Since
+	http.ServeContent(c.Writer, c.Request, fileName, fileInfo.ModTime(), content)
 }
 
 // UploadFile 上传文件（优化版本）
@@ -313,16 +397,19 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 		return
 	}
 
-	userID := c.PostForm("user_id")
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
 	folderIDStr := c.PostForm("folder_id")
 	confirmReplace := c.PostForm("confirm_replace") // 新增：确认替换参数
 
 	// 获取缩略图数据（如果有的话）
 	thumbnailData := c.PostForm("thumbnail")
 
-	// 验证用户ID
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少用户ID"})
+	// 上传到共享文件夹时，文件归属并计入文件夹所有者的配额
+	ownerID, targetFolderID, ok := h.resolveUploadOwner(c, userID, folderIDStr)
+	if !ok {
 		return
 	}
 
@@ -355,9 +442,9 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 		return
 	}
 
-	// 检查同名文件
-	existingFile, err := h.fileRepo.GetFileByNameAndUser(header.Filename, userID)
-	if err != nil && err != sql.ErrNoRows {
+	// 检查目标文件夹中的同名文件
+	existingFile, err := h.fileRepo.GetFileByNameInFolder(ownerID, header.Filename, targetFolderID)
+	if err != nil && !isRecordNotFound(err) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查文件失败"})
 		return
 	}
@@ -393,22 +480,14 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 			return
 		}
 
-		// 用户确认替换，删除原文件
-		// 删除原文件的物理文件
-		absolutePath := utils.GetFileAbsolutePath(existingFile.Path)
-		if err := os.Remove(absolutePath); err != nil && !os.IsNotExist(err) {
-			// 继续执行，不因为删除失败而中断上传
-		}
-
-		// 删除数据库记录
-		if err := h.fileRepo.DeleteFile(existingFile.ID, userID); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "删除原文件失败"})
+		// 用户确认替换：需要对被替换的文件有编辑权限，原文件在新文件写入成功后保留为历史版本
+		if _, ok := resolveFileAccess(c, h.grantRepo, existingFile.ID, userID, models.PermissionEditor); !ok {
 			return
 		}
 	}
 
-	// 获取用户存储信息
-	usedSpace, storageLimit, err := h.userRepo.GetUserStorageInfo(userID)
+	// 获取用户存储信息（历史版本计入配额，替换时原文件不会释放空间）
+	usedSpace, storageLimit, err := h.userRepo.GetUserStorageInfo(ownerID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取存储信息失败"})
 		return
@@ -420,9 +499,6 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 		return
 	}
 
-	// 确定文件类型和路径
-	uploadPath := utils.GetUploadPath(header.Filename, fileType)
-
 	// 创建上传目录
 	uploadDir := utils.GetFileUploadDir(fileType)
 	if err := os.MkdirAll(uploadDir, 0755); err != nil {
@@ -451,15 +527,29 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 		}
 	}
 
+	// 登记上传任务，写入期间可通过任务接口查询进度或取消
+	task := h.queueManager.CreateTask(userID, "", originalFileName, header.Size)
+	taskID := task.ID
+
 	// 保存文件（使用缓冲写入，提高性能）
 	filePath := filepath.Join(uploadDir, fileName)
 	dst, err := os.Create(filePath)
 	if err != nil {
+		h.queueManager.UpdateTaskError(taskID, "保存文件失败")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件失败"})
 		return
 	}
 	defer dst.Close()
 
+	// 启用存储加密时以所有者的数据密钥加密写入
+	writer, err := utils.NewStoredContentWriter(dst, ownerID)
+	if err != nil {
+		os.Remove(filePath)
+		h.queueManager.UpdateTaskError(taskID, "保存文件失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件失败"})
+		return
+	}
+
 	// 使用缓冲写入，提高大文件上传性能
 	buffer := make([]byte, 32*1024) // 32KB buffer
 
@@ -473,10 +563,34 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 		}
 		reader = rateLimitedReader
 	}
-	written, err := io.CopyBuffer(dst, reader, buffer)
+
+	h.queueManager.UpdateTaskStatus(taskID, models.UploadTaskUploading)
+	ctx, release := h.queueManager.TaskContext(c.Request.Context(), taskID)
+	defer release()
+	reader = &ProgressReader{
+		Reader:  reader,
+		Context: ctx,
+		OnProgress: func(read int64) {
+			h.queueManager.UpdateTaskBytes(taskID, read, header.Size)
+		},
+	}
+
+	// 写入的同时计算校验和
+	hasher := sha256.New()
+	written, err := io.CopyBuffer(io.MultiWriter(writer, hasher), reader, buffer)
+	release()
+	if err == nil {
+		err = writer.Close()
+	}
+	if utils.TaskCancelled(ctx) {
+		os.Remove(filePath)
+		c.JSON(http.StatusConflict, gin.H{"error": "上传已取消", "task_id": taskID})
+		return
+	}
 	if err != nil {
 		// 删除部分写入的文件
 		os.Remove(filePath)
+		h.queueManager.UpdateTaskError(taskID, "保存文件失败")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件失败"})
 		return
 	}
@@ -485,40 +599,92 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 	if written != header.Size {
 		// 删除不完整的文件
 		os.Remove(filePath)
+		h.queueManager.UpdateTaskError(taskID, "文件写入不完整")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "文件写入不完整"})
 		return
 	}
+	h.queueManager.UpdateTaskStatus(taskID, models.UploadTaskProcessing)
+
+	checksum := hex.EncodeToString(hasher.Sum(nil))
+
+	// 确定文件路径（使用去重后的实际文件名）
+	uploadPath := utils.GetUploadPath(fileName, fileType)
+
+	// 替换已有文件：原内容归档为历史版本，文件记录指向新内容
+	if existingFile != nil {
+		replaced, err := archiveReplacedContent(h.versionRepo, existingFile)
+		if err != nil {
+			os.Remove(filePath)
+			h.queueManager.UpdateTaskError(taskID, "保存历史版本失败")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存历史版本失败"})
+			return
+		}
+
+		existingFile.Size = written
+		existingFile.Type = fileType
+		existingFile.Path = uploadPath
+		existingFile.Checksum = checksum
+		existingFile.EncryptionKeyID = writer.KeyID()
+		existingFile.UploadedBy = userID
+		if fileType == "video" && thumbnailData != "" {
+			existingFile.ThumbnailData = thumbnailData
+		}
+		h.scanner.ResetScanState(existingFile)
+
+		if err := h.fileRepo.UpdateFile(existingFile); err != nil {
+			os.Remove(filePath)
+			replaced.Rollback()
+			h.queueManager.UpdateTaskError(taskID, "更新文件记录失败")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新文件记录失败"})
+			return
+		}
+		replaced.Commit()
+
+		// 清理超出保留数量的历史版本
+		pruneFileVersions(h.versionRepo, h.userRepo, existingFile.ID, ownerID)
+		h.indexer.Notify()
+
+		if errors.Is(h.scanner.ScanUpload(existingFile), models.ErrFileInfected) {
+			h.queueManager.UpdateTaskError(taskID, "文件包含病毒，已被隔离")
+			respondInfectedUpload(c, existingFile)
+			return
+		}
+
+		h.queueManager.UpdateTaskResult(taskID, fmt.Sprintf("/api/files/%d", existingFile.ID))
+		c.JSON(http.StatusOK, gin.H{
+			"success":  true,
+			"message":  "文件替换成功，原文件已保存为历史版本",
+			"file":     existingFile,
+			"replaced": true,
+			"task_id":  taskID,
+		})
+		return
+	}
 
 	// 创建文件记录
 	newFile := &models.File{
-		Name:     originalFileName, // 保存原始文件名
-		Size:     written,
-		Type:     fileType,
-		Path:     uploadPath,
-		UserID:   userID,
-		FolderID: nil,
+		Name:            originalFileName, // 保存原始文件名
+		Size:            written,
+		Type:            fileType,
+		Path:            uploadPath,
+		UserID:          ownerID,
+		FolderID:        targetFolderID,
+		Checksum:        checksum,
+		UploadedBy:      userID,
+		EncryptionKeyID: writer.KeyID(),
 	}
 
 	// 如果是视频文件且有缩略图数据，保存到thumbnail_data字段
-			if fileType == "video" && thumbnailData != "" {
-			newFile.ThumbnailData = thumbnailData
-		}
-
-	// 如果指定了文件夹ID
-	if folderIDStr != "" {
-		if folderIDInt, err := strconv.Atoi(folderIDStr); err == nil {
-			// 检查文件夹是否存在
-			if exists, _ := h.folderRepo.CheckFolderExists(uint(folderIDInt), userID); exists {
-				folderID := uint(folderIDInt)
-				newFile.FolderID = &folderID
-			}
-		}
+	if fileType == "video" && thumbnailData != "" {
+		newFile.ThumbnailData = thumbnailData
 	}
+	h.scanner.ResetScanState(newFile)
 
 	// 保存到数据库
 	if err := h.fileRepo.CreateFile(newFile); err != nil {
 		// 删除已保存的文件
 		os.Remove(filePath)
+		h.queueManager.UpdateTaskError(taskID, "保存文件记录失败")
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":  "保存文件记录失败",
 			"detail": err.Error(),
@@ -529,13 +695,57 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 	// 注意：已使用的存储空间通过计算文件大小动态获取，不需要更新数据库
 	// 存储限制只能通过管理员设置接口修改
 
+	// 后台提取文件内容建立全文索引
+	h.indexer.Notify()
+
+	// 小文件同步扫描，大文件由后台扫描
+	if errors.Is(h.scanner.ScanUpload(newFile), models.ErrFileInfected) {
+		h.queueManager.UpdateTaskError(taskID, "文件包含病毒，已被隔离")
+		respondInfectedUpload(c, newFile)
+		return
+	}
+
+	h.queueManager.UpdateTaskResult(taskID, fmt.Sprintf("/api/files/%d", newFile.ID))
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "文件上传成功",
 		"file":    newFile,
+		"task_id": taskID,
 	})
 }
 
+// resolveUploadOwner 解析上传目标文件夹及其所有者：未指定文件夹时上传到当前用户根目录，
+// 指定的文件夹不存在时返回404，共享给我的文件夹需要编辑者权限，归属文件夹所有者。
+// 权限按登录用户判断，user_id 必须为登录用户本人
+func (h *FileHandler) resolveUploadOwner(c *gin.Context, userID, folderIDStr string) (string, *uint, bool) {
+	if !requireSessionUser(c, userID) {
+		return "", nil, false
+	}
+
+	if folderIDStr == "" {
+		return userID, nil, true
+	}
+
+	folderIDInt, err := strconv.Atoi(folderIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件夹ID"})
+		return "", nil, false
+	}
+
+	// 目标文件夹不存在或查询失败时不回退到根目录；保险库文件需要客户端加密后通过保险库接口上传
+	folder, _, ok := resolveFolderAccess(c, h.grantRepo, uint(folderIDInt), userID, models.PermissionEditor)
+	if !ok {
+		return "", nil, false
+	}
+
+	return folder.UserID, &folder.ID, true
+}
+
+// isRecordNotFound 判断错误是否为记录不存在（兼容原生SQL与GORM）
+func isRecordNotFound(err error) bool {
+	return errors.Is(err, sql.ErrNoRows) || errors.Is(err, gorm.ErrRecordNotFound)
+}
+
 // abs 计算绝对值
 func abs(x int64) int64 {
 	if x < 0 {
@@ -547,10 +757,8 @@ func abs(x int64) int64 {
 // DeleteFile 删除文件
 func (h *FileHandler) DeleteFile(c *gin.Context) {
 	fileIDStr := c.Param("id")
-	userID := c.Query("user_id")
-
-	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+	userID, ok := sessionUserID(c)
+	if !ok {
 		return
 	}
 
@@ -561,25 +769,14 @@ func (h *FileHandler) DeleteFile(c *gin.Context) {
 	}
 	fileID := uint(fileIDInt)
 
-	// 获取文件信息
-	file, err := h.fileRepo.GetFileByID(fileID, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件信息失败"})
-		}
+	// 获取文件信息（编辑者可以删除共享文件夹中的文件）
+	file, ok := resolveFileAccess(c, h.grantRepo, fileID, userID, models.PermissionEditor)
+	if !ok {
 		return
 	}
 
-	// 删除物理文件
-	absolutePath := utils.GetFileAbsolutePath(file.Path)
-	if err := os.Remove(absolutePath); err != nil && !os.IsNotExist(err) {
-		// 静默处理删除错误
-	}
-
-	// 删除数据库记录
-	if err := h.fileRepo.DeleteFile(fileID, userID); err != nil {
+	// 删除数据库记录、物理文件，同时清理该文件的所有历史版本和共享授权
+	if err := purgeFile(h.fileRepo, h.versionRepo, h.grantRepo, file); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除文件失败"})
 		return
 	}
@@ -593,10 +790,8 @@ func (h *FileHandler) DeleteFile(c *gin.Context) {
 // MoveFile 移动文件
 func (h *FileHandler) MoveFile(c *gin.Context) {
 	fileIDStr := c.Param("id")
-	userID := c.Query("user_id")
-
-	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+	userID, ok := sessionUserID(c)
+	if !ok {
 		return
 	}
 
@@ -613,28 +808,25 @@ func (h *FileHandler) MoveFile(c *gin.Context) {
 		return
 	}
 
-	// 检查文件是否存在
-	_, err = h.fileRepo.GetFileByID(fileID, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件信息失败"})
-		}
+	// 检查文件是否存在（编辑者可以移动共享文件夹中的文件）
+	file, ok := resolveFileAccess(c, h.grantRepo, fileID, userID, models.PermissionEditor)
+	if !ok {
 		return
 	}
 
-	// 检查目标文件夹是否存在
+	// 检查目标文件夹是否存在，且与文件属于同一所有者
 	if moveRequest.FolderID > 0 {
-		exists, err := h.folderRepo.CheckFolderExists(uint(moveRequest.FolderID), userID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "检查文件夹失败"})
+		folder, _, ok := resolveFolderAccess(c, h.grantRepo, uint(moveRequest.FolderID), userID, models.PermissionEditor)
+		if !ok {
 			return
 		}
-		if !exists {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "目标文件夹不存在"})
+		if folder.UserID != file.UserID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不能将文件移动到其他用户的文件夹"})
 			return
 		}
+	} else if file.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有所有者可以将文件移动到根目录"})
+		return
 	}
 
 	// 移动文件
@@ -644,7 +836,7 @@ func (h *FileHandler) MoveFile(c *gin.Context) {
 		folderID = &folderIDUint
 	}
 
-	if err := h.fileRepo.MoveFile(fileID, userID, folderID); err != nil {
+	if err := h.fileRepo.MoveFile(fileID, file.UserID, folderID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "移动文件失败"})
 		return
 	}
@@ -703,6 +895,9 @@ func (h *FileHandler) DownloadFileRedirect(c *gin.Context) {
 		}
 		return
 	}
+	if rejectInfectedFile(c, file) {
+		return
+	}
 
 	// 构建静态文件URL - 修复重复路径问题
 	staticURL := file.Path
@@ -715,48 +910,6 @@ func (h *FileHandler) DownloadFileRedirect(c *gin.Context) {
 	c.Redirect(http.StatusFound, redirectURL)
 }
 
-// SearchFiles 搜索文件
-func (h *FileHandler) SearchFiles(c *gin.Context) {
-	userID := c.Query("user_id")
-	query := c.Query("q")
-	folderIDStr := c.Query("folder_id")
-
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少用户ID"})
-		return
-	}
-
-	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少搜索关键词"})
-		return
-	}
-
-	var folderID *uint
-	if folderIDStr != "" {
-		if id, err := strconv.Atoi(folderIDStr); err == nil {
-			folderIDUint := uint(id)
-			folderID = &folderIDUint
-		}
-	}
-
-	files, err := h.fileRepo.SearchFilesByUserID(userID, query, folderID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索文件失败"})
-		return
-	}
-
-	// 确保files不为nil，如果为nil则初始化为空数组
-	if files == nil {
-		files = []models.File{}
-	}
-
-	response := models.FileListResponse{
-		Success: true,
-		Files:   files,
-	}
-	c.JSON(http.StatusOK, response)
-}
-
 // UploadFiles 批量上传文件
 func (h *FileHandler) UploadFiles(c *gin.Context) {
 	defer func() {
@@ -786,14 +939,11 @@ func (h *FileHandler) UploadFiles(c *gin.Context) {
 		return
 	}
 
-	userID := c.PostForm("user_id")
-	folderIDStr := c.PostForm("folder_id")
-
-	// 验证用户ID
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少用户ID"})
+	userID, ok := sessionUserID(c)
+	if !ok {
 		return
 	}
+	folderIDStr := c.PostForm("folder_id")
 
 	// 获取所有上传的文件
 	form := c.Request.MultipartForm
@@ -808,8 +958,14 @@ func (h *FileHandler) UploadFiles(c *gin.Context) {
 		return
 	}
 
+	// 上传到共享文件夹时，文件归属并计入文件夹所有者的配额
+	ownerID, targetFolderID, ok := h.resolveUploadOwner(c, userID, folderIDStr)
+	if !ok {
+		return
+	}
+
 	// 获取用户存储信息
-	usedSpace, storageLimit, err := h.userRepo.GetUserStorageInfo(userID)
+	usedSpace, storageLimit, err := h.userRepo.GetUserStorageInfo(ownerID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取存储信息失败"})
 		return
@@ -861,9 +1017,9 @@ func (h *FileHandler) UploadFiles(c *gin.Context) {
 			continue
 		}
 
-		// 检查同名文件
-		existingFile, err := h.fileRepo.GetFileByNameAndUser(file.Filename, userID)
-		if err != nil && err != sql.ErrNoRows {
+		// 检查目标文件夹中的同名文件
+		existingFile, err := h.fileRepo.GetFileByNameInFolder(ownerID, file.Filename, targetFolderID)
+		if err != nil && !isRecordNotFound(err) {
 			fileResult["error"] = "检查文件失败"
 			failedCount++
 			results = append(results, fileResult)
@@ -924,10 +1080,27 @@ func (h *FileHandler) UploadFiles(c *gin.Context) {
 			}
 		}
 
+		// 每个文件登记一个上传任务，可单独查询进度或取消
+		task := h.queueManager.CreateTask(userID, "", originalFileName, file.Size)
+		taskID := task.ID
+		fileResult["task_id"] = taskID
+
 		// 保存文件
 		filePath := filepath.Join(uploadDir, fileName)
 		dst, err := os.Create(filePath)
 		if err != nil {
+			h.queueManager.UpdateTaskError(taskID, "保存文件失败")
+			fileResult["error"] = "保存文件失败"
+			failedCount++
+			results = append(results, fileResult)
+			continue
+		}
+
+		writer, err := utils.NewStoredContentWriter(dst, ownerID)
+		if err != nil {
+			dst.Close()
+			os.Remove(filePath)
+			h.queueManager.UpdateTaskError(taskID, "保存文件失败")
 			fileResult["error"] = "保存文件失败"
 			failedCount++
 			results = append(results, fileResult)
@@ -938,6 +1111,8 @@ func (h *FileHandler) UploadFiles(c *gin.Context) {
 		src, err := file.Open()
 		if err != nil {
 			dst.Close()
+			os.Remove(filePath)
+			h.queueManager.UpdateTaskError(taskID, "打开文件失败")
 			fileResult["error"] = "打开文件失败"
 			failedCount++
 			results = append(results, fileResult)
@@ -945,14 +1120,39 @@ func (h *FileHandler) UploadFiles(c *gin.Context) {
 		}
 
 		// 使用缓冲写入，提高性能
+		h.queueManager.UpdateTaskStatus(taskID, models.UploadTaskUploading)
+		ctx, release := h.queueManager.TaskContext(c.Request.Context(), taskID)
+		fileSize := file.Size
+		reader := &ProgressReader{
+			Reader:  src,
+			Context: ctx,
+			OnProgress: func(read int64) {
+				h.queueManager.UpdateTaskBytes(taskID, read, fileSize)
+			},
+		}
 		buffer := make([]byte, 32*1024) // 32KB buffer
-		written, err := io.CopyBuffer(dst, src, buffer)
+		written, err := io.CopyBuffer(writer, reader, buffer)
+		release()
+		if err == nil {
+			err = writer.Close()
+		}
 		src.Close()
-		dst.Close()
+		if closeErr := dst.Close(); err == nil {
+			err = closeErr
+		}
+
+		if utils.TaskCancelled(ctx) {
+			os.Remove(filePath)
+			fileResult["error"] = "上传已取消"
+			failedCount++
+			results = append(results, fileResult)
+			continue
+		}
 
 		if err != nil {
 			// 删除部分写入的文件
 			os.Remove(filePath)
+			h.queueManager.UpdateTaskError(taskID, "保存文件失败")
 			fileResult["error"] = "保存文件失败"
 			failedCount++
 			results = append(results, fileResult)
@@ -963,44 +1163,50 @@ func (h *FileHandler) UploadFiles(c *gin.Context) {
 		if written != file.Size {
 			// 删除不完整的文件
 			os.Remove(filePath)
+			h.queueManager.UpdateTaskError(taskID, "文件写入不完整")
 			fileResult["error"] = "文件写入不完整"
 			failedCount++
 			results = append(results, fileResult)
 			continue
 		}
+		h.queueManager.UpdateTaskStatus(taskID, models.UploadTaskProcessing)
 
 		// 创建文件记录
 		newFile := &models.File{
-			Name:     originalFileName, // 保存原始文件名
-			Size:     written,
-			Type:     fileType,
-			Path:     uploadPath,
-			UserID:   userID,
-			FolderID: nil,
-		}
-
-		// 如果指定了文件夹ID
-		if folderIDStr != "" {
-			if folderIDInt, err := strconv.Atoi(folderIDStr); err == nil {
-				// 检查文件夹是否存在
-				if exists, _ := h.folderRepo.CheckFolderExists(uint(folderIDInt), userID); exists {
-					folderID := uint(folderIDInt)
-					newFile.FolderID = &folderID
-				}
-			}
+			Name:            originalFileName, // 保存原始文件名
+			Size:            written,
+			Type:            fileType,
+			Path:            uploadPath,
+			UserID:          ownerID,
+			FolderID:        targetFolderID,
+			UploadedBy:      userID,
+			EncryptionKeyID: writer.KeyID(),
 		}
 
 		// 保存到数据库
+		h.scanner.ResetScanState(newFile)
 		if err := h.fileRepo.CreateFile(newFile); err != nil {
 			// 删除已保存的文件
 			os.Remove(filePath)
+			h.queueManager.UpdateTaskError(taskID, "保存文件记录失败")
 			fileResult["error"] = "保存文件记录失败"
 			failedCount++
 			results = append(results, fileResult)
 			continue
 		}
 
+		// 发现病毒的文件已被隔离，记为失败
+		if errors.Is(h.scanner.ScanUpload(newFile), models.ErrFileInfected) {
+			h.queueManager.UpdateTaskError(taskID, "文件包含病毒，已被隔离")
+			fileResult["error"] = "文件包含病毒，已被隔离"
+			fileResult["file"] = newFile
+			failedCount++
+			results = append(results, fileResult)
+			continue
+		}
+
 		// 更新统计信息
+		h.queueManager.UpdateTaskResult(taskID, fmt.Sprintf("/api/files/%d", newFile.ID))
 		totalUploadedSize += written
 		successCount++
 
@@ -1013,6 +1219,10 @@ func (h *FileHandler) UploadFiles(c *gin.Context) {
 	// 注意：已使用的存储空间通过计算文件大小动态获取，不需要更新数据库
 	// 存储限制只能通过管理员设置接口修改
 
+	if successCount > 0 {
+		h.indexer.Notify()
+	}
+
 	// 返回批量上传结果
 	c.JSON(http.StatusOK, gin.H{
 		"success":       true,
@@ -0,0 +1,707 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validateItemName 校验文件或文件夹名称：非空、不含路径分隔符且长度不超过255
+func validateItemName(name string) bool {
+	if name == "" || name == "." || name == ".." || len(name) > 255 {
+		return false
+	}
+	return !strings.ContainsAny(name, "/\\")
+}
+
+// numberedName 生成带序号的名称，文件保留扩展名："报告 (1).pdf"，文件夹："资料 (1)"
+func numberedName(name string, counter int, keepExt bool) string {
+	ext := ""
+	if keepExt {
+		ext = filepath.Ext(name)
+	}
+	return fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), counter, ext)
+}
+
+// sameFolderID 判断两个可空的文件夹ID是否指向同一位置（nil 表示根目录）
+func sameFolderID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// parseConflictStrategy 解析冲突处理策略，无效时直接写入响应
+func parseConflictStrategy(c *gin.Context, strategy string) (string, bool) {
+	normalized, ok := models.NormalizeConflictStrategy(strategy)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的冲突处理策略，可选值: fail、rename、overwrite"})
+		return "", false
+	}
+	return normalized, true
+}
+
+// resolveTargetFolder 解析复制操作的目标文件夹：requested 为 nil 时使用 fallback，0 表示当前用户的根目录。
+// 写入共享文件夹需要编辑者权限，返回目标文件夹ID（根目录为nil）和新内容的所有者ID
+func resolveTargetFolder(c *gin.Context, grantRepo database.GrantRepositoryInterface, requested, fallback *uint, userID string) (*uint, string, bool) {
+	target := requested
+	if target == nil {
+		target = fallback
+	}
+	if target == nil || *target == 0 {
+		return nil, userID, true
+	}
+
+	folder, _, ok := resolveFolderAccess(c, grantRepo, *target, userID, models.PermissionEditor)
+	if !ok {
+		return nil, "", false
+	}
+	return &folder.ID, folder.UserID, true
+}
+
+// errNoUniqueName 无法在有限次数内生成不重复的名称
+var errNoUniqueName = errors.New("无法生成不重复的名称")
+
+// findFileNameConflict 按冲突策略检查目标文件夹中的同名文件。
+// 返回最终使用的名称、需要被覆盖的文件，以及 fail 策略下发生冲突的文件
+func findFileNameConflict(fileRepo database.FileRepositoryInterface, ownerID, name string, folderID *uint, strategy string, excludeID uint) (string, *models.File, *models.File, error) {
+	existing, err := fileRepo.GetFileByNameInFolder(ownerID, name, folderID)
+	if err != nil && !isRecordNotFound(err) {
+		return "", nil, nil, err
+	}
+	if existing == nil || existing.ID == excludeID {
+		return name, nil, nil, nil
+	}
+
+	switch strategy {
+	case models.ConflictOverwrite:
+		return name, existing, nil, nil
+	case models.ConflictRename:
+		for counter := 1; counter <= 1000; counter++ {
+			candidate := numberedName(name, counter, true)
+			other, err := fileRepo.GetFileByNameInFolder(ownerID, candidate, folderID)
+			if err != nil && !isRecordNotFound(err) {
+				return "", nil, nil, err
+			}
+			if other == nil {
+				return candidate, nil, nil, nil
+			}
+		}
+		return "", nil, nil, errNoUniqueName
+	default:
+		return "", nil, existing, nil
+	}
+}
+
+// resolveFileNameConflict 按冲突策略处理目标文件夹中的同名文件，返回最终使用的名称和需要被覆盖的文件，失败时直接写入响应
+func resolveFileNameConflict(c *gin.Context, fileRepo database.FileRepositoryInterface, ownerID, name string, folderID *uint, strategy string, excludeID uint) (string, *models.File, bool) {
+	finalName, replaced, conflict, err := findFileNameConflict(fileRepo, ownerID, name, folderID, strategy, excludeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查文件名称失败"})
+		return "", nil, false
+	}
+	if conflict != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         "目标位置已存在同名文件",
+			"conflict_type": "name_exists",
+			"existing_file": conflict,
+		})
+		return "", nil, false
+	}
+	return finalName, replaced, true
+}
+
+// resolveFolderNameConflict 按冲突策略检查目标父文件夹下的同名文件夹。
+// 返回最终使用的名称、是否合并到已存在的同名文件夹，以及 fail 策略下发生冲突的文件夹；
+// excluded 中的文件夹（通常为复制源子树）不能作为合并目标
+func resolveFolderNameConflict(folderRepo database.FolderRepositoryInterface, ownerID, name string, parentID *uint, strategy string, excluded map[uint]bool) (string, bool, *models.Folder, error) {
+	existing, err := folderRepo.GetFolderByNameInParent(ownerID, name, parentID)
+	if err != nil && !isRecordNotFound(err) {
+		return "", false, nil, err
+	}
+	if existing == nil {
+		return name, false, nil, nil
+	}
+
+	switch strategy {
+	case models.ConflictOverwrite:
+		if excluded[existing.ID] {
+			return "", false, nil, errMergeIntoSelf
+		}
+		return name, true, nil, nil
+	case models.ConflictRename:
+		for counter := 1; counter <= 1000; counter++ {
+			candidate := numberedName(name, counter, false)
+			other, err := folderRepo.GetFolderByNameInParent(ownerID, candidate, parentID)
+			if err != nil && !isRecordNotFound(err) {
+				return "", false, nil, err
+			}
+			if other == nil {
+				return candidate, false, nil, nil
+			}
+		}
+		return "", false, nil, errNoUniqueName
+	default:
+		return "", false, existing, nil
+	}
+}
+
+// errMergeIntoSelf 文件夹不能合并到自身或其子文件夹
+var errMergeIntoSelf = errors.New("不能将文件夹合并到自身或其子文件夹")
+
+// purgeFileRecords 删除文件的数据库记录、历史版本记录和共享授权，返回需要删除的物理文件路径
+func purgeFileRecords(fileRepo database.FileRepositoryInterface, versionRepo database.FileVersionRepositoryInterface, grantRepo database.GrantRepositoryInterface, file *models.File) ([]string, error) {
+	versions, err := versionRepo.GetVersionsByFileID(file.ID, file.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := fileRepo.DeleteFile(file.ID, file.UserID); err != nil {
+		return nil, err
+	}
+	if err := versionRepo.DeleteVersionsByFileID(file.ID, file.UserID); err != nil {
+		return nil, err
+	}
+	if err := grantRepo.DeleteGrantsByResource(models.ShareResourceFile, file.ID); err != nil {
+		return nil, err
+	}
+
+	paths := []string{utils.GetFileAbsolutePath(file.Path)}
+	if file.IsInfected() {
+		paths = append(paths, utils.GetQuarantinePath(file.Path))
+	}
+	for _, version := range versions {
+		paths = append(paths, utils.GetFileAbsolutePath(version.Path))
+	}
+	return paths, nil
+}
+
+// removeStoredPaths 删除物理文件，忽略不存在的文件
+func removeStoredPaths(paths []string) {
+	for _, absolutePath := range paths {
+		os.Remove(absolutePath)
+	}
+}
+
+// purgeFile 删除文件的物理内容、数据库记录、历史版本和共享授权
+func purgeFile(fileRepo database.FileRepositoryInterface, versionRepo database.FileVersionRepositoryInterface, grantRepo database.GrantRepositoryInterface, file *models.File) error {
+	paths, err := purgeFileRecords(fileRepo, versionRepo, grantRepo, file)
+	if err != nil {
+		return err
+	}
+	removeStoredPaths(paths)
+	return nil
+}
+
+// RenameFile 重命名文件，同时重命名存储的物理文件
+func (h *FileHandler) RenameFile(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	fileIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件ID"})
+		return
+	}
+
+	var request models.RenameFileRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	name := strings.TrimSpace(request.Name)
+	if !validateItemName(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件名"})
+		return
+	}
+	strategy, ok := parseConflictStrategy(c, request.OnConflict)
+	if !ok {
+		return
+	}
+
+	// 编辑者可以重命名共享文件夹中的文件；被隔离的文件不在上传目录中，无法重命名
+	file, ok := resolveFileAccess(c, h.grantRepo, uint(fileIDInt), userID, models.PermissionEditor)
+	if !ok || rejectInfectedFile(c, file) {
+		return
+	}
+	if name == file.Name {
+		c.JSON(http.StatusOK, gin.H{"success": true, "message": "文件名未变化", "file": file})
+		return
+	}
+
+	finalName, replaced, ok := resolveFileNameConflict(c, h.fileRepo, file.UserID, name, file.FolderID, strategy, file.ID)
+	if !ok {
+		return
+	}
+
+	// 先重命名物理文件，数据库更新失败时还原
+	stored, err := utils.RenameStoredFile(file.Path, finalName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重命名存储文件失败"})
+		return
+	}
+
+	oldPath := file.Path
+	oldName, oldType := file.Name, file.Type
+	file.Name = finalName
+	file.Path = stored.Path
+	file.Type = stored.FileType
+	if err := h.fileRepo.UpdateFile(file); err != nil {
+		os.Rename(stored.AbsolutePath, utils.GetFileAbsolutePath(oldPath))
+		file.Name, file.Path, file.Type = oldName, oldPath, oldType
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重命名文件失败"})
+		return
+	}
+
+	if replaced != nil {
+		purgeFile(h.fileRepo, h.versionRepo, h.grantRepo, replaced)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"message":     "文件重命名成功",
+		"file":        file,
+		"overwritten": replaced != nil,
+	})
+}
+
+// CopyFile 复制文件，副本拥有独立的物理文件并计入目标所有者的存储配额
+func (h *FileHandler) CopyFile(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	fileIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件ID"})
+		return
+	}
+
+	var request models.CopyFileRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	strategy, ok := parseConflictStrategy(c, request.OnConflict)
+	if !ok {
+		return
+	}
+
+	// 查看者即可复制文件
+	source, ok := resolveFileAccess(c, h.grantRepo, uint(fileIDInt), userID, models.PermissionViewer)
+	if !ok {
+		return
+	}
+	if rejectInfectedFile(c, source) || rejectVaultFile(c, source) {
+		return
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		name = source.Name
+	}
+	if !validateItemName(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件名"})
+		return
+	}
+
+	folderID, ownerID, ok := resolveTargetFolder(c, h.grantRepo, request.FolderID, source.FolderID, userID)
+	if !ok {
+		return
+	}
+
+	finalName, replaced, ok := resolveFileNameConflict(c, h.fileRepo, ownerID, name, folderID, strategy, 0)
+	if !ok {
+		return
+	}
+	if replaced != nil && replaced.ID == source.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能用文件副本覆盖文件自身"})
+		return
+	}
+
+	// 检查目标所有者的存储空间
+	usedSpace, storageLimit, err := h.userRepo.GetUserStorageInfo(ownerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取存储信息失败"})
+		return
+	}
+	if !utils.ValidateFileSize(source.Size, storageLimit, usedSpace) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "存储空间不足"})
+		return
+	}
+
+	stored, err := utils.CopyStoredFile(source.Path, source.EncryptionKeyID, finalName, ownerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "复制存储文件失败"})
+		return
+	}
+
+	newFile := &models.File{
+		Name:            finalName,
+		Size:            stored.Size,
+		Type:            stored.FileType,
+		Path:            stored.Path,
+		UserID:          ownerID,
+		FolderID:        folderID,
+		ThumbnailData:   source.ThumbnailData,
+		Checksum:        stored.Checksum,
+		EncryptionKeyID: stored.KeyID,
+		UploadedBy:      userID,
+		Description:     source.Description,
+		Metadata:        source.Metadata,
+		// 副本内容相同，沿用源文件的扫描结果
+		ScanStatus: source.ScanStatus,
+		ScanResult: source.ScanResult,
+		ScannedAt:  source.ScannedAt,
+	}
+	if err := h.fileRepo.CreateFile(newFile); err != nil {
+		os.Remove(stored.AbsolutePath)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件记录失败"})
+		return
+	}
+
+	if replaced != nil {
+		purgeFile(h.fileRepo, h.versionRepo, h.grantRepo, replaced)
+	}
+	h.indexer.Notify()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"message":     "文件复制成功",
+		"file":        newFile,
+		"overwritten": replaced != nil,
+	})
+}
+
+// folderSnapshot 复制前采集的文件夹子树快照，避免复制到自身子文件夹时无限递归
+type folderSnapshot struct {
+	folder   models.Folder
+	files    []models.File
+	urlFiles []models.UrlFile
+	children []*folderSnapshot
+}
+
+// folderCopy 一次文件夹复制的执行状态，失败时按记录回滚
+type folderCopy struct {
+	fileRepo    database.FileRepositoryInterface
+	folderRepo  database.FolderRepositoryInterface
+	urlFileRepo database.UrlFileRepositoryInterface
+	userID      string
+	ownerID     string
+	merge       bool
+	replaced    []models.File
+	visited     map[uint]bool // 源子树中的文件夹，合并时不能复用
+	result      models.CopyFolderResult
+
+	createdFolders  []uint
+	createdFiles    []models.File
+	createdUrlFiles []uint
+}
+
+// newFolderCopy 创建文件夹复制器，ownerID 为副本的所有者
+func newFolderCopy(fileRepo database.FileRepositoryInterface, folderRepo database.FolderRepositoryInterface, urlFileRepo database.UrlFileRepositoryInterface, userID, ownerID string) *folderCopy {
+	return &folderCopy{
+		fileRepo:    fileRepo,
+		folderRepo:  folderRepo,
+		urlFileRepo: urlFileRepo,
+		userID:      userID,
+		ownerID:     ownerID,
+		visited:     make(map[uint]bool),
+	}
+}
+
+// snapshot 递归采集文件夹及其全部子孙内容，同时统计数量和总大小
+func (fc *folderCopy) snapshot(folder models.Folder) (*folderSnapshot, error) {
+	fc.visited[folder.ID] = true
+	node := &folderSnapshot{folder: folder}
+	fc.result.FolderCount++
+
+	files, err := fc.fileRepo.GetFilesByUserID(folder.UserID, &folder.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		// 被隔离的文件不复制
+		if file.IsInfected() {
+			fc.result.InfectedCount++
+			continue
+		}
+		node.files = append(node.files, file)
+		fc.result.FileCount++
+		fc.result.TotalSize += file.Size
+	}
+
+	urlFiles, err := fc.urlFileRepo.GetUrlFilesByUserID(folder.UserID, &folder.ID)
+	if err != nil {
+		return nil, err
+	}
+	node.urlFiles = urlFiles
+	fc.result.UrlFileCount += len(urlFiles)
+
+	subFolders, err := fc.folderRepo.GetSubFolders(folder.ID, folder.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range subFolders {
+		if fc.visited[sub.ID] {
+			continue
+		}
+		child, err := fc.snapshot(sub)
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, child)
+	}
+	return node, nil
+}
+
+// run 以 name 为名称在 parentID 下创建副本（合并模式下复用同名文件夹），并复制快照中的全部内容，返回副本根文件夹ID
+func (fc *folderCopy) run(root *folderSnapshot, name string, parentID *uint) (uint, error) {
+	rootID, err := fc.ensureFolder(root.folder, name, parentID)
+	if err != nil {
+		return 0, err
+	}
+	if err := fc.copyInto(root, rootID); err != nil {
+		return 0, err
+	}
+	fc.result.OverwriteCount = len(fc.replaced)
+	return rootID, nil
+}
+
+// copyInto 将快照中的内容复制到目标文件夹
+func (fc *folderCopy) copyInto(node *folderSnapshot, targetID uint) error {
+	for _, file := range node.files {
+		var replaced *models.File
+		if fc.merge {
+			existing, err := fc.fileRepo.GetFileByNameInFolder(fc.ownerID, file.Name, &targetID)
+			if err != nil && !isRecordNotFound(err) {
+				return err
+			}
+			replaced = existing
+		}
+
+		stored, err := utils.CopyStoredFile(file.Path, file.EncryptionKeyID, file.Name, fc.ownerID)
+		if err != nil {
+			return err
+		}
+		newFile := models.File{
+			Name:            file.Name,
+			Size:            stored.Size,
+			Type:            stored.FileType,
+			Path:            stored.Path,
+			UserID:          fc.ownerID,
+			FolderID:        &targetID,
+			ThumbnailData:   file.ThumbnailData,
+			Checksum:        stored.Checksum,
+			EncryptionKeyID: stored.KeyID,
+			UploadedBy:      fc.userID,
+			Description:     file.Description,
+			Metadata:        file.Metadata,
+			ScanStatus:      file.ScanStatus,
+			ScanResult:      file.ScanResult,
+			ScannedAt:       file.ScannedAt,
+		}
+		if err := fc.fileRepo.CreateFile(&newFile); err != nil {
+			os.Remove(stored.AbsolutePath)
+			return err
+		}
+		fc.createdFiles = append(fc.createdFiles, newFile)
+		if replaced != nil {
+			fc.replaced = append(fc.replaced, *replaced)
+		}
+	}
+
+	for _, urlFile := range node.urlFiles {
+		newUrlFile := models.UrlFile{
+			Title:       urlFile.Title,
+			URL:         urlFile.URL,
+			Description: urlFile.Description,
+			UserID:      fc.ownerID,
+			FolderID:    &targetID,
+		}
+		if err := fc.urlFileRepo.CreateUrlFile(&newUrlFile); err != nil {
+			return err
+		}
+		fc.createdUrlFiles = append(fc.createdUrlFiles, newUrlFile.ID)
+	}
+
+	for _, child := range node.children {
+		childID, err := fc.ensureFolder(child.folder, child.folder.Name, &targetID)
+		if err != nil {
+			return err
+		}
+		if err := fc.copyInto(child, childID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureFolder 在目标父文件夹下创建副本文件夹；合并模式下复用已存在的同名文件夹
+func (fc *folderCopy) ensureFolder(source models.Folder, name string, parentID *uint) (uint, error) {
+	if fc.merge {
+		existing, err := fc.folderRepo.GetFolderByNameInParent(fc.ownerID, name, parentID)
+		if err != nil && !isRecordNotFound(err) {
+			return 0, err
+		}
+		if existing != nil && !fc.visited[existing.ID] {
+			return existing.ID, nil
+		}
+	}
+
+	folder := &models.Folder{
+		Name:        name,
+		UserID:      fc.ownerID,
+		Category:    source.Category,
+		ParentID:    parentID,
+		Description: source.Description,
+		Metadata:    source.Metadata,
+	}
+	if err := fc.folderRepo.CreateFolder(folder); err != nil {
+		return 0, err
+	}
+	fc.createdFolders = append(fc.createdFolders, folder.ID)
+	return folder.ID, nil
+}
+
+// storedPaths 返回本次复制写入的物理文件路径
+func (fc *folderCopy) storedPaths() []string {
+	paths := make([]string, 0, len(fc.createdFiles))
+	for _, file := range fc.createdFiles {
+		paths = append(paths, utils.GetFileAbsolutePath(file.Path))
+	}
+	return paths
+}
+
+// rollback 复制失败时删除已创建的文件、URL文件和文件夹
+func (fc *folderCopy) rollback() {
+	for _, file := range fc.createdFiles {
+		fc.fileRepo.DeleteFile(file.ID, fc.ownerID)
+	}
+	removeStoredPaths(fc.storedPaths())
+	for _, urlFileID := range fc.createdUrlFiles {
+		fc.urlFileRepo.DeleteUrlFile(urlFileID, fc.ownerID)
+	}
+	for i := len(fc.createdFolders) - 1; i >= 0; i-- {
+		fc.folderRepo.DeleteFolder(fc.createdFolders[i], fc.ownerID)
+	}
+}
+
+// CopyFolder 递归复制文件夹（包括子文件夹、文件和URL文件）到另一个父文件夹
+func (h *FolderHandler) CopyFolder(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	folderIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件夹ID"})
+		return
+	}
+
+	var request models.CopyFolderRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	strategy, ok := parseConflictStrategy(c, request.OnConflict)
+	if !ok {
+		return
+	}
+
+	source, _, ok := resolveFolderAccess(c, h.grantRepo, uint(folderIDInt), userID, models.PermissionViewer)
+	if !ok || rejectVaultFolder(c, source) {
+		return
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		name = source.Name
+	}
+	if !validateItemName(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件夹名称"})
+		return
+	}
+
+	parentID, ownerID, ok := resolveTargetFolder(c, h.grantRepo, request.ParentID, source.ParentID, userID)
+	if !ok {
+		return
+	}
+
+	// 先采集完整子树，统计所需空间
+	copier := newFolderCopy(h.fileRepo, h.folderRepo, h.urlFileRepo, userID, ownerID)
+	snapshot, err := copier.snapshot(*source)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取文件夹内容失败"})
+		return
+	}
+
+	usedSpace, storageLimit, err := h.userRepo.GetUserStorageInfo(ownerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取存储信息失败"})
+		return
+	}
+	if !utils.ValidateFileSize(copier.result.TotalSize, storageLimit, usedSpace) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         "存储空间不足",
+			"required_size": copier.result.TotalSize,
+		})
+		return
+	}
+
+	// 处理目标位置的同名文件夹
+	finalName, merge, conflict, err := resolveFolderNameConflict(h.folderRepo, ownerID, name, parentID, strategy, copier.visited)
+	if err != nil {
+		if errors.Is(err, errMergeIntoSelf) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "检查文件夹名称失败"})
+		}
+		return
+	}
+	if conflict != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":           "目标位置已存在同名文件夹",
+			"conflict_type":   "name_exists",
+			"existing_folder": conflict,
+		})
+		return
+	}
+	copier.merge = merge
+
+	rootID, err := copier.run(snapshot, finalName, parentID)
+	if err != nil {
+		copier.rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "复制文件夹失败"})
+		return
+	}
+
+	// 合并模式下，副本写入成功后再删除被覆盖的同名文件
+	for i := range copier.replaced {
+		purgeFile(h.fileRepo, h.versionRepo, h.grantRepo, &copier.replaced[i])
+	}
+
+	result := copier.result
+	if root, err := h.folderRepo.GetFolderByID(rootID, ownerID); err == nil {
+		result.Folder = *root
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "文件夹复制成功",
+		"result":  result,
+	})
+}
@@ -0,0 +1,532 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"backend/models"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 文件搜索分页参数
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+)
+
+// searchableTypes 可用于 type 筛选的文件类型，与 utils.GetFileType 的返回值一致
+var searchableTypes = map[string]bool{
+	"image": true, "video": true, "audio": true, "pdf": true, "document": true,
+	"word": true, "excel": true, "powerpoint": true, "other": true, models.SearchTypeUrl: true,
+}
+
+// SearchFiles 按名称关键词和结构化条件搜索文件与URL文件（item_type 可加入文件夹），支持标签筛选、排序和游标分页；
+// mode=fuzzy 时改为按拼音和编辑距离模糊匹配，结果同时包含文件夹
+func (h *FileHandler) SearchFiles(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	switch c.DefaultQuery("mode", models.SearchModeExact) {
+	case models.SearchModeExact:
+	case models.SearchModeFuzzy, "pinyin":
+		h.fuzzySearch(c, userID)
+		return
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode 只支持 exact 或 fuzzy"})
+		return
+	}
+
+	query, ok := h.parseFileSearchQuery(c, userID, false)
+	if !ok {
+		return
+	}
+	h.writeSearchPage(c, query)
+}
+
+// ListTagItems 跨文件夹列出带有指定标签的文件、文件夹和URL文件，支持与搜索相同的筛选、排序和游标分页
+func (h *FileHandler) ListTagItems(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	tagID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的标签ID"})
+		return
+	}
+	if _, err := h.tagRepo.GetTagByID(uint(tagID), userID); err != nil {
+		if isRecordNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "标签不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取标签失败"})
+		return
+	}
+
+	query, ok := h.parseFileSearchQuery(c, userID, true)
+	if !ok {
+		return
+	}
+	// 路径中的标签与 tag、tag_id 参数同时存在时，按 tag_mode 组合
+	if !containsUint(query.TagIDs, uint(tagID)) {
+		query.TagIDs = append(query.TagIDs, uint(tagID))
+	}
+	h.writeSearchPage(c, query)
+}
+
+// writeSearchPage 执行结构化搜索并写入一页结果
+func (h *FileHandler) writeSearchPage(c *gin.Context, query *models.FileSearchQuery) {
+	page, err := h.fileRepo.SearchItems(query)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索文件失败"})
+		return
+	}
+
+	response := models.FileSearchResponse{
+		Success:    true,
+		Items:      page.Items,
+		Files:      []models.File{},
+		UrlFiles:   []models.UrlFile{},
+		NextCursor: page.NextCursor,
+		HasMore:    page.HasMore,
+	}
+	for _, item := range page.Items {
+		switch {
+		case item.File != nil:
+			response.Files = append(response.Files, *item.File)
+		case item.Folder != nil:
+			response.Folders = append(response.Folders, *item.Folder)
+		case item.UrlFile != nil:
+			response.UrlFiles = append(response.UrlFiles, *item.UrlFile)
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// parseFileSearchQuery 解析搜索参数并校验文件夹权限，失败时直接写入响应
+func (h *FileHandler) parseFileSearchQuery(c *gin.Context, userID string, defaultFolders bool) (*models.FileSearchQuery, bool) {
+	query, ok := h.parseSearchFilters(c, userID, defaultFolders)
+	if !ok {
+		return nil, false
+	}
+
+	query.Sort = c.DefaultQuery("sort", models.SearchSortUpdatedAt)
+	if query.Sort == "date" {
+		query.Sort = models.SearchSortUpdatedAt
+	}
+	switch query.Sort {
+	case models.SearchSortName, models.SearchSortSize, models.SearchSortCreatedAt, models.SearchSortUpdatedAt:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort 只支持 name、size、created_at、updated_at"})
+		return nil, false
+	}
+	switch c.Query("order") {
+	case "":
+		query.Desc = query.Sort != models.SearchSortName
+	case "asc":
+		query.Desc = false
+	case "desc":
+		query.Desc = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order 只支持 asc 或 desc"})
+		return nil, false
+	}
+
+	limit, _, ok := parseLimitOffset(c, defaultSearchLimit, maxSearchLimit)
+	if !ok {
+		return nil, false
+	}
+	query.Limit = limit
+
+	if encoded := c.Query("cursor"); encoded != "" {
+		cursor, err := models.DecodeSearchCursor(encoded, query.Sort, query.Desc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		query.Cursor = cursor
+	}
+
+	return query, true
+}
+
+// parseSearchFilters 解析关键词以外的筛选条件（类型、扩展名、大小、时间、标签、元数据、对象类型、文件夹），失败时直接写入响应。
+// defaultFolders 表示未传 item_type 时是否包含文件夹
+func (h *FileHandler) parseSearchFilters(c *gin.Context, userID string, defaultFolders bool) (*models.FileSearchQuery, bool) {
+	query := &models.FileSearchQuery{
+		OwnerID: userID,
+		Keyword: strings.TrimSpace(c.Query("q")),
+	}
+
+	if !h.applySearchTags(c, query, userID) {
+		return nil, false
+	}
+
+	metaParams := c.QueryArray("meta")
+	if len(metaParams) > models.MaxMetadataFilters {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "meta 条件最多 " + strconv.Itoa(models.MaxMetadataFilters) + " 个"})
+		return nil, false
+	}
+	for _, param := range metaParams {
+		filter, err := models.ParseMetadataFilter(param)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		query.Metadata = append(query.Metadata, filter)
+	}
+
+	query.Types = splitQueryList(c, "type")
+	for _, fileType := range query.Types {
+		if !searchableTypes[fileType] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的文件类型: " + fileType})
+			return nil, false
+		}
+	}
+	for _, ext := range splitQueryList(c, "ext") {
+		query.Extensions = append(query.Extensions, strings.TrimPrefix(ext, "."))
+	}
+
+	var ok bool
+	if query.MinSize, ok = parseOptionalSize(c, "min_size"); !ok {
+		return nil, false
+	}
+	if query.MaxSize, ok = parseOptionalSize(c, "max_size"); !ok {
+		return nil, false
+	}
+	if query.MinSize != nil && query.MaxSize != nil && *query.MinSize > *query.MaxSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_size 不能大于 max_size"})
+		return nil, false
+	}
+
+	for _, bound := range []struct {
+		key    string
+		target **time.Time
+		end    bool
+	}{
+		{"created_from", &query.CreatedFrom, false},
+		{"created_to", &query.CreatedTo, true},
+		{"updated_from", &query.UpdatedFrom, false},
+		{"updated_to", &query.UpdatedTo, true},
+	} {
+		if *bound.target, ok = parseSearchTime(c, bound.key, bound.end); !ok {
+			return nil, false
+		}
+	}
+
+	// 类型筛选决定包含哪类记录；扩展名和大小只适用于普通文件
+	query.IncludeFiles = len(query.Types) == 0
+	query.IncludeUrls = len(query.Types) == 0
+	for _, fileType := range query.Types {
+		if fileType == models.SearchTypeUrl {
+			query.IncludeUrls = true
+		} else {
+			query.IncludeFiles = true
+		}
+	}
+	// URL文件没有元数据，按元数据筛选时同样不返回
+	if len(query.Extensions) > 0 || query.MinSize != nil || query.MaxSize != nil || len(query.Metadata) > 0 {
+		query.IncludeUrls = false
+	}
+
+	itemTypes := splitQueryList(c, "item_type")
+	wanted := map[string]bool{}
+	for _, itemType := range itemTypes {
+		switch itemType {
+		case models.SearchItemFile, models.SearchItemFolder, models.SearchItemUrlFile:
+			wanted[itemType] = true
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "item_type 只支持 file、folder、url_file"})
+			return nil, false
+		}
+	}
+	if len(itemTypes) > 0 {
+		query.IncludeFiles = query.IncludeFiles && wanted[models.SearchItemFile]
+		query.IncludeUrls = query.IncludeUrls && wanted[models.SearchItemUrlFile]
+	}
+	// 文件夹没有类型、扩展名和大小，使用这些筛选时不返回文件夹
+	query.IncludeFolders = (wanted[models.SearchItemFolder] || len(itemTypes) == 0 && defaultFolders) &&
+		len(query.Types) == 0 && len(query.Extensions) == 0 && query.MinSize == nil && query.MaxSize == nil
+
+	if !h.applySearchFolderScope(c, query, userID) {
+		return nil, false
+	}
+	return query, true
+}
+
+// maxFuzzyQueryLength 模糊搜索关键词的最大字符数
+const maxFuzzyQueryLength = 100
+
+// fuzzySearch 按名称、拼音全拼、拼音首字母模糊搜索文件、文件夹和URL文件，按编辑距离排序后按 offset 分页
+func (h *FileHandler) fuzzySearch(c *gin.Context, userID string) {
+	keyword := utils.NormalizeFuzzyText(c.Query("q"))
+	if keyword == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "模糊搜索需要提供关键词 q"})
+		return
+	}
+	if utf8.RuneCountInString(keyword) > maxFuzzyQueryLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "关键词过长"})
+		return
+	}
+
+	query, ok := h.parseSearchFilters(c, userID, true)
+	if !ok {
+		return
+	}
+
+	// 结果在内存中按匹配程度排序，没有稳定的游标键，只支持 offset 分页
+	if c.Query("cursor") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "模糊搜索不支持 cursor，请使用 offset 分页"})
+		return
+	}
+	limit, offset, ok := parseLimitOffset(c, defaultSearchLimit, maxSearchLimit)
+	if !ok {
+		return
+	}
+
+	candidates, err := h.nameRepo.GetFuzzyCandidates(query, h.searchCfg.MaxFuzzyCandidates)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索文件失败"})
+		return
+	}
+
+	type rankedCandidate struct {
+		candidate models.FuzzyCandidate
+		match     utils.FuzzyMatch
+		length    int
+	}
+	var ranked []rankedCandidate
+	for _, candidate := range candidates {
+		var fullPinyin, initials []string
+		if candidate.IndexedName != nil && *candidate.IndexedName == candidate.Name {
+			fullPinyin = strings.Fields(candidate.FullPinyin)
+			initials = strings.Fields(candidate.Initials)
+		} else {
+			// 新建或刚重命名的对象尚未建立索引，即时计算拼音
+			fullPinyin, initials = utils.NamePinyin(candidate.Name)
+		}
+		if match, matched := utils.FuzzyMatchName(keyword, candidate.Name, fullPinyin, initials); matched {
+			ranked = append(ranked, rankedCandidate{candidate, match, utf8.RuneCountInString(candidate.Name)})
+		}
+	}
+	// 匹配程度相同时名称越短越接近关键词
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.match.Better(b.match) || b.match.Better(a.match) {
+			return a.match.Better(b.match)
+		}
+		if a.length != b.length {
+			return a.length < b.length
+		}
+		return a.candidate.Name < b.candidate.Name
+	})
+
+	response := models.FuzzySearchResponse{
+		Success:   true,
+		Mode:      models.SearchModeFuzzy,
+		Query:     keyword,
+		Total:     len(ranked),
+		Items:     []models.FuzzySearchItem{},
+		Truncated: len(candidates) >= h.searchCfg.MaxFuzzyCandidates,
+	}
+	if offset >= len(ranked) {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	pageRanked := ranked[offset:min(offset+limit, len(ranked))]
+
+	pageCandidates := make([]models.FuzzyCandidate, 0, len(pageRanked))
+	matches := make(map[string]utils.FuzzyMatch, len(pageRanked))
+	for _, entry := range pageRanked {
+		pageCandidates = append(pageCandidates, entry.candidate)
+		matches[fuzzyItemKey(entry.candidate.ItemType, entry.candidate.ID)] = entry.match
+	}
+	items, err := h.nameRepo.LoadItems(pageCandidates)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索文件失败"})
+		return
+	}
+	for _, item := range items {
+		match := matches[fuzzyItemKey(item.ItemType, item.RecordID())]
+		response.Items = append(response.Items, models.FuzzySearchItem{
+			SearchItem:   item,
+			Distance:     match.Distance,
+			MatchedField: match.Field,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// fuzzyItemKey 模糊搜索结果的唯一键
+func fuzzyItemKey(itemType string, id uint) string {
+	return itemType + ":" + strconv.FormatUint(uint64(id), 10)
+}
+
+// applySearchTags 处理 tag（标签名称）、tag_id 和 tag_mode 参数，标签必须属于当前用户。
+// tag_mode 为 all（默认）时要求带有全部标签，any 时带有任一标签即可
+func (h *FileHandler) applySearchTags(c *gin.Context, query *models.FileSearchQuery, userID string) bool {
+	names := splitQueryList(c, "tag")
+	rawIDs := splitQueryList(c, "tag_id")
+	if len(names)+len(rawIDs) > models.MaxTagsPerRequest {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "标签数量过多"})
+		return false
+	}
+
+	switch c.DefaultQuery("tag_mode", "all") {
+	case "all":
+		query.TagMatchAll = true
+	case "any":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tag_mode 只支持 all 或 any"})
+		return false
+	}
+	if len(names) == 0 && len(rawIDs) == 0 {
+		return true
+	}
+
+	var ids []uint
+	for _, raw := range rawIDs {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil || id == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的标签ID: " + raw})
+			return false
+		}
+		ids = append(ids, uint(id))
+	}
+	byID, err := h.tagRepo.GetTagsByIDs(userID, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取标签失败"})
+		return false
+	}
+	if len(byID) != len(ids) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "标签不存在"})
+		return false
+	}
+	byName, err := h.tagRepo.GetTagsByNames(userID, names)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取标签失败"})
+		return false
+	}
+	if len(byName) != len(names) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "标签不存在"})
+		return false
+	}
+
+	for _, tag := range append(byID, byName...) {
+		if !containsUint(query.TagIDs, tag.ID) {
+			query.TagIDs = append(query.TagIDs, tag.ID)
+		}
+	}
+	return true
+}
+
+// containsUint 判断切片中是否包含指定值
+func containsUint(values []uint, target uint) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+// applySearchFolderScope 处理 folder_id 和 recursive 参数：
+// 不传表示全部文件，0 表示根目录，其他值需要该文件夹的查看权限（可以是共享给我的文件夹）
+func (h *FileHandler) applySearchFolderScope(c *gin.Context, query *models.FileSearchQuery, userID string) bool {
+	folderIDStr := c.Query("folder_id")
+	if folderIDStr == "" {
+		return true
+	}
+	recursive := c.DefaultQuery("recursive", "true") != "false"
+
+	folderIDInt, err := strconv.Atoi(folderIDStr)
+	if err != nil || folderIDInt < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件夹ID"})
+		return false
+	}
+	if folderIDInt == 0 {
+		query.RootOnly = !recursive
+		return true
+	}
+
+	folder, _, ok := resolveFolderAccess(c, h.grantRepo, uint(folderIDInt), userID, models.PermissionViewer)
+	if !ok || rejectVaultFolder(c, folder) {
+		return false
+	}
+	query.OwnerID = folder.UserID
+
+	if !recursive {
+		query.FolderIDs = []uint{folder.ID}
+		return true
+	}
+	folderIDs, err := h.folderRepo.GetDescendantFolderIDs(folder.ID, folder.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取子文件夹失败"})
+		return false
+	}
+	query.FolderIDs = folderIDs
+	return true
+}
+
+// splitQueryList 读取可重复且支持逗号分隔的参数，返回去重后的小写值
+func splitQueryList(c *gin.Context, key string) []string {
+	var values []string
+	seen := make(map[string]bool)
+	for _, raw := range c.QueryArray(key) {
+		for _, value := range strings.Split(raw, ",") {
+			value = strings.ToLower(strings.TrimSpace(value))
+			if value != "" && !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// parseOptionalSize 解析可选的字节数参数，失败时直接写入响应
+func parseOptionalSize(c *gin.Context, key string) (*int64, bool) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, true
+	}
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的" + key + "参数"})
+		return nil, false
+	}
+	return &size, true
+}
+
+// parseSearchTime 解析 RFC3339 时间或 YYYY-MM-DD 日期；作为结束时间的日期包含当天，失败时直接写入响应
+func parseSearchTime(c *gin.Context, key string, end bool) (*time.Time, bool) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, true
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return &parsed, true
+	}
+	parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的" + key + "参数，应为 YYYY-MM-DD 或 RFC3339 格式"})
+		return nil, false
+	}
+	if end {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+	return &parsed, true
+}
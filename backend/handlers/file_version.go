@@ -0,0 +1,505 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"backend/database"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FileVersionHandler 文件版本处理器
+type FileVersionHandler struct {
+	fileRepo    database.FileRepositoryInterface
+	userRepo    database.UserRepositoryInterface
+	versionRepo database.FileVersionRepositoryInterface
+	indexer     *services.ContentIndexService
+	scanner     *services.VirusScanService
+}
+
+// NewFileVersionHandler 创建文件版本处理器实例
+func NewFileVersionHandler(fileRepo database.FileRepositoryInterface, userRepo database.UserRepositoryInterface, versionRepo database.FileVersionRepositoryInterface, indexer *services.ContentIndexService, scanner *services.VirusScanService) *FileVersionHandler {
+	return &FileVersionHandler{
+		fileRepo:    fileRepo,
+		userRepo:    userRepo,
+		versionRepo: versionRepo,
+		indexer:     indexer,
+		scanner:     scanner,
+	}
+}
+
+// replacedContent 替换文件内容前对原内容的处理结果。文件记录更新成功后调用 Commit，
+// 之后任何一步失败都调用 Rollback 恢复原内容，避免文件记录指向不存在的路径或留下多余的版本记录
+type replacedContent struct {
+	versionRepo   database.FileVersionRepositoryInterface
+	sourcePath    string              // 原内容的存储位置
+	version       *models.FileVersion // 原内容归档成的历史版本，被隔离的内容为 nil
+	discardedPath string              // 被隔离的原内容，提交后删除
+}
+
+// archiveReplacedContent 替换文件内容前处理当前内容：被隔离的内容不保留为历史版本，
+// 在提交后删除；其余内容归档为历史版本
+func archiveReplacedContent(versionRepo database.FileVersionRepositoryInterface, file *models.File) (*replacedContent, error) {
+	replaced := &replacedContent{
+		versionRepo: versionRepo,
+		sourcePath:  utils.GetFileAbsolutePath(file.Path),
+	}
+	if file.IsInfected() {
+		replaced.discardedPath = utils.GetQuarantinePath(file.Path)
+		return replaced, nil
+	}
+
+	version, err := archiveFileVersion(versionRepo, file)
+	if err != nil {
+		return nil, err
+	}
+	replaced.version = version
+	return replaced, nil
+}
+
+// Commit 文件记录已指向新内容，删除被丢弃的隔离内容
+func (r *replacedContent) Commit() {
+	if r.discardedPath != "" {
+		os.Remove(r.discardedPath)
+	}
+}
+
+// Rollback 撤销归档：把版本文件移回原位置并删除版本记录
+func (r *replacedContent) Rollback() {
+	if r.version == nil {
+		return
+	}
+	if err := os.Rename(utils.GetFileAbsolutePath(r.version.Path), r.sourcePath); err != nil {
+		return
+	}
+	r.versionRepo.DeleteVersion(r.version.ID, r.version.UserID)
+}
+
+// archiveFileVersion 将文件当前内容归档为一个历史版本
+func archiveFileVersion(versionRepo database.FileVersionRepositoryInterface, file *models.File) (*models.FileVersion, error) {
+	versionNumber, err := versionRepo.GetNextVersionNumber(file.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	versionDir := utils.GetVersionUploadDir(file.UserID)
+	if err := os.MkdirAll(versionDir, 0755); err != nil {
+		return nil, err
+	}
+
+	sourcePath := utils.GetFileAbsolutePath(file.Path)
+	versionFileName := fmt.Sprintf("%d_v%d_%s", file.ID, versionNumber, file.Name)
+	targetPath := filepath.Join(versionDir, versionFileName)
+
+	// 旧记录可能没有校验和，归档前补齐
+	checksum := file.Checksum
+	if checksum == "" {
+		if checksum, err = utils.CalculateFileChecksum(sourcePath, file.EncryptionKeyID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.Rename(sourcePath, targetPath); err != nil {
+		return nil, err
+	}
+
+	uploadedBy := file.UploadedBy
+	if uploadedBy == "" {
+		uploadedBy = file.UserID
+	}
+
+	version := &models.FileVersion{
+		FileID:     file.ID,
+		UserID:     file.UserID,
+		Version:    versionNumber,
+		Name:       file.Name,
+		Size:       file.Size,
+		Checksum:   checksum,
+		Path:       utils.GetVersionPath(file.UserID, versionFileName),
+		UploadedBy: uploadedBy,
+		CreatedAt:  file.UpdatedAt,
+		// 版本文件由原内容移动而来，加密所用的数据密钥不变
+		EncryptionKeyID: file.EncryptionKeyID,
+	}
+
+	if err := versionRepo.CreateVersion(version); err != nil {
+		// 记录保存失败时把文件移回原位置
+		os.Rename(targetPath, sourcePath)
+		return nil, err
+	}
+
+	return version, nil
+}
+
+// pruneFileVersions 按用户设置的保留数量清理最旧的历史版本
+func pruneFileVersions(versionRepo database.FileVersionRepositoryInterface, userRepo database.UserRepositoryInterface, fileID uint, userID string) {
+	maxVersions := models.DefaultMaxFileVersions
+	if user, err := userRepo.GetUserByUUID(userID); err == nil && user != nil {
+		maxVersions = user.MaxFileVersions
+	}
+
+	versions, err := versionRepo.GetVersionsByFileID(fileID, userID)
+	if err != nil || len(versions) <= maxVersions {
+		return
+	}
+
+	// 版本按版本号倒序排列，保留最新的 maxVersions 个
+	for _, version := range versions[maxVersions:] {
+		removeFileVersion(versionRepo, &version)
+	}
+}
+
+// deleteAllFileVersions 删除文件的全部历史版本（文件被删除时调用）
+func deleteAllFileVersions(versionRepo database.FileVersionRepositoryInterface, fileID uint, userID string) {
+	versions, err := versionRepo.GetVersionsByFileID(fileID, userID)
+	if err != nil {
+		return
+	}
+
+	for _, version := range versions {
+		os.Remove(utils.GetFileAbsolutePath(version.Path))
+	}
+	versionRepo.DeleteVersionsByFileID(fileID, userID)
+}
+
+// removeFileVersion 删除单个历史版本的物理文件和记录
+func removeFileVersion(versionRepo database.FileVersionRepositoryInterface, version *models.FileVersion) error {
+	if err := os.Remove(utils.GetFileAbsolutePath(version.Path)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return versionRepo.DeleteVersion(version.ID, version.UserID)
+}
+
+// parseFileVersionParams 解析请求中的文件ID和版本ID
+func parseFileVersionParams(c *gin.Context) (uint, uint, bool) {
+	fileIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件ID"})
+		return 0, 0, false
+	}
+
+	versionIDInt, err := strconv.Atoi(c.Param("version_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的版本ID"})
+		return 0, 0, false
+	}
+
+	return uint(fileIDInt), uint(versionIDInt), true
+}
+
+// loadFileAndVersion 查询文件及其指定版本，失败时直接写入响应
+func (h *FileVersionHandler) loadFileAndVersion(c *gin.Context, userID string) (*models.File, *models.FileVersion, bool) {
+	fileID, versionID, ok := parseFileVersionParams(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	file, err := h.fileRepo.GetFileByID(fileID, userID)
+	if err != nil {
+		if isRecordNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件信息失败"})
+		}
+		return nil, nil, false
+	}
+
+	version, err := h.versionRepo.GetVersionByID(versionID, fileID, userID)
+	if err != nil {
+		if isRecordNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "版本不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取版本信息失败"})
+		}
+		return nil, nil, false
+	}
+
+	return file, version, true
+}
+
+// GetFileVersions 获取文件的历史版本列表
+func (h *FileVersionHandler) GetFileVersions(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	fileIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件ID"})
+		return
+	}
+	fileID := uint(fileIDInt)
+
+	file, err := h.fileRepo.GetFileByID(fileID, userID)
+	if err != nil {
+		if isRecordNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件信息失败"})
+		}
+		return
+	}
+
+	versions, err := h.versionRepo.GetVersionsByFileID(fileID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取版本列表失败"})
+		return
+	}
+
+	// 确保versions不为nil，如果为nil则初始化为空数组
+	if versions == nil {
+		versions = []models.FileVersion{}
+	}
+
+	response := models.FileVersionListResponse{
+		Success:  true,
+		File:     *file,
+		Versions: versions,
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DownloadFileVersion 下载指定的历史版本
+func (h *FileVersionHandler) DownloadFileVersion(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	_, version, ok := h.loadFileAndVersion(c, userID)
+	if !ok {
+		return
+	}
+
+	serveFileAttachment(c, utils.GetFileAbsolutePath(version.Path), version.EncryptionKeyID, version.Name)
+}
+
+// RestoreFileVersion 将历史版本恢复为当前版本（当前内容会被归档为新版本）
+func (h *FileVersionHandler) RestoreFileVersion(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	file, version, ok := h.loadFileAndVersion(c, userID)
+	if !ok {
+		return
+	}
+
+	versionPath := utils.GetFileAbsolutePath(version.Path)
+	if _, err := os.Stat(versionPath); os.IsNotExist(err) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "版本文件不存在"})
+		return
+	}
+
+	// 归档当前内容（被隔离的内容直接丢弃），之后任何一步失败都撤销归档
+	replaced, err := archiveReplacedContent(h.versionRepo, file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存当前版本失败"})
+		return
+	}
+
+	// 将版本文件移回普通上传目录
+	fileType := utils.GetFileType(version.Name)
+	uploadDir := utils.GetFileUploadDir(fileType)
+	if err := os.MkdirAll(uploadDir, 0755); err != nil {
+		replaced.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建上传目录失败"})
+		return
+	}
+
+	fileName, err := utils.GenerateUniqueFileName(uploadDir, version.Name)
+	if err != nil {
+		replaced.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法生成唯一文件名"})
+		return
+	}
+
+	restoredPath := filepath.Join(uploadDir, fileName)
+	if err := os.Rename(versionPath, restoredPath); err != nil {
+		replaced.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复版本文件失败"})
+		return
+	}
+
+	file.Name = version.Name
+	file.Size = version.Size
+	file.Type = fileType
+	file.Path = utils.GetUploadPath(fileName, fileType)
+	file.Checksum = version.Checksum
+	file.EncryptionKeyID = version.EncryptionKeyID
+	file.UploadedBy = version.UploadedBy
+	h.scanner.ResetScanState(file)
+
+	if err := h.fileRepo.UpdateFile(file); err != nil {
+		os.Rename(restoredPath, versionPath)
+		replaced.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新文件记录失败"})
+		return
+	}
+	replaced.Commit()
+
+	// 已恢复的版本不再作为历史版本保留
+	if err := h.versionRepo.DeleteVersion(version.ID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除已恢复的版本记录失败"})
+		return
+	}
+
+	pruneFileVersions(h.versionRepo, h.userRepo, file.ID, userID)
+	h.indexer.Notify()
+
+	// 历史版本的内容未经扫描，恢复后重新扫描
+	if errors.Is(h.scanner.ScanUpload(file), models.ErrFileInfected) {
+		respondInfectedUpload(c, file)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("已恢复到版本 %d", version.Version),
+		"file":    file,
+	})
+}
+
+// DeleteFileVersion 删除单个历史版本
+func (h *FileVersionHandler) DeleteFileVersion(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	_, version, ok := h.loadFileAndVersion(c, userID)
+	if !ok {
+		return
+	}
+
+	if err := removeFileVersion(h.versionRepo, version); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除版本失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "版本删除成功",
+	})
+}
+
+// DeleteOldFileVersions 删除旧版本，仅保留最新的 keep 个（默认全部删除）
+func (h *FileVersionHandler) DeleteOldFileVersions(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	fileIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件ID"})
+		return
+	}
+	fileID := uint(fileIDInt)
+
+	keep := 0
+	if keepStr := c.Query("keep"); keepStr != "" {
+		keep, err = strconv.Atoi(keepStr)
+		if err != nil || keep < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的保留数量"})
+			return
+		}
+	}
+
+	if _, err := h.fileRepo.GetFileByID(fileID, userID); err != nil {
+		if isRecordNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件信息失败"})
+		}
+		return
+	}
+
+	versions, err := h.versionRepo.GetVersionsByFileID(fileID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取版本列表失败"})
+		return
+	}
+
+	deleted := 0
+	if len(versions) > keep {
+		for _, version := range versions[keep:] {
+			if err := removeFileVersion(h.versionRepo, &version); err != nil {
+				continue
+			}
+			deleted++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"message":       fmt.Sprintf("已删除 %d 个历史版本", deleted),
+		"deleted_count": deleted,
+	})
+}
+
+// GetVersionSettings 获取用户的版本保留设置
+func (h *FileVersionHandler) GetVersionSettings(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userRepo.GetUserByUUID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+		return
+	}
+
+	versionStorage, err := h.versionRepo.GetUserVersionStorage(userID)
+	if err != nil {
+		versionStorage = 0
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":             true,
+		"max_versions":        user.MaxFileVersions,
+		"version_storage":     versionStorage,
+		"version_storage_str": utils.FormatStorageSize(versionStorage),
+	})
+}
+
+// UpdateVersionSettings 更新用户的版本保留数量
+func (h *FileVersionHandler) UpdateVersionSettings(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.UpdateVersionSettingsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	if request.MaxVersions < 0 || request.MaxVersions > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "版本保留数量必须在0到100之间"})
+		return
+	}
+
+	if err := h.userRepo.UpdateUserMaxFileVersions(userID, request.MaxVersions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新版本设置失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"message":      "版本设置更新成功",
+		"max_versions": request.MaxVersions,
+	})
+}
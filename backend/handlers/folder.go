@@ -1,27 +1,46 @@
 package handlers
 
 import (
-	"database/sql"
+	"errors"
 	"net/http"
+	"os"
 	"strconv"
 
 	"backend/database"
 	"backend/models"
+	"backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 // FolderHandler 文件夹处理器
 type FolderHandler struct {
-	folderRepo database.FolderRepositoryInterface
+	folderRepo  database.FolderRepositoryInterface
+	fileRepo    database.FileRepositoryInterface
+	urlFileRepo database.UrlFileRepositoryInterface
+	userRepo    database.UserRepositoryInterface
+	versionRepo database.FileVersionRepositoryInterface
+	grantRepo   database.GrantRepositoryInterface
+	tagRepo     database.TagRepositoryInterface
 }
 
 // NewFolderHandler 创建文件夹处理器实例
-func NewFolderHandler(folderRepo database.FolderRepositoryInterface) *FolderHandler {
-	return &FolderHandler{folderRepo: folderRepo}
+func NewFolderHandler(folderRepo database.FolderRepositoryInterface, fileRepo database.FileRepositoryInterface, urlFileRepo database.UrlFileRepositoryInterface, userRepo database.UserRepositoryInterface, versionRepo database.FileVersionRepositoryInterface, grantRepo database.GrantRepositoryInterface, tagRepo database.TagRepositoryInterface) *FolderHandler {
+	return &FolderHandler{
+		folderRepo:  folderRepo,
+		fileRepo:    fileRepo,
+		urlFileRepo: urlFileRepo,
+		userRepo:    userRepo,
+		versionRepo: versionRepo,
+		grantRepo:   grantRepo,
+		tagRepo:     tagRepo,
+	}
 }
 
-// GetFolders 获取用户文件夹列表
+// folderListSorts 文件夹列表支持的排序字段
+var folderListSorts = []string{models.SearchSortName, models.SearchSortCreatedAt, models.SearchSortUpdatedAt}
+
+// GetFolders 获取用户文件夹列表，支持游标分页和排序
 func (h *FolderHandler) GetFolders(c *gin.Context) {
 	userID := c.Query("user_id")
 
@@ -30,25 +49,35 @@ func (h *FolderHandler) GetFolders(c *gin.Context) {
 		return
 	}
 
-	folders, err := h.folderRepo.GetFoldersByUserID(userID)
+	query, ok := parseListQuery(c, models.SearchItemFolder, folderListSorts, models.SearchSortCreatedAt)
+	if !ok {
+		return
+	}
+	query.OwnerID = userID
+
+	folders, nextCursor, err := h.folderRepo.ListFolders(query)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件夹列表失败"})
 		return
 	}
 
 	response := models.FolderListResponse{
-		Success: true,
-		Folders: folders,
+		Success:    true,
+		Folders:    folders,
+		NextCursor: nextCursor,
+		HasMore:    nextCursor != "",
 	}
 	c.JSON(http.StatusOK, response)
 }
 
 // CreateFolder 创建文件夹
 func (h *FolderHandler) CreateFolder(c *gin.Context) {
-	userID := c.Query("user_id")
-
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少用户ID"})
+	userID, ok := sessionUserID(c)
+	if !ok {
 		return
 	}
 
@@ -63,8 +92,18 @@ func (h *FolderHandler) CreateFolder(c *gin.Context) {
 		createRequest.Category = "all"
 	}
 
+	// 在共享文件夹中创建子文件夹需要编辑者权限，新文件夹归属父文件夹所有者
+	ownerID := userID
+	if createRequest.ParentID != nil {
+		parent, _, ok := resolveFolderAccess(c, h.grantRepo, uint(*createRequest.ParentID), userID, models.PermissionEditor)
+		if !ok {
+			return
+		}
+		ownerID = parent.UserID
+	}
+
 	// 检查是否存在同名文件夹
-	exists, err := h.folderRepo.CheckFolderNameExists(userID, createRequest.Name, createRequest.Category, 0)
+	exists, err := h.folderRepo.CheckFolderNameExists(ownerID, createRequest.Name, createRequest.Category, 0)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查文件夹名称失败"})
 		return
@@ -84,7 +123,7 @@ func (h *FolderHandler) CreateFolder(c *gin.Context) {
 	// 创建文件夹记录
 	folder := &models.Folder{
 		Name:     createRequest.Name,
-		UserID:   userID,
+		UserID:   ownerID,
 		Category: createRequest.Category,
 		ParentID: parentID,
 	}
@@ -104,10 +143,8 @@ func (h *FolderHandler) CreateFolder(c *gin.Context) {
 // UpdateFolder 更新文件夹
 func (h *FolderHandler) UpdateFolder(c *gin.Context) {
 	folderIDStr := c.Param("id")
-	userID := c.Query("user_id")
-
-	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+	userID, ok := sessionUserID(c)
+	if !ok {
 		return
 	}
 
@@ -124,19 +161,15 @@ func (h *FolderHandler) UpdateFolder(c *gin.Context) {
 		return
 	}
 
-	// 检查文件夹是否存在
-	_, err = h.folderRepo.GetFolderByID(folderID, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "文件夹不存在"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件夹信息失败"})
-		}
+	// 检查文件夹是否存在（编辑者可以修改共享文件夹）
+	folder, _, ok := resolveFolderAccess(c, h.grantRepo, folderID, userID, models.PermissionEditor)
+	if !ok {
 		return
 	}
+	ownerID := folder.UserID
 
 	// 检查是否存在同名文件夹（排除当前文件夹）
-	exists, err := h.folderRepo.CheckFolderNameExists(userID, updateRequest.Name, updateRequest.Category, folderID)
+	exists, err := h.folderRepo.CheckFolderNameExists(ownerID, updateRequest.Name, updateRequest.Category, folderID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查文件夹名称失败"})
 		return
@@ -147,7 +180,7 @@ func (h *FolderHandler) UpdateFolder(c *gin.Context) {
 	}
 
 	// 更新文件夹
-	if err := h.folderRepo.UpdateFolder(folderID, userID, updateRequest.Name, updateRequest.Category); err != nil {
+	if err := h.folderRepo.UpdateFolder(folderID, ownerID, updateRequest.Name, updateRequest.Category); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新文件夹失败"})
 		return
 	}
@@ -161,10 +194,8 @@ func (h *FolderHandler) UpdateFolder(c *gin.Context) {
 // DeleteFolder 删除文件夹
 func (h *FolderHandler) DeleteFolder(c *gin.Context) {
 	folderIDStr := c.Param("id")
-	userID := c.Query("user_id")
-
-	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+	userID, ok := sessionUserID(c)
+	if !ok {
 		return
 	}
 
@@ -175,36 +206,143 @@ func (h *FolderHandler) DeleteFolder(c *gin.Context) {
 	}
 	folderID := uint(folderIDInt)
 
-	// 检查文件夹是否存在
-	_, err = h.folderRepo.GetFolderByID(folderID, userID)
+	// 检查文件夹是否存在（删除共享文件夹需要共同所有者权限）
+	folder, _, ok := resolveFolderAccess(c, h.grantRepo, folderID, userID, models.PermissionCoOwner)
+	if !ok {
+		return
+	}
+
+	// 在事务中递归删除文件夹、子文件夹、文件、历史版本、URL文件和共享授权
+	result, err := h.folderRepo.DeleteFolderRecursive(folderID, folder.UserID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "文件夹不存在"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件夹信息失败"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除文件夹失败"})
+		return
+	}
+
+	// 事务提交后再删除物理文件
+	for _, storedPath := range result.StoredPaths {
+		os.Remove(utils.GetFileAbsolutePath(storedPath))
+		// 被隔离的文件内容位于隔离目录
+		os.Remove(utils.GetQuarantinePath(storedPath))
+	}
+
+	response := gin.H{
+		"success": true,
+		"message": "文件夹删除成功",
+		"result":  result,
+	}
+
+	// 返回重新计算后的存储占用
+	if usedSpace, storageLimit, err := h.userRepo.GetUserStorageInfo(folder.UserID); err == nil {
+		response["storage"] = models.StorageInfo{
+			UsedSpace:     usedSpace,
+			TotalSpace:    storageLimit,
+			UsedSpaceStr:  utils.FormatStorageSize(usedSpace),
+			TotalSpaceStr: utils.FormatStorageSize(storageLimit),
 		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// MoveFolder 移动文件夹到新的父文件夹，拒绝移动到自身或其子孙文件夹
+func (h *FolderHandler) MoveFolder(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
 		return
 	}
 
-	// 删除文件夹（包括其中的文件）
-	if err := h.folderRepo.DeleteFolder(folderID, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除文件夹失败"})
+	folderIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件夹ID"})
+		return
+	}
+	folderID := uint(folderIDInt)
+
+	var moveRequest models.MoveFolderRequest
+	if err := c.ShouldBindJSON(&moveRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	strategy, ok := parseConflictStrategy(c, moveRequest.OnConflict)
+	if !ok {
+		return
+	}
+	if strategy == models.ConflictOverwrite {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "移动文件夹不支持覆盖策略"})
+		return
+	}
+
+	// 编辑者可以移动共享文件夹中的子文件夹
+	folder, _, ok := resolveFolderAccess(c, h.grantRepo, folderID, userID, models.PermissionEditor)
+	if !ok {
+		return
+	}
+
+	// 检查目标父文件夹，且与文件夹属于同一所有者
+	var parentID *uint
+	if moveRequest.ParentID != nil && *moveRequest.ParentID > 0 {
+		parent, _, ok := resolveFolderAccess(c, h.grantRepo, *moveRequest.ParentID, userID, models.PermissionEditor)
+		if !ok {
+			return
+		}
+		if parent.UserID != folder.UserID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不能将文件夹移动到其他用户的文件夹"})
+			return
+		}
+		parentID = &parent.ID
+	} else if folder.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有所有者可以将文件夹移动到根目录"})
+		return
+	}
+
+	// 父文件夹未变化时无需移动
+	if sameFolderID(folder.ParentID, parentID) {
+		c.JSON(http.StatusOK, gin.H{"success": true, "message": "文件夹位置未变化", "folder": folder})
 		return
 	}
 
+	finalName, _, conflict, err := resolveFolderNameConflict(h.folderRepo, folder.UserID, folder.Name, parentID, strategy, map[uint]bool{folder.ID: true})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查文件夹名称失败"})
+		return
+	}
+	if conflict != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":           "目标位置已存在同名文件夹",
+			"conflict_type":   "name_exists",
+			"existing_folder": conflict,
+		})
+		return
+	}
+
+	newName := ""
+	if finalName != folder.Name {
+		newName = finalName
+	}
+	if err := h.folderRepo.MoveFolder(folderID, folder.UserID, parentID, newName); err != nil {
+		if errors.Is(err, database.ErrFolderCycle) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移动文件夹失败"})
+		return
+	}
+
+	folder.ParentID = parentID
+	folder.Name = finalName
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "文件夹删除成功",
+		"message": "文件夹移动成功",
+		"folder":  folder,
 	})
 }
 
 // GetFolderFileCount 获取文件夹中的文件数量
 func (h *FolderHandler) GetFolderFileCount(c *gin.Context) {
 	folderIDStr := c.Param("id")
-	userID := c.Query("user_id")
-
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少用户ID"})
+	userID, ok := sessionUserID(c)
+	if !ok {
 		return
 	}
 
@@ -215,7 +353,12 @@ func (h *FolderHandler) GetFolderFileCount(c *gin.Context) {
 	}
 	folderID := uint(folderIDInt)
 
-	count, err := h.folderRepo.GetFolderFileCount(folderID, userID)
+	folder, _, ok := resolveFolderAccess(c, h.grantRepo, folderID, userID, models.PermissionViewer)
+	if !ok {
+		return
+	}
+
+	count, err := h.folderRepo.GetFolderFileCount(folderID, folder.UserID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件夹文件数量失败"})
 		return
@@ -226,3 +369,153 @@ func (h *FolderHandler) GetFolderFileCount(c *gin.Context) {
 		"count":   count,
 	})
 }
+
+// GetFolderTree 获取文件夹树，包含每个文件夹的文件数量和字节统计；指定 root_id 时只返回该文件夹的子树
+func (h *FolderHandler) GetFolderTree(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	ownerID := userID
+	var rootID *uint
+	if rootIDStr := c.Query("root_id"); rootIDStr != "" {
+		rootIDInt, err := strconv.Atoi(rootIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件夹ID"})
+			return
+		}
+		// 共享给我的文件夹可以查看其子树
+		folder, _, ok := resolveFolderAccess(c, h.grantRepo, uint(rootIDInt), userID, models.PermissionViewer)
+		if !ok {
+			return
+		}
+		ownerID = folder.UserID
+		rootID = &folder.ID
+	}
+
+	nodes, err := h.folderRepo.GetFolderTree(ownerID, rootID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件夹树失败"})
+		return
+	}
+
+	// 标签属于各自的用户，只有浏览自己的文件夹时才统计标签
+	tags := []models.Tag{}
+	if ownerID == userID && len(nodes) > 0 {
+		if tags, err = h.applyFolderTreeTags(nodes, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取标签统计失败"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.FolderTreeResponse{
+		Success:     true,
+		FolderCount: len(nodes),
+		Tree:        models.BuildFolderTree(nodes),
+		Tags:        tags,
+	})
+}
+
+// applyFolderTreeTags 填充文件夹树节点的标签和各标签数量，返回树中出现过的标签
+func (h *FolderHandler) applyFolderTreeTags(nodes []*models.FolderTreeNode, userID string) ([]models.Tag, error) {
+	byID := make(map[uint]*models.FolderTreeNode, len(nodes))
+	folderIDs := make([]uint, 0, len(nodes))
+	for _, node := range nodes {
+		byID[node.ID] = node
+		folderIDs = append(folderIDs, node.ID)
+	}
+
+	used := make(map[uint]bool)
+	links, err := h.tagRepo.GetItemTags(userID, models.SearchItemFolder, folderIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		node := byID[link.ItemID]
+		node.TagIDs = append(node.TagIDs, link.TagID)
+		used[link.TagID] = true
+	}
+
+	counts, err := h.tagRepo.GetFolderTagCounts(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, count := range counts {
+		node, exists := byID[count.FolderID]
+		if !exists {
+			continue
+		}
+		if node.TagCounts == nil {
+			node.TagCounts = make(map[uint]int)
+		}
+		node.TagCounts[count.TagID] += count.Count
+		used[count.TagID] = true
+	}
+
+	tags := []models.Tag{}
+	if len(used) == 0 {
+		return tags, nil
+	}
+	all, err := h.tagRepo.GetTagsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range all {
+		if used[tag.ID] {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// GetFolderPath 获取文件夹的祖先链，用于面包屑导航
+func (h *FolderHandler) GetFolderPath(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	folderIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件夹ID"})
+		return
+	}
+
+	folder, permission, ok := resolveFolderAccess(c, h.grantRepo, uint(folderIDInt), userID, models.PermissionViewer)
+	if !ok {
+		return
+	}
+
+	path, err := h.folderRepo.GetFolderPath(folder.ID, folder.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件夹路径失败"})
+		return
+	}
+
+	// 共享给我的文件夹只返回从被共享的文件夹开始的路径，不暴露所有者的上层目录
+	if permission != models.PermissionOwner {
+		grants, err := h.grantRepo.GetGrantsForUser(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取共享授权失败"})
+			return
+		}
+		granted := make(map[uint]bool)
+		for _, grant := range grants {
+			if grant.ResourceType == models.ShareResourceFolder && grant.OwnerID == folder.UserID {
+				granted[grant.ResourceID] = true
+			}
+		}
+		for i, ancestor := range path {
+			if granted[ancestor.ID] {
+				path = path[i:]
+				break
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, models.FolderPathResponse{
+		Success: true,
+		Path:    path,
+	})
+}
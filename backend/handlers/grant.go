@@ -0,0 +1,747 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionUserID 返回登录用户的ID。共享权限按登录身份判断，不使用 user_id 参数，
+// 否则任何登录用户都能冒用他人的 user_id 获得其被授予的权限
+func sessionUserID(c *gin.Context) (string, bool) {
+	user, ok := credentialUser(c)
+	if !ok {
+		return "", false
+	}
+	return user.UUID, true
+}
+
+// requireSessionUser 检查请求中的 user_id 是否为登录用户本人，失败时直接写入响应
+func requireSessionUser(c *gin.Context, userID string) bool {
+	sessionID, ok := sessionUserID(c)
+	if !ok {
+		return false
+	}
+	if userID != sessionID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "用户身份与登录状态不一致"})
+		return false
+	}
+	return true
+}
+
+// resolveFileAccess 检查登录用户对文件是否具有所需权限（所有者拥有全部权限），失败时直接写入响应。
+// 保险库文件只能通过保险库接口修改，这里只允许查看
+func resolveFileAccess(c *gin.Context, grantRepo database.GrantRepositoryInterface, fileID uint, userID, required string) (*models.File, bool) {
+	if !requireSessionUser(c, userID) {
+		return nil, false
+	}
+
+	file, permission, err := grantRepo.GetFilePermission(fileID, userID)
+	if err != nil {
+		if isRecordNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件信息失败"})
+		}
+		return nil, false
+	}
+
+	// 无任何权限时与文件不存在返回相同结果，避免泄露他人文件
+	if permission == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
+		return nil, false
+	}
+
+	if !models.HasPermission(permission, required) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有操作该文件的权限"})
+		return nil, false
+	}
+
+	if required != models.PermissionViewer && rejectVaultFile(c, file) {
+		return nil, false
+	}
+
+	return file, true
+}
+
+// resolveFolderAccess 检查登录用户对文件夹是否具有所需权限（所有者拥有全部权限），失败时直接写入响应。
+// 保险库文件夹只能通过保险库接口修改，也不能作为上传、移动、复制的目标，这里只允许查看
+func resolveFolderAccess(c *gin.Context, grantRepo database.GrantRepositoryInterface, folderID uint, userID, required string) (*models.Folder, string, bool) {
+	if !requireSessionUser(c, userID) {
+		return nil, "", false
+	}
+
+	folder, permission, err := grantRepo.GetFolderPermission(folderID, userID)
+	if err != nil {
+		if isRecordNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "文件夹不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件夹信息失败"})
+		}
+		return nil, "", false
+	}
+
+	if permission == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文件夹不存在"})
+		return nil, "", false
+	}
+
+	if !models.HasPermission(permission, required) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有操作该文件夹的权限"})
+		return nil, "", false
+	}
+
+	if required != models.PermissionViewer && rejectVaultFolder(c, folder) {
+		return nil, "", false
+	}
+
+	return folder, permission, true
+}
+
+// GrantHandler 用户间共享处理器
+type GrantHandler struct {
+	grantRepo   database.GrantRepositoryInterface
+	groupRepo   database.UserGroupRepositoryInterface
+	userRepo    database.UserRepositoryInterface
+	fileRepo    database.FileRepositoryInterface
+	folderRepo  database.FolderRepositoryInterface
+	urlFileRepo database.UrlFileRepositoryInterface
+}
+
+// NewGrantHandler 创建用户间共享处理器实例
+func NewGrantHandler(grantRepo database.GrantRepositoryInterface, groupRepo database.UserGroupRepositoryInterface, userRepo database.UserRepositoryInterface, fileRepo database.FileRepositoryInterface, folderRepo database.FolderRepositoryInterface, urlFileRepo database.UrlFileRepositoryInterface) *GrantHandler {
+	return &GrantHandler{
+		grantRepo:   grantRepo,
+		groupRepo:   groupRepo,
+		userRepo:    userRepo,
+		fileRepo:    fileRepo,
+		folderRepo:  folderRepo,
+		urlFileRepo: urlFileRepo,
+	}
+}
+
+// resourceManagePermission 检查用户能否管理资源的共享（需所有者或共同所有者），返回资源所有者ID
+func (h *GrantHandler) resourceManagePermission(c *gin.Context, resourceType string, resourceID uint, userID string) (string, bool) {
+	switch resourceType {
+	case models.ShareResourceFile:
+		file, ok := resolveFileAccess(c, h.grantRepo, resourceID, userID, models.PermissionCoOwner)
+		if !ok {
+			return "", false
+		}
+		return file.UserID, true
+	case models.ShareResourceFolder:
+		folder, _, ok := resolveFolderAccess(c, h.grantRepo, resourceID, userID, models.PermissionCoOwner)
+		if !ok {
+			return "", false
+		}
+		return folder.UserID, true
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{"error": "无效的资源类型"})
+	return "", false
+}
+
+// CreateGrant 将文件或文件夹共享给其他用户或用户组，已存在授权时更新权限
+func (h *GrantHandler) CreateGrant(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.CreateGrantRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	if !models.IsGrantablePermission(request.Permission) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的权限级别"})
+		return
+	}
+
+	if (request.Username == "") == (request.GroupID == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定共享的用户名或用户组（二选一）"})
+		return
+	}
+
+	ownerID, ok := h.resourceManagePermission(c, request.ResourceType, request.ResourceID, userID)
+	if !ok {
+		return
+	}
+
+	grant := &models.ShareGrant{
+		OwnerID:      ownerID,
+		ResourceType: request.ResourceType,
+		ResourceID:   request.ResourceID,
+		Permission:   request.Permission,
+		CreatedBy:    userID,
+	}
+
+	if request.Username != "" {
+		grantee, err := h.userRepo.GetUserByUsername(request.Username)
+		if err != nil || grantee == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+			return
+		}
+		if grantee.UUID == ownerID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不能共享给资源所有者"})
+			return
+		}
+		grant.GranteeType = models.GranteeTypeUser
+		grant.GranteeUserID = grantee.UUID
+	} else {
+		group, err := h.groupRepo.GetGroupByID(request.GroupID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户组不存在"})
+			return
+		}
+
+		// 只能共享给自己创建或所属的用户组
+		if group.OwnerID != userID {
+			isMember, err := h.groupRepo.IsMember(group.ID, userID)
+			if err != nil || !isMember {
+				c.JSON(http.StatusForbidden, gin.H{"error": "无权使用该用户组"})
+				return
+			}
+		}
+		grant.GranteeType = models.GranteeTypeGroup
+		grant.GranteeGroupID = &group.ID
+	}
+
+	// 同一资源对同一对象只保留一条授权
+	existing, err := h.grantRepo.GetExistingGrant(grant.ResourceType, grant.ResourceID, grant.GranteeUserID, grant.GranteeGroupID)
+	if err != nil && !isRecordNotFound(err) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查已有共享失败"})
+		return
+	}
+
+	if existing != nil {
+		if err := h.grantRepo.UpdateGrantPermission(existing.ID, request.Permission); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新共享权限失败"})
+			return
+		}
+		existing.Permission = request.Permission
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "共享权限已更新",
+			"grant":   existing,
+		})
+		return
+	}
+
+	if err := h.grantRepo.CreateGrant(grant); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建共享失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "共享成功",
+		"grant":   grant,
+	})
+}
+
+// GetGrants 获取资源的共享列表；不指定资源时返回当前用户作为所有者的全部共享
+func (h *GrantHandler) GetGrants(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var grants []models.ShareGrant
+	var err error
+
+	resourceType := c.Query("resource_type")
+	if resourceType != "" {
+		resourceIDInt, convErr := strconv.Atoi(c.Query("resource_id"))
+		if convErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的资源ID"})
+			return
+		}
+		resourceID := uint(resourceIDInt)
+
+		if _, ok := h.resourceManagePermission(c, resourceType, resourceID, userID); !ok {
+			return
+		}
+		grants, err = h.grantRepo.GetGrantsByResource(resourceType, resourceID)
+	} else {
+		grants, err = h.grantRepo.GetGrantsByOwner(userID)
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取共享列表失败"})
+		return
+	}
+
+	if grants == nil {
+		grants = []models.ShareGrant{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"grants":  grants,
+	})
+}
+
+// loadManagedGrant 加载授权并检查当前用户能否管理该授权
+func (h *GrantHandler) loadManagedGrant(c *gin.Context, userID string) (*models.ShareGrant, bool) {
+	grantIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的共享ID"})
+		return nil, false
+	}
+
+	grant, err := h.grantRepo.GetGrantByID(uint(grantIDInt))
+	if err != nil {
+		if isRecordNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "共享不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取共享信息失败"})
+		}
+		return nil, false
+	}
+
+	if _, ok := h.resourceManagePermission(c, grant.ResourceType, grant.ResourceID, userID); !ok {
+		return nil, false
+	}
+
+	return grant, true
+}
+
+// UpdateGrant 修改共享权限级别
+func (h *GrantHandler) UpdateGrant(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.UpdateGrantRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	if !models.IsGrantablePermission(request.Permission) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的权限级别"})
+		return
+	}
+
+	grant, ok := h.loadManagedGrant(c, userID)
+	if !ok {
+		return
+	}
+
+	if err := h.grantRepo.UpdateGrantPermission(grant.ID, request.Permission); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新共享权限失败"})
+		return
+	}
+
+	grant.Permission = request.Permission
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "共享权限已更新",
+		"grant":   grant,
+	})
+}
+
+// DeleteGrant 取消共享；被共享的用户也可以主动退出直接授予自己的共享
+func (h *GrantHandler) DeleteGrant(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	grantIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的共享ID"})
+		return
+	}
+
+	grant, err := h.grantRepo.GetGrantByID(uint(grantIDInt))
+	if err != nil {
+		if isRecordNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "共享不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取共享信息失败"})
+		}
+		return
+	}
+
+	isGrantee := grant.GranteeType == models.GranteeTypeUser && grant.GranteeUserID == userID
+	if !isGrantee {
+		if _, ok := h.resourceManagePermission(c, grant.ResourceType, grant.ResourceID, userID); !ok {
+			return
+		}
+	}
+
+	if err := h.grantRepo.DeleteGrant(grant.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "取消共享失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已取消共享",
+	})
+}
+
+// GetSharedWithMe 获取其他用户共享给我的文件和文件夹
+func (h *GrantHandler) GetSharedWithMe(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	grants, err := h.grantRepo.GetGrantsForUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取共享列表失败"})
+		return
+	}
+
+	// 同一资源可能通过多个授权共享给我，只保留权限最高的一条
+	itemIndex := make(map[string]int)
+	items := []models.SharedWithMeItem{}
+	ownerNames := make(map[string]string)
+
+	for _, grant := range grants {
+		key := grant.ResourceType + ":" + strconv.FormatUint(uint64(grant.ResourceID), 10)
+		if index, exists := itemIndex[key]; exists {
+			if models.PermissionLevel(grant.Permission) > models.PermissionLevel(items[index].Permission) {
+				items[index].Permission = grant.Permission
+				items[index].GrantID = grant.ID
+				items[index].ViaGroupID = grant.GranteeGroupID
+			}
+			continue
+		}
+
+		item := models.SharedWithMeItem{
+			GrantID:      grant.ID,
+			ResourceType: grant.ResourceType,
+			ResourceID:   grant.ResourceID,
+			Permission:   grant.Permission,
+			OwnerID:      grant.OwnerID,
+			ViaGroupID:   grant.GranteeGroupID,
+			SharedAt:     grant.CreatedAt,
+		}
+
+		// 加载资源信息，资源已被删除的授权直接跳过
+		if grant.ResourceType == models.ShareResourceFile {
+			file, _, err := h.grantRepo.GetFilePermission(grant.ResourceID, userID)
+			if err != nil {
+				continue
+			}
+			item.Name = file.Name
+			item.Size = file.Size
+			item.Type = file.Type
+		} else {
+			folder, _, err := h.grantRepo.GetFolderPermission(grant.ResourceID, userID)
+			if err != nil {
+				continue
+			}
+			item.Name = folder.Name
+		}
+
+		if _, exists := ownerNames[grant.OwnerID]; !exists {
+			if owner, err := h.userRepo.GetUserByUUID(grant.OwnerID); err == nil && owner != nil {
+				ownerNames[grant.OwnerID] = owner.Username
+			} else {
+				ownerNames[grant.OwnerID] = ""
+			}
+		}
+		item.OwnerUsername = ownerNames[grant.OwnerID]
+
+		itemIndex[key] = len(items)
+		items = append(items, item)
+	}
+
+	response := models.SharedWithMeResponse{
+		Success: true,
+		Items:   items,
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetSharedFolderContents 浏览共享给我的文件夹内容（子文件夹、文件和URL文件）
+func (h *GrantHandler) GetSharedFolderContents(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	folderIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件夹ID"})
+		return
+	}
+	folderID := uint(folderIDInt)
+
+	folder, permission, ok := resolveFolderAccess(c, h.grantRepo, folderID, userID, models.PermissionViewer)
+	if !ok {
+		return
+	}
+
+	subFolders, err := h.folderRepo.GetSubFolders(folder.ID, folder.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件夹内容失败"})
+		return
+	}
+
+	files, err := h.fileRepo.GetFilesByUserID(folder.UserID, &folder.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件夹内容失败"})
+		return
+	}
+
+	urlFiles, err := h.urlFileRepo.GetUrlFilesByUserID(folder.UserID, &folder.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件夹内容失败"})
+		return
+	}
+
+	if subFolders == nil {
+		subFolders = []models.Folder{}
+	}
+	if files == nil {
+		files = []models.File{}
+	}
+	if urlFiles == nil {
+		urlFiles = []models.UrlFile{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"folder":     folder,
+		"permission": permission,
+		"folders":    subFolders,
+		"files":      files,
+		"url_files":  urlFiles,
+	})
+}
+
+// ===== 用户组 =====
+
+// CreateGroup 创建用户组
+func (h *GrantHandler) CreateGroup(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.CreateUserGroupRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	group := &models.UserGroup{
+		Name:    request.Name,
+		OwnerID: userID,
+	}
+
+	if err := h.groupRepo.CreateGroup(group); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建用户组失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"group":   group,
+	})
+}
+
+// GetGroups 获取我创建的和我所属的用户组
+func (h *GrantHandler) GetGroups(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	groups, err := h.groupRepo.GetGroupsForUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户组失败"})
+		return
+	}
+
+	if groups == nil {
+		groups = []models.UserGroup{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"groups":  groups,
+	})
+}
+
+// loadOwnedGroup 加载当前用户创建的用户组
+func (h *GrantHandler) loadOwnedGroup(c *gin.Context, userID string) (*models.UserGroup, bool) {
+	groupIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户组ID"})
+		return nil, false
+	}
+
+	group, err := h.groupRepo.GetGroupByID(uint(groupIDInt))
+	if err != nil || group.OwnerID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户组不存在"})
+		return nil, false
+	}
+
+	return group, true
+}
+
+// DeleteGroup 删除用户组，通过该组的共享一并失效
+func (h *GrantHandler) DeleteGroup(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	group, ok := h.loadOwnedGroup(c, userID)
+	if !ok {
+		return
+	}
+
+	if err := h.groupRepo.DeleteGroup(group.ID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除用户组失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "用户组已删除",
+	})
+}
+
+// GetGroupMembers 获取用户组成员
+func (h *GrantHandler) GetGroupMembers(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	groupIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户组ID"})
+		return
+	}
+
+	group, err := h.groupRepo.GetGroupByID(uint(groupIDInt))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户组不存在"})
+		return
+	}
+
+	if group.OwnerID != userID {
+		isMember, err := h.groupRepo.IsMember(group.ID, userID)
+		if err != nil || !isMember {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户组不存在"})
+			return
+		}
+	}
+
+	members, err := h.groupRepo.GetMembers(group.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户组成员失败"})
+		return
+	}
+
+	memberList := make([]gin.H, 0, len(members))
+	for _, member := range members {
+		username := ""
+		if user, err := h.userRepo.GetUserByUUID(member.UserID); err == nil && user != nil {
+			username = user.Username
+		}
+		memberList = append(memberList, gin.H{
+			"user_id":  member.UserID,
+			"username": username,
+			"joined":   member.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"group":   group,
+		"members": memberList,
+	})
+}
+
+// AddGroupMember 添加用户组成员
+func (h *GrantHandler) AddGroupMember(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.AddGroupMemberRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	group, ok := h.loadOwnedGroup(c, userID)
+	if !ok {
+		return
+	}
+
+	member, err := h.userRepo.GetUserByUsername(request.Username)
+	if err != nil || member == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	isMember, err := h.groupRepo.IsMember(group.ID, member.UUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查用户组成员失败"})
+		return
+	}
+	if isMember {
+		c.JSON(http.StatusConflict, gin.H{"error": "该用户已在用户组中"})
+		return
+	}
+
+	if err := h.groupRepo.AddMember(group.ID, member.UUID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加用户组成员失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "成员添加成功",
+	})
+}
+
+// RemoveGroupMember 移除用户组成员；成员也可以主动退出用户组
+func (h *GrantHandler) RemoveGroupMember(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	memberID := c.Param("member_id")
+
+	if memberID != userID {
+		if _, ok := h.loadOwnedGroup(c, userID); !ok {
+			return
+		}
+	}
+
+	groupIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户组ID"})
+		return
+	}
+
+	if err := h.groupRepo.RemoveMember(uint(groupIDInt), memberID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除用户组成员失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "成员已移除",
+	})
+}
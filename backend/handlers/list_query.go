@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 列表接口分页参数
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// parseListQuery 解析列表接口通用的 sort、order、limit、cursor 参数，失败时直接写入响应。
+// sorts 为该列表支持的排序字段；未传 limit 和 cursor 时不分页，返回全部记录
+func parseListQuery(c *gin.Context, itemType string, sorts []string, defaultSort string) (*models.ListQuery, bool) {
+	query := &models.ListQuery{Sort: c.DefaultQuery("sort", defaultSort)}
+	if query.Sort == "date" {
+		query.Sort = models.SearchSortUpdatedAt
+	}
+	supported := false
+	for _, sort := range sorts {
+		if sort == query.Sort {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort 只支持 " + strings.Join(sorts, "、")})
+		return nil, false
+	}
+
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order 只支持 asc 或 desc"})
+		return nil, false
+	}
+
+	encoded := c.Query("cursor")
+	if c.Query("limit") == "" && encoded == "" {
+		return query, true
+	}
+	limit, _, ok := parseLimitOffset(c, defaultListLimit, maxListLimit)
+	if !ok {
+		return nil, false
+	}
+	query.Limit = limit
+
+	if encoded != "" {
+		cursor, err := models.DecodeListCursor(encoded, query.Sort, query.Desc, itemType)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		query.Cursor = cursor
+	}
+	return query, true
+}
+
+// parseListFields 解析 fields 参数中需要额外返回的重字段，失败时直接写入响应
+func parseListFields(c *gin.Context, allowed ...string) (map[string]bool, bool) {
+	fields := make(map[string]bool)
+	for _, field := range splitQueryList(c, "fields") {
+		known := false
+		for _, name := range allowed {
+			if field == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的 fields 参数: " + field})
+			return nil, false
+		}
+		fields[field] = true
+	}
+	return fields, true
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateFileMetadata 修改文件的描述和自定义元数据（PATCH），编辑者可以修改共享文件夹中的文件
+func (h *FileHandler) UpdateFileMetadata(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	fileIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件ID"})
+		return
+	}
+
+	request, ok := bindMetadataRequest(c)
+	if !ok {
+		return
+	}
+
+	file, ok := resolveFileAccess(c, h.grantRepo, uint(fileIDInt), userID, models.PermissionEditor)
+	if !ok {
+		return
+	}
+
+	if err := h.fileRepo.UpdateFileMetadata(file.ID, file.UserID, request.Description, request.Metadata); err != nil {
+		writeMetadataError(c, err, "文件不存在")
+		return
+	}
+	updated, err := h.fileRepo.GetFileByID(file.ID, file.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件信息失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "文件信息更新成功",
+		"file":    updated,
+	})
+}
+
+// UpdateFolderMetadata 修改文件夹的描述和自定义元数据（PATCH），编辑者可以修改共享文件夹
+func (h *FolderHandler) UpdateFolderMetadata(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	folderIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件夹ID"})
+		return
+	}
+
+	request, ok := bindMetadataRequest(c)
+	if !ok {
+		return
+	}
+
+	folder, _, ok := resolveFolderAccess(c, h.grantRepo, uint(folderIDInt), userID, models.PermissionEditor)
+	if !ok {
+		return
+	}
+
+	if err := h.folderRepo.UpdateFolderMetadata(folder.ID, folder.UserID, request.Description, request.Metadata); err != nil {
+		writeMetadataError(c, err, "文件夹不存在")
+		return
+	}
+	updated, err := h.folderRepo.GetFolderByID(folder.ID, folder.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件夹信息失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "文件夹信息更新成功",
+		"folder":  updated,
+	})
+}
+
+// bindMetadataRequest 解析并校验描述和元数据修改请求，失败时直接写入响应
+func bindMetadataRequest(c *gin.Context) (*models.UpdateItemMetadataRequest, bool) {
+	var request models.UpdateItemMetadataRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return nil, false
+	}
+	if request.Description == nil && len(request.Metadata) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "description 和 metadata 不能同时为空"})
+		return nil, false
+	}
+	if request.Description != nil {
+		description := strings.TrimSpace(*request.Description)
+		if err := models.ValidateDescription(description); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		request.Description = &description
+	}
+	if err := models.ValidateMetadataPatch(request.Metadata); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return &request, true
+}
+
+// writeMetadataError 将修改元数据时的错误写入响应
+func writeMetadataError(c *gin.Context, err error, notFoundMessage string) {
+	switch {
+	case errors.Is(err, models.ErrTooManyMetadataKeys):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case isRecordNotFound(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": notFoundMessage})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新描述和元数据失败"})
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler 站内通知处理器
+type NotificationHandler struct {
+	notificationRepo database.NotificationRepositoryInterface
+}
+
+// NewNotificationHandler 创建站内通知处理器实例
+func NewNotificationHandler(notificationRepo database.NotificationRepositoryInterface) *NotificationHandler {
+	return &NotificationHandler{notificationRepo: notificationRepo}
+}
+
+// GetNotifications 按时间倒序分页获取通知，unread=true 时只返回未读通知
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := parseLimitOffset(c, models.DefaultNotificationLimit, models.MaxNotificationLimit)
+	if !ok {
+		return
+	}
+	unreadOnly := c.Query("unread") == "true"
+
+	notifications, total, err := h.notificationRepo.GetNotifications(userID, unreadOnly, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取通知失败"})
+		return
+	}
+	unreadCount, err := h.notificationRepo.CountUnread(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "统计未读通知失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NotificationListResponse{
+		Success:       true,
+		Notifications: notifications,
+		Total:         total,
+		UnreadCount:   unreadCount,
+	})
+}
+
+// MarkNotificationRead 将单条通知标记为已读
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	notificationID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的通知ID"})
+		return
+	}
+
+	found, err := h.notificationRepo.MarkRead(userID, uint(notificationID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "标记通知失败"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "通知不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "通知已标记为已读"})
+}
+
+// MarkAllNotificationsRead 将全部未读通知标记为已读
+func (h *NotificationHandler) MarkAllNotificationsRead(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	count, err := h.notificationRepo.MarkAllRead(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "标记通知失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "全部通知已标记为已读", "count": count})
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend/async"
+	"backend/database"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rejectInfectedFile 文件已被隔离时写入 403 响应并返回 true
+func rejectInfectedFile(c *gin.Context, file *models.File) bool {
+	if !file.IsInfected() {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": models.ErrFileInfected.Error()})
+	return true
+}
+
+// respondInfectedUpload 上传的文件同步扫描发现病毒：文件记录保留（内容已隔离），返回 422
+func respondInfectedUpload(c *gin.Context, file *models.File) {
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":     "文件包含病毒，已被隔离",
+		"signature": file.ScanResult,
+		"file":      file,
+	})
+}
+
+// ScanHandler 病毒扫描管理处理器（管理员）
+type ScanHandler struct {
+	scanRepo     database.FileScanRepositoryInterface
+	scanner      *services.VirusScanService
+	queueManager *utils.UploadQueueManager
+	taskManager  *async.TaskManager
+}
+
+// NewScanHandler 创建病毒扫描管理处理器实例
+func NewScanHandler(scanRepo database.FileScanRepositoryInterface, scanner *services.VirusScanService, queueManager *utils.UploadQueueManager, taskManager *async.TaskManager) *ScanHandler {
+	return &ScanHandler{
+		scanRepo:     scanRepo,
+		scanner:      scanner,
+		queueManager: queueManager,
+		taskManager:  taskManager,
+	}
+}
+
+// GetScanStatus 获取扫描服务的可用性、各扫描状态的文件数量和正在进行的重新扫描
+func (h *ScanHandler) GetScanStatus(c *gin.Context) {
+	counts, err := h.scanRepo.GetScanStatusCounts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "统计扫描状态失败"})
+		return
+	}
+
+	response := models.ScanStatusResponse{
+		Success: true,
+		Enabled: h.scanner.Enabled(),
+		Counts:  counts,
+	}
+	if response.Enabled {
+		if err := h.scanner.Ping(); err != nil {
+			response.Error = err.Error()
+		} else {
+			response.Available = true
+		}
+		response.Rescan = h.scanner.RescanProgress()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetInfectedFiles 分页获取被隔离的文件
+func (h *ScanHandler) GetInfectedFiles(c *gin.Context) {
+	limit, offset, ok := parseLimitOffset(c, 50, 200)
+	if !ok {
+		return
+	}
+
+	files, total, err := h.scanRepo.GetInfectedFiles(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取隔离文件失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"files":   files,
+		"total":   total,
+	})
+}
+
+// RescanAll 在后台重新扫描全部文件，进度通过上传任务接口查询
+func (h *ScanHandler) RescanAll(c *gin.Context) {
+	if !h.scanner.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "未配置病毒扫描服务"})
+		return
+	}
+	if err := h.scanner.Ping(); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "扫描服务不可用: " + err.Error()})
+		return
+	}
+
+	currentUser, _ := c.Get("currentUser")
+	admin, _ := currentUser.(*models.User)
+	if admin == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+		return
+	}
+
+	if progress := h.scanner.RescanProgress(); progress != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "已有重新扫描任务正在进行", "rescan": progress})
+		return
+	}
+
+	task := h.queueManager.CreateTask(admin.UUID, "virus_rescan", "重新扫描全部文件", 0)
+	taskID := task.ID
+	if !h.scanner.BeginRescan(taskID) {
+		h.queueManager.UpdateTaskError(taskID, "已有重新扫描任务正在进行")
+		c.JSON(http.StatusConflict, gin.H{"error": "已有重新扫描任务正在进行", "rescan": h.scanner.RescanProgress()})
+		return
+	}
+
+	job := func() error {
+		if !h.queueManager.StartTask(taskID) {
+			h.scanner.EndRescan()
+			return nil
+		}
+
+		lastProgress := -1
+		err := h.scanner.RunRescan(func(progress models.RescanProgress) {
+			if progress.Total <= 0 {
+				return
+			}
+			percent := progress.Scanned * 100 / progress.Total
+			if percent >= 100 {
+				percent = 99
+			}
+			if percent != lastProgress {
+				lastProgress = percent
+				h.queueManager.UpdateTaskProgress(taskID, percent)
+			}
+		})
+		if err != nil {
+			h.queueManager.UpdateTaskError(taskID, "重新扫描失败: "+err.Error())
+			return err
+		}
+
+		h.queueManager.UpdateTaskResult(taskID, "/api/admin/scan/status")
+		return nil
+	}
+
+	if err := h.taskManager.SubmitTask(async.NewBaseTask(taskID, 1, 0, job)); err != nil {
+		h.scanner.EndRescan()
+		h.queueManager.UpdateTaskError(taskID, "任务队列繁忙")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "任务队列繁忙，请稍后重试"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "已开始重新扫描全部文件",
+		"task_id": taskID,
+	})
+}
@@ -0,0 +1,447 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend/config"
+	"backend/database"
+	"backend/middleware"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// sftpHandshakeTimeout SSH 握手和认证的超时时间
+const sftpHandshakeTimeout = 30 * time.Second
+
+// SSH 连接认证通过后保存在 ssh.Permissions 中的信息
+const (
+	sftpUserKey   = "user-uuid"
+	sftpMethodKey = "auth-method"
+)
+
+// errSFTPAuthFailed 用户名、密码或公钥不正确，不向客户端区分具体原因
+var errSFTPAuthFailed = errors.New("认证失败")
+
+// SFTPServer 内置 SFTP 服务，将用户自己的文件夹树通过 SSH 提供给 sftp、rclone、sshfs 等工具，
+// 读写与 WebDAV 共用同一套路径映射，写入受存储配额和上传文件大小规则限制，全部操作写入审计日志
+type SFTPServer struct {
+	driveRepos
+	appTokenRepo database.AppTokenRepositoryInterface
+	sshKeyRepo   database.SSHKeyRepositoryInterface
+	config       config.SFTPConfig
+
+	// 按IP记录密码认证失败，防止暴力破解密码
+	failures *middleware.RateLimiter
+
+	audit     *log.Logger
+	auditFile *os.File
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewSFTPServer 创建 SFTP 服务实例，配置中未启用时 Start 不做任何事
+func NewSFTPServer(sftpConfig config.SFTPConfig, userRepo database.UserRepositoryInterface, appTokenRepo database.AppTokenRepositoryInterface, sshKeyRepo database.SSHKeyRepositoryInterface, fileRepo database.FileRepositoryInterface, folderRepo database.FolderRepositoryInterface, versionRepo database.FileVersionRepositoryInterface, grantRepo database.GrantRepositoryInterface, indexer *services.ContentIndexService, scanner *services.VirusScanService) *SFTPServer {
+	sftpConfig = sftpConfig.WithDefaults()
+	return &SFTPServer{
+		driveRepos: driveRepos{
+			userRepo:    userRepo,
+			fileRepo:    fileRepo,
+			folderRepo:  folderRepo,
+			versionRepo: versionRepo,
+			grantRepo:   grantRepo,
+			indexer:     indexer,
+			scanner:     scanner,
+			maxFileSize: uploadSizeLimit,
+		},
+		appTokenRepo: appTokenRepo,
+		sshKeyRepo:   sshKeyRepo,
+		config:       sftpConfig,
+		failures:     middleware.NewRateLimiter(sftpConfig.MaxAuthFailures, sftpConfig.AuthFailureWindow),
+		conns:        make(map[net.Conn]struct{}),
+	}
+}
+
+// uploadSizeLimit 与上传接口相同的单文件大小规则：视频文件使用视频大小上限
+func uploadSizeLimit(name string) int64 {
+	uploadConfig := config.GetUploadConfig()
+	if utils.GetFileType(name) == "video" {
+		return uploadConfig.MaxVideoSize
+	}
+	return uploadConfig.MaxFileSize
+}
+
+// Start 加载主机密钥并开始监听，连接在后台处理
+func (s *SFTPServer) Start() error {
+	if !s.config.Enabled {
+		return nil
+	}
+
+	hostKey, err := loadOrCreateHostKey(s.config.HostKeyFile)
+	if err != nil {
+		return fmt.Errorf("加载SFTP主机密钥失败: %v", err)
+	}
+
+	s.audit = log.New(log.Writer(), "[SFTP] ", log.LstdFlags)
+	if s.config.AuditLogFile != "" {
+		if err := os.MkdirAll(filepath.Dir(s.config.AuditLogFile), 0755); err != nil {
+			return fmt.Errorf("创建SFTP审计日志目录失败: %v", err)
+		}
+		auditFile, err := os.OpenFile(s.config.AuditLogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+		if err != nil {
+			return fmt.Errorf("打开SFTP审计日志失败: %v", err)
+		}
+		s.auditFile = auditFile
+		s.audit = log.New(auditFile, "", log.LstdFlags)
+	}
+
+	listener, err := net.Listen("tcp", s.config.Listen)
+	if err != nil {
+		return fmt.Errorf("SFTP监听 %s 失败: %v", s.config.Listen, err)
+	}
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	sshConfig := s.sshConfig()
+	sshConfig.AddHostKey(hostKey)
+
+	log.Printf("🔐 SFTP服务已启动: %s，主机密钥指纹 %s", listener.Addr(), ssh.FingerprintSHA256(hostKey.PublicKey()))
+	s.wg.Add(1)
+	go s.acceptLoop(listener, sshConfig)
+	return nil
+}
+
+// Addr 实际监听的地址，未启动时返回 nil
+func (s *SFTPServer) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Stop 停止监听并断开全部连接，正在进行的写入不会保存
+func (s *SFTPServer) Stop() {
+	s.mu.Lock()
+	s.closed = true
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	if s.auditFile != nil {
+		s.auditFile.Close()
+	}
+}
+
+// sshConfig 构造认证配置：账号密码或应用专用密码、登记的SSH公钥
+func (s *SFTPServer) sshConfig() *ssh.ServerConfig {
+	sshConfig := &ssh.ServerConfig{
+		ServerVersion: "SSH-2.0-StarCloud",
+		MaxAuthTries:  6,
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			return s.authenticateKey(meta, key)
+		},
+	}
+	if *s.config.PasswordAuth {
+		sshConfig.PasswordCallback = func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			return s.authenticatePassword(meta, string(password))
+		}
+	}
+	return sshConfig
+}
+
+// authenticatePassword 校验账号密码或应用专用密码，失败计入该IP的认证失败次数
+func (s *SFTPServer) authenticatePassword(meta ssh.ConnMetadata, password string) (*ssh.Permissions, error) {
+	clientIP := remoteIP(meta.RemoteAddr())
+	if s.failures.Blocked(clientIP) {
+		s.audit.Printf("登录拒绝 - 用户名: %s, IP: %s, 原因: 认证失败次数过多", meta.User(), clientIP)
+		return nil, errSFTPAuthFailed
+	}
+
+	user, err := authenticatePassword(s.userRepo, s.appTokenRepo, meta.User(), password)
+	if err != nil {
+		log.Printf("SFTP认证失败: %v", err)
+		return nil, errSFTPAuthFailed
+	}
+	if user == nil {
+		s.failures.Allow(clientIP)
+		s.audit.Printf("登录失败 - 用户名: %s, IP: %s, 方式: password", meta.User(), clientIP)
+		return nil, errSFTPAuthFailed
+	}
+	return &ssh.Permissions{Extensions: map[string]string{sftpUserKey: user.UUID, sftpMethodKey: "password"}}, nil
+}
+
+// authenticateKey 校验客户端公钥是否为该用户登记的公钥。客户端会依次尝试多把公钥，未匹配不计入认证失败
+func (s *SFTPServer) authenticateKey(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	clientIP := remoteIP(meta.RemoteAddr())
+	if s.failures.Blocked(clientIP) {
+		return nil, errSFTPAuthFailed
+	}
+
+	user, err := s.userRepo.GetUserByUsername(meta.User())
+	if err != nil || user == nil {
+		if err != nil && !isRecordNotFound(err) {
+			log.Printf("SFTP认证失败: %v", err)
+		}
+		return nil, errSFTPAuthFailed
+	}
+
+	registered, err := s.sshKeyRepo.GetSSHKeyByFingerprint(ssh.FingerprintSHA256(key))
+	if err != nil {
+		log.Printf("SFTP认证失败: %v", err)
+		return nil, errSFTPAuthFailed
+	}
+	if registered == nil || registered.UserID != user.UUID {
+		return nil, errSFTPAuthFailed
+	}
+	// 指纹一致时再比较完整公钥
+	storedKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(registered.PublicKey))
+	if err != nil || subtle.ConstantTimeCompare(storedKey.Marshal(), key.Marshal()) != 1 {
+		return nil, errSFTPAuthFailed
+	}
+
+	// 每次登录会回调两次（询问和签名验证），最近使用时间每分钟最多更新一次
+	if registered.LastUsedAt == nil || time.Since(*registered.LastUsedAt) > time.Minute {
+		s.sshKeyRepo.TouchSSHKey(registered.ID)
+	}
+	return &ssh.Permissions{Extensions: map[string]string{sftpUserKey: user.UUID, sftpMethodKey: "publickey " + registered.Fingerprint}}, nil
+}
+
+// acceptLoop 接受连接直到监听关闭
+func (s *SFTPServer) acceptLoop(listener net.Listener, sshConfig *ssh.ServerConfig) {
+	defer s.wg.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return
+		}
+		if !s.track(conn) {
+			conn.Close()
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(conn)
+			s.handleConn(conn, sshConfig)
+		}()
+	}
+}
+
+// track 登记连接，服务已停止或连接数达到上限时返回 false
+func (s *SFTPServer) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || len(s.conns) >= s.config.MaxConnections {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *SFTPServer) untrack(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	conn.Close()
+}
+
+// handleConn 完成 SSH 握手后只接受 session 通道，每个通道只能启动 sftp 子系统
+func (s *SFTPServer) handleConn(netConn net.Conn, sshConfig *ssh.ServerConfig) {
+	clientIP := remoteIP(netConn.RemoteAddr())
+
+	// 握手和认证使用固定的截止时间，完成后改为空闲超时
+	conn := &idleConn{Conn: netConn, timeout: s.config.IdleTimeout}
+	netConn.SetDeadline(time.Now().Add(sftpHandshakeTimeout))
+	sshConn, channels, requests, err := ssh.NewServerConn(conn, sshConfig)
+	if err != nil {
+		var authErr *ssh.ServerAuthError
+		if errors.As(err, &authErr) {
+			s.audit.Printf("连接关闭 - IP: %s, 原因: 认证未通过", clientIP)
+		}
+		return
+	}
+	defer sshConn.Close()
+	conn.activate()
+
+	userID := sshConn.Permissions.Extensions[sftpUserKey]
+	user, err := s.userRepo.GetUserByUUID(userID)
+	if err != nil || user == nil {
+		return
+	}
+	s.audit.Printf("登录成功 - 用户名: %s, IP: %s, 方式: %s, 客户端: %s",
+		user.Username, clientIP, sshConn.Permissions.Extensions[sftpMethodKey], sshConn.ClientVersion())
+
+	// 连接断开后等待会话结束，确保正在写入的文件已保存或清理
+	var sessions sync.WaitGroup
+	defer sessions.Wait()
+
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "只支持 session 通道")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		session := &sftpSession{server: s, user: user, clientIP: clientIP}
+		sessions.Add(1)
+		go func() {
+			defer sessions.Done()
+			session.serve(channel, channelRequests)
+		}()
+	}
+	s.audit.Printf("断开连接 - 用户名: %s, IP: %s", user.Username, clientIP)
+}
+
+// sftpSession 一个 SSH session 通道上的 SFTP 会话
+type sftpSession struct {
+	server   *SFTPServer
+	user     *models.User
+	clientIP string
+}
+
+// serve 等待客户端请求 sftp 子系统，拒绝 shell 和 exec
+func (session *sftpSession) serve(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	for request := range requests {
+		var payload struct{ Name string }
+		if request.Type != "subsystem" || ssh.Unmarshal(request.Payload, &payload) != nil || payload.Name != "sftp" {
+			if request.Type == "exec" || request.Type == "shell" {
+				io.WriteString(channel.Stderr(), "本服务只提供SFTP，不支持执行命令\r\n")
+			}
+			request.Reply(false, nil)
+			continue
+		}
+		request.Reply(true, nil)
+		go ssh.DiscardRequests(requests)
+
+		drive := &sftpDrive{session: session}
+		server := sftp.NewRequestServer(channel, sftp.Handlers{
+			FileGet:  drive,
+			FilePut:  drive,
+			FileCmd:  drive,
+			FileList: drive,
+		})
+		if err := server.Serve(); err != nil && !errors.Is(err, io.EOF) {
+			log.Printf("SFTP会话异常结束: %v", err)
+		}
+		server.Close()
+		return
+	}
+}
+
+// auditf 记录一次文件操作，err 不为 nil 时记为失败
+func (session *sftpSession) auditf(operation, target string, err error, format string, args ...interface{}) {
+	result := "成功"
+	if err != nil {
+		result = "失败: " + err.Error()
+	}
+	detail := ""
+	if format != "" {
+		detail = ", " + fmt.Sprintf(format, args...)
+	}
+	session.server.audit.Printf("%s - 用户名: %s, IP: %s, 路径: %s, 结果: %s%s",
+		operation, session.user.Username, session.clientIP, target, result, detail)
+}
+
+// idleConn 认证通过后每次读写时顺延截止时间，连接空闲超过 timeout 后读写失败并断开
+type idleConn struct {
+	net.Conn
+	timeout time.Duration
+	active  atomic.Bool
+}
+
+// activate 结束握手阶段的固定截止时间，开始按空闲时间计算
+func (c *idleConn) activate() {
+	c.active.Store(true)
+	c.extend()
+}
+
+func (c *idleConn) extend() {
+	if c.active.Load() {
+		c.Conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+}
+
+func (c *idleConn) Read(p []byte) (int, error) {
+	c.extend()
+	return c.Conn.Read(p)
+}
+
+func (c *idleConn) Write(p []byte) (int, error) {
+	c.extend()
+	return c.Conn.Write(p)
+}
+
+// remoteIP 去掉地址中的端口
+func remoteIP(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// loadOrCreateHostKey 读取主机私钥，文件不存在时生成 ed25519 密钥并保存，保证重启后指纹不变
+func loadOrCreateHostKey(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return ssh.ParsePrivateKey(data)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	block, err := ssh.MarshalPrivateKey(privateKey, "star-cloud sftp host key")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		return nil, err
+	}
+	log.Printf("🔑 已生成SFTP主机密钥: %s", path)
+	return ssh.NewSignerFromKey(privateKey)
+}
@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"backend/utils"
+
+	"github.com/pkg/sftp"
+)
+
+// errSFTPNotEmpty 删除的文件夹不为空，SFTP 的 rmdir 只能删除空文件夹
+var errSFTPNotEmpty = errors.New("文件夹不为空")
+
+// sftpDrive 实现 sftp.Handlers，每个操作创建新的 driveFS：SFTP 请求会并发处理，路径缓存不能共用
+type sftpDrive struct {
+	session *sftpSession
+}
+
+func (d *sftpDrive) fs() *driveFS {
+	return newDriveFS(&d.session.server.driveRepos, d.session.user.UUID)
+}
+
+// Fileread 打开文件用于下载，被隔离的文件拒绝读取
+func (d *sftpDrive) Fileread(request *sftp.Request) (io.ReaderAt, error) {
+	name := davClean(request.Filepath)
+	node, err := d.fs().resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if node.file == nil {
+		return nil, errDavIsDirectory
+	}
+	if node.file.IsInfected() {
+		d.session.auditf("下载", name, os.ErrPermission, "原因: 文件已被隔离")
+		return nil, os.ErrPermission
+	}
+
+	handle, err := utils.OpenStoredContent(utils.GetFileAbsolutePath(node.file.Path), node.file.EncryptionKeyID)
+	d.session.auditf("下载", name, err, "大小: %d", node.file.Size)
+	if err != nil {
+		return nil, err
+	}
+	return handle, nil
+}
+
+// Filewrite 打开文件用于写入。内容先写入临时文件，关闭时登记为新文件或作为已有文件的新版本；
+// 未带 TRUNC 打开已有文件（续传、追加）时以原内容为基础
+func (d *sftpDrive) Filewrite(request *sftp.Request) (io.WriterAt, error) {
+	name := davClean(request.Filepath)
+	flags := request.Pflags()
+	fs := d.fs()
+
+	if name == "/" {
+		return nil, errDavIsDirectory
+	}
+	parent, base, err := fs.resolveParent(name)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := fs.resolve(name)
+	switch {
+	case err == nil && node.file == nil:
+		return nil, errDavIsDirectory
+	case err == nil && flags.Excl:
+		return nil, os.ErrExist
+	case err != nil && !os.IsNotExist(err):
+		return nil, err
+	case err != nil && !flags.Creat:
+		return nil, os.ErrNotExist
+	}
+
+	limit, err := fs.writeLimit(base)
+	if err != nil {
+		return nil, err
+	}
+
+	writer, err := newSFTPWriter(d.session, fs, parent.folderID(), name, base, limit, flags.Append)
+	if err != nil {
+		return nil, err
+	}
+	if node != nil && !flags.Trunc {
+		if err := writer.seed(node); err != nil {
+			writer.discard()
+			return nil, err
+		}
+	}
+	return writer, nil
+}
+
+// Filecmd 处理创建文件夹、删除、重命名和修改属性
+func (d *sftpDrive) Filecmd(request *sftp.Request) error {
+	name := davClean(request.Filepath)
+	fs := d.fs()
+
+	switch request.Method {
+	case "Mkdir":
+		err := fs.Mkdir(request.Context(), name, 0)
+		d.session.auditf("创建文件夹", name, err, "")
+		return err
+
+	case "Rmdir":
+		err := d.removeDir(request, fs, name)
+		d.session.auditf("删除文件夹", name, err, "")
+		return err
+
+	case "Remove":
+		node, err := fs.resolve(name)
+		if err == nil && node.file == nil {
+			err = errDavIsDirectory
+		}
+		if err == nil {
+			err = fs.RemoveAll(request.Context(), name)
+		}
+		d.session.auditf("删除文件", name, err, "")
+		return err
+
+	case "Rename":
+		target := davClean(request.Target)
+		err := fs.Rename(request.Context(), name, target)
+		d.session.auditf("重命名", name, err, "目标: %s", target)
+		return err
+
+	case "Setstat":
+		return d.setstat(fs, name, request)
+	}
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+// PosixRename 覆盖已存在目标文件的重命名（posix-rename@openssh.com），被覆盖的文件与删除接口一样清理
+func (d *sftpDrive) PosixRename(request *sftp.Request) error {
+	name, target := davClean(request.Filepath), davClean(request.Target)
+	fs := d.fs()
+
+	err := func() error {
+		source, err := fs.resolve(name)
+		if err != nil {
+			return err
+		}
+		existing, err := fs.resolve(target)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return fs.Rename(request.Context(), name, target)
+			}
+			return err
+		}
+		if sameDavNode(existing, source) {
+			return fs.Rename(request.Context(), name, target)
+		}
+		if existing.file == nil || source.file == nil {
+			return os.ErrExist
+		}
+		if err := fs.RemoveAll(request.Context(), target); err != nil {
+			return err
+		}
+		return fs.Rename(request.Context(), name, target)
+	}()
+	d.session.auditf("重命名", name, err, "目标: %s, 覆盖: 是", target)
+	return err
+}
+
+// removeDir 只删除空文件夹
+func (d *sftpDrive) removeDir(request *sftp.Request, fs *driveFS, name string) error {
+	if name == "/" {
+		return os.ErrPermission
+	}
+	node, err := fs.resolve(name)
+	if err != nil {
+		return err
+	}
+	if node.folder == nil {
+		return os.ErrInvalid
+	}
+	children, err := fs.children(name, node)
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		return errSFTPNotEmpty
+	}
+	return fs.RemoveAll(request.Context(), name)
+}
+
+// setstat 文件权限、属主和时间由服务端管理，客户端的修改直接忽略；不支持改变文件大小
+func (d *sftpDrive) setstat(fs *driveFS, name string, request *sftp.Request) error {
+	node, err := fs.resolve(name)
+	if err != nil {
+		return err
+	}
+	if request.AttrFlags().Size {
+		if node.file == nil || int64(request.Attributes().Size) != node.file.Size {
+			return sftp.ErrSSHFxOpUnsupported
+		}
+	}
+	return nil
+}
+
+// Filelist 列出文件夹或获取单个路径的信息
+func (d *sftpDrive) Filelist(request *sftp.Request) (sftp.ListerAt, error) {
+	name := davClean(request.Filepath)
+	fs := d.fs()
+
+	node, err := fs.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	switch request.Method {
+	case "List":
+		if node.file != nil {
+			return nil, os.ErrInvalid
+		}
+		entries, err := fs.children(name, node)
+		if err != nil {
+			return nil, err
+		}
+		return sftpListing(entries), nil
+	case "Stat":
+		return sftpListing{fs.info(name, node)}, nil
+	}
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+// sftpListing 实现 sftp.ListerAt
+type sftpListing []os.FileInfo
+
+func (l sftpListing) ListAt(buffer []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(buffer, l[offset:])
+	if n < len(buffer) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// sftpWriter SFTP 写入的目标。客户端会并发发送多个不同偏移的写请求，先写入临时文件，关闭时再登记
+type sftpWriter struct {
+	session  *sftpSession
+	fs       *driveFS
+	parentID *uint
+	path     string
+	name     string
+	limit    int64
+	append   bool
+
+	mu          sync.Mutex
+	temp        *os.File
+	size        int64
+	writeErr    error
+	transferErr error
+	closed      bool
+}
+
+func newSFTPWriter(session *sftpSession, fs *driveFS, parentID *uint, path, name string, limit int64, appendMode bool) (*sftpWriter, error) {
+	tempDir := utils.GetUploadTempDir()
+	if err := os.MkdirAll(tempDir, 0700); err != nil {
+		return nil, err
+	}
+	temp, err := os.CreateTemp(tempDir, "sftp-*")
+	if err != nil {
+		return nil, err
+	}
+	return &sftpWriter{
+		session:  session,
+		fs:       fs,
+		parentID: parentID,
+		path:     path,
+		name:     name,
+		limit:    limit,
+		append:   appendMode,
+		temp:     temp,
+	}, nil
+}
+
+// seed 以已有文件的内容作为写入的基础
+func (w *sftpWriter) seed(node *davNode) error {
+	if node.file.IsInfected() {
+		return os.ErrPermission
+	}
+	if node.file.Size > w.limit {
+		return errDavTooLarge
+	}
+	source, err := utils.OpenStoredContent(utils.GetFileAbsolutePath(node.file.Path), node.file.EncryptionKeyID)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	written, err := io.Copy(w.temp, source)
+	w.size = written
+	return err
+}
+
+// WriteAt 写入临时文件，超出上限时拒绝；追加模式下忽略偏移写到末尾
+func (w *sftpWriter) WriteAt(p []byte, offset int64) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.writeErr != nil {
+		return 0, w.writeErr
+	}
+	if w.append {
+		offset = w.size
+	}
+	end := offset + int64(len(p))
+	if offset < 0 || end > w.limit {
+		w.writeErr = errDavTooLarge
+		return 0, w.writeErr
+	}
+
+	n, err := w.temp.WriteAt(p, offset)
+	if end := offset + int64(n); end > w.size {
+		w.size = end
+	}
+	if err != nil {
+		w.writeErr = err
+	}
+	return n, err
+}
+
+// TransferError 连接在文件关闭前中断，写入的内容不完整
+func (w *sftpWriter) TransferError(err error) {
+	w.mu.Lock()
+	w.transferErr = err
+	w.mu.Unlock()
+}
+
+// Close 写入成功时登记文件，否则删除临时文件
+func (w *sftpWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	abortErr := w.writeErr
+	if abortErr == nil {
+		abortErr = w.transferErr
+	}
+	if err := w.temp.Close(); err != nil && abortErr == nil {
+		abortErr = err
+	}
+	if abortErr != nil {
+		os.Remove(w.temp.Name())
+		w.session.auditf("上传", w.path, abortErr, "已写入: %d", w.size)
+		return abortErr
+	}
+
+	stored, err := utils.StoreTempFile(w.temp.Name(), w.name, w.fs.userID)
+	if err != nil {
+		os.Remove(w.temp.Name())
+		w.session.auditf("上传", w.path, err, "")
+		return err
+	}
+	err = w.fs.commit(w.parentID, w.name, stored)
+	w.session.auditf("上传", w.path, err, "大小: %d, SHA-256: %s", stored.Size, stored.Checksum)
+	return err
+}
+
+// discard 打开失败时清理临时文件
+func (w *sftpWriter) discard() {
+	w.closed = true
+	w.temp.Close()
+	os.Remove(w.temp.Name())
+}
@@ -0,0 +1,591 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"backend/config"
+	"backend/database"
+	"backend/middleware"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShareHandler 分享链接处理器
+type ShareHandler struct {
+	shareRepo  database.ShareRepositoryInterface
+	fileRepo   database.FileRepositoryInterface
+	folderRepo database.FolderRepositoryInterface
+	grantRepo  database.GrantRepositoryInterface
+	userRepo   database.UserRepositoryInterface
+	scanner    *services.VirusScanService
+
+	// 按分享和IP记录密码错误，防止暴力破解分享密码
+	failures *middleware.RateLimiter
+}
+
+// NewShareHandler 创建分享链接处理器实例
+func NewShareHandler(shareRepo database.ShareRepositoryInterface, fileRepo database.FileRepositoryInterface, folderRepo database.FolderRepositoryInterface, grantRepo database.GrantRepositoryInterface, userRepo database.UserRepositoryInterface, scanner *services.VirusScanService) *ShareHandler {
+	return &ShareHandler{
+		shareRepo:  shareRepo,
+		fileRepo:   fileRepo,
+		folderRepo: folderRepo,
+		grantRepo:  grantRepo,
+		userRepo:   userRepo,
+		scanner:    scanner,
+		failures:   middleware.NewRateLimiter(models.MaxSharePasswordFailures, models.SharePasswordFailureWindow),
+	}
+}
+
+// ===== 分享者管理接口 =====
+
+// CreateShare 创建分享链接，需要是资源所有者或共同所有者。分享链接归资源所有者，公开访问时读取所有者的内容
+func (h *ShareHandler) CreateShare(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.CreateShareRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	if request.Mode == "" {
+		request.Mode = models.ShareModeReadOnly
+	}
+	if request.Mode != models.ShareModeReadOnly && request.Mode != models.ShareModeUpload {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分享模式"})
+		return
+	}
+	if request.MaxDownloads < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "下载次数限制不能为负数"})
+		return
+	}
+
+	// 检查被分享的资源是否存在，以及登录用户能否管理其共享
+	var ownerID string
+	switch request.ResourceType {
+	case models.ShareResourceFile:
+		if request.Mode == models.ShareModeUpload {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "只有文件夹分享支持上传模式"})
+			return
+		}
+		file, ok := resolveFileAccess(c, h.grantRepo, request.ResourceID, userID, models.PermissionCoOwner)
+		if !ok {
+			return
+		}
+		ownerID = file.UserID
+	case models.ShareResourceFolder:
+		folder, _, ok := resolveFolderAccess(c, h.grantRepo, request.ResourceID, userID, models.PermissionCoOwner)
+		if !ok {
+			return
+		}
+		ownerID = folder.UserID
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的资源类型"})
+		return
+	}
+
+	// 计算过期时间
+	expiresAt := request.ExpiresAt
+	if expiresAt == nil && request.ExpiresInHours > 0 {
+		t := time.Now().Add(time.Duration(request.ExpiresInHours) * time.Hour)
+		expiresAt = &t
+	}
+	if expiresAt != nil && expiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "过期时间不能早于当前时间"})
+		return
+	}
+
+	slug, err := utils.GenerateRandomSlug(12)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成分享标识失败"})
+		return
+	}
+
+	share := &models.ShareLink{
+		Slug:         slug,
+		UserID:       ownerID,
+		ResourceType: request.ResourceType,
+		ResourceID:   request.ResourceID,
+		Mode:         request.Mode,
+		ExpiresAt:    expiresAt,
+		MaxDownloads: request.MaxDownloads,
+	}
+
+	if request.Password != "" {
+		hash, err := utils.HashSecret(request.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "处理分享密码失败"})
+			return
+		}
+		share.PasswordHash = hash
+		share.HasPassword = true
+	}
+
+	if err := h.shareRepo.CreateShare(share); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建分享失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "分享创建成功",
+		"share":   share,
+		"url":     "/s/" + share.Slug,
+	})
+}
+
+// GetShares 获取当前用户创建的分享列表
+func (h *ShareHandler) GetShares(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	shares, err := h.shareRepo.GetSharesByUserID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分享列表失败"})
+		return
+	}
+
+	// 确保shares不为nil，如果为nil则初始化为空数组
+	if shares == nil {
+		shares = []models.ShareLink{}
+	}
+
+	response := models.ShareListResponse{
+		Success: true,
+		Shares:  shares,
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeShare 撤销分享链接
+func (h *ShareHandler) RevokeShare(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	shareIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分享ID"})
+		return
+	}
+	shareID := uint(shareIDInt)
+
+	if _, err := h.shareRepo.GetShareByID(shareID, userID); err != nil {
+		if isRecordNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "分享不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分享信息失败"})
+		}
+		return
+	}
+
+	if err := h.shareRepo.RevokeShare(shareID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "撤销分享失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "分享已撤销",
+	})
+}
+
+// GetShareLogs 获取分享的访问日志
+func (h *ShareHandler) GetShareLogs(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	shareIDInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分享ID"})
+		return
+	}
+	shareID := uint(shareIDInt)
+
+	share, err := h.shareRepo.GetShareByID(shareID, userID)
+	if err != nil {
+		if isRecordNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "分享不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分享信息失败"})
+		}
+		return
+	}
+
+	logs, err := h.shareRepo.GetAccessLogs(shareID, 200)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取访问日志失败"})
+		return
+	}
+
+	if logs == nil {
+		logs = []models.ShareAccessLog{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"share":   share,
+		"logs":    logs,
+	})
+}
+
+// ===== 公开访问接口（无需登录） =====
+
+// logAccess 记录分享访问日志
+func (h *ShareHandler) logAccess(c *gin.Context, share *models.ShareLink, action string, fileID *uint, detail string) {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	h.shareRepo.CreateAccessLog(&models.ShareAccessLog{
+		ShareID:   share.ID,
+		Action:    action,
+		FileID:    fileID,
+		IP:        c.ClientIP(),
+		UserAgent: userAgent,
+		Detail:    detail,
+	})
+}
+
+// loadPublicShare 加载并校验公开分享（是否存在、撤销、过期、密码），失败时直接写入响应
+func (h *ShareHandler) loadPublicShare(c *gin.Context) (*models.ShareLink, bool) {
+	share, err := h.shareRepo.GetShareBySlug(c.Param("slug"))
+	if err != nil {
+		if isRecordNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "分享不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分享信息失败"})
+		}
+		return nil, false
+	}
+
+	if share.Revoked {
+		c.JSON(http.StatusGone, gin.H{"error": "分享已被取消"})
+		return nil, false
+	}
+
+	if share.IsExpired() {
+		c.JSON(http.StatusGone, gin.H{"error": "分享已过期"})
+		return nil, false
+	}
+
+	if share.PasswordHash != "" {
+		failureKey := share.Slug + "|" + c.ClientIP()
+		if h.failures.Blocked(failureKey) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "密码错误次数过多，请稍后再试"})
+			return nil, false
+		}
+
+		// 密码只从请求头或 POST 表单读取，不接受查询参数，避免出现在访问日志中
+		password := c.GetHeader("X-Share-Password")
+		if password == "" && c.Request.Method == http.MethodPost {
+			password = c.PostForm("password")
+		}
+
+		if password == "" || !utils.VerifySecret(password, share.PasswordHash) {
+			if password != "" {
+				h.failures.Allow(failureKey)
+			}
+			h.logAccess(c, share, "denied", nil, "密码错误")
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":             "需要正确的分享密码",
+				"requires_password": true,
+			})
+			return nil, false
+		}
+	}
+
+	return share, true
+}
+
+// resolveShareFolder 解析分享文件夹内的目标文件夹，确保其位于分享的子树内
+func (h *ShareHandler) resolveShareFolder(share *models.ShareLink, folderIDStr string) (uint, bool) {
+	if folderIDStr == "" {
+		return share.ResourceID, true
+	}
+
+	folderIDInt, err := strconv.Atoi(folderIDStr)
+	if err != nil {
+		return 0, false
+	}
+
+	allowedIDs, err := h.folderRepo.GetDescendantFolderIDs(share.ResourceID, share.UserID)
+	if err != nil {
+		return 0, false
+	}
+
+	for _, id := range allowedIDs {
+		if id == uint(folderIDInt) {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// GetPublicShare 分享落地页接口：返回分享的文件信息或文件夹内容。
+// 有密码的分享通过 X-Share-Password 请求头或 POST 表单提交密码
+func (h *ShareHandler) GetPublicShare(c *gin.Context) {
+	share, ok := h.loadPublicShare(c)
+	if !ok {
+		return
+	}
+
+	h.shareRepo.IncrementAccessCount(share.ID)
+	h.logAccess(c, share, "view", nil, "")
+
+	shareInfo := gin.H{
+		"slug":           share.Slug,
+		"resource_type":  share.ResourceType,
+		"mode":           share.Mode,
+		"expires_at":     share.ExpiresAt,
+		"max_downloads":  share.MaxDownloads,
+		"download_count": share.DownloadCount,
+		"created_at":     share.CreatedAt,
+	}
+
+	if share.ResourceType == models.ShareResourceFile {
+		file, err := h.fileRepo.GetFileByID(share.ResourceID, share.UserID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "分享的文件已不存在"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"share":   shareInfo,
+			"file": gin.H{
+				"id":         file.ID,
+				"name":       file.Name,
+				"size":       file.Size,
+				"type":       file.Type,
+				"created_at": file.CreatedAt,
+			},
+		})
+		return
+	}
+
+	folderID, ok := h.resolveShareFolder(share, c.Query("folder_id"))
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权访问该文件夹"})
+		return
+	}
+
+	folder, err := h.folderRepo.GetFolderByID(folderID, share.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "分享的文件夹已不存在"})
+		return
+	}
+
+	subFolders, err := h.folderRepo.GetSubFolders(folderID, share.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件夹内容失败"})
+		return
+	}
+
+	files, err := h.fileRepo.GetFilesByUserID(share.UserID, &folderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件夹内容失败"})
+		return
+	}
+
+	folderList := make([]gin.H, 0, len(subFolders))
+	for _, sub := range subFolders {
+		folderList = append(folderList, gin.H{"id": sub.ID, "name": sub.Name})
+	}
+
+	fileList := make([]gin.H, 0, len(files))
+	for _, file := range files {
+		fileList = append(fileList, gin.H{
+			"id":         file.ID,
+			"name":       file.Name,
+			"size":       file.Size,
+			"type":       file.Type,
+			"created_at": file.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"share":   shareInfo,
+		"folder":  gin.H{"id": folder.ID, "name": folder.Name},
+		"folders": folderList,
+		"files":   fileList,
+	})
+}
+
+// DownloadPublicShare 通过分享链接下载文件，浏览器可用 POST 表单提交密码后直接下载
+func (h *ShareHandler) DownloadPublicShare(c *gin.Context) {
+	share, ok := h.loadPublicShare(c)
+	if !ok {
+		return
+	}
+
+	var file *models.File
+	var err error
+
+	if share.ResourceType == models.ShareResourceFile {
+		file, err = h.fileRepo.GetFileByID(share.ResourceID, share.UserID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "分享的文件已不存在"})
+			return
+		}
+	} else {
+		fileIDInt, convErr := strconv.Atoi(c.Query("file_id"))
+		if convErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件ID"})
+			return
+		}
+
+		file, err = h.fileRepo.GetFileByID(uint(fileIDInt), share.UserID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
+			return
+		}
+
+		// 文件必须位于分享文件夹的子树内
+		if file.FolderID == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "无权访问该文件"})
+			return
+		}
+		if _, ok := h.resolveShareFolder(share, strconv.FormatUint(uint64(*file.FolderID), 10)); !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "无权访问该文件"})
+			return
+		}
+	}
+
+	if rejectInfectedFile(c, file) {
+		return
+	}
+
+	// HEAD请求不计入下载次数
+	if c.Request.Method != "HEAD" {
+		allowed, err := h.shareRepo.TryIncrementDownloadCount(share.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新下载次数失败"})
+			return
+		}
+		if !allowed {
+			h.logAccess(c, share, "denied", &file.ID, "下载次数已达上限")
+			c.JSON(http.StatusForbidden, gin.H{"error": "下载次数已达上限"})
+			return
+		}
+		h.logAccess(c, share, "download", &file.ID, "")
+	}
+
+	serveFileAttachment(c, utils.GetFileAbsolutePath(file.Path), file.EncryptionKeyID, file.Name)
+}
+
+// UploadToPublicShare 向允许上传的分享文件夹上传文件（配额计入分享者）
+func (h *ShareHandler) UploadToPublicShare(c *gin.Context) {
+	share, ok := h.loadPublicShare(c)
+	if !ok {
+		return
+	}
+
+	if share.ResourceType != models.ShareResourceFolder || share.Mode != models.ShareModeUpload {
+		c.JSON(http.StatusForbidden, gin.H{"error": "该分享不允许上传"})
+		return
+	}
+
+	folderID, ok := h.resolveShareFolder(share, c.PostForm("folder_id"))
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权上传到该文件夹"})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请选择要上传的文件"})
+		return
+	}
+	defer file.Close()
+
+	if header.Size == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件不能为空"})
+		return
+	}
+
+	// 检查文件类型和大小限制
+	uploadConfig := config.GetUploadConfig()
+	fileType := utils.GetFileType(header.Filename)
+	if fileType == "video" && header.Size > uploadConfig.MaxVideoSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("视频文件大小不能超过%.0fMB", float64(uploadConfig.MaxVideoSize)/1024/1024)})
+		return
+	}
+	if header.Size > uploadConfig.MaxFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("文件大小不能超过%.0fMB", float64(uploadConfig.MaxFileSize)/1024/1024)})
+		return
+	}
+
+	// 检查分享者的存储空间
+	usedSpace, storageLimit, err := h.userRepo.GetUserStorageInfo(share.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取存储信息失败"})
+		return
+	}
+	if !utils.ValidateFileSize(header.Size, storageLimit, usedSpace) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "分享者存储空间不足"})
+		return
+	}
+
+	stored, err := utils.StoreFileContent(file, header.Filename, share.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件失败"})
+		return
+	}
+
+	newFile := &models.File{
+		Name:            header.Filename,
+		Size:            stored.Size,
+		Type:            stored.FileType,
+		Path:            stored.Path,
+		UserID:          share.UserID,
+		FolderID:        &folderID,
+		Checksum:        stored.Checksum,
+		UploadedBy:      "share:" + share.Slug,
+		EncryptionKeyID: stored.KeyID,
+	}
+	h.scanner.ResetScanState(newFile)
+
+	if err := h.fileRepo.CreateFile(newFile); err != nil {
+		os.Remove(stored.AbsolutePath)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件记录失败"})
+		return
+	}
+
+	h.logAccess(c, share, "upload", &newFile.ID, header.Filename)
+
+	// 匿名上传者只获知结果，文件记录和隔离通知留给分享者
+	if errors.Is(h.scanner.ScanUpload(newFile), models.ErrFileInfected) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "文件包含病毒，已被隔离"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "文件上传成功",
+		"file": gin.H{
+			"id":   newFile.ID,
+			"name": newFile.Name,
+			"size": newFile.Size,
+			"type": newFile.Type,
+		},
+	})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"crypto/rsa"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+)
+
+// minRSAKeyBits 可登记的RSA公钥的最小长度
+const minRSAKeyBits = 2048
+
+// SSHKeyHandler SSH公钥处理器
+type SSHKeyHandler struct {
+	sshKeyRepo database.SSHKeyRepositoryInterface
+}
+
+// NewSSHKeyHandler 创建SSH公钥处理器实例
+func NewSSHKeyHandler(sshKeyRepo database.SSHKeyRepositoryInterface) *SSHKeyHandler {
+	return &SSHKeyHandler{sshKeyRepo: sshKeyRepo}
+}
+
+// GetSSHKeys 获取当前用户登记的SSH公钥列表
+func (h *SSHKeyHandler) GetSSHKeys(c *gin.Context) {
+	user, ok := credentialUser(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.sshKeyRepo.GetSSHKeysByUser(user.UUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取SSH公钥失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"keys":    keys,
+	})
+}
+
+// AddSSHKey 登记SSH公钥，公钥为 authorized_keys 格式的一行
+func (h *SSHKeyHandler) AddSSHKey(c *gin.Context) {
+	user, ok := credentialUser(c)
+	if !ok {
+		return
+	}
+
+	var request models.AddSSHKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	publicKey, comment, options, rest, err := ssh.ParseAuthorizedKey([]byte(strings.TrimSpace(request.PublicKey)))
+	if err != nil || len(options) > 0 || len(strings.TrimSpace(string(rest))) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的SSH公钥，请粘贴一行 authorized_keys 格式的公钥"})
+		return
+	}
+	if !acceptableSSHKey(publicKey) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持DSA公钥和短于2048位的RSA公钥"})
+		return
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		name = strings.TrimSpace(comment)
+	}
+	if name == "" {
+		name = publicKey.Type()
+	}
+	if len(name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "名称不能超过100个字符"})
+		return
+	}
+
+	fingerprint := ssh.FingerprintSHA256(publicKey)
+	existing, err := h.sshKeyRepo.GetSSHKeyByFingerprint(fingerprint)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取SSH公钥失败"})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "该公钥已被登记"})
+		return
+	}
+
+	count, err := h.sshKeyRepo.CountSSHKeys(user.UUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取SSH公钥失败"})
+		return
+	}
+	if count >= models.MaxSSHKeysPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "SSH公钥数量已达上限"})
+		return
+	}
+
+	key := models.SSHKey{
+		UserID:      user.UUID,
+		Name:        name,
+		KeyType:     publicKey.Type(),
+		PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey))),
+		Fingerprint: fingerprint,
+	}
+	if err := h.sshKeyRepo.CreateSSHKey(&key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存SSH公钥失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"key":     key,
+	})
+}
+
+// DeleteSSHKey 删除SSH公钥，之后无法再用该公钥登录SFTP
+func (h *SSHKeyHandler) DeleteSSHKey(c *gin.Context) {
+	user, ok := credentialUser(c)
+	if !ok {
+		return
+	}
+
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的SSH公钥ID"})
+		return
+	}
+
+	found, err := h.sshKeyRepo.DeleteSSHKey(uint(keyID), user.UUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除SSH公钥失败"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "SSH公钥不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "SSH公钥已删除"})
+}
+
+// acceptableSSHKey 拒绝DSA公钥和过短的RSA公钥
+func acceptableSSHKey(key ssh.PublicKey) bool {
+	switch key.Type() {
+	case ssh.KeyAlgoDSA:
+		return false
+	case ssh.KeyAlgoRSA:
+		cryptoKey, ok := key.(ssh.CryptoPublicKey)
+		if !ok {
+			return false
+		}
+		rsaKey, ok := cryptoKey.CryptoPublicKey().(*rsa.PublicKey)
+		return ok && rsaKey.N.BitLen() >= minRSAKeyBits
+	}
+	return true
+}
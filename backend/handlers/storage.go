@@ -5,7 +5,7 @@ import (
 
 	"backend/database"
 	"backend/models"
-	"backend/utils"
+	"backend/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,50 +31,12 @@ func (h *StorageHandler) GetStorageInfo(c *gin.Context) {
 		return
 	}
 
-	// 获取用户存储限制
-	_, storageLimit, err := h.userRepo.GetUserStorageInfo(userID)
+	storageInfo, err := services.LoadStorageInfo(h.userRepo, h.fileRepo, h.urlFileRepo, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取存储信息失败"})
 		return
 	}
 
-	// 获取用户实际使用的存储空间（普通文件）
-	fileUsedSpace, err := h.fileRepo.GetUserTotalStorage(userID)
-	if err != nil {
-		// 如果获取已使用空间失败，使用默认值0
-		fileUsedSpace = 0
-	}
-
-	// 获取用户URL文件数量（URL文件不占用实际存储空间，但计入总数）
-	urlFileCount, err := h.urlFileRepo.GetUserTotalUrlFileCount(userID)
-	if err != nil {
-		// 如果获取URL文件数量失败，使用默认值0
-		urlFileCount = 0
-	}
-
-	// 计算总使用空间（普通文件大小 + URL文件计数）
-	// 这里我们将每个URL文件计为1字节，以便在存储计算中体现
-	urlFileSpace := int64(urlFileCount) // 每个URL文件计为1字节
-	totalUsedSpace := fileUsedSpace + urlFileSpace
-
-	// 创建存储信息对象
-	storageInfo := &models.StorageInfo{
-		UsedSpace:  totalUsedSpace,
-		TotalSpace: storageLimit,
-	}
-
-	// 防御性处理，只在真正异常时才重置
-	if storageInfo.UsedSpace < 0 {
-		storageInfo.UsedSpace = 0
-	}
-	if storageInfo.TotalSpace < 0 {
-		storageInfo.TotalSpace = 0
-	}
-
-	// 格式化存储大小
-	storageInfo.UsedSpaceStr = utils.FormatStorageSize(storageInfo.UsedSpace)
-	storageInfo.TotalSpaceStr = utils.FormatStorageSize(storageInfo.TotalSpace)
-
 	response := models.StorageInfoResponse{
 		Success: true,
 		Storage: *storageInfo,
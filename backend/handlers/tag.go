@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TagHandler 标签处理器
+type TagHandler struct {
+	tagRepo database.TagRepositoryInterface
+}
+
+// NewTagHandler 创建标签处理器实例
+func NewTagHandler(tagRepo database.TagRepositoryInterface) *TagHandler {
+	return &TagHandler{tagRepo: tagRepo}
+}
+
+// GetTags 获取当前用户的标签列表及各标签关联的对象数量
+func (h *TagHandler) GetTags(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	tags, err := h.tagRepo.GetTagsByUserID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取标签失败"})
+		return
+	}
+	if tags == nil {
+		tags = []models.Tag{}
+	}
+
+	c.JSON(http.StatusOK, models.TagListResponse{Success: true, Tags: tags})
+}
+
+// CreateTag 创建标签，同一用户的标签名称不能重复
+func (h *TagHandler) CreateTag(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.CreateTagRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	tag := &models.Tag{UserID: userID, Name: strings.TrimSpace(request.Name), Color: strings.TrimSpace(request.Color)}
+	if tag.Color == "" {
+		tag.Color = models.DefaultTagColor
+	}
+	if !h.validateTag(c, tag) {
+		return
+	}
+
+	if err := h.tagRepo.CreateTag(tag); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建标签失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TagResponse{Success: true, Tag: *tag})
+}
+
+// UpdateTag 重命名标签或修改颜色
+func (h *TagHandler) UpdateTag(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	tag, ok := h.loadTag(c, userID)
+	if !ok {
+		return
+	}
+
+	var request models.UpdateTagRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	if request.Name != nil {
+		tag.Name = strings.TrimSpace(*request.Name)
+	}
+	if request.Color != nil {
+		tag.Color = strings.TrimSpace(*request.Color)
+	}
+	if !h.validateTag(c, tag) {
+		return
+	}
+
+	if err := h.tagRepo.UpdateTag(tag); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新标签失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TagResponse{Success: true, Tag: *tag})
+}
+
+// DeleteTag 删除标签，同时移除该标签在所有对象上的关联，对象本身不受影响
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	tag, ok := h.loadTag(c, userID)
+	if !ok {
+		return
+	}
+
+	if err := h.tagRepo.DeleteTag(tag.ID, userID); err != nil {
+		if isRecordNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "标签不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除标签失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "标签删除成功"})
+}
+
+// AttachTags 批量为文件、文件夹、URL文件打上标签
+func (h *TagHandler) AttachTags(c *gin.Context) {
+	h.changeItemTags(c, true)
+}
+
+// DetachTags 批量取消文件、文件夹、URL文件上的标签
+func (h *TagHandler) DetachTags(c *gin.Context) {
+	h.changeItemTags(c, false)
+}
+
+// changeItemTags 对请求中的每个对象应用全部标签。标签必须属于当前用户；
+// 只能给自己拥有的对象打标签，不存在或不属于当前用户的对象会在 missing 中返回
+func (h *TagHandler) changeItemTags(c *gin.Context, attach bool) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.TagItemsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	if len(request.TagIDs) == 0 || len(request.Items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tag_ids 和 items 不能为空"})
+		return
+	}
+	if len(request.TagIDs) > models.MaxTagsPerRequest {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "标签数量过多"})
+		return
+	}
+	if len(request.Items) > models.MaxTagItemsPerOp {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "单次最多操作 " + strconv.Itoa(models.MaxTagItemsPerOp) + " 个对象"})
+		return
+	}
+
+	tagIDs := make([]uint, 0, len(request.TagIDs))
+	for _, tagID := range request.TagIDs {
+		if !containsUint(tagIDs, tagID) {
+			tagIDs = append(tagIDs, tagID)
+		}
+	}
+	items := make([]models.TagItemRef, 0, len(request.Items))
+	seen := make(map[models.TagItemRef]bool, len(request.Items))
+	for _, item := range request.Items {
+		switch item.ItemType {
+		case models.SearchItemFile, models.SearchItemFolder, models.SearchItemUrlFile:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "对象类型只支持 file、folder、url_file"})
+			return
+		}
+		if !seen[item] {
+			seen[item] = true
+			items = append(items, item)
+		}
+	}
+
+	tags, err := h.tagRepo.GetTagsByIDs(userID, tagIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取标签失败"})
+		return
+	}
+	if len(tags) != len(tagIDs) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "标签不存在"})
+		return
+	}
+
+	owned, err := h.tagRepo.FilterOwnedItems(userID, items)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查对象失败"})
+		return
+	}
+	ownedSet := make(map[models.TagItemRef]bool, len(owned))
+	for _, item := range owned {
+		ownedSet[item] = true
+	}
+	var missing []models.TagItemRef
+	for _, item := range items {
+		if !ownedSet[item] {
+			missing = append(missing, item)
+		}
+	}
+
+	var affected int64
+	if attach {
+		affected, err = h.tagRepo.AddItemTags(userID, tagIDs, owned)
+	} else {
+		affected, err = h.tagRepo.RemoveItemTags(userID, tagIDs, owned)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新标签失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TagItemsResponse{Success: true, Affected: affected, Missing: missing})
+}
+
+// GetItemTags 批量查询对象上的标签，用于在列表中展示标签
+func (h *TagHandler) GetItemTags(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	itemType := c.Query("type")
+	switch itemType {
+	case models.SearchItemFile, models.SearchItemFolder, models.SearchItemUrlFile:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type 只支持 file、folder、url_file"})
+		return
+	}
+	rawIDs := splitQueryList(c, "ids")
+	if len(rawIDs) == 0 || len(rawIDs) > models.MaxTagItemsPerOp {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids 数量应为 1 到 " + strconv.Itoa(models.MaxTagItemsPerOp) + " 个"})
+		return
+	}
+	ids := make([]uint, 0, len(rawIDs))
+	for _, raw := range rawIDs {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的对象ID: " + raw})
+			return
+		}
+		ids = append(ids, uint(id))
+	}
+
+	links, err := h.tagRepo.GetItemTags(userID, itemType, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取标签失败"})
+		return
+	}
+
+	response := models.ItemTagsResponse{Success: true, Type: itemType, Tags: make(map[uint][]uint, len(ids))}
+	for _, id := range ids {
+		response.Tags[id] = []uint{}
+	}
+	for _, link := range links {
+		response.Tags[link.ItemID] = append(response.Tags[link.ItemID], link.TagID)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// loadTag 加载路径中指定的、属于当前用户的标签，失败时直接写入响应
+func (h *TagHandler) loadTag(c *gin.Context, userID string) (*models.Tag, bool) {
+	tagID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的标签ID"})
+		return nil, false
+	}
+	tag, err := h.tagRepo.GetTagByID(uint(tagID), userID)
+	if err != nil {
+		if isRecordNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "标签不存在"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取标签失败"})
+		return nil, false
+	}
+	return tag, true
+}
+
+// validateTag 校验标签名称和颜色，并检查同名标签，失败时直接写入响应
+func (h *TagHandler) validateTag(c *gin.Context, tag *models.Tag) bool {
+	if tag.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "标签名称不能为空"})
+		return false
+	}
+	if utf8.RuneCountInString(tag.Name) > models.MaxTagNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "标签名称不能超过 " + strconv.Itoa(models.MaxTagNameLength) + " 个字符"})
+		return false
+	}
+	if strings.Contains(tag.Name, ",") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "标签名称不能包含逗号"})
+		return false
+	}
+	if !models.IsValidTagColor(tag.Color) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "标签颜色应为 #RGB 或 #RRGGBB 格式"})
+		return false
+	}
+
+	existing, err := h.tagRepo.GetTagByName(tag.UserID, tag.Name)
+	if err != nil && !isRecordNotFound(err) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查标签名称失败"})
+		return false
+	}
+	if err == nil && existing.ID != tag.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "已存在同名标签"})
+		return false
+	}
+	return true
+}
@@ -20,31 +20,43 @@ func NewUploadProgressHandler(queueManager *utils.UploadQueueManager) *UploadPro
 	}
 }
 
-// GetUploadTask 获取上传任务状态
+// GetUploadTask 获取上传任务状态，服务重启前的任务从数据库查询
 func (h *UploadProgressHandler) GetUploadTask(c *gin.Context) {
+	task, ok := h.userTask(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"task":    task,
+	})
+}
+
+// userTask 获取路径中的任务，只能访问自己的任务
+func (h *UploadProgressHandler) userTask(c *gin.Context) (*utils.UploadTask, bool) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return nil, false
+	}
 	taskID := c.Param("task_id")
 	if taskID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少任务ID"})
-		return
+		return nil, false
 	}
 
 	task := h.queueManager.GetTask(taskID)
-	if task == nil {
+	if task == nil || task.UserID != userID {
 		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
-		return
+		return nil, false
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"task":    task,
-	})
+	return task, true
 }
 
-// GetUserUploadTasks 获取用户的所有上传任务
+// GetUserUploadTasks 获取用户最近的任务，包括已结束的任务
 func (h *UploadProgressHandler) GetUserUploadTasks(c *gin.Context) {
-	userID := c.Query("user_id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少用户ID"})
+	userID, ok := sessionUserID(c)
+	if !ok {
 		return
 	}
 
@@ -64,27 +76,18 @@ func (h *UploadProgressHandler) GetQueueStats(c *gin.Context) {
 	})
 }
 
-// CancelUploadTask 取消上传任务
+// CancelUploadTask 取消任务：等待中的任务不再执行，正在写入的上传或导入立即中止并删除已写入的部分
 func (h *UploadProgressHandler) CancelUploadTask(c *gin.Context) {
-	taskID := c.Param("task_id")
-	if taskID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少任务ID"})
-		return
-	}
-
-	task := h.queueManager.GetTask(taskID)
-	if task == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	task, ok := h.userTask(c)
+	if !ok {
 		return
 	}
 
-	// 只能取消pending状态的任务
-	if task.Status != "pending" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "只能取消等待中的任务"})
+	if err := h.queueManager.CancelTask(task.ID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "task": task})
 		return
 	}
 
-	h.queueManager.UpdateTaskStatus(taskID, "cancelled")
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "任务已取消",
@@ -1,32 +1,57 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"backend/async"
 	"backend/database"
 	"backend/models"
+	"backend/services"
+	"backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 // UrlFileHandler URL文件处理器
 type UrlFileHandler struct {
-	urlFileRepo database.UrlFileRepositoryInterface
-	userRepo    database.UserRepositoryInterface
-	folderRepo  database.FolderRepositoryInterface
+	urlFileRepo  database.UrlFileRepositoryInterface
+	userRepo     database.UserRepositoryInterface
+	folderRepo   database.FolderRepositoryInterface
+	activityRepo database.ActivityRepositoryInterface
+
+	// 从远程URL导入文件使用
+	fileRepo     database.FileRepositoryInterface
+	grantRepo    database.GrantRepositoryInterface
+	versionRepo  database.FileVersionRepositoryInterface
+	queueManager *utils.UploadQueueManager
+	taskManager  *async.TaskManager
+	indexer      *services.ContentIndexService
+	scanner      *services.VirusScanService
 }
 
 // NewUrlFileHandler 创建URL文件处理器实例
-func NewUrlFileHandler(urlFileRepo database.UrlFileRepositoryInterface, userRepo database.UserRepositoryInterface, folderRepo database.FolderRepositoryInterface) *UrlFileHandler {
+func NewUrlFileHandler(urlFileRepo database.UrlFileRepositoryInterface, userRepo database.UserRepositoryInterface, folderRepo database.FolderRepositoryInterface, activityRepo database.ActivityRepositoryInterface, fileRepo database.FileRepositoryInterface, grantRepo database.GrantRepositoryInterface, versionRepo database.FileVersionRepositoryInterface, queueManager *utils.UploadQueueManager, taskManager *async.TaskManager, indexer *services.ContentIndexService, scanner *services.VirusScanService) *UrlFileHandler {
 	return &UrlFileHandler{
-		urlFileRepo: urlFileRepo,
-		userRepo:    userRepo,
-		folderRepo:  folderRepo,
+		urlFileRepo:  urlFileRepo,
+		userRepo:     userRepo,
+		folderRepo:   folderRepo,
+		activityRepo: activityRepo,
+		fileRepo:     fileRepo,
+		grantRepo:    grantRepo,
+		versionRepo:  versionRepo,
+		queueManager: queueManager,
+		taskManager:  taskManager,
+		indexer:      indexer,
+		scanner:      scanner,
 	}
 }
 
-// GetUrlFiles 获取用户URL文件列表
+// urlFileListSorts URL文件列表支持的排序字段，name 按标题排序
+var urlFileListSorts = []string{models.SearchSortName, models.SearchSortCreatedAt, models.SearchSortUpdatedAt}
+
+// GetUrlFiles 获取用户URL文件列表，支持游标分页和排序
 func (h *UrlFileHandler) GetUrlFiles(c *gin.Context) {
 	userID := c.Query("user_id")
 	folderIDStr := c.Query("folder_id")
@@ -44,8 +69,19 @@ func (h *UrlFileHandler) GetUrlFiles(c *gin.Context) {
 		}
 	}
 
-	files, err := h.urlFileRepo.GetUrlFilesByUserID(userID, folderID)
+	query, ok := parseListQuery(c, models.SearchItemUrlFile, urlFileListSorts, models.SearchSortCreatedAt)
+	if !ok {
+		return
+	}
+	query.OwnerID = userID
+	query.FolderID = folderID
+
+	files, nextCursor, err := h.urlFileRepo.ListUrlFiles(query)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取URL文件列表失败"})
 		return
 	}
@@ -56,8 +92,10 @@ func (h *UrlFileHandler) GetUrlFiles(c *gin.Context) {
 	}
 
 	response := models.UrlFileListResponse{
-		Success: true,
-		Files:   files,
+		Success:    true,
+		Files:      files,
+		NextCursor: nextCursor,
+		HasMore:    nextCursor != "",
 	}
 	c.JSON(http.StatusOK, response)
 }
@@ -84,6 +122,7 @@ func (h *UrlFileHandler) GetUrlFile(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "URL文件不存在"})
 		return
 	}
+	recordRecentAccess(h.activityRepo, userID, models.SearchItemUrlFile, file.ID)
 
 	response := models.UrlFileResponse{
 		Success: true,
@@ -182,6 +221,11 @@ func (h *UrlFileHandler) MoveUrlFile(c *gin.Context) {
 	if request.FolderID > 0 {
 		folderIDUint := uint(request.FolderID)
 		folderID = &folderIDUint
+
+		// 保险库中只能保存客户端加密的文件
+		if folder, err := h.folderRepo.GetFolderByID(folderIDUint, userID); err == nil && rejectVaultFolder(c, folder) {
+			return
+		}
 	}
 
 	if err := h.urlFileRepo.MoveUrlFile(fileID, userID, folderID); err != nil {
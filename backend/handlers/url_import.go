@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"backend/async"
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errImportTooLarge 远程文件超过导入大小上限或剩余存储空间
+var errImportTooLarge = errors.New("文件大小超过限制")
+
+// importProgressReader 读取远程内容时报告已下载的字节数，并在超过上限时中止
+type importProgressReader struct {
+	reader io.Reader
+	limit  int64 // 允许写入的最大字节数
+	read   int64
+	report func(read int64)
+}
+
+func (r *importProgressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.read > r.limit {
+		return n, errImportTooLarge
+	}
+	if n > 0 {
+		r.report(r.read)
+	}
+	return n, err
+}
+
+// ImportFromURL 在服务端下载远程URL的内容并保存为文件，下载在后台任务中进行，进度通过上传任务接口查询
+func (h *UrlFileHandler) ImportFromURL(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ImportURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	importConfig := config.GetImportConfig()
+	target, err := utils.ValidateFetchURL(req.URL, importConfig.AllowedSchemes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name != "" && !validateItemName(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件名"})
+		return
+	}
+
+	if req.OnConflict == "" {
+		req.OnConflict = models.ConflictRename
+	}
+	strategy, ok := parseConflictStrategy(c, req.OnConflict)
+	if !ok {
+		return
+	}
+
+	folderID, ownerID, ok := resolveTargetFolder(c, h.grantRepo, req.FolderID, nil, userID)
+	if !ok {
+		return
+	}
+
+	usedSpace, storageLimit, err := h.userRepo.GetUserStorageInfo(ownerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取存储信息失败"})
+		return
+	}
+	if !utils.ValidateFileSize(1, storageLimit, usedSpace) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "存储空间不足"})
+		return
+	}
+
+	displayName := name
+	if displayName == "" {
+		displayName = target.String()
+	}
+	task := h.queueManager.CreateTask(userID, "url_import", displayName, 0)
+	taskID := task.ID
+
+	importJob := &urlImport{
+		handler:  h,
+		config:   importConfig,
+		taskID:   taskID,
+		userID:   userID,
+		ownerID:  ownerID,
+		folderID: folderID,
+		url:      target.String(),
+		name:     name,
+		strategy: strategy,
+	}
+
+	if err := h.taskManager.SubmitTask(async.NewBaseTask(taskID, 1, 0, importJob.run)); err != nil {
+		h.queueManager.UpdateTaskError(taskID, "任务队列繁忙")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "任务队列繁忙，请稍后重试"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "已开始导入，可通过任务ID查询进度",
+		"task_id": taskID,
+	})
+}
+
+// urlImport 一次URL导入任务的参数
+type urlImport struct {
+	handler  *UrlFileHandler
+	config   *config.ImportConfig
+	taskID   string
+	userID   string
+	ownerID  string
+	folderID *uint
+	url      string
+	name     string
+	strategy string
+}
+
+// fail 记录任务失败原因并返回错误
+func (u *urlImport) fail(message string, err error) error {
+	u.handler.queueManager.UpdateTaskError(u.taskID, message)
+	if err == nil {
+		err = errors.New(message)
+	}
+	return err
+}
+
+// run 下载远程内容、写入存储并登记为文件
+func (u *urlImport) run() error {
+	h := u.handler
+	if !h.queueManager.StartTask(u.taskID) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), u.config.Timeout)
+	defer cancel()
+	// 下载期间可以取消任务，取消后请求中止，已写入的部分由 StoreFileContent 删除
+	ctx, release := h.queueManager.TaskContext(ctx, u.taskID)
+	defer release()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u.url, nil)
+	if err != nil {
+		return u.fail("无效的URL", err)
+	}
+	request.Header.Set("User-Agent", "StarCloud-Importer/1.0")
+
+	client := utils.NewSafeHTTPClient(u.config.Timeout, u.config.MaxRedirects, u.config.AllowedSchemes)
+	response, err := client.Do(request)
+	if err != nil {
+		if utils.TaskCancelled(ctx) {
+			return context.Cause(ctx)
+		}
+		if errors.Is(err, utils.ErrBlockedAddress) {
+			return u.fail(utils.ErrBlockedAddress.Error(), err)
+		}
+		return u.fail("下载失败: "+err.Error(), err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return u.fail(fmt.Sprintf("远程服务器返回错误状态: %d", response.StatusCode), nil)
+	}
+
+	// 可写入的上限：单文件上限与剩余存储空间中较小的一个
+	usedSpace, storageLimit, err := h.userRepo.GetUserStorageInfo(u.ownerID)
+	if err != nil {
+		return u.fail("获取存储信息失败", err)
+	}
+	limit := u.config.MaxFileSize
+	if remaining := storageLimit - usedSpace; remaining < limit {
+		limit = remaining
+	}
+	if limit <= 0 {
+		return u.fail("存储空间不足", nil)
+	}
+	if response.ContentLength > u.config.MaxFileSize {
+		return u.fail(fmt.Sprintf("文件大小超过导入上限 %s", utils.FormatStorageSize(u.config.MaxFileSize)), nil)
+	}
+	if response.ContentLength > limit {
+		return u.fail("存储空间不足", nil)
+	}
+
+	fileName := u.name
+	if fileName == "" {
+		fileName = utils.RemoteFileName(response)
+	}
+
+	total := max(response.ContentLength, 0)
+	reader := &importProgressReader{
+		reader: response.Body,
+		limit:  limit,
+		report: func(read int64) {
+			h.queueManager.UpdateTaskBytes(u.taskID, read, total)
+		},
+	}
+	stored, err := utils.StoreFileContent(reader, fileName, u.ownerID)
+	release()
+	if utils.TaskCancelled(ctx) {
+		if err == nil {
+			os.Remove(stored.AbsolutePath)
+		}
+		return context.Cause(ctx)
+	}
+	if err != nil {
+		if errors.Is(err, errImportTooLarge) {
+			if reader.read > u.config.MaxFileSize {
+				return u.fail(fmt.Sprintf("文件大小超过导入上限 %s", utils.FormatStorageSize(u.config.MaxFileSize)), err)
+			}
+			return u.fail("存储空间不足", err)
+		}
+		return u.fail("下载失败: "+err.Error(), err)
+	}
+
+	finalName, replaced, conflict, err := findFileNameConflict(h.fileRepo, u.ownerID, fileName, u.folderID, u.strategy, 0)
+	if err != nil {
+		os.Remove(stored.AbsolutePath)
+		if errors.Is(err, errNoUniqueName) {
+			return u.fail("无法生成唯一文件名", err)
+		}
+		return u.fail("检查文件名称失败", err)
+	}
+	if conflict != nil {
+		os.Remove(stored.AbsolutePath)
+		return u.fail(fmt.Sprintf("目标文件夹中已存在同名文件: %s", conflict.Name), nil)
+	}
+
+	var file *models.File
+	if replaced != nil {
+		// 覆盖同名文件：与上传替换一致，原内容归档为历史版本，文件记录指向新内容
+		archived, err := archiveReplacedContent(h.versionRepo, replaced)
+		if err != nil {
+			os.Remove(stored.AbsolutePath)
+			return u.fail("保存历史版本失败", err)
+		}
+
+		replaced.Size = stored.Size
+		replaced.Type = stored.FileType
+		replaced.Path = stored.Path
+		replaced.Checksum = stored.Checksum
+		replaced.EncryptionKeyID = stored.KeyID
+		replaced.UploadedBy = u.userID
+		h.scanner.ResetScanState(replaced)
+		if err := h.fileRepo.UpdateFile(replaced); err != nil {
+			os.Remove(stored.AbsolutePath)
+			archived.Rollback()
+			return u.fail("更新文件记录失败", err)
+		}
+		archived.Commit()
+		pruneFileVersions(h.versionRepo, h.userRepo, replaced.ID, u.ownerID)
+		file = replaced
+	} else {
+		file = &models.File{
+			Name:            finalName,
+			Size:            stored.Size,
+			Type:            stored.FileType,
+			Path:            stored.Path,
+			UserID:          u.ownerID,
+			FolderID:        u.folderID,
+			Checksum:        stored.Checksum,
+			UploadedBy:      u.userID,
+			EncryptionKeyID: stored.KeyID,
+		}
+		h.scanner.ResetScanState(file)
+		if err := h.fileRepo.CreateFile(file); err != nil {
+			os.Remove(stored.AbsolutePath)
+			return u.fail("保存文件记录失败", err)
+		}
+	}
+
+	h.indexer.Notify()
+	if errors.Is(h.scanner.ScanUpload(file), models.ErrFileInfected) {
+		return u.fail("文件包含病毒，已被隔离", models.ErrFileInfected)
+	}
+
+	h.queueManager.UpdateTaskResult(u.taskID, fmt.Sprintf("/api/files/%d", file.ID))
+	return nil
+}
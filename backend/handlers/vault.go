@@ -0,0 +1,869 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"backend/config"
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VaultHandler 零知识保险库处理器。保险库中的文件内容、文件名和文件夹名均由客户端加密，
+// 服务端只保存密文、以保险库密钥包装的文件密钥，以及以各设备公钥包装的保险库密钥信封
+type VaultHandler struct {
+	vaultRepo   database.VaultRepositoryInterface
+	fileRepo    database.FileRepositoryInterface
+	folderRepo  database.FolderRepositoryInterface
+	userRepo    database.UserRepositoryInterface
+	versionRepo database.FileVersionRepositoryInterface
+	grantRepo   database.GrantRepositoryInterface
+}
+
+// NewVaultHandler 创建保险库处理器实例
+func NewVaultHandler(vaultRepo database.VaultRepositoryInterface, fileRepo database.FileRepositoryInterface, folderRepo database.FolderRepositoryInterface, userRepo database.UserRepositoryInterface, versionRepo database.FileVersionRepositoryInterface, grantRepo database.GrantRepositoryInterface) *VaultHandler {
+	return &VaultHandler{
+		vaultRepo:   vaultRepo,
+		fileRepo:    fileRepo,
+		folderRepo:  folderRepo,
+		userRepo:    userRepo,
+		versionRepo: versionRepo,
+		grantRepo:   grantRepo,
+	}
+}
+
+// rejectVaultFile 拒绝对保险库文件执行服务端需要读取内容或明文名称的操作
+func rejectVaultFile(c *gin.Context, file *models.File) bool {
+	if file.IsVault() {
+		c.JSON(http.StatusForbidden, gin.H{"error": models.ErrVaultItem.Error()})
+		return true
+	}
+	return false
+}
+
+// rejectVaultFolder 拒绝对保险库文件夹执行服务端需要读取内容或明文名称的操作
+func rejectVaultFolder(c *gin.Context, folder *models.Folder) bool {
+	if folder.IsVault() {
+		c.JSON(http.StatusForbidden, gin.H{"error": models.ErrVaultItem.Error()})
+		return true
+	}
+	return false
+}
+
+// validateVaultName 校验客户端加密后的名称，要求为不含路径分隔符的编码（如 base64url）
+func validateVaultName(c *gin.Context, name string) bool {
+	if !validateItemName(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的加密名称，需为不超过255个字符且不含路径分隔符的编码"})
+		return false
+	}
+	return true
+}
+
+// GetVaultDevices 获取当前用户登记的保险库设备
+func (h *VaultHandler) GetVaultDevices(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	devices, err := h.vaultRepo.GetVaultDevices(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取设备列表失败"})
+		return
+	}
+	if devices == nil {
+		devices = []models.VaultDevice{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"devices": devices,
+	})
+}
+
+// AddVaultDevice 登记设备公钥。已登记的设备用自己的私钥解开保险库密钥后，
+// 以新设备的公钥包装并写入信封，新设备即可访问保险库
+func (h *VaultHandler) AddVaultDevice(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.AddVaultDeviceRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	name := strings.TrimSpace(request.Name)
+	algorithm := strings.TrimSpace(request.Algorithm)
+	publicKey := strings.TrimSpace(request.PublicKey)
+	if name == "" || len(name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "设备名称不能为空且不能超过100个字符"})
+		return
+	}
+	if algorithm == "" || len(algorithm) > 50 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的公钥算法"})
+		return
+	}
+	if publicKey == "" || len(publicKey) > models.MaxVaultPublicKeyLen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的设备公钥"})
+		return
+	}
+
+	sum := sha256.Sum256([]byte(publicKey))
+	fingerprint := hex.EncodeToString(sum[:])
+	existing, err := h.vaultRepo.GetVaultDeviceByFingerprint(userID, fingerprint)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取设备失败"})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "该公钥已登记", "device": existing})
+		return
+	}
+
+	count, err := h.vaultRepo.CountVaultDevices(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取设备失败"})
+		return
+	}
+	if count >= models.MaxVaultDevicesPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "设备数量已达上限"})
+		return
+	}
+
+	device := models.VaultDevice{
+		UserID:      userID,
+		Name:        name,
+		Algorithm:   algorithm,
+		PublicKey:   publicKey,
+		Fingerprint: fingerprint,
+	}
+	if err := h.vaultRepo.CreateVaultDevice(&device); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "登记设备失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"device":  device,
+	})
+}
+
+// DeleteVaultDevice 删除设备，同时删除为该设备包装的全部保险库密钥信封。
+// 已下载到设备上的密钥无法收回，需要彻底撤销时应轮换保险库密钥
+func (h *VaultHandler) DeleteVaultDevice(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的设备ID"})
+		return
+	}
+
+	found, err := h.vaultRepo.DeleteVaultDevice(uint(deviceID), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除设备失败"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "设备不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "设备已删除"})
+}
+
+// GetVaults 获取当前用户的保险库（根文件夹），名称为密文
+func (h *VaultHandler) GetVaults(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	vaults, err := h.vaultRepo.GetVaults(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取保险库列表失败"})
+		return
+	}
+	if vaults == nil {
+		vaults = []models.Folder{}
+	}
+
+	c.JSON(http.StatusOK, models.VaultListResponse{Success: true, Vaults: vaults})
+}
+
+// CreateVault 在根目录创建保险库，同时写入至少一个设备的密钥信封
+func (h *VaultHandler) CreateVault(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.CreateVaultRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	if !validateVaultName(c, request.Name) {
+		return
+	}
+	envelopes, ok := h.buildEnvelopes(c, userID, request.Envelopes)
+	if !ok {
+		return
+	}
+
+	folder := &models.Folder{
+		Name:     request.Name,
+		UserID:   userID,
+		Category: "all",
+	}
+	if err := h.vaultRepo.CreateVault(folder, envelopes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建保险库失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"vault":     folder,
+		"envelopes": envelopes,
+	})
+}
+
+// DeleteVault 删除保险库及其中的全部文件、文件夹和密钥信封
+func (h *VaultHandler) DeleteVault(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	vault, ok := h.loadVault(c, userID)
+	if !ok {
+		return
+	}
+	if !h.deleteVaultFolder(c, vault) {
+		return
+	}
+	if err := h.vaultRepo.DeleteVaultKeyEnvelopes(vault.ID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除保险库密钥失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "保险库已删除"})
+}
+
+// GetVaultKeys 获取保险库的密钥信封，device_id 指定时只返回该设备的信封
+func (h *VaultHandler) GetVaultKeys(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	vault, ok := h.loadVault(c, userID)
+	if !ok {
+		return
+	}
+
+	var deviceID *uint
+	if deviceIDStr := c.Query("device_id"); deviceIDStr != "" {
+		id, err := strconv.ParseUint(deviceIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的设备ID"})
+			return
+		}
+		deviceIDUint := uint(id)
+		deviceID = &deviceIDUint
+	}
+
+	envelopes, err := h.vaultRepo.GetVaultKeyEnvelopes(vault.ID, userID, deviceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取保险库密钥失败"})
+		return
+	}
+	if deviceID != nil && len(envelopes) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "该设备没有此保险库的密钥"})
+		return
+	}
+	if envelopes == nil {
+		envelopes = []models.VaultKeyEnvelope{}
+	}
+
+	c.JSON(http.StatusOK, models.VaultKeysResponse{Success: true, VaultID: vault.ID, Envelopes: envelopes})
+}
+
+// PutVaultKeys 新增或覆盖设备的密钥信封，用于授权新设备或轮换保险库密钥后重新分发
+func (h *VaultHandler) PutVaultKeys(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	vault, ok := h.loadVault(c, userID)
+	if !ok {
+		return
+	}
+
+	var request models.PutVaultKeysRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	envelopes, ok := h.buildEnvelopes(c, userID, request.Envelopes)
+	if !ok {
+		return
+	}
+	for i := range envelopes {
+		envelopes[i].VaultID = vault.ID
+	}
+
+	if err := h.vaultRepo.PutVaultKeyEnvelopes(envelopes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存保险库密钥失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "保险库密钥已保存",
+		"count":   len(envelopes),
+	})
+}
+
+// DeleteVaultKey 撤销某台设备对保险库的访问
+func (h *VaultHandler) DeleteVaultKey(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	vault, ok := h.loadVault(c, userID)
+	if !ok {
+		return
+	}
+
+	deviceID, err := strconv.ParseUint(c.Param("device_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的设备ID"})
+		return
+	}
+
+	found, err := h.vaultRepo.DeleteVaultKeyEnvelope(vault.ID, uint(deviceID), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除保险库密钥失败"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "该设备没有此保险库的密钥"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "已撤销该设备的访问"})
+}
+
+// CreateVaultFolder 在保险库中创建文件夹
+func (h *VaultHandler) CreateVaultFolder(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	vault, ok := h.loadVault(c, userID)
+	if !ok {
+		return
+	}
+
+	var request models.CreateVaultFolderRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	if !validateVaultName(c, request.Name) {
+		return
+	}
+	parent, ok := h.loadVaultFolder(c, vault, request.ParentID)
+	if !ok {
+		return
+	}
+
+	folder := &models.Folder{
+		Name:     request.Name,
+		UserID:   userID,
+		Category: vault.Category,
+		ParentID: &parent.ID,
+		VaultID:  &vault.ID,
+	}
+	if err := h.folderRepo.CreateFolder(folder); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建文件夹失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.FolderResponse{Success: true, Folder: *folder})
+}
+
+// UpdateVaultFolder 重命名文件夹或在保险库内移动；保险库根文件夹只能重命名
+func (h *VaultHandler) UpdateVaultFolder(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	vault, ok := h.loadVault(c, userID)
+	if !ok {
+		return
+	}
+
+	folderID, err := strconv.ParseUint(c.Param("folder_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件夹ID"})
+		return
+	}
+	idValue := uint(folderID)
+	folder, ok := h.loadVaultFolder(c, vault, &idValue)
+	if !ok {
+		return
+	}
+
+	var request models.UpdateVaultItemRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	if request.Name != "" && !validateVaultName(c, request.Name) {
+		return
+	}
+
+	if request.FolderID == nil || sameFolderID(request.FolderID, folder.ParentID) {
+		if request.Name != "" && request.Name != folder.Name {
+			if err := h.folderRepo.UpdateFolder(folder.ID, userID, request.Name, folder.Category); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "更新文件夹失败"})
+				return
+			}
+			folder.Name = request.Name
+		}
+		c.JSON(http.StatusOK, models.FolderResponse{Success: true, Folder: *folder})
+		return
+	}
+
+	if folder.IsVaultRoot() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "保险库根文件夹不能移动"})
+		return
+	}
+	parent, ok := h.loadVaultFolder(c, vault, request.FolderID)
+	if !ok {
+		return
+	}
+	if err := h.folderRepo.MoveFolder(folder.ID, userID, &parent.ID, request.Name); err != nil {
+		if errors.Is(err, database.ErrFolderCycle) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移动文件夹失败"})
+		return
+	}
+
+	folder.ParentID = &parent.ID
+	if request.Name != "" {
+		folder.Name = request.Name
+	}
+	c.JSON(http.StatusOK, models.FolderResponse{Success: true, Folder: *folder})
+}
+
+// DeleteVaultFolder 删除保险库中的文件夹及其内容，删除整个保险库使用 DeleteVault
+func (h *VaultHandler) DeleteVaultFolder(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	vault, ok := h.loadVault(c, userID)
+	if !ok {
+		return
+	}
+
+	folderID, err := strconv.ParseUint(c.Param("folder_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件夹ID"})
+		return
+	}
+	idValue := uint(folderID)
+	folder, ok := h.loadVaultFolder(c, vault, &idValue)
+	if !ok {
+		return
+	}
+	if folder.IsVaultRoot() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "删除保险库根文件夹请使用删除保险库接口"})
+		return
+	}
+	if !h.deleteVaultFolder(c, folder) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "文件夹删除成功"})
+}
+
+// UploadVaultFile 上传客户端加密后的文件。表单字段：file 为密文，name 为加密后的文件名，
+// encrypted_key 为以保险库密钥包装的文件密钥，folder_id 为空时上传到保险库根文件夹
+func (h *VaultHandler) UploadVaultFile(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	vault, ok := h.loadVault(c, userID)
+	if !ok {
+		return
+	}
+
+	name := c.PostForm("name")
+	if !validateVaultName(c, name) {
+		return
+	}
+	encryptedKey := c.PostForm("encrypted_key")
+	if !validateEncryptedKey(c, encryptedKey) {
+		return
+	}
+
+	var folderID *uint
+	if folderIDStr := c.PostForm("folder_id"); folderIDStr != "" {
+		id, err := strconv.ParseUint(folderIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件夹ID"})
+			return
+		}
+		idValue := uint(id)
+		folderID = &idValue
+	}
+	folder, ok := h.loadVaultFolder(c, vault, folderID)
+	if !ok {
+		return
+	}
+
+	stored, ok := h.storeBlob(c, userID, 0)
+	if !ok {
+		return
+	}
+
+	file := &models.File{
+		Name:         name,
+		Size:         stored.Size,
+		Type:         models.VaultFileType,
+		Path:         stored.Path,
+		UserID:       userID,
+		FolderID:     &folder.ID,
+		Checksum:     stored.Checksum,
+		UploadedBy:   userID,
+		VaultID:      &vault.ID,
+		EncryptedKey: encryptedKey,
+		// 客户端密文在服务端按存储加密配置再加密一层
+		EncryptionKeyID: stored.KeyID,
+	}
+	if err := h.fileRepo.CreateFile(file); err != nil {
+		os.Remove(stored.AbsolutePath)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件记录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "文件上传成功",
+		"file":    file,
+	})
+}
+
+// ReplaceVaultFileContent 以新的密文替换保险库文件的内容，需同时提供新的 encrypted_key。
+// 保险库文件不保留历史版本，原密文在替换后删除
+func (h *VaultHandler) ReplaceVaultFileContent(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	vault, ok := h.loadVault(c, userID)
+	if !ok {
+		return
+	}
+	file, ok := h.loadVaultFile(c, vault, userID)
+	if !ok {
+		return
+	}
+	encryptedKey := c.PostForm("encrypted_key")
+	if !validateEncryptedKey(c, encryptedKey) {
+		return
+	}
+
+	stored, ok := h.storeBlob(c, userID, file.Size)
+	if !ok {
+		return
+	}
+
+	oldPath := file.Path
+	file.Size = stored.Size
+	file.Path = stored.Path
+	file.Checksum = stored.Checksum
+	file.EncryptionKeyID = stored.KeyID
+	file.EncryptedKey = encryptedKey
+	file.UploadedBy = userID
+	if err := h.fileRepo.UpdateFile(file); err != nil {
+		os.Remove(stored.AbsolutePath)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新文件记录失败"})
+		return
+	}
+	os.Remove(utils.GetFileAbsolutePath(oldPath))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "文件内容已替换",
+		"file":    file,
+	})
+}
+
+// UpdateVaultFile 重命名文件、在保险库内移动文件或更新包装后的文件密钥
+func (h *VaultHandler) UpdateVaultFile(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	vault, ok := h.loadVault(c, userID)
+	if !ok {
+		return
+	}
+	file, ok := h.loadVaultFile(c, vault, userID)
+	if !ok {
+		return
+	}
+
+	var request models.UpdateVaultItemRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	if request.Name != "" {
+		if !validateVaultName(c, request.Name) {
+			return
+		}
+		file.Name = request.Name
+	}
+	if request.EncryptedKey != "" {
+		if !validateEncryptedKey(c, request.EncryptedKey) {
+			return
+		}
+		file.EncryptedKey = request.EncryptedKey
+	}
+	if request.FolderID != nil {
+		folder, ok := h.loadVaultFolder(c, vault, request.FolderID)
+		if !ok {
+			return
+		}
+		file.FolderID = &folder.ID
+	}
+
+	if err := h.fileRepo.UpdateFile(file); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新文件失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"file":    file,
+	})
+}
+
+// DeleteVaultFile 删除保险库文件
+func (h *VaultHandler) DeleteVaultFile(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	vault, ok := h.loadVault(c, userID)
+	if !ok {
+		return
+	}
+	file, ok := h.loadVaultFile(c, vault, userID)
+	if !ok {
+		return
+	}
+
+	if err := purgeFile(h.fileRepo, h.versionRepo, h.grantRepo, file); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除文件失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "文件删除成功",
+	})
+}
+
+// loadVault 按路径参数 id 获取当前用户的保险库根文件夹，失败时直接写入响应
+func (h *VaultHandler) loadVault(c *gin.Context, userID string) (*models.Folder, bool) {
+	vaultID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的保险库ID"})
+		return nil, false
+	}
+
+	folder, err := h.folderRepo.GetFolderByID(uint(vaultID), userID)
+	if err != nil {
+		if isRecordNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "保险库不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取保险库失败"})
+		}
+		return nil, false
+	}
+	if !folder.IsVaultRoot() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "保险库不存在"})
+		return nil, false
+	}
+	return folder, true
+}
+
+// loadVaultFolder 获取保险库中的文件夹，folderID 为空表示保险库根文件夹
+func (h *VaultHandler) loadVaultFolder(c *gin.Context, vault *models.Folder, folderID *uint) (*models.Folder, bool) {
+	if folderID == nil || *folderID == vault.ID {
+		return vault, true
+	}
+
+	folder, err := h.folderRepo.GetFolderByID(*folderID, vault.UserID)
+	if err != nil && !isRecordNotFound(err) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件夹信息失败"})
+		return nil, false
+	}
+	if err != nil || folder.VaultID == nil || *folder.VaultID != vault.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "保险库中不存在该文件夹"})
+		return nil, false
+	}
+	return folder, true
+}
+
+// loadVaultFile 按路径参数 file_id 获取保险库中的文件
+func (h *VaultHandler) loadVaultFile(c *gin.Context, vault *models.Folder, userID string) (*models.File, bool) {
+	fileID, err := strconv.ParseUint(c.Param("file_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件ID"})
+		return nil, false
+	}
+
+	file, err := h.fileRepo.GetFileByID(uint(fileID), userID)
+	if err != nil && !isRecordNotFound(err) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件信息失败"})
+		return nil, false
+	}
+	if err != nil || file.VaultID == nil || *file.VaultID != vault.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "保险库中不存在该文件"})
+		return nil, false
+	}
+	return file, true
+}
+
+// buildEnvelopes 校验信封对应的设备属于当前用户，每台设备最多一个信封
+func (h *VaultHandler) buildEnvelopes(c *gin.Context, userID string, inputs []models.VaultKeyEnvelopeInput) ([]models.VaultKeyEnvelope, bool) {
+	if len(inputs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "至少需要一个设备的密钥信封"})
+		return nil, false
+	}
+	if len(inputs) > models.MaxVaultDevicesPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "密钥信封数量超过设备数量上限"})
+		return nil, false
+	}
+
+	envelopes := make([]models.VaultKeyEnvelope, 0, len(inputs))
+	seen := make(map[uint]bool, len(inputs))
+	for _, input := range inputs {
+		algorithm := strings.TrimSpace(input.Algorithm)
+		if input.DeviceID == 0 || algorithm == "" || len(algorithm) > 50 ||
+			input.WrappedKey == "" || len(input.WrappedKey) > models.MaxVaultWrappedKeyLen {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的密钥信封"})
+			return nil, false
+		}
+		if seen[input.DeviceID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "同一设备只能提供一个密钥信封"})
+			return nil, false
+		}
+		seen[input.DeviceID] = true
+
+		device, err := h.vaultRepo.GetVaultDevice(input.DeviceID, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取设备失败"})
+			return nil, false
+		}
+		if device == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "设备不存在: " + strconv.FormatUint(uint64(input.DeviceID), 10)})
+			return nil, false
+		}
+
+		envelopes = append(envelopes, models.VaultKeyEnvelope{
+			DeviceID:   input.DeviceID,
+			UserID:     userID,
+			Algorithm:  algorithm,
+			WrappedKey: input.WrappedKey,
+		})
+	}
+	return envelopes, true
+}
+
+// validateEncryptedKey 校验包装后的文件密钥，失败时直接写入响应
+func validateEncryptedKey(c *gin.Context, encryptedKey string) bool {
+	if encryptedKey == "" || len(encryptedKey) > models.MaxVaultWrappedKeyLen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少或无效的文件密钥"})
+		return false
+	}
+	return true
+}
+
+// storeBlob 检查大小和配额后保存表单中的密文，replacedSize 为被替换内容的大小，替换后会释放
+func (h *VaultHandler) storeBlob(c *gin.Context, userID string, replacedSize int64) (*utils.StoredFile, bool) {
+	blob, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请选择要上传的文件"})
+		return nil, false
+	}
+	defer blob.Close()
+
+	if header.Size == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件不能为空"})
+		return nil, false
+	}
+	if maxSize := config.GetUploadConfig().MaxFileSize; header.Size > maxSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件大小超过限制"})
+		return nil, false
+	}
+
+	usedSpace, storageLimit, err := h.userRepo.GetUserStorageInfo(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取存储信息失败"})
+		return nil, false
+	}
+	if !utils.ValidateFileSize(header.Size, storageLimit, usedSpace-replacedSize) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "存储空间不足"})
+		return nil, false
+	}
+
+	stored, err := utils.StoreVaultBlob(blob, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件失败"})
+		return nil, false
+	}
+	if stored.Size != header.Size {
+		os.Remove(stored.AbsolutePath)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "文件写入不完整"})
+		return nil, false
+	}
+	return stored, true
+}
+
+// deleteVaultFolder 递归删除保险库中的文件夹并删除物理文件，失败时直接写入响应
+func (h *VaultHandler) deleteVaultFolder(c *gin.Context, folder *models.Folder) bool {
+	result, err := h.folderRepo.DeleteFolderRecursive(folder.ID, folder.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除文件夹失败"})
+		return false
+	}
+
+	// 事务提交后再删除物理文件
+	for _, storedPath := range result.StoredPaths {
+		os.Remove(utils.GetFileAbsolutePath(storedPath))
+	}
+	return true
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/config"
+	"backend/database"
+	"backend/middleware"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/webdav"
+)
+
+// davPrefix WebDAV 服务的URL前缀
+const davPrefix = "/dav"
+
+// WebDAVHandler 通过 WebDAV 将用户自己的文件夹树挂载为网络驱动器
+type WebDAVHandler struct {
+	driveRepos
+	appTokenRepo database.AppTokenRepositoryInterface
+	config       *config.WebDAVConfig
+
+	// 按IP记录认证失败，防止暴力破解密码
+	failures *middleware.RateLimiter
+
+	// 每个用户独立的锁表，路径只在各自的文件夹树内有意义
+	locksMu sync.Mutex
+	locks   map[string]webdav.LockSystem
+}
+
+// NewWebDAVHandler 创建 WebDAV 处理器实例
+func NewWebDAVHandler(userRepo database.UserRepositoryInterface, appTokenRepo database.AppTokenRepositoryInterface, fileRepo database.FileRepositoryInterface, folderRepo database.FolderRepositoryInterface, versionRepo database.FileVersionRepositoryInterface, grantRepo database.GrantRepositoryInterface, indexer *services.ContentIndexService, scanner *services.VirusScanService) *WebDAVHandler {
+	davConfig := config.GetWebDAVConfig()
+	return &WebDAVHandler{
+		driveRepos: driveRepos{
+			userRepo:    userRepo,
+			fileRepo:    fileRepo,
+			folderRepo:  folderRepo,
+			versionRepo: versionRepo,
+			grantRepo:   grantRepo,
+			indexer:     indexer,
+			scanner:     scanner,
+			maxFileSize: func(string) int64 { return davConfig.MaxFileSize },
+		},
+		appTokenRepo: appTokenRepo,
+		config:       davConfig,
+		failures:     middleware.NewRateLimiter(davConfig.MaxAuthFailures, davConfig.AuthFailureWindow),
+		locks:        make(map[string]webdav.LockSystem),
+	}
+}
+
+// WebDAVMethods WebDAV 服务需要处理的全部HTTP方法
+var WebDAVMethods = []string{
+	"OPTIONS", "GET", "HEAD", "PUT", "DELETE", "PROPFIND", "PROPPATCH", "MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK",
+}
+
+// Authenticate WebDAV 客户端无法使用 Cookie 登录，使用 HTTP Basic 认证：
+// 用户名加账号密码或应用专用密码
+func (h *WebDAVHandler) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username, password, ok := c.Request.BasicAuth()
+		if !ok {
+			davChallenge(c)
+			return
+		}
+
+		clientIP := c.ClientIP()
+		if h.failures.Blocked(clientIP) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "认证失败次数过多，请稍后再试"})
+			return
+		}
+
+		user, err := authenticatePassword(h.userRepo, h.appTokenRepo, username, password)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if user == nil {
+			h.failures.Allow(clientIP)
+			davChallenge(c)
+			return
+		}
+
+		c.Set("currentUser", user)
+		c.Next()
+	}
+}
+
+// davChallenge 返回 401 并要求客户端提供 Basic 认证信息
+func davChallenge(c *gin.Context) {
+	c.Header("WWW-Authenticate", `Basic realm="star-cloud", charset="UTF-8"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "请使用用户名和密码或应用专用密码登录"})
+}
+
+// authenticatePassword 校验用户名和密码，密码可以是账号密码或该用户的应用专用密码，失败时返回 nil。
+// WebDAV 和 SFTP 共用
+func authenticatePassword(userRepo database.UserRepositoryInterface, appTokenRepo database.AppTokenRepositoryInterface, username, password string) (*models.User, error) {
+	user, err := userRepo.GetUserByUsername(username)
+	if err != nil {
+		if isRecordNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if user == nil {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(password, models.AppTokenPrefix) {
+		token, err := appTokenRepo.GetAppTokenByHash(utils.HashAppToken(password))
+		if err != nil {
+			return nil, err
+		}
+		if token != nil && token.UserID == user.UUID {
+			// 客户端每个请求都会认证，最近使用时间每分钟最多更新一次
+			if token.LastUsedAt == nil || time.Since(*token.LastUsedAt) > time.Minute {
+				appTokenRepo.TouchAppToken(token.ID)
+			}
+			return user, nil
+		}
+	}
+
+	// 账号密码与登录接口的校验方式一致
+	if subtle.ConstantTimeCompare([]byte(password), []byte(user.Password)) == 1 {
+		return user, nil
+	}
+	return nil, nil
+}
+
+// lockSystem 获取用户的锁表
+func (h *WebDAVHandler) lockSystem(userID string) webdav.LockSystem {
+	h.locksMu.Lock()
+	defer h.locksMu.Unlock()
+
+	ls, exists := h.locks[userID]
+	if !exists {
+		ls = webdav.NewMemLS()
+		h.locks[userID] = ls
+	}
+	return ls
+}
+
+// davUploadKey 请求上下文中保存 PUT 请求体读取状态的键
+type davUploadKey struct{}
+
+// davUpload 记录 PUT 请求体的读取情况，请求体不完整时不保存文件
+type davUpload struct {
+	expected int64 // Content-Length，未知时为 -1
+	received int64
+	bodyErr  error
+}
+
+// complete 请求体是否已完整读取
+func (u *davUpload) complete() bool {
+	return u.bodyErr == nil && (u.expected < 0 || u.received == u.expected)
+}
+
+// davBodyReader 读取请求体时更新 davUpload
+type davBodyReader struct {
+	io.ReadCloser
+	upload *davUpload
+}
+
+func (r *davBodyReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.upload.received += int64(n)
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.upload.bodyErr = err
+	}
+	return n, err
+}
+
+// ServeDAV 处理 /dav/ 下的全部 WebDAV 请求，根目录对应当前用户自己的根目录
+func (h *WebDAVHandler) ServeDAV(c *gin.Context) {
+	currentUser, _ := c.Get("currentUser")
+	user, _ := currentUser.(*models.User)
+	if user == nil {
+		davChallenge(c)
+		return
+	}
+
+	fs := newDriveFS(&h.driveRepos, user.UUID)
+	request := c.Request
+
+	switch request.Method {
+	case http.MethodPut:
+		// 声明了长度的上传提前检查大小和配额，未声明长度的上传在写入时检查
+		if request.ContentLength > h.config.MaxFileSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "文件大小超过限制"})
+			return
+		}
+		if request.ContentLength > 0 {
+			usedSpace, storageLimit, err := h.userRepo.GetUserStorageInfo(user.UUID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "获取存储信息失败"})
+				return
+			}
+			if !utils.ValidateFileSize(request.ContentLength, storageLimit, usedSpace) {
+				c.JSON(http.StatusInsufficientStorage, gin.H{"error": "存储空间不足"})
+				return
+			}
+		}
+		upload := &davUpload{expected: request.ContentLength}
+		request.Body = &davBodyReader{ReadCloser: request.Body, upload: upload}
+		request = request.WithContext(context.WithValue(request.Context(), davUploadKey{}, upload))
+	case http.MethodGet, http.MethodHead:
+		// 被隔离的文件仍会出现在目录列表中，但不能下载
+		node, err := fs.resolve(strings.TrimPrefix(request.URL.Path, davPrefix))
+		if err == nil && node.file != nil && rejectInfectedFile(c, node.file) {
+			return
+		}
+	}
+
+	handler := &webdav.Handler{
+		Prefix:     davPrefix,
+		FileSystem: fs,
+		LockSystem: h.lockSystem(user.UUID),
+	}
+	handler.ServeHTTP(c.Writer, request)
+}
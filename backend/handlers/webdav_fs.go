@@ -0,0 +1,724 @@
+package handlers
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"backend/database"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+
+	"golang.org/x/net/webdav"
+)
+
+// errDavIsDirectory 写入的目标是文件夹
+var errDavIsDirectory = errors.New("目标是一个文件夹")
+
+// errDavTooLarge 写入的内容超过单文件上限或剩余存储空间
+var errDavTooLarge = errors.New("文件大小超过限制或存储空间不足")
+
+// errDavIncomplete 请求体没有完整读取，不保存写入的内容
+var errDavIncomplete = errors.New("上传内容不完整")
+
+// davNode 路径解析的结果：folder 与 file 均为 nil 表示根目录
+type davNode struct {
+	folder *models.Folder
+	file   *models.File
+}
+
+// folderID 节点作为父文件夹时的ID，根目录为 nil
+func (n *davNode) folderID() *uint {
+	if n.folder == nil {
+		return nil
+	}
+	return &n.folder.ID
+}
+
+// driveRepos 以路径方式读写用户文件夹树所需的仓库和服务，WebDAV 与 SFTP 共用
+type driveRepos struct {
+	userRepo    database.UserRepositoryInterface
+	fileRepo    database.FileRepositoryInterface
+	folderRepo  database.FolderRepositoryInterface
+	versionRepo database.FileVersionRepositoryInterface
+	grantRepo   database.GrantRepositoryInterface
+	indexer     *services.ContentIndexService
+	scanner     *services.VirusScanService
+
+	// maxFileSize 按文件名返回单个文件的大小上限
+	maxFileSize func(name string) int64
+}
+
+// driveFS 将用户的 Folder 树和 File 记录映射为 webdav.FileSystem，每个请求创建一个实例
+type driveFS struct {
+	drive  *driveRepos
+	userID string
+
+	// 请求内的路径解析缓存，PROPFIND 列目录时每个子项都会重新解析一次路径；任何写操作后清空
+	cache map[string]*davNode
+}
+
+func newDriveFS(drive *driveRepos, userID string) *driveFS {
+	return &driveFS{
+		drive:  drive,
+		userID: userID,
+		cache:  make(map[string]*davNode),
+	}
+}
+
+// davClean 规范化 WebDAV 路径，结果总是以 / 开头
+func davClean(name string) string {
+	return path.Clean("/" + name)
+}
+
+// invalidate 清空路径解析缓存
+func (fs *driveFS) invalidate() {
+	fs.cache = make(map[string]*davNode)
+}
+
+// resolve 逐级解析路径，不存在时返回 os.ErrNotExist
+func (fs *driveFS) resolve(name string) (*davNode, error) {
+	name = davClean(name)
+	if name == "/" {
+		return &davNode{}, nil
+	}
+	if node, exists := fs.cache[name]; exists {
+		return node, nil
+	}
+
+	parent, err := fs.resolve(path.Dir(name))
+	if err != nil {
+		return nil, err
+	}
+	if parent.file != nil {
+		return nil, os.ErrNotExist
+	}
+
+	node, err := fs.lookup(parent.folderID(), path.Base(name))
+	if err != nil {
+		return nil, err
+	}
+	fs.cache[name] = node
+	return node, nil
+}
+
+// lookup 在文件夹中按名称查找子项。文件夹与文件同名时 WebDAV 无法同时表示，文件夹优先。
+// 保险库只能由客户端解密，WebDAV 和 SFTP 中不显示；保险库都位于根目录，隐藏根文件夹即可隐藏全部内容
+func (fs *driveFS) lookup(parentID *uint, name string) (*davNode, error) {
+	folder, err := fs.drive.folderRepo.GetFolderByNameInParent(fs.userID, name, parentID)
+	if err == nil && !folder.IsVault() {
+		return &davNode{folder: folder}, nil
+	}
+	if err != nil && !isRecordNotFound(err) {
+		return nil, err
+	}
+
+	file, err := fs.drive.fileRepo.GetFileByNameInFolder(fs.userID, name, parentID)
+	if err == nil {
+		return &davNode{file: file}, nil
+	}
+	if isRecordNotFound(err) {
+		return nil, os.ErrNotExist
+	}
+	return nil, err
+}
+
+// resolveParent 解析路径的父文件夹并校验最后一段名称，用于创建、写入和移动的目标
+func (fs *driveFS) resolveParent(name string) (*davNode, string, error) {
+	parent, err := fs.resolve(path.Dir(name))
+	if err != nil {
+		return nil, "", err
+	}
+	if parent.file != nil {
+		return nil, "", os.ErrNotExist
+	}
+	base := path.Base(name)
+	if !validateItemName(base) {
+		return nil, "", os.ErrInvalid
+	}
+	return parent, base, nil
+}
+
+// info 构造节点的文件信息
+func (fs *driveFS) info(name string, node *davNode) *davFileInfo {
+	switch {
+	case node.file != nil:
+		return &davFileInfo{
+			name:    node.file.Name,
+			size:    node.file.Size,
+			modTime: node.file.UpdatedAt,
+			etag:    node.file.Checksum,
+		}
+	case node.folder != nil:
+		return &davFileInfo{name: node.folder.Name, modTime: node.folder.UpdatedAt, dir: true}
+	default:
+		return &davFileInfo{name: path.Base(name), modTime: time.Now(), dir: true}
+	}
+}
+
+// children 列出文件夹的子文件夹和文件，同时写入路径解析缓存，根目录不列出保险库
+func (fs *driveFS) children(dirPath string, node *davNode) ([]os.FileInfo, error) {
+	var folders []models.Folder
+	if node.folder == nil {
+		all, err := fs.drive.folderRepo.GetFoldersByUserID(fs.userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, folder := range all {
+			if folder.ParentID == nil && !folder.IsVault() {
+				folders = append(folders, folder)
+			}
+		}
+	} else {
+		subFolders, err := fs.drive.folderRepo.GetSubFolders(node.folder.ID, fs.userID)
+		if err != nil {
+			return nil, err
+		}
+		folders = subFolders
+	}
+
+	files, err := fs.drive.fileRepo.GetFilesByUserID(fs.userID, node.folderID())
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]os.FileInfo, 0, len(folders)+len(files))
+	seen := make(map[string]bool)
+	for i := range folders {
+		child := &davNode{folder: &folders[i]}
+		childPath := path.Join(dirPath, folders[i].Name)
+		seen[folders[i].Name] = true
+		fs.cache[childPath] = child
+		infos = append(infos, fs.info(childPath, child))
+	}
+	for i := range files {
+		if seen[files[i].Name] {
+			continue
+		}
+		child := &davNode{file: &files[i]}
+		childPath := path.Join(dirPath, files[i].Name)
+		seen[files[i].Name] = true
+		fs.cache[childPath] = child
+		infos = append(infos, fs.info(childPath, child))
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	return infos, nil
+}
+
+// Stat 获取路径对应的文件信息
+func (fs *driveFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	node, err := fs.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.info(davClean(name), node), nil
+}
+
+// OpenFile 打开文件或文件夹；PUT 和 COPY 以 O_CREATE|O_TRUNC 打开时创建或覆盖文件，内容在 Close 时保存。
+// PROPPATCH 仅以 O_RDWR 打开以读写属性，不能当作覆盖写入
+func (fs *driveFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	name = davClean(name)
+	if flag&(os.O_CREATE|os.O_TRUNC) != 0 {
+		return fs.create(ctx, name, flag)
+	}
+
+	node, err := fs.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	info := fs.info(name, node)
+	if node.file == nil {
+		return &davDir{fs: fs, path: name, node: node, info: info}, nil
+	}
+	return &davFile{file: node.file, info: info}, nil
+}
+
+// create 打开写入目标：校验父文件夹、名称和配额，返回在 Close 时登记文件的写入器
+func (fs *driveFS) create(ctx context.Context, name string, flag int) (webdav.File, error) {
+	if name == "/" {
+		return nil, errDavIsDirectory
+	}
+	parent, base, err := fs.resolveParent(name)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := fs.resolve(name)
+	switch {
+	case err == nil && node.file == nil:
+		return nil, errDavIsDirectory
+	case err == nil && flag&os.O_EXCL != 0:
+		return nil, os.ErrExist
+	case err != nil && !os.IsNotExist(err):
+		return nil, err
+	case err != nil && flag&os.O_CREATE == 0:
+		return nil, os.ErrNotExist
+	}
+
+	limit, err := fs.writeLimit(base)
+	if err != nil {
+		return nil, err
+	}
+
+	// COPY 在服务端内部写入，没有请求体状态
+	upload, _ := ctx.Value(davUploadKey{}).(*davUpload)
+	return newDavWriter(fs, parent.folderID(), base, limit, upload), nil
+}
+
+// writeLimit 写入文件的上限：单文件上限与剩余存储空间中较小的一个（被替换的内容保留为历史版本，仍占用配额）
+func (fs *driveFS) writeLimit(name string) (int64, error) {
+	usedSpace, storageLimit, err := fs.drive.userRepo.GetUserStorageInfo(fs.userID)
+	if err != nil {
+		return 0, err
+	}
+	limit := fs.drive.maxFileSize(name)
+	if remaining := storageLimit - usedSpace; remaining < limit {
+		limit = remaining
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	return limit, nil
+}
+
+// commit 写入完成后登记文件：同名文件的原内容保存为历史版本，新文件创建记录，随后建立索引并扫描
+func (fs *driveFS) commit(parentID *uint, name string, stored *utils.StoredFile) error {
+	h := fs.drive
+	defer fs.invalidate()
+
+	// 写入期间可能有同名文件被创建，保存前重新查找
+	existing, err := h.fileRepo.GetFileByNameInFolder(fs.userID, name, parentID)
+	if err != nil && !isRecordNotFound(err) {
+		os.Remove(stored.AbsolutePath)
+		return err
+	}
+
+	var file *models.File
+	if err == nil {
+		replaced, err := archiveReplacedContent(h.versionRepo, existing)
+		if err != nil {
+			os.Remove(stored.AbsolutePath)
+			return err
+		}
+
+		existing.Size = stored.Size
+		existing.Type = stored.FileType
+		existing.Path = stored.Path
+		existing.Checksum = stored.Checksum
+		existing.EncryptionKeyID = stored.KeyID
+		existing.UploadedBy = fs.userID
+		h.scanner.ResetScanState(existing)
+		if err := h.fileRepo.UpdateFile(existing); err != nil {
+			os.Remove(stored.AbsolutePath)
+			replaced.Rollback()
+			return err
+		}
+		replaced.Commit()
+		pruneFileVersions(h.versionRepo, h.userRepo, existing.ID, fs.userID)
+		file = existing
+	} else {
+		file = &models.File{
+			Name:            name,
+			Size:            stored.Size,
+			Type:            stored.FileType,
+			Path:            stored.Path,
+			UserID:          fs.userID,
+			FolderID:        parentID,
+			Checksum:        stored.Checksum,
+			UploadedBy:      fs.userID,
+			EncryptionKeyID: stored.KeyID,
+		}
+		h.scanner.ResetScanState(file)
+		if err := h.fileRepo.CreateFile(file); err != nil {
+			os.Remove(stored.AbsolutePath)
+			return err
+		}
+	}
+
+	h.indexer.Notify()
+	// 发现病毒时文件被隔离并通知所有者，写入本身仍视为成功
+	h.scanner.ScanUpload(file)
+	return nil
+}
+
+// Mkdir 创建文件夹
+func (fs *driveFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	name = davClean(name)
+	if name == "/" {
+		return os.ErrExist
+	}
+	parent, base, err := fs.resolveParent(name)
+	if err != nil {
+		return err
+	}
+	if _, err := fs.resolve(name); err == nil {
+		return os.ErrExist
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	folder := &models.Folder{
+		Name:     base,
+		UserID:   fs.userID,
+		Category: "all",
+		ParentID: parent.folderID(),
+	}
+	if err := fs.drive.folderRepo.CreateFolder(folder); err != nil {
+		return err
+	}
+	fs.invalidate()
+	return nil
+}
+
+// RemoveAll 删除文件（含历史版本）或递归删除文件夹，与删除接口的行为一致
+func (fs *driveFS) RemoveAll(ctx context.Context, name string) error {
+	name = davClean(name)
+	if name == "/" {
+		return os.ErrInvalid
+	}
+	node, err := fs.resolve(name)
+	if err != nil {
+		return err
+	}
+	defer fs.invalidate()
+
+	h := fs.drive
+	if node.file != nil {
+		return purgeFile(h.fileRepo, h.versionRepo, h.grantRepo, node.file)
+	}
+
+	result, err := h.folderRepo.DeleteFolderRecursive(node.folder.ID, fs.userID)
+	if err != nil {
+		return err
+	}
+	// 事务提交后再删除物理文件
+	for _, storedPath := range result.StoredPaths {
+		os.Remove(utils.GetFileAbsolutePath(storedPath))
+		// 被隔离的文件内容位于隔离目录
+		os.Remove(utils.GetQuarantinePath(storedPath))
+	}
+	return nil
+}
+
+// Rename 移动或重命名文件和文件夹。目标已存在且允许覆盖时，webdav 包会先删除目标
+func (fs *driveFS) Rename(ctx context.Context, oldName, newName string) error {
+	oldName, newName = davClean(oldName), davClean(newName)
+	if oldName == "/" || newName == "/" {
+		return os.ErrInvalid
+	}
+	node, err := fs.resolve(oldName)
+	if err != nil {
+		return err
+	}
+	parent, base, err := fs.resolveParent(newName)
+	if err != nil {
+		return err
+	}
+	// 数据库排序规则不区分大小写时，仅修改大小写的重命名会解析到自身
+	if target, err := fs.resolve(newName); err == nil {
+		if !sameDavNode(target, node) {
+			return os.ErrExist
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	defer fs.invalidate()
+
+	h := fs.drive
+	if node.folder != nil {
+		if err := h.folderRepo.MoveFolder(node.folder.ID, fs.userID, parent.folderID(), base); err != nil {
+			if errors.Is(err, database.ErrFolderCycle) {
+				return os.ErrInvalid
+			}
+			return err
+		}
+		return nil
+	}
+
+	file := node.file
+	if base == file.Name {
+		return h.fileRepo.MoveFile(file.ID, fs.userID, parent.folderID())
+	}
+
+	// 被隔离的文件不在上传目录中，无法重命名
+	if file.IsInfected() {
+		return os.ErrPermission
+	}
+
+	// 先重命名物理文件，数据库更新失败时还原
+	stored, err := utils.RenameStoredFile(file.Path, base)
+	if err != nil {
+		return err
+	}
+	oldPath, oldFileName, oldType, oldFolderID := file.Path, file.Name, file.Type, file.FolderID
+	file.Name = base
+	file.Path = stored.Path
+	file.Type = stored.FileType
+	file.FolderID = parent.folderID()
+	if err := h.fileRepo.UpdateFile(file); err != nil {
+		os.Rename(stored.AbsolutePath, utils.GetFileAbsolutePath(oldPath))
+		file.Name, file.Path, file.Type, file.FolderID = oldFileName, oldPath, oldType, oldFolderID
+		return err
+	}
+	return nil
+}
+
+// sameDavNode 两个节点是否指向同一条记录
+func sameDavNode(a, b *davNode) bool {
+	switch {
+	case a.folder != nil && b.folder != nil:
+		return a.folder.ID == b.folder.ID
+	case a.file != nil && b.file != nil:
+		return a.file.ID == b.file.ID
+	default:
+		return false
+	}
+}
+
+// davFileInfo 实现 os.FileInfo，并提供基于校验和的 ETag 和按扩展名推断的内容类型
+type davFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+	etag    string
+}
+
+func (fi *davFileInfo) Name() string       { return fi.name }
+func (fi *davFileInfo) Size() int64        { return fi.size }
+func (fi *davFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *davFileInfo) IsDir() bool        { return fi.dir }
+func (fi *davFileInfo) Sys() interface{}   { return nil }
+
+func (fi *davFileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+// ETag 实现 webdav.ETager，没有校验和的旧记录使用默认的修改时间加大小
+func (fi *davFileInfo) ETag(ctx context.Context) (string, error) {
+	if fi.etag == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	return `"` + fi.etag + `"`, nil
+}
+
+// ContentType 实现 webdav.ContentTyper，避免列目录时为每个文件读取内容探测类型
+func (fi *davFileInfo) ContentType(ctx context.Context) (string, error) {
+	if contentType := mime.TypeByExtension(path.Ext(fi.name)); contentType != "" {
+		return contentType, nil
+	}
+	return "application/octet-stream", nil
+}
+
+// davDeadProps 接受客户端写入的自定义属性（如 Windows 资源管理器写入的 Win32 时间戳）但不保存，
+// 避免客户端因 PROPPATCH 失败而中止复制
+type davDeadProps struct{}
+
+func (davDeadProps) DeadProps() (map[xml.Name]webdav.Property, error) {
+	return nil, nil
+}
+
+func (davDeadProps) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	propstat := webdav.Propstat{Status: http.StatusOK}
+	for _, patch := range patches {
+		for _, prop := range patch.Props {
+			propstat.Props = append(propstat.Props, webdav.Property{XMLName: prop.XMLName})
+		}
+	}
+	return []webdav.Propstat{propstat}, nil
+}
+
+// davDir 只读打开的文件夹
+type davDir struct {
+	davDeadProps
+	fs      *driveFS
+	path    string
+	node    *davNode
+	info    os.FileInfo
+	entries []os.FileInfo
+	loaded  bool
+	offset  int
+}
+
+func (d *davDir) Close() error                                 { return nil }
+func (d *davDir) Read(p []byte) (int, error)                   { return 0, errDavIsDirectory }
+func (d *davDir) Write(p []byte) (int, error)                  { return 0, errDavIsDirectory }
+func (d *davDir) Stat() (os.FileInfo, error)                   { return d.info, nil }
+func (d *davDir) Seek(offset int64, whence int) (int64, error) { return 0, nil }
+
+// Readdir 与 os.File.Readdir 语义一致
+func (d *davDir) Readdir(count int) ([]os.FileInfo, error) {
+	if !d.loaded {
+		entries, err := d.fs.children(d.path, d.node)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.loaded = true
+	}
+
+	remaining := d.entries[d.offset:]
+	if count <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(remaining) {
+		count = len(remaining)
+	}
+	d.offset += count
+	return remaining[:count], nil
+}
+
+// davFile 只读打开的文件，第一次读取时才打开物理文件，列目录时不会打开
+type davFile struct {
+	davDeadProps
+	file   *models.File
+	info   os.FileInfo
+	handle *utils.StoredContent
+}
+
+func (f *davFile) open() error {
+	if f.handle != nil {
+		return nil
+	}
+	if f.file.IsInfected() {
+		return os.ErrPermission
+	}
+	handle, err := utils.OpenStoredContent(utils.GetFileAbsolutePath(f.file.Path), f.file.EncryptionKeyID)
+	if err != nil {
+		return err
+	}
+	f.handle = handle
+	return nil
+}
+
+func (f *davFile) Read(p []byte) (int, error) {
+	if err := f.open(); err != nil {
+		return 0, err
+	}
+	return f.handle.Read(p)
+}
+
+func (f *davFile) Seek(offset int64, whence int) (int64, error) {
+	if err := f.open(); err != nil {
+		return 0, err
+	}
+	return f.handle.Seek(offset, whence)
+}
+
+func (f *davFile) Close() error {
+	if f.handle == nil {
+		return nil
+	}
+	return f.handle.Close()
+}
+
+func (f *davFile) Write(p []byte) (int, error)              { return 0, os.ErrPermission }
+func (f *davFile) Readdir(count int) ([]os.FileInfo, error) { return nil, os.ErrInvalid }
+func (f *davFile) Stat() (os.FileInfo, error)               { return f.info, nil }
+
+// davWriter 写入打开的文件：内容通过管道流式写入存储，Close 时登记文件记录
+type davWriter struct {
+	davDeadProps
+	fs       *driveFS
+	parentID *uint
+	name     string
+	limit    int64
+	upload   *davUpload
+
+	pipe     *io.PipeWriter
+	done     chan struct{}
+	stored   *utils.StoredFile
+	storeErr error
+
+	written  int64
+	writeErr error
+	closed   bool
+}
+
+func newDavWriter(fs *driveFS, parentID *uint, name string, limit int64, upload *davUpload) *davWriter {
+	reader, writer := io.Pipe()
+	w := &davWriter{
+		fs:       fs,
+		parentID: parentID,
+		name:     name,
+		limit:    limit,
+		upload:   upload,
+		pipe:     writer,
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(w.done)
+		w.stored, w.storeErr = utils.StoreFileContent(reader, name, fs.userID)
+		// 存储提前失败时让后续写入立即返回错误
+		reader.CloseWithError(w.storeErr)
+	}()
+	return w
+}
+
+func (w *davWriter) Write(p []byte) (int, error) {
+	if w.writeErr != nil {
+		return 0, w.writeErr
+	}
+	if w.written+int64(len(p)) > w.limit {
+		w.writeErr = errDavTooLarge
+		return 0, w.writeErr
+	}
+	n, err := w.pipe.Write(p)
+	w.written += int64(n)
+	if err != nil {
+		w.writeErr = err
+	}
+	return n, err
+}
+
+// Close 结束写入：内容完整时登记文件，否则删除已写入的内容
+func (w *davWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	abortErr := w.writeErr
+	if abortErr == nil && w.upload != nil && !w.upload.complete() {
+		abortErr = errDavIncomplete
+	}
+	if abortErr != nil {
+		w.pipe.CloseWithError(abortErr)
+	} else {
+		w.pipe.Close()
+	}
+	<-w.done
+
+	if w.storeErr != nil {
+		return w.storeErr
+	}
+	if abortErr != nil {
+		os.Remove(w.stored.AbsolutePath)
+		return abortErr
+	}
+	return w.fs.commit(w.parentID, w.name, w.stored)
+}
+
+func (w *davWriter) Stat() (os.FileInfo, error) {
+	return &davFileInfo{name: w.name, size: w.written, modTime: time.Now()}, nil
+}
+
+func (w *davWriter) Read(p []byte) (int, error)                   { return 0, os.ErrInvalid }
+func (w *davWriter) Seek(offset int64, whence int) (int64, error) { return 0, os.ErrInvalid }
+func (w *davWriter) Readdir(count int) ([]os.FileInfo, error)     { return nil, os.ErrInvalid }
@@ -94,6 +94,21 @@ func (rl *RateLimiter) Allow(clientID string) bool {
 	return true
 }
 
+// Blocked 检查客户端在当前时间窗口内是否已达到限制，不记录本次请求
+func (rl *RateLimiter) Blocked(clientID string) bool {
+	rl.mutex.RLock()
+	defer rl.mutex.RUnlock()
+
+	windowStart := time.Now().Add(-rl.window)
+	count := 0
+	for _, reqTime := range rl.requests[clientID] {
+		if reqTime.After(windowStart) {
+			count++
+		}
+	}
+	return count >= rl.limit
+}
+
 // UserRateLimit 基于用户的速率限制
 func UserRateLimit(limit int, window time.Duration) gin.HandlerFunc {
 	limiter := NewRateLimiter(limit, window)
@@ -0,0 +1,97 @@
+package models
+
+import "time"
+
+// 最近访问列表相关限制
+const (
+	MaxRecentItems     = 200 // 每个用户保留的最近访问记录数，超出时删除最早的记录
+	DefaultRecentLimit = 50
+	MaxRecentLimit     = 200
+)
+
+// ActivitySortTime 收藏与最近访问列表的游标排序字段（收藏时间或访问时间）
+const ActivitySortTime = "time"
+
+// Favorite 用户收藏（星标）的文件、文件夹或URL文件，ItemType 取值与搜索结果的对象类型一致
+type Favorite struct {
+	UserID    string    `gorm:"primaryKey;type:varchar(50)" json:"-"`
+	ItemType  string    `gorm:"primaryKey;type:varchar(20)" json:"type"`
+	ItemID    uint      `gorm:"primaryKey;autoIncrement:false" json:"id"`
+	CreatedAt time.Time `gorm:"type:timestamp(3);default:CURRENT_TIMESTAMP(3)" json:"created_at"`
+}
+
+// TableName 指定表名
+func (Favorite) TableName() string {
+	return "favorites"
+}
+
+// RecentItem 用户最近访问的文件、文件夹或URL文件，同一对象只保留一条记录
+type RecentItem struct {
+	UserID      string    `gorm:"primaryKey;type:varchar(50)" json:"-"`
+	ItemType    string    `gorm:"primaryKey;type:varchar(20)" json:"type"`
+	ItemID      uint      `gorm:"primaryKey;autoIncrement:false" json:"id"`
+	AccessedAt  time.Time `gorm:"type:timestamp(3);default:CURRENT_TIMESTAMP(3)" json:"accessed_at"`
+	AccessCount int       `gorm:"type:int;default:1" json:"access_count"`
+}
+
+// TableName 指定表名
+func (RecentItem) TableName() string {
+	return "recent_items"
+}
+
+// ActivityQuery 收藏或最近访问列表的查询条件，按时间倒序
+type ActivityQuery struct {
+	UserID   string
+	ItemType string // file、folder、url_file，空表示全部
+	Limit    int
+	Cursor   *SearchCursor
+}
+
+// ActivityItem 收藏或最近访问列表中的一项
+type ActivityItem struct {
+	SearchItem
+	OwnerID     string     `json:"owner_id"`
+	FavoritedAt *time.Time `json:"favorited_at,omitempty"`
+	AccessedAt  *time.Time `json:"accessed_at,omitempty"`
+	AccessCount int        `json:"access_count,omitempty"`
+}
+
+// DecodeActivityCursor 解析收藏或最近访问列表的分页游标
+func DecodeActivityCursor(encoded string) (*SearchCursor, error) {
+	cursor, err := decodeCursor(encoded, ActivitySortTime, true)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.ItemType != SearchItemFile && cursor.ItemType != SearchItemFolder && cursor.ItemType != SearchItemUrlFile {
+		return nil, ErrInvalidCursor
+	}
+	if _, err := time.Parse(time.RFC3339Nano, cursor.Value); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// FavoriteRequest 收藏请求结构体
+type FavoriteRequest struct {
+	ItemType string `json:"type" binding:"required"` // file、folder、url_file
+	ID       uint   `json:"id" binding:"required"`
+}
+
+// RecentSettingsRequest 最近访问记录设置请求结构体
+type RecentSettingsRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"` // false 时停止记录并清空已有记录
+}
+
+// ActivityListResponse 收藏或最近访问列表响应结构体
+type ActivityListResponse struct {
+	Success    bool           `json:"success"`
+	Items      []ActivityItem `json:"items"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	HasMore    bool           `json:"has_more"`
+}
+
+// RecentListResponse 最近访问列表响应结构体，Enabled 为用户是否开启了最近访问记录
+type RecentListResponse struct {
+	ActivityListResponse
+	Enabled bool `json:"enabled"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// AppTokenPrefix 应用专用密码的固定前缀，便于识别和密钥扫描
+const AppTokenPrefix = "sct_"
+
+// MaxAppTokensPerUser 每个用户可创建的应用专用密码数量上限
+const MaxAppTokensPerUser = 20
+
+// AppToken 应用专用密码，用于 WebDAV 等无法使用 Cookie 登录的客户端，只保存 SHA-256 摘要
+type AppToken struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     string     `gorm:"type:varchar(50);not null;index" json:"-"`
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	TokenHash  string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	Hint       string     `gorm:"type:varchar(16);not null" json:"hint"` // 密码末尾几位，用于区分
+	LastUsedAt *time.Time `gorm:"type:timestamp;null" json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (AppToken) TableName() string {
+	return "app_tokens"
+}
+
+// CreateAppTokenRequest 创建应用专用密码请求结构体
+type CreateAppTokenRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// CreateAppTokenResponse 创建应用专用密码响应，明文密码只返回这一次
+type CreateAppTokenResponse struct {
+	Success  bool     `json:"success"`
+	Token    string   `json:"token"`
+	AppToken AppToken `json:"app_token"`
+}
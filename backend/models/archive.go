@@ -0,0 +1,28 @@
+package models
+
+// CreateArchiveRequest 打包下载请求结构体
+type CreateArchiveRequest struct {
+	FileIDs   []uint `json:"file_ids"`
+	FolderIDs []uint `json:"folder_ids"`
+	Name      string `json:"name"`  // 压缩包文件名，可选
+	Async     bool   `json:"async"` // 强制使用异步任务生成
+
+	IncludeMetadata bool `json:"include_metadata"` // 在压缩包根目录附带 metadata.json，导出描述和自定义元数据
+}
+
+// ArchiveMetadataFileName 导出描述和自定义元数据时在压缩包根目录生成的文件名
+const ArchiveMetadataFileName = "metadata.json"
+
+// ArchiveMetadataEntry metadata.json 中的一项，Path 为该文件或文件夹在压缩包内的路径
+type ArchiveMetadataEntry struct {
+	Path        string   `json:"path"`
+	Type        string   `json:"type"` // file、folder
+	Description string   `json:"description,omitempty"`
+	Metadata    Metadata `json:"metadata,omitempty"`
+}
+
+// ExtractArchiveRequest 解压请求结构体
+type ExtractArchiveRequest struct {
+	FolderID     *uint `json:"folder_id"`     // 解压目标文件夹，为空表示根目录
+	CreateFolder *bool `json:"create_folder"` // 是否以压缩包名称新建文件夹，默认是
+}
@@ -0,0 +1,62 @@
+package models
+
+// 批量操作类型
+const (
+	BatchOpMove   = "move"
+	BatchOpDelete = "delete"
+	BatchOpCopy   = "copy"
+	BatchOpTag    = "tag"
+	BatchOpUntag  = "untag"
+)
+
+// 批量操作的对象类型
+const (
+	BatchItemFile    = "file"
+	BatchItemUrlFile = "url_file"
+	BatchItemFolder  = "folder"
+)
+
+// MaxBatchOperations 单次批量请求允许的最大操作数
+const MaxBatchOperations = 500
+
+// BatchOperation 批量请求中的单个操作
+type BatchOperation struct {
+	Op             string `json:"op"`               // move、delete、copy、tag、untag
+	ItemType       string `json:"type"`             // file、url_file、folder
+	ID             uint   `json:"id"`               // 操作对象ID
+	TargetFolderID *uint  `json:"target_folder_id"` // move/copy 的目标文件夹，0表示根目录；copy 不传时为原文件夹
+	Name           string `json:"name"`             // copy 的副本名称，不传沿用原名称
+	OnConflict     string `json:"on_conflict"`      // 同名冲突策略：fail、rename、overwrite
+	TagIDs         []uint `json:"tag_ids"`          // tag/untag 操作的标签ID
+}
+
+// BatchRequest 批量操作请求结构体
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations" binding:"required"`
+	DryRun     bool             `json:"dry_run"` // 仅校验，不实际执行
+	Atomic     bool             `json:"atomic"`  // 任一操作失败时回滚全部操作
+}
+
+// BatchItemResult 单个操作的执行结果
+type BatchItemResult struct {
+	Index    int         `json:"index"`
+	Op       string      `json:"op"`
+	ItemType string      `json:"type"`
+	ID       uint        `json:"id"`
+	Success  bool        `json:"success"`
+	Code     string      `json:"code,omitempty"` // 失败原因：invalid、not_found、forbidden、conflict、quota_exceeded、rolled_back、skipped、internal
+	Error    string      `json:"error,omitempty"`
+	Result   interface{} `json:"result,omitempty"`
+}
+
+// BatchResponse 批量操作响应结构体
+type BatchResponse struct {
+	Success   bool              `json:"success"`
+	DryRun    bool              `json:"dry_run"`
+	Atomic    bool              `json:"atomic"`
+	Committed bool              `json:"committed"` // 变更是否已提交
+	Total     int               `json:"total"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []BatchItemResult `json:"results"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// 变更类型
+const (
+	ChangeCreate = "create" // 新建
+	ChangeUpdate = "update" // 内容、描述或其他属性被修改
+	ChangeMove   = "move"   // 移动到其他文件夹（可能同时重命名）
+	ChangeRename = "rename" // 在原文件夹中重命名
+	ChangeDelete = "delete" // 删除，删除文件夹时其中的内容一并删除，不再单独记录
+)
+
+// 变更日志相关限制
+const (
+	DefaultChangeLimit = 500
+	MaxChangeLimit     = 1000
+	MaxChangeWait      = 30 * time.Second    // 长轮询最长等待时间，需小于反向代理的读取超时
+	ChangeRetention    = 30 * 24 * time.Hour // 变更记录保留时间，更早的游标需要重新全量同步
+)
+
+// ErrChangeCursorExpired 游标之后的变更已被清理或游标无效，客户端需要重新全量同步
+var ErrChangeCursorExpired = errors.New("变更游标已失效")
+
+// Change 变更日志中的一条记录。Seq 在每个用户内从1开始连续递增，
+// Name、ParentID、Size、Checksum 为变更后对象的状态（删除时为删除前的状态）
+type Change struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	UserID    string    `gorm:"type:varchar(50);not null" json:"-"`
+	Seq       int64     `gorm:"not null" json:"seq"`
+	ItemType  string    `gorm:"type:varchar(20);not null" json:"item_type"` // 取值与搜索结果的对象类型一致
+	ItemID    uint      `gorm:"not null" json:"item_id"`
+	Action    string    `gorm:"type:varchar(20);not null" json:"action"`
+	Name      string    `gorm:"type:varchar(255);not null" json:"name"`     // 文件名、文件夹名或URL文件标题
+	ParentID  *uint     `json:"parent_id"`                                  // 所在文件夹ID，null表示根目录
+	Size      int64     `gorm:"not null;default:0" json:"size,omitempty"`   // 仅文件
+	Checksum  string    `gorm:"type:varchar(64)" json:"checksum,omitempty"` // 仅文件
+	CreatedAt time.Time `gorm:"type:timestamp(3);default:CURRENT_TIMESTAMP(3)" json:"created_at"`
+}
+
+// TableName 指定表名
+func (Change) TableName() string {
+	return "changes"
+}
+
+// ChangeListResponse 变更列表响应结构体，Cursor 为下次请求使用的游标
+type ChangeListResponse struct {
+	Success bool     `json:"success"`
+	Changes []Change `json:"changes"`
+	Cursor  string   `json:"cursor"`
+	HasMore bool     `json:"has_more"`
+}
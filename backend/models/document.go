@@ -21,8 +21,10 @@ func (Document) TableName() string {
 
 // DocumentListResponse 文档列表响应结构体
 type DocumentListResponse struct {
-	Success   bool       `json:"success"`
-	Documents []Document `json:"documents"`
+	Success    bool       `json:"success"`
+	Documents  []Document `json:"documents"`
+	NextCursor string     `json:"next_cursor,omitempty"` // 分页时下一页的游标
+	HasMore    bool       `json:"has_more"`
 }
 
 // DocumentResponse 单个文档响应结构体
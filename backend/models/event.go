@@ -0,0 +1,19 @@
+package models
+
+// 事件流中的事件类型
+const (
+	EventUpload       = "upload"       // 上传、打包等任务的进度和状态，数据为任务快照
+	EventChange       = "change"       // 文件、文件夹或URL文件的变更，数据为变更日志中的一条记录
+	EventQuota        = "quota"        // 存储空间使用情况变化，数据与 GET /api/storage 的 storage 字段一致
+	EventNotification = "notification" // 新的站内通知
+	EventReady        = "ready"        // 连接建立，断线期间的事件已补发
+	EventReset        = "reset"        // 无法补发断线期间的事件，客户端需要重新获取状态
+	EventPing         = "ping"         // WebSocket 心跳，SSE 使用注释行作为心跳
+)
+
+// Event 推送给客户端的事件，ID 用于断线重连时通过 Last-Event-ID 补发之后的事件
+type Event struct {
+	ID   string      `json:"id,omitempty"`
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
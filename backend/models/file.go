@@ -4,16 +4,27 @@ import "time"
 
 // File 结构体表示文件数据
 type File struct {
-	ID            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	Name          string    `gorm:"type:varchar(255);not null" json:"name"`
-	Size          int64     `gorm:"type:bigint;not null" json:"size"`
-	Type          string    `gorm:"type:varchar(50);not null" json:"type"`
-	Path          string    `gorm:"type:varchar(500);not null" json:"path"`
-	UserID        string    `gorm:"type:varchar(50);not null;index" json:"user_id"`
-	FolderID      *uint     `gorm:"index" json:"folder_id"`                // 所属文件夹ID，null表示根目录
-	ThumbnailData string    `gorm:"type:longtext" json:"thumbnail_data,omitempty"` // 缩略图数据，用于存储视频缩略图
-	CreatedAt     time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt     time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+	ID              uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name            string     `gorm:"type:varchar(255);not null" json:"name"`
+	Size            int64      `gorm:"type:bigint;not null" json:"size"`
+	Type            string     `gorm:"type:varchar(50);not null" json:"type"`
+	Path            string     `gorm:"type:varchar(500);not null" json:"path"`
+	UserID          string     `gorm:"type:varchar(50);not null;index" json:"user_id"`
+	FolderID        *uint      `gorm:"index" json:"folder_id"`                                   // 所属文件夹ID，null表示根目录
+	ThumbnailData   string     `gorm:"type:longtext" json:"thumbnail_data,omitempty"`            // 缩略图数据，用于存储视频缩略图
+	Checksum        string     `gorm:"type:varchar(64)" json:"checksum,omitempty"`               // 文件内容SHA-256校验和
+	UploadedBy      string     `gorm:"type:varchar(50)" json:"uploaded_by,omitempty"`            // 当前版本的上传者ID
+	HasThumbnail    bool       `gorm:"->;-:migration" json:"has_thumbnail,omitempty"`            // 是否有缩略图，仅在列表省略 thumbnail_data 时查询
+	Description     string     `gorm:"type:text" json:"description,omitempty"`                   // 用户填写的描述
+	Metadata        Metadata   `gorm:"type:json" json:"metadata,omitempty"`                      // 用户自定义键值对
+	ScanStatus      string     `gorm:"type:varchar(20);default:''" json:"scan_status,omitempty"` // 病毒扫描状态，空表示未扫描
+	ScanResult      string     `gorm:"type:varchar(255)" json:"scan_result,omitempty"`           // 命中的病毒特征名或扫描失败原因
+	ScannedAt       *time.Time `gorm:"type:timestamp;null" json:"scanned_at,omitempty"`          // 最近一次扫描时间
+	VaultID         *uint      `gorm:"index" json:"vault_id,omitempty"`                          // 所属保险库，内容和名称均为客户端加密
+	EncryptedKey    string     `gorm:"type:text" json:"encrypted_key,omitempty"`                 // 以保险库密钥包装的文件密钥，仅保险库文件
+	EncryptionKeyID uint64     `gorm:"not null;default:0" json:"-"`                              // 存储加密使用的数据密钥ID，0表示明文存储
+	CreatedAt       time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
 }
 
 // TableName 指定表名
@@ -23,8 +34,10 @@ func (File) TableName() string {
 
 // FileListResponse 文件列表响应结构体
 type FileListResponse struct {
-	Success bool   `json:"success"`
-	Files   []File `json:"files"`
+	Success    bool   `json:"success"`
+	Files      []File `json:"files"`
+	NextCursor string `json:"next_cursor,omitempty"` // 分页时下一页的游标
+	HasMore    bool   `json:"has_more"`
 }
 
 // FileResponse 单个文件响应结构体
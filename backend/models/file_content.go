@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// 文件内容索引状态
+const (
+	ContentIndexed     = "indexed"     // 已提取文本并写入全文索引
+	ContentUnsupported = "unsupported" // 文件类型不支持提取文本
+	ContentFailed      = "failed"      // 提取失败，文件更新后会重新尝试
+)
+
+// FileContent 文件内容全文索引，每个文件一条记录
+// SourcePath、SourceChecksum 记录建立索引时文件的存储路径和校验和，文件内容替换或恢复版本后任一变化即需要重建索引
+type FileContent struct {
+	FileID         uint      `gorm:"primaryKey;autoIncrement:false" json:"file_id"`
+	UserID         string    `gorm:"type:varchar(50);not null;index" json:"user_id"`
+	SourcePath     string    `gorm:"type:varchar(500);not null" json:"-"`
+	SourceChecksum string    `gorm:"type:varchar(64);not null;default:''" json:"-"`
+	Status         string    `gorm:"type:varchar(20);not null;default:indexed" json:"status"`
+	Content        string    `gorm:"type:longtext" json:"-"`
+	Error          string    `gorm:"type:varchar(500)" json:"error,omitempty"`
+	IndexedAt      time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"indexed_at"`
+}
+
+// TableName 指定表名
+func (FileContent) TableName() string {
+	return "file_contents"
+}
+
+// ContentSearchHit 内容搜索的原始命中记录
+type ContentSearchHit struct {
+	File
+	Score   float64 `gorm:"column:score"`
+	Content string  `gorm:"column:content"`
+}
+
+// ContentSearchResult 内容搜索结果，Snippets 中的命中词已用 <mark> 标记并做过HTML转义
+type ContentSearchResult struct {
+	File     File     `json:"file"`
+	Score    float64  `json:"score"`
+	Snippets []string `json:"snippets"`
+}
+
+// ContentSearchResponse 内容搜索响应结构体
+type ContentSearchResponse struct {
+	Success bool                  `json:"success"`
+	Query   string                `json:"query"`
+	Total   int64                 `json:"total"`
+	Results []ContentSearchResult `json:"results"`
+}
+
+// ContentIndexStatus 用户文件内容索引状态统计
+type ContentIndexStatus struct {
+	TotalFiles  int64 `json:"total_files"`
+	Indexed     int64 `json:"indexed"`
+	Unsupported int64 `json:"unsupported"`
+	Failed      int64 `json:"failed"`
+	Pending     int64 `json:"pending"`
+}
+
+// ContentSearchScope 内容搜索范围，满足任一条件的文件都在范围内
+type ContentSearchScope struct {
+	OwnerID   string // 该用户拥有的全部文件，为空表示不按所有者匹配
+	FolderIDs []uint // 直接位于这些文件夹中的文件
+	FileIDs   []uint // 指定的文件
+}
+
+// IsEmpty 范围内是否没有任何条件
+func (s ContentSearchScope) IsEmpty() bool {
+	return s.OwnerID == "" && len(s.FolderIDs) == 0 && len(s.FileIDs) == 0
+}
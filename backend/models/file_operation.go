@@ -0,0 +1,50 @@
+package models
+
+// 同名冲突处理策略，重命名、复制等操作共用
+const (
+	ConflictFail      = "fail"      // 存在同名项时返回冲突错误（默认）
+	ConflictRename    = "rename"    // 自动在名称后追加 " (n)" 序号
+	ConflictOverwrite = "overwrite" // 覆盖同名文件；复制文件夹时合并到同名文件夹
+)
+
+// NormalizeConflictStrategy 校验冲突处理策略，空值按 fail 处理
+func NormalizeConflictStrategy(strategy string) (string, bool) {
+	switch strategy {
+	case "":
+		return ConflictFail, true
+	case ConflictFail, ConflictRename, ConflictOverwrite:
+		return strategy, true
+	}
+	return "", false
+}
+
+// RenameFileRequest 重命名文件请求结构体
+type RenameFileRequest struct {
+	Name       string `json:"name" binding:"required"`
+	OnConflict string `json:"on_conflict"`
+}
+
+// CopyFileRequest 复制文件请求结构体
+type CopyFileRequest struct {
+	FolderID   *uint  `json:"folder_id"` // 目标文件夹ID，不传表示源文件所在文件夹，0表示根目录
+	Name       string `json:"name"`      // 副本名称，不传沿用源文件名称
+	OnConflict string `json:"on_conflict"`
+}
+
+// CopyFolderRequest 复制文件夹请求结构体
+type CopyFolderRequest struct {
+	ParentID   *uint  `json:"parent_id"` // 目标父文件夹ID，不传表示源文件夹的父文件夹，0表示根目录
+	Name       string `json:"name"`      // 副本名称，不传沿用源文件夹名称
+	OnConflict string `json:"on_conflict"`
+}
+
+// CopyFolderResult 复制文件夹结果
+type CopyFolderResult struct {
+	Folder         Folder `json:"folder"`
+	FolderCount    int    `json:"folder_count"`
+	FileCount      int    `json:"file_count"`
+	UrlFileCount   int    `json:"url_file_count"`
+	TotalSize      int64  `json:"total_size"`
+	OverwriteCount int    `json:"overwrite_count"`
+	InfectedCount  int    `json:"infected_count,omitempty"` // 因已被隔离而未复制的文件数量
+}
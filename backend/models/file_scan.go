@@ -0,0 +1,44 @@
+package models
+
+import "errors"
+
+// 文件的病毒扫描状态，未配置扫描服务时为空
+const (
+	ScanStatusPending  = "pending"  // 等待后台扫描
+	ScanStatusClean    = "clean"    // 未发现病毒
+	ScanStatusInfected = "infected" // 发现病毒，内容已移入隔离目录，禁止下载
+	ScanStatusError    = "error"    // 扫描器拒绝处理（如超过大小限制）
+	ScanStatusSkipped  = "skipped"  // 超过扫描大小上限或为保险库文件，未扫描
+)
+
+// ErrFileInfected 文件被判定为感染病毒，已隔离
+var ErrFileInfected = errors.New("文件包含病毒，已被隔离，禁止下载")
+
+// IsInfected 文件是否被判定为感染病毒
+func (f *File) IsInfected() bool {
+	return f.ScanStatus == ScanStatusInfected
+}
+
+// ScanStatusCount 某个扫描状态的文件数量
+type ScanStatusCount struct {
+	ScanStatus string `json:"status"`
+	Count      int64  `json:"count"`
+}
+
+// ScanStatusResponse 病毒扫描服务状态响应结构体（管理员）
+type ScanStatusResponse struct {
+	Success   bool              `json:"success"`
+	Enabled   bool              `json:"enabled"`
+	Available bool              `json:"available"`       // 扫描服务是否可以连接
+	Error     string            `json:"error,omitempty"` // 无法连接时的错误信息
+	Counts    []ScanStatusCount `json:"counts"`
+	Rescan    *RescanProgress   `json:"rescan,omitempty"` // 正在进行的全量重新扫描
+}
+
+// RescanProgress 全量重新扫描的进度
+type RescanProgress struct {
+	TaskID   string `json:"task_id"`
+	Total    int    `json:"total"`
+	Scanned  int    `json:"scanned"`
+	Infected int    `json:"infected"`
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// 搜索结果的排序字段
+const (
+	SearchSortName      = "name"
+	SearchSortSize      = "size"
+	SearchSortCreatedAt = "created_at"
+	SearchSortUpdatedAt = "updated_at"
+)
+
+// 搜索结果的对象类型，URL文件的 type 筛选值为 url；文件夹只出现在模糊搜索和标签查询结果中
+const (
+	SearchItemFile    = "file"
+	SearchItemFolder  = "folder"
+	SearchItemUrlFile = "url_file"
+	SearchTypeUrl     = "url"
+)
+
+// ErrInvalidCursor 分页游标无效或与当前排序方式不匹配
+var ErrInvalidCursor = errors.New("无效的分页游标")
+
+// FileSearchQuery 文件与URL文件的结构化搜索条件
+type FileSearchQuery struct {
+	OwnerID string // 搜索该用户拥有的文件
+	Keyword string // 名称关键词，同时匹配描述，URL文件还匹配链接
+
+	Types          []string // 文件类型（image、video、pdf 等），url 表示URL文件
+	Extensions     []string // 扩展名，不含点，小写
+	MinSize        *int64
+	MaxSize        *int64
+	CreatedFrom    *time.Time
+	CreatedTo      *time.Time // 不含
+	UpdatedFrom    *time.Time
+	UpdatedTo      *time.Time       // 不含
+	FolderIDs      []uint           // 限定所在文件夹，nil 表示不限
+	RootOnly       bool             // 只搜索根目录
+	IncludeFiles   bool             // 是否包含普通文件
+	IncludeUrls    bool             // 是否包含URL文件
+	IncludeFolders bool             // 是否包含文件夹
+	TagIDs         []uint           // 限定带有这些标签的记录
+	TagMatchAll    bool             // 为 true 时要求带有全部标签，否则带有任一标签即可
+	Metadata       []MetadataFilter // 自定义元数据条件，需全部满足；URL文件没有元数据，有条件时不参与搜索
+
+	Sort   string // name、size、created_at、updated_at
+	Desc   bool
+	Limit  int
+	Cursor *SearchCursor
+}
+
+// SearchCursor 基于排序值的分页游标，指向上一页最后一条记录
+type SearchCursor struct {
+	Sort     string `json:"s"`
+	Desc     bool   `json:"d"`
+	Value    string `json:"v"` // 排序字段的值，时间使用 RFC3339Nano 格式
+	ItemType string `json:"t"`
+	ID       uint   `json:"id"`
+}
+
+// Encode 将游标编码为URL安全的字符串
+func (c *SearchCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeSearchCursor 解析搜索结果的分页游标，并校验排序方式是否与本次请求一致
+func DecodeSearchCursor(encoded, sort string, desc bool) (*SearchCursor, error) {
+	cursor, err := decodeCursor(encoded, sort, desc)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.ItemType != SearchItemFile && cursor.ItemType != SearchItemFolder && cursor.ItemType != SearchItemUrlFile {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// decodeCursor 解码游标并校验排序方式
+func decodeCursor(encoded, sort string, desc bool) (*SearchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor SearchCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != sort || cursor.Desc != desc {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// SearchItem 搜索结果中的一项，按 ItemType 填充 File、Folder、UrlFile 之一
+type SearchItem struct {
+	ItemType string   `json:"type"`
+	File     *File    `json:"file,omitempty"`
+	Folder   *Folder  `json:"folder,omitempty"`
+	UrlFile  *UrlFile `json:"url_file,omitempty"`
+}
+
+// RecordID 返回对应记录的ID
+func (i SearchItem) RecordID() uint {
+	switch {
+	case i.File != nil:
+		return i.File.ID
+	case i.Folder != nil:
+		return i.Folder.ID
+	case i.UrlFile != nil:
+		return i.UrlFile.ID
+	}
+	return 0
+}
+
+// FileSearchPage 一页搜索结果
+type FileSearchPage struct {
+	Items      []SearchItem
+	NextCursor string
+	HasMore    bool
+}
+
+// FileSearchResponse 文件搜索响应结构体。
+// Items 按排序混合返回各类记录，Files、Folders 与 UrlFiles 为同一页结果按类型拆分，兼容旧的 files 字段
+type FileSearchResponse struct {
+	Success    bool         `json:"success"`
+	Items      []SearchItem `json:"items"`
+	Files      []File       `json:"files"`
+	Folders    []Folder     `json:"folders,omitempty"`
+	UrlFiles   []UrlFile    `json:"url_files"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	HasMore    bool         `json:"has_more"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// DefaultMaxFileVersions 每个文件默认保留的历史版本数量
+const DefaultMaxFileVersions = 10
+
+// FileVersion 文件历史版本结构体
+type FileVersion struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	FileID          uint      `gorm:"not null;index" json:"file_id"`                  // 所属文件ID
+	UserID          string    `gorm:"type:varchar(50);not null;index" json:"user_id"` // 文件所有者ID
+	Version         int       `gorm:"type:int;not null" json:"version"`               // 版本号，从1开始递增
+	Name            string    `gorm:"type:varchar(255);not null" json:"name"`         // 该版本的文件名
+	Size            int64     `gorm:"type:bigint;not null" json:"size"`
+	Checksum        string    `gorm:"type:varchar(64)" json:"checksum"`                           // SHA-256校验和
+	Path            string    `gorm:"type:varchar(500);not null" json:"path"`                     // 版本文件存储路径
+	UploadedBy      string    `gorm:"type:varchar(50)" json:"uploaded_by"`                        // 上传该版本的用户ID
+	EncryptionKeyID uint64    `gorm:"not null;default:0" json:"-"`                                // 存储加密使用的数据密钥ID，0表示明文存储
+	CreatedAt       time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"` // 该版本原始上传时间
+}
+
+// TableName 指定表名
+func (FileVersion) TableName() string {
+	return "file_versions"
+}
+
+// FileVersionListResponse 文件版本列表响应结构体
+type FileVersionListResponse struct {
+	Success  bool          `json:"success"`
+	File     File          `json:"file"`
+	Versions []FileVersion `json:"versions"`
+}
+
+// UpdateVersionSettingsRequest 更新版本保留设置请求结构体
+type UpdateVersionSettingsRequest struct {
+	MaxVersions int `json:"max_versions"`
+}
@@ -1,16 +1,22 @@
 package models
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 // Folder 结构体表示文件夹数据
 type Folder struct {
-	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	Name      string    `gorm:"type:varchar(255);not null" json:"name"`
-	UserID    string    `gorm:"type:varchar(50);not null;index" json:"user_id"`
-	Category  string    `gorm:"type:varchar(50);default:'all';index" json:"category"` // 分类字段
-	ParentID  *uint     `gorm:"index" json:"parent_id"`                               // 父文件夹ID
-	CreatedAt time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string    `gorm:"type:varchar(255);not null" json:"name"`
+	UserID      string    `gorm:"type:varchar(50);not null;index" json:"user_id"`
+	Category    string    `gorm:"type:varchar(50);default:'all';index" json:"category"` // 分类字段
+	ParentID    *uint     `gorm:"index" json:"parent_id"`                               // 父文件夹ID
+	Description string    `gorm:"type:text" json:"description,omitempty"`               // 用户填写的描述
+	Metadata    Metadata  `gorm:"type:json" json:"metadata,omitempty"`                  // 用户自定义键值对
+	VaultID     *uint     `gorm:"index" json:"vault_id,omitempty"`                      // 所属保险库（根文件夹ID），根文件夹指向自身
+	CreatedAt   time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
 }
 
 // TableName 指定表名
@@ -20,8 +26,10 @@ func (Folder) TableName() string {
 
 // FolderListResponse 文件夹列表响应结构体
 type FolderListResponse struct {
-	Success bool     `json:"success"`
-	Folders []Folder `json:"folders"`
+	Success    bool     `json:"success"`
+	Folders    []Folder `json:"folders"`
+	NextCursor string   `json:"next_cursor,omitempty"` // 分页时下一页的游标
+	HasMore    bool     `json:"has_more"`
 }
 
 // FolderResponse 单个文件夹响应结构体
@@ -42,3 +50,126 @@ type UpdateFolderRequest struct {
 	Name     string `json:"name"`
 	Category string `json:"category"`
 }
+
+// MoveFolderRequest 移动文件夹请求结构体
+type MoveFolderRequest struct {
+	ParentID   *uint  `json:"parent_id"`   // 目标父文件夹ID，null 或 0 表示根目录
+	OnConflict string `json:"on_conflict"` // 同名冲突策略：fail、rename
+}
+
+// FolderDeleteResult 递归删除文件夹的统计结果
+type FolderDeleteResult struct {
+	FolderCount  int      `json:"folder_count"`
+	FileCount    int      `json:"file_count"`
+	UrlFileCount int      `json:"url_file_count"`
+	VersionCount int      `json:"version_count"`
+	FreedSize    int64    `json:"freed_size"` // 释放的存储空间（包括历史版本）
+	StoredPaths  []string `json:"-"`          // 需要在事务提交后删除的物理文件相对路径
+}
+
+// FolderTreeNode 文件夹树节点，统计直接包含的内容以及包含子孙文件夹在内的合计
+type FolderTreeNode struct {
+	ID                uint              `json:"id"`
+	Name              string            `json:"name"`
+	Category          string            `json:"category"`
+	ParentID          *uint             `json:"parent_id"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+	FileCount         int               `json:"file_count"`                          // 直接包含的文件数量
+	UrlFileCount      int               `json:"url_file_count"`                      // 直接包含的URL文件数量
+	Size              int64             `json:"size"`                                // 直接包含文件的总字节数
+	TotalFileCount    int               `json:"total_file_count"`                    // 包含子孙文件夹的文件数量
+	TotalUrlFileCount int               `json:"total_url_file_count"`                // 包含子孙文件夹的URL文件数量
+	TotalSize         int64             `json:"total_size"`                          // 包含子孙文件夹的总字节数
+	TagIDs            []uint            `gorm:"-" json:"tag_ids,omitempty"`          // 文件夹本身的标签
+	TagCounts         map[uint]int      `gorm:"-" json:"tag_counts,omitempty"`       // 直接包含的对象上各标签的数量
+	TotalTagCounts    map[uint]int      `gorm:"-" json:"total_tag_counts,omitempty"` // 包含子孙文件夹的各标签数量
+	Children          []*FolderTreeNode `gorm:"-" json:"children"`
+}
+
+// FolderTreeResponse 文件夹树响应结构体，Tags 为树中统计引用的标签
+type FolderTreeResponse struct {
+	Success     bool              `json:"success"`
+	FolderCount int               `json:"folder_count"`
+	Tree        []*FolderTreeNode `json:"tree"`
+	Tags        []Tag             `json:"tags"`
+}
+
+// FolderPathResponse 文件夹路径（祖先链）响应结构体
+type FolderPathResponse struct {
+	Success bool     `json:"success"`
+	Path    []Folder `json:"path"` // 从最上层文件夹到当前文件夹
+}
+
+// BuildFolderTree 将扁平的节点列表组装为树，父节点不在列表中的节点作为根节点，并汇总子孙文件夹的统计
+func BuildFolderTree(nodes []*FolderTreeNode) []*FolderTreeNode {
+	byID := make(map[uint]*FolderTreeNode, len(nodes))
+	for _, node := range nodes {
+		node.Children = []*FolderTreeNode{}
+		byID[node.ID] = node
+	}
+
+	var roots []*FolderTreeNode
+	for _, node := range nodes {
+		if node.ParentID != nil && *node.ParentID != node.ID {
+			if parent, exists := byID[*node.ParentID]; exists {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	visited := make(map[uint]bool, len(nodes))
+	for _, root := range roots {
+		sumFolderTree(root, visited)
+	}
+	sortFolderTree(roots)
+	return roots
+}
+
+// sumFolderTree 递归汇总子孙文件夹的统计，visited 防止脏数据中的环导致死循环
+func sumFolderTree(node *FolderTreeNode, visited map[uint]bool) {
+	visited[node.ID] = true
+	node.TotalFileCount = node.FileCount
+	node.TotalUrlFileCount = node.UrlFileCount
+	node.TotalSize = node.Size
+	node.TotalTagCounts = nil
+	for tagID, count := range node.TagCounts {
+		addTagCount(&node.TotalTagCounts, tagID, count)
+	}
+
+	children := node.Children[:0]
+	for _, child := range node.Children {
+		if visited[child.ID] {
+			continue
+		}
+		sumFolderTree(child, visited)
+		node.TotalFileCount += child.TotalFileCount
+		node.TotalUrlFileCount += child.TotalUrlFileCount
+		node.TotalSize += child.TotalSize
+		for tagID, count := range child.TotalTagCounts {
+			addTagCount(&node.TotalTagCounts, tagID, count)
+		}
+		children = append(children, child)
+	}
+	node.Children = children
+}
+
+// addTagCount 累加标签数量，按需创建 map，使没有标签的节点不输出该字段
+func addTagCount(counts *map[uint]int, tagID uint, count int) {
+	if *counts == nil {
+		*counts = make(map[uint]int)
+	}
+	(*counts)[tagID] += count
+}
+
+// sortFolderTree 按名称排序各层节点
+func sortFolderTree(nodes []*FolderTreeNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+	for _, node := range nodes {
+		sortFolderTree(node.Children)
+	}
+}
@@ -0,0 +1,134 @@
+package models
+
+import "time"
+
+// 共享权限级别（由低到高）
+const (
+	PermissionViewer  = "viewer"   // 查看者：浏览和下载
+	PermissionEditor  = "editor"   // 编辑者：上传、替换、重命名、移动和删除文件
+	PermissionCoOwner = "co_owner" // 共同所有者：在编辑者基础上可管理共享和删除文件夹
+	PermissionOwner   = "owner"    // 资源所有者（仅用于权限计算，不能授予）
+)
+
+// 被授权对象类型
+const (
+	GranteeTypeUser  = "user"
+	GranteeTypeGroup = "group"
+)
+
+// PermissionLevel 返回权限级别的数值，数值越大权限越高，无效权限返回0
+func PermissionLevel(permission string) int {
+	switch permission {
+	case PermissionViewer:
+		return 1
+	case PermissionEditor:
+		return 2
+	case PermissionCoOwner:
+		return 3
+	case PermissionOwner:
+		return 4
+	}
+	return 0
+}
+
+// HasPermission 判断已有权限是否满足所需权限
+func HasPermission(granted, required string) bool {
+	return PermissionLevel(granted) > 0 && PermissionLevel(granted) >= PermissionLevel(required)
+}
+
+// IsGrantablePermission 判断权限是否可以授予他人
+func IsGrantablePermission(permission string) bool {
+	return permission == PermissionViewer || permission == PermissionEditor || permission == PermissionCoOwner
+}
+
+// ShareGrant 用户间共享授权结构体，授权文件夹时对整个子树生效
+type ShareGrant struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	OwnerID        string    `gorm:"type:varchar(50);not null;index" json:"owner_id"`         // 资源所有者ID
+	ResourceType   string    `gorm:"type:varchar(20);not null" json:"resource_type"`          // file 或 folder
+	ResourceID     uint      `gorm:"not null;index" json:"resource_id"`                       // 文件ID或文件夹ID
+	GranteeType    string    `gorm:"type:varchar(20);not null" json:"grantee_type"`           // user 或 group
+	GranteeUserID  string    `gorm:"type:varchar(50);index" json:"grantee_user_id,omitempty"` // 被授权用户ID
+	GranteeGroupID *uint     `gorm:"index" json:"grantee_group_id,omitempty"`                 // 被授权用户组ID
+	Permission     string    `gorm:"type:varchar(20);not null" json:"permission"`             // viewer, editor, co_owner
+	CreatedBy      string    `gorm:"type:varchar(50)" json:"created_by"`                      // 创建授权的用户ID
+	CreatedAt      time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt      time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (ShareGrant) TableName() string {
+	return "share_grants"
+}
+
+// UserGroup 用户组结构体，用于批量授权
+type UserGroup struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"type:varchar(100);not null" json:"name"`
+	OwnerID   string    `gorm:"type:varchar(50);not null;index" json:"owner_id"` // 创建者ID
+	CreatedAt time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (UserGroup) TableName() string {
+	return "user_groups"
+}
+
+// UserGroupMember 用户组成员结构体
+type UserGroupMember struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	GroupID   uint      `gorm:"not null;index" json:"group_id"`
+	UserID    string    `gorm:"type:varchar(50);not null;index" json:"user_id"`
+	CreatedAt time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (UserGroupMember) TableName() string {
+	return "user_group_members"
+}
+
+// CreateGrantRequest 创建共享授权请求结构体（Username 与 GroupID 二选一）
+type CreateGrantRequest struct {
+	ResourceType string `json:"resource_type" binding:"required"`
+	ResourceID   uint   `json:"resource_id" binding:"required"`
+	Username     string `json:"username"`
+	GroupID      uint   `json:"group_id"`
+	Permission   string `json:"permission" binding:"required"`
+}
+
+// UpdateGrantRequest 更新共享授权请求结构体
+type UpdateGrantRequest struct {
+	Permission string `json:"permission" binding:"required"`
+}
+
+// SharedWithMeItem 共享给我的资源条目
+type SharedWithMeItem struct {
+	GrantID       uint      `json:"grant_id"`
+	ResourceType  string    `json:"resource_type"`
+	ResourceID    uint      `json:"resource_id"`
+	Name          string    `json:"name"`
+	Size          int64     `json:"size,omitempty"`
+	Type          string    `json:"type,omitempty"`
+	Permission    string    `json:"permission"`
+	OwnerID       string    `json:"owner_id"`
+	OwnerUsername string    `json:"owner_username"`
+	ViaGroupID    *uint     `json:"via_group_id,omitempty"`
+	SharedAt      time.Time `json:"shared_at"`
+}
+
+// SharedWithMeResponse 共享给我的资源列表响应结构体
+type SharedWithMeResponse struct {
+	Success bool               `json:"success"`
+	Items   []SharedWithMeItem `json:"items"`
+}
+
+// CreateUserGroupRequest 创建用户组请求结构体
+type CreateUserGroupRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// AddGroupMemberRequest 添加用户组成员请求结构体
+type AddGroupMemberRequest struct {
+	Username string `json:"username" binding:"required"`
+}
@@ -0,0 +1,42 @@
+package models
+
+// 列表排序字段，其余与搜索共用 SearchSortName 等常量
+const (
+	ListSortOrder = "order" // 文档的展示顺序
+)
+
+// 列表中可按需返回的重字段
+const (
+	ListFieldThumbnailData = "thumbnail_data"
+)
+
+// 列表接口对象类型，用于校验游标是否属于当前列表
+const (
+	ListItemDocument = "document"
+)
+
+// ListQuery 列表接口的分页与排序条件。
+// Limit 为 0 时不分页，返回全部记录（兼容未传 limit 的旧客户端）
+type ListQuery struct {
+	OwnerID  string
+	FolderID *uint // 所在文件夹，nil 表示根目录；文件夹和文档列表不使用
+
+	Sort   string
+	Desc   bool
+	Limit  int
+	Cursor *SearchCursor
+
+	WithThumbnail bool // 是否返回 thumbnail_data
+}
+
+// DecodeListCursor 解析列表的分页游标，校验排序方式和对象类型是否与本次请求一致
+func DecodeListCursor(encoded, sort string, desc bool, itemType string) (*SearchCursor, error) {
+	cursor, err := decodeCursor(encoded, sort, desc)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.ItemType != itemType {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// 自定义元数据限制
+const (
+	MaxMetadataKeys        = 32   // 每个文件或文件夹最多的键数量
+	MaxMetadataKeyLength   = 64   // 键的最大字符数
+	MaxMetadataValueLength = 1024 // 字符串值的最大字符数
+	MaxDescriptionLength   = 2000 // 描述的最大字符数
+	MaxMetadataFilters     = 10   // 搜索时最多的元数据条件数
+)
+
+// metadataKeyPattern 元数据键：字母、数字、下划线开头，可包含 . 和 -
+var metadataKeyPattern = regexp.MustCompile(`^[\p{L}\p{N}_][\p{L}\p{N}_.\-]*$`)
+
+// Metadata 文件和文件夹的自定义键值对，值只能是字符串、数字或布尔值，以 JSON 存储
+type Metadata map[string]interface{}
+
+// Scan 实现 sql.Scanner，数字保留原始精度
+func (m *Metadata) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("无法解析元数据: %T", value)
+	}
+	if len(data) == 0 {
+		*m = nil
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var result map[string]interface{}
+	if err := decoder.Decode(&result); err != nil {
+		return err
+	}
+	*m = result
+	return nil
+}
+
+// Value 实现 driver.Valuer，空元数据存为 NULL
+func (m Metadata) Value() (driver.Value, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(map[string]interface{}(m))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// ValidateMetadataKey 校验元数据键
+func ValidateMetadataKey(key string) error {
+	if key == "" || utf8.RuneCountInString(key) > MaxMetadataKeyLength || !metadataKeyPattern.MatchString(key) {
+		return fmt.Errorf("无效的元数据键 %q：最多%d个字符，只能包含字母、数字、下划线、点和连字符", key, MaxMetadataKeyLength)
+	}
+	return nil
+}
+
+// DecodeMetadataValue 解析单个元数据值，只接受字符串、数字和布尔值
+func DecodeMetadataValue(key string, raw json.RawMessage) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("元数据 %q 的值无效", key)
+	}
+	switch v := value.(type) {
+	case string:
+		if utf8.RuneCountInString(v) > MaxMetadataValueLength {
+			return nil, fmt.Errorf("元数据 %q 的值不能超过%d个字符", key, MaxMetadataValueLength)
+		}
+	case json.Number, bool:
+	default:
+		return nil, fmt.Errorf("元数据 %q 的值只能是字符串、数字或布尔值", key)
+	}
+	return value, nil
+}
+
+// ErrTooManyMetadataKeys 合并后的元数据键数量超过上限
+var ErrTooManyMetadataKeys = fmt.Errorf("元数据最多%d个键", MaxMetadataKeys)
+
+// ValidateMetadataPatch 校验元数据修改中的键和值，值为 null 表示删除该键
+func ValidateMetadataPatch(patch map[string]json.RawMessage) error {
+	for key, raw := range patch {
+		if err := ValidateMetadataKey(key); err != nil {
+			return err
+		}
+		if isJSONNull(raw) {
+			continue
+		}
+		if _, err := DecodeMetadataValue(key, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyPatch 按 JSON Merge Patch 语义合并已校验的元数据修改：值为 null 的键被删除，其他键被设置。
+// 返回新的元数据，键数量超过上限时返回 ErrTooManyMetadataKeys
+func (m Metadata) ApplyPatch(patch map[string]json.RawMessage) (Metadata, error) {
+	result := make(Metadata, len(m)+len(patch))
+	for key, value := range m {
+		result[key] = value
+	}
+	for key, raw := range patch {
+		if isJSONNull(raw) {
+			delete(result, key)
+			continue
+		}
+		value, err := DecodeMetadataValue(key, raw)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	if len(result) > MaxMetadataKeys {
+		return nil, ErrTooManyMetadataKeys
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// isJSONNull 判断原始 JSON 值是否为 null
+func isJSONNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// ValidateDescription 校验描述长度
+func ValidateDescription(description string) error {
+	if utf8.RuneCountInString(description) > MaxDescriptionLength {
+		return fmt.Errorf("描述不能超过%d个字符", MaxDescriptionLength)
+	}
+	return nil
+}
+
+// MetadataFilter 搜索时的元数据条件，Values 为空表示只要求存在该键，否则匹配其中任一值
+type MetadataFilter struct {
+	Key    string
+	Values []interface{}
+}
+
+// ParseMetadataFilter 解析 meta 查询参数：key 表示存在该键，key:value 表示值相等。
+// 值同时按字符串匹配，形如数字或 true/false 时也按对应类型匹配
+func ParseMetadataFilter(param string) (MetadataFilter, error) {
+	key, value, hasValue := strings.Cut(param, ":")
+	key = strings.TrimSpace(key)
+	if err := ValidateMetadataKey(key); err != nil {
+		return MetadataFilter{}, err
+	}
+	filter := MetadataFilter{Key: key}
+	if !hasValue {
+		return filter, nil
+	}
+
+	filter.Values = append(filter.Values, value)
+	var number json.Number
+	if err := json.Unmarshal([]byte(value), &number); err == nil && !strings.HasPrefix(value, `"`) && strings.TrimSpace(value) == value {
+		filter.Values = append(filter.Values, number)
+	}
+	switch value {
+	case "true":
+		filter.Values = append(filter.Values, true)
+	case "false":
+		filter.Values = append(filter.Values, false)
+	}
+	return filter, nil
+}
+
+// UpdateItemMetadataRequest 修改文件或文件夹描述与元数据的请求结构体（PATCH）。
+// description 不传时保持不变；metadata 按 JSON Merge Patch 合并，值为 null 的键被删除
+type UpdateItemMetadataRequest struct {
+	Description *string                    `json:"description"`
+	Metadata    map[string]json.RawMessage `json:"metadata"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// 名称搜索模式
+const (
+	SearchModeExact = "exact" // 名称 LIKE 匹配（默认）
+	SearchModeFuzzy = "fuzzy" // 拼音与错字容忍的模糊匹配
+)
+
+// NamePinyin 文件、文件夹、URL文件名称的拼音索引，每个对象一条记录。
+// 多音字产生的多个组合以空格分隔；Name 记录建立索引时的名称，与当前名称不一致即需要重建
+type NamePinyin struct {
+	ItemType   string    `gorm:"primaryKey;type:varchar(20)" json:"item_type"`
+	ItemID     uint      `gorm:"primaryKey;autoIncrement:false" json:"item_id"`
+	UserID     string    `gorm:"type:varchar(50);not null;index" json:"user_id"`
+	Name       string    `gorm:"type:varchar(255);not null" json:"name"`
+	FullPinyin string    `gorm:"type:text" json:"full_pinyin"`
+	Initials   string    `gorm:"type:text" json:"initials"`
+	UpdatedAt  time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (NamePinyin) TableName() string {
+	return "name_pinyin"
+}
+
+// NameIndexSource 需要建立拼音索引的对象名称
+type NameIndexSource struct {
+	ItemType string
+	ItemID   uint
+	UserID   string
+	Name     string
+}
+
+// FuzzyCandidate 模糊搜索的候选记录。
+// IndexedName 为空或与 Name 不一致时索引已过期，拼音需要即时计算
+type FuzzyCandidate struct {
+	ItemType    string
+	ID          uint
+	Name        string
+	IndexedName *string
+	FullPinyin  string
+	Initials    string
+}
+
+// FuzzySearchItem 模糊搜索结果，Distance 为关键词与名称（或拼音）最接近片段的编辑距离
+type FuzzySearchItem struct {
+	SearchItem
+	Distance     int    `json:"distance"`
+	MatchedField string `json:"matched_field"` // name、pinyin、initials
+}
+
+// FuzzySearchResponse 模糊搜索响应结构体，结果按匹配程度排序
+type FuzzySearchResponse struct {
+	Success bool              `json:"success"`
+	Mode    string            `json:"mode"`
+	Query   string            `json:"query"`
+	Total   int               `json:"total"`
+	Items   []FuzzySearchItem `json:"items"`
+	// 候选记录超过上限时只在最近更新的部分中匹配
+	Truncated bool `json:"truncated,omitempty"`
+}
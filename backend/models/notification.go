@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// 通知类型
+const (
+	NotificationFileInfected = "file_infected" // 文件被扫描出病毒并隔离
+)
+
+// 通知列表相关限制
+const (
+	DefaultNotificationLimit = 20
+	MaxNotificationLimit     = 100
+)
+
+// Notification 站内通知
+type Notification struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    string     `gorm:"type:varchar(50);not null;index" json:"-"`
+	Type      string     `gorm:"type:varchar(50);not null" json:"type"`
+	Title     string     `gorm:"type:varchar(255);not null" json:"title"`
+	Content   string     `gorm:"type:text" json:"content"`
+	ItemType  string     `gorm:"type:varchar(20)" json:"item_type,omitempty"` // 关联对象类型，取值与搜索结果的对象类型一致
+	ItemID    *uint      `json:"item_id,omitempty"`
+	ReadAt    *time.Time `gorm:"type:timestamp;null" json:"read_at,omitempty"`
+	CreatedAt time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// NotificationListResponse 通知列表响应结构体
+type NotificationListResponse struct {
+	Success       bool           `json:"success"`
+	Notifications []Notification `json:"notifications"`
+	Total         int64          `json:"total"`
+	UnreadCount   int64          `json:"unread_count"`
+}
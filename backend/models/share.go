@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+// 分享资源类型
+const (
+	ShareResourceFile   = "file"
+	ShareResourceFolder = "folder"
+)
+
+// 分享模式
+const (
+	ShareModeReadOnly = "read_only" // 只读：仅允许浏览和下载
+	ShareModeUpload   = "upload"    // 允许上传：访客可向分享的文件夹上传文件
+)
+
+// 分享密码错误限制：同一分享、同一IP在时间窗口内允许的失败次数，超过后暂时拒绝验证
+const (
+	MaxSharePasswordFailures   = 10
+	SharePasswordFailureWindow = 15 * time.Minute
+)
+
+// ShareLink 公开分享链接结构体
+type ShareLink struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Slug          string     `gorm:"type:varchar(32);not null;uniqueIndex" json:"slug"` // 随机访问标识
+	UserID        string     `gorm:"type:varchar(50);not null;index" json:"user_id"`    // 分享者ID
+	ResourceType  string     `gorm:"type:varchar(20);not null" json:"resource_type"`    // file 或 folder
+	ResourceID    uint       `gorm:"not null" json:"resource_id"`                       // 文件ID或文件夹ID
+	PasswordHash  string     `gorm:"type:varchar(255)" json:"-"`                        // 访问密码哈希，空表示无密码
+	Mode          string     `gorm:"type:varchar(20);default:'read_only'" json:"mode"`  // 分享模式
+	ExpiresAt     *time.Time `gorm:"type:timestamp;null" json:"expires_at"`             // 过期时间，null表示永不过期
+	MaxDownloads  int        `gorm:"type:int;default:0" json:"max_downloads"`           // 最大下载次数，0表示不限制
+	DownloadCount int        `gorm:"type:int;default:0" json:"download_count"`          // 已下载次数
+	AccessCount   int        `gorm:"type:int;default:0" json:"access_count"`            // 访问次数（浏览和下载）
+	Revoked       bool       `gorm:"type:boolean;default:false" json:"revoked"`         // 是否已撤销
+	CreatedAt     time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+
+	HasPassword bool `gorm:"-" json:"has_password"`
+}
+
+// TableName 指定表名
+func (ShareLink) TableName() string {
+	return "share_links"
+}
+
+// IsExpired 分享是否已过期
+func (s *ShareLink) IsExpired() bool {
+	return s.ExpiresAt != nil && time.Now().After(*s.ExpiresAt)
+}
+
+// IsDownloadLimitReached 下载次数是否已用完
+func (s *ShareLink) IsDownloadLimitReached() bool {
+	return s.MaxDownloads > 0 && s.DownloadCount >= s.MaxDownloads
+}
+
+// ShareAccessLog 分享访问日志结构体
+type ShareAccessLog struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ShareID   uint      `gorm:"not null;index" json:"share_id"`
+	Action    string    `gorm:"type:varchar(20);not null" json:"action"` // view, download, upload, denied
+	FileID    *uint     `json:"file_id,omitempty"`                       // 涉及的文件ID
+	IP        string    `gorm:"type:varchar(64)" json:"ip"`
+	UserAgent string    `gorm:"type:varchar(255)" json:"user_agent"`
+	Detail    string    `gorm:"type:varchar(255)" json:"detail,omitempty"`
+	CreatedAt time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (ShareAccessLog) TableName() string {
+	return "share_access_logs"
+}
+
+// CreateShareRequest 创建分享请求结构体
+type CreateShareRequest struct {
+	ResourceType   string     `json:"resource_type" binding:"required"`
+	ResourceID     uint       `json:"resource_id" binding:"required"`
+	Password       string     `json:"password"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	ExpiresInHours int        `json:"expires_in_hours"`
+	MaxDownloads   int        `json:"max_downloads"`
+	Mode           string     `json:"mode"`
+}
+
+// ShareListResponse 分享列表响应结构体
+type ShareListResponse struct {
+	Success bool        `json:"success"`
+	Shares  []ShareLink `json:"shares"`
+}
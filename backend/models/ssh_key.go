@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// MaxSSHKeysPerUser 每个用户可登记的SSH公钥数量上限
+const MaxSSHKeysPerUser = 20
+
+// SSHKey 用户登记的SSH公钥，用于SFTP登录。同一公钥只能属于一个用户
+type SSHKey struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID      string     `gorm:"type:varchar(50);not null;index" json:"-"`
+	Name        string     `gorm:"type:varchar(100);not null" json:"name"`
+	KeyType     string     `gorm:"type:varchar(50);not null" json:"key_type"`
+	PublicKey   string     `gorm:"type:text;not null" json:"public_key"`                     // authorized_keys 格式，不含注释
+	Fingerprint string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"fingerprint"` // SHA256:...
+	LastUsedAt  *time.Time `gorm:"type:timestamp;null" json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (SSHKey) TableName() string {
+	return "ssh_keys"
+}
+
+// AddSSHKeyRequest 登记SSH公钥请求结构体，Name 为空时使用公钥的注释
+type AddSSHKeyRequest struct {
+	Name      string `json:"name"`
+	PublicKey string `json:"public_key" binding:"required"`
+}
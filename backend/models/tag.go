@@ -0,0 +1,109 @@
+package models
+
+import (
+	"regexp"
+	"time"
+)
+
+// 标签相关限制
+const (
+	MaxTagNameLength  = 50  // 标签名称最大字符数
+	MaxTagItemsPerOp  = 500 // 单次打标签/取消标签的最大对象数
+	DefaultTagColor   = "#6b7280"
+	MaxTagsPerRequest = 50 // 单次请求最多涉及的标签数
+)
+
+// tagColorPattern 标签颜色格式：#RGB 或 #RRGGBB
+var tagColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// IsValidTagColor 检查标签颜色格式
+func IsValidTagColor(color string) bool {
+	return tagColorPattern.MatchString(color)
+}
+
+// Tag 用户自定义标签
+type Tag struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    string    `gorm:"type:varchar(50);not null;uniqueIndex:uk_user_name" json:"user_id"`
+	Name      string    `gorm:"type:varchar(50);not null;uniqueIndex:uk_user_name" json:"name"`
+	Color     string    `gorm:"type:varchar(20);not null;default:'#6b7280'" json:"color"`
+	ItemCount int       `gorm:"->;-:migration" json:"item_count"` // 已打上该标签的对象数量，仅列表查询时统计
+	CreatedAt time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Tag) TableName() string {
+	return "tags"
+}
+
+// ItemTag 标签与文件、文件夹、URL文件的关联，ItemType 取值与搜索结果的对象类型一致
+type ItemTag struct {
+	TagID     uint      `gorm:"primaryKey;autoIncrement:false" json:"tag_id"`
+	ItemType  string    `gorm:"primaryKey;type:varchar(20)" json:"type"`
+	ItemID    uint      `gorm:"primaryKey;autoIncrement:false" json:"id"`
+	UserID    string    `gorm:"type:varchar(50);not null;index" json:"-"`
+	CreatedAt time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (ItemTag) TableName() string {
+	return "item_tags"
+}
+
+// TagItemRef 标签操作的对象
+type TagItemRef struct {
+	ItemType string `json:"type"` // file、folder、url_file
+	ID       uint   `json:"id"`
+}
+
+// FolderTagCount 文件夹中直接包含的、打上某个标签的对象数量
+type FolderTagCount struct {
+	FolderID uint
+	TagID    uint
+	Count    int
+}
+
+// CreateTagRequest 创建标签请求结构体
+type CreateTagRequest struct {
+	Name  string `json:"name" binding:"required"`
+	Color string `json:"color"`
+}
+
+// UpdateTagRequest 修改标签请求结构体，不传的字段保持不变
+type UpdateTagRequest struct {
+	Name  *string `json:"name"`
+	Color *string `json:"color"`
+}
+
+// TagItemsRequest 批量打标签或取消标签请求结构体，对 items 中的每个对象应用全部 tag_ids
+type TagItemsRequest struct {
+	TagIDs []uint       `json:"tag_ids" binding:"required"`
+	Items  []TagItemRef `json:"items" binding:"required"`
+}
+
+// TagListResponse 标签列表响应结构体
+type TagListResponse struct {
+	Success bool  `json:"success"`
+	Tags    []Tag `json:"tags"`
+}
+
+// TagResponse 单个标签响应结构体
+type TagResponse struct {
+	Success bool `json:"success"`
+	Tag     Tag  `json:"tag"`
+}
+
+// TagItemsResponse 批量打标签或取消标签响应结构体
+type TagItemsResponse struct {
+	Success  bool         `json:"success"`
+	Affected int64        `json:"affected"`          // 新增或删除的关联数量，已存在的关联不重复计数
+	Missing  []TagItemRef `json:"missing,omitempty"` // 不存在或不属于当前用户而被跳过的对象
+}
+
+// ItemTagsResponse 对象标签查询响应结构体，键为对象ID
+type ItemTagsResponse struct {
+	Success bool            `json:"success"`
+	Type    string          `json:"type"`
+	Tags    map[uint][]uint `json:"tags"` // 对象ID -> 标签ID列表
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// 上传任务状态
+const (
+	UploadTaskPending    = "pending"    // 等待执行
+	UploadTaskUploading  = "uploading"  // 正在写入上传内容
+	UploadTaskProcessing = "processing" // 后台任务执行中
+	UploadTaskCompleted  = "completed"  // 已完成
+	UploadTaskFailed     = "failed"     // 失败，原因见 error
+	UploadTaskCancelled  = "cancelled"  // 已被用户取消
+)
+
+// UploadTaskRetention 已结束的任务记录在数据库中的保留时长
+const UploadTaskRetention = 7 * 24 * time.Hour
+
+// MaxUploadTaskList 任务列表最多返回的数量
+const MaxUploadTaskList = 100
+
+// UploadTask 上传、导入、打包等任务的状态，持久化后服务重启或客户端重连仍可查询
+type UploadTask struct {
+	ID        string    `gorm:"type:varchar(100);primaryKey" json:"id"`
+	UserID    string    `gorm:"type:varchar(50);not null;index" json:"user_id"`
+	FileName  string    `gorm:"type:varchar(500);not null" json:"file_name"`
+	FileSize  int64     `gorm:"not null" json:"file_size"`  // 总字节数，未知时为0
+	BytesDone int64     `gorm:"not null" json:"bytes_done"` // 已写入的字节数
+	Speed     int64     `gorm:"not null" json:"speed"`      // 最近的写入速度，字节/秒
+	Progress  int       `gorm:"not null" json:"progress"`
+	Status    string    `gorm:"type:varchar(20);not null" json:"status"` // pending, uploading, processing, completed, failed, cancelled
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Error     string    `gorm:"type:text" json:"error,omitempty"`
+	Type      string    `gorm:"type:varchar(20)" json:"type,omitempty"`        // 任务类型：upload（默认）、archive 等
+	ResultURL string    `gorm:"type:varchar(500)" json:"result_url,omitempty"` // 任务完成后的结果下载地址
+}
+
+// TableName 指定表名
+func (UploadTask) TableName() string {
+	return "upload_tasks"
+}
+
+// Finished 任务是否已结束
+func (t *UploadTask) Finished() bool {
+	return t.Status == UploadTaskCompleted || t.Status == UploadTaskFailed || t.Status == UploadTaskCancelled
+}
@@ -21,8 +21,10 @@ func (UrlFile) TableName() string {
 
 // UrlFileListResponse URL文件列表响应结构体
 type UrlFileListResponse struct {
-	Success bool      `json:"success"`
-	Files   []UrlFile `json:"files"`
+	Success    bool      `json:"success"`
+	Files      []UrlFile `json:"files"`
+	NextCursor string    `json:"next_cursor,omitempty"` // 分页时下一页的游标
+	HasMore    bool      `json:"has_more"`
 }
 
 // UrlFileResponse 单个URL文件响应结构体
@@ -30,3 +32,11 @@ type UrlFileResponse struct {
 	Success bool    `json:"success"`
 	File    UrlFile `json:"file"`
 }
+
+// ImportURLRequest 从远程URL导入文件请求结构体
+type ImportURLRequest struct {
+	URL        string `json:"url" binding:"required"` // 远程文件地址，仅支持 http/https
+	FolderID   *uint  `json:"folder_id"`              // 保存的目标文件夹，为空表示根目录
+	Name       string `json:"name"`                   // 保存的文件名，可选，默认从响应头或URL推断
+	OnConflict string `json:"on_conflict"`            // 同名文件处理策略：fail、rename（默认）、overwrite
+}
@@ -4,17 +4,19 @@ import "time"
 
 // User 结构体表示用户数据
 type User struct {
-	UUID          string     `gorm:"primaryKey;type:varchar(36)" json:"uuid"`
-	Username      string     `gorm:"uniqueIndex;type:varchar(50);not null" json:"username"`
-	Password      string     `gorm:"type:varchar(255);not null" json:"password"`
-	Email         string     `gorm:"type:varchar(100)" json:"email"`
-	Bio           string     `gorm:"type:text" json:"bio"`
-	Avatar        string     `gorm:"type:varchar(255)" json:"avatar"`
-	StorageLimit  int64      `gorm:"type:bigint;default:1073741824" json:"storage_limit"`  // 存储空间限制（字节）
-	LastLoginTime *time.Time `gorm:"type:timestamp;null" json:"last_login_time,omitempty"` // 最后登录时间
-	IsOnline      bool       `gorm:"type:boolean;default:false" json:"is_online"`          // 在线状态
-	CreatedAt     time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt     time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+	UUID                 string     `gorm:"primaryKey;type:varchar(36)" json:"uuid"`
+	Username             string     `gorm:"uniqueIndex;type:varchar(50);not null" json:"username"`
+	Password             string     `gorm:"type:varchar(255);not null" json:"password"`
+	Email                string     `gorm:"type:varchar(100)" json:"email"`
+	Bio                  string     `gorm:"type:text" json:"bio"`
+	Avatar               string     `gorm:"type:varchar(255)" json:"avatar"`
+	StorageLimit         int64      `gorm:"type:bigint;default:1073741824" json:"storage_limit"`     // 存储空间限制（字节）
+	LastLoginTime        *time.Time `gorm:"type:timestamp;null" json:"last_login_time,omitempty"`    // 最后登录时间
+	IsOnline             bool       `gorm:"type:boolean;default:false" json:"is_online"`             // 在线状态
+	MaxFileVersions      int        `gorm:"type:int;default:10" json:"max_file_versions"`            // 每个文件保留的历史版本数量
+	RecentHistoryEnabled bool       `gorm:"type:boolean;default:true" json:"recent_history_enabled"` // 是否记录最近访问
+	CreatedAt            time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt            time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
 }
 
 // TableName 指定表名
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// 用户数据密钥状态
+const (
+	UserKeyActive  = "active"  // 用于加密新写入的内容
+	UserKeyRetired = "retired" // 已被轮换，只用于解密仍由其加密的内容
+)
+
+// 存储加密后台任务类型
+const (
+	EncryptionJobMigrate = "encryption_migrate" // 加密已有的明文文件
+	EncryptionJobRotate  = "key_rotation"       // 密钥轮换
+)
+
+// UserKey 用户的存储加密数据密钥，以主密钥加密后保存，明文密钥只存在于内存中
+type UserKey struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID      string    `gorm:"type:varchar(50);not null;index" json:"user_id"`
+	WrappedKey  []byte    `gorm:"type:varbinary(128);not null" json:"-"`                // 随机数 + 主密钥加密的数据密钥
+	MasterKeyID string    `gorm:"type:varchar(16);not null;index" json:"master_key_id"` // 加密数据密钥的主密钥指纹
+	Status      string    `gorm:"type:varchar(20);not null" json:"status"`
+	CreatedAt   time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (UserKey) TableName() string {
+	return "user_keys"
+}
+
+// StoredContentRef 磁盘上的一份用户文件内容（当前文件或历史版本），用于加密迁移和密钥轮换
+type StoredContentRef struct {
+	ID          uint
+	UserID      string
+	Path        string
+	KeyID       uint64 // 记录中的存储加密数据密钥ID，0表示明文
+	Quarantined bool   // 文件已被隔离，内容位于隔离目录
+}
+
+// EncryptionJobProgress 存储加密后台任务的进度
+type EncryptionJobProgress struct {
+	TaskID    string `json:"task_id"`
+	Type      string `json:"type"`
+	Total     int    `json:"total"`
+	Processed int    `json:"processed"`
+	Rewritten int    `json:"rewritten"` // 重新写入（加密或换用新密钥）的文件数
+	Failed    int    `json:"failed"`
+}
+
+// EncryptionStatusResponse 存储加密状态响应结构体（管理员）
+type EncryptionStatusResponse struct {
+	Success      bool                   `json:"success"`
+	Enabled      bool                   `json:"enabled"`        // 是否加密新写入的文件
+	Configured   bool                   `json:"configured"`     // 是否配置了主密钥
+	MasterKeyID  string                 `json:"master_key_id"`  // 当前主密钥指纹
+	ActiveKeys   int64                  `json:"active_keys"`    // 使用中的用户数据密钥数量
+	RetiredKeys  int64                  `json:"retired_keys"`   // 已轮换的数据密钥数量
+	KeysToRewrap int64                  `json:"keys_to_rewrap"` // 仍由旧主密钥加密的数据密钥数量
+	Job          *EncryptionJobProgress `json:"job,omitempty"`  // 正在进行的后台任务
+}
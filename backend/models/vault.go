@@ -0,0 +1,121 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// 保险库相关限制
+const (
+	MaxVaultDevicesPerUser = 20   // 每个用户可登记的设备数量上限
+	MaxVaultPublicKeyLen   = 8192 // 设备公钥的最大长度
+	MaxVaultWrappedKeyLen  = 4096 // 包装后的保险库密钥或文件密钥的最大长度
+)
+
+// VaultFileType 保险库文件的类型，同时作为上传目录名。内容为客户端加密的密文
+const VaultFileType = "vault"
+
+// ErrVaultItem 保险库中的文件和文件夹只能通过保险库接口修改，不支持服务端预览、搜索、打包和分享
+var ErrVaultItem = errors.New("保险库中的内容不支持该操作")
+
+// VaultDevice 用户登记的保险库设备。私钥只保存在设备上，服务端仅保存公钥，
+// 供其他设备为其包装保险库密钥
+type VaultDevice struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID      string    `gorm:"type:varchar(50);not null;index" json:"-"`
+	Name        string    `gorm:"type:varchar(100);not null" json:"name"`
+	Algorithm   string    `gorm:"type:varchar(50);not null" json:"algorithm"` // 客户端约定的公钥算法，如 RSA-OAEP-256
+	PublicKey   string    `gorm:"type:text;not null" json:"public_key"`
+	Fingerprint string    `gorm:"type:varchar(64);not null" json:"fingerprint"` // 公钥的 SHA-256 十六进制，同一用户内唯一
+	CreatedAt   time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (VaultDevice) TableName() string {
+	return "vault_devices"
+}
+
+// VaultKeyEnvelope 以某台设备的公钥包装的保险库密钥。每个保险库对每台设备最多一个信封
+type VaultKeyEnvelope struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	VaultID    uint      `gorm:"not null;uniqueIndex:uk_vault_device" json:"vault_id"`
+	DeviceID   uint      `gorm:"not null;uniqueIndex:uk_vault_device;index" json:"device_id"`
+	UserID     string    `gorm:"type:varchar(50);not null;index" json:"-"`
+	Algorithm  string    `gorm:"type:varchar(50);not null" json:"algorithm"`
+	WrappedKey string    `gorm:"type:text;not null" json:"wrapped_key"`
+	CreatedAt  time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (VaultKeyEnvelope) TableName() string {
+	return "vault_key_envelopes"
+}
+
+// IsVault 文件夹是否属于保险库（包括保险库根文件夹）
+func (f *Folder) IsVault() bool {
+	return f.VaultID != nil
+}
+
+// IsVaultRoot 文件夹是否为保险库根文件夹
+func (f *Folder) IsVaultRoot() bool {
+	return f.VaultID != nil && *f.VaultID == f.ID
+}
+
+// IsVault 文件是否属于保险库
+func (f *File) IsVault() bool {
+	return f.VaultID != nil
+}
+
+// AddVaultDeviceRequest 登记保险库设备请求结构体，公钥格式由客户端约定，服务端不解析
+type AddVaultDeviceRequest struct {
+	Name      string `json:"name" binding:"required"`
+	Algorithm string `json:"algorithm" binding:"required"`
+	PublicKey string `json:"public_key" binding:"required"`
+}
+
+// VaultKeyEnvelopeInput 写入保险库密钥信封的请求项
+type VaultKeyEnvelopeInput struct {
+	DeviceID   uint   `json:"device_id" binding:"required"`
+	Algorithm  string `json:"algorithm" binding:"required"`
+	WrappedKey string `json:"wrapped_key" binding:"required"`
+}
+
+// CreateVaultRequest 创建保险库请求结构体，Name 为客户端加密后的名称，
+// 至少需要一个信封，否则任何设备都无法解开保险库密钥
+type CreateVaultRequest struct {
+	Name      string                  `json:"name" binding:"required"`
+	Envelopes []VaultKeyEnvelopeInput `json:"envelopes" binding:"required"`
+}
+
+// PutVaultKeysRequest 新增或覆盖保险库密钥信封请求结构体，用于授权新设备或轮换保险库密钥
+type PutVaultKeysRequest struct {
+	Envelopes []VaultKeyEnvelopeInput `json:"envelopes" binding:"required"`
+}
+
+// CreateVaultFolderRequest 在保险库中创建文件夹请求结构体，ParentID 为空表示保险库根文件夹
+type CreateVaultFolderRequest struct {
+	Name     string `json:"name" binding:"required"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// UpdateVaultItemRequest 重命名或在保险库内移动文件、文件夹的请求结构体，
+// 字段为空表示不修改；文件的 FolderID 和文件夹的 ParentID 均指目标文件夹
+type UpdateVaultItemRequest struct {
+	Name         string `json:"name"`
+	FolderID     *uint  `json:"folder_id"`
+	EncryptedKey string `json:"encrypted_key"` // 仅文件：重新包装后的文件密钥
+}
+
+// VaultListResponse 保险库列表响应结构体
+type VaultListResponse struct {
+	Success bool     `json:"success"`
+	Vaults  []Folder `json:"vaults"`
+}
+
+// VaultKeysResponse 保险库密钥信封响应结构体
+type VaultKeysResponse struct {
+	Success   bool               `json:"success"`
+	VaultID   uint               `json:"vault_id"`
+	Envelopes []VaultKeyEnvelope `json:"envelopes"`
+}
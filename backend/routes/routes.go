@@ -73,6 +73,23 @@ func (r *Router) SetupRoutes(
 	urlFileHandler *handlers.UrlFileHandler,
 	uploadProgressHandler *handlers.UploadProgressHandler,
 	updateLogHandler *handlers.UpdateLogHandler,
+	fileVersionHandler *handlers.FileVersionHandler,
+	shareHandler *handlers.ShareHandler,
+	grantHandler *handlers.GrantHandler,
+	archiveHandler *handlers.ArchiveHandler,
+	batchHandler *handlers.BatchHandler,
+	contentSearchHandler *handlers.ContentSearchHandler,
+	tagHandler *handlers.TagHandler,
+	activityHandler *handlers.ActivityHandler,
+	scanHandler *handlers.ScanHandler,
+	notificationHandler *handlers.NotificationHandler,
+	appTokenHandler *handlers.AppTokenHandler,
+	webdavHandler *handlers.WebDAVHandler,
+	sshKeyHandler *handlers.SSHKeyHandler,
+	changeHandler *handlers.ChangeHandler,
+	eventHandler *handlers.EventHandler,
+	encryptionHandler *handlers.EncryptionHandler,
+	vaultHandler *handlers.VaultHandler,
 ) {
 	// 注册API路由组
 	apiGroup := r.RegisterGroup("api", "/api")
@@ -98,13 +115,117 @@ func (r *Router) SetupRoutes(
 	userGroup.AddRoute("GET", "/files", fileHandler.GetFiles, "获取文件列表")
 	userGroup.AddRoute("GET", "/files/count", fileHandler.GetTotalFileCount, "获取用户所有文件总数")
 	userGroup.AddRoute("GET", "/files/search", fileHandler.SearchFiles, "搜索文件")
+	userGroup.AddRoute("GET", "/files/search/content", contentSearchHandler.SearchContent, "全文搜索文件内容")
+	userGroup.AddRoute("GET", "/files/search/content/status", contentSearchHandler.GetIndexStatus, "获取文件内容索引状态")
+	userGroup.AddRoute("POST", "/files/search/content/reindex", contentSearchHandler.RebuildIndex, "重建文件内容索引")
 	userGroup.AddRoute("GET", "/files/:id", fileHandler.GetFile, "获取单个文件信息")
 	userGroup.AddRoute("GET", "/files/:id/download", fileHandler.DownloadFile, "下载文件")
 	userGroup.AddRoute("GET", "/download", fileHandler.DownloadFileRedirect, "下载文件重定向（优化版本）")
 	userGroup.AddRoute("POST", "/upload", fileHandler.UploadFile, "上传文件")
 	userGroup.AddRoute("POST", "/upload/batch", fileHandler.UploadFiles, "批量上传文件")
+	userGroup.AddRoute("PATCH", "/files/:id", fileHandler.UpdateFileMetadata, "修改文件描述和自定义元数据")
 	userGroup.AddRoute("DELETE", "/files/:id", fileHandler.DeleteFile, "删除文件")
 	userGroup.AddRoute("PUT", "/files/:id/move", fileHandler.MoveFile, "移动文件")
+	userGroup.AddRoute("PUT", "/files/:id/rename", fileHandler.RenameFile, "重命名文件")
+	userGroup.AddRoute("POST", "/files/:id/copy", fileHandler.CopyFile, "复制文件")
+	userGroup.AddRoute("POST", "/files/archive", archiveHandler.CreateArchive, "打包下载文件和文件夹")
+	userGroup.AddRoute("POST", "/files/import-url", urlFileHandler.ImportFromURL, "从远程URL导入文件")
+	userGroup.AddRoute("GET", "/files/archive/:task_id/download", archiveHandler.DownloadArchive, "下载异步生成的压缩包")
+	userGroup.AddRoute("POST", "/files/:id/extract", archiveHandler.ExtractArchive, "解压压缩包到文件夹")
+
+	// 批量操作路由
+	userGroup.AddRoute("POST", "/batch", batchHandler.ExecuteBatch, "批量移动、删除、复制文件、URL文件和文件夹")
+
+	// 文件版本相关路由（需要用户权限）
+	userGroup.AddRoute("GET", "/files/versions/settings", fileVersionHandler.GetVersionSettings, "获取版本保留设置")
+	userGroup.AddRoute("PUT", "/files/versions/settings", fileVersionHandler.UpdateVersionSettings, "更新版本保留设置")
+	userGroup.AddRoute("GET", "/files/:id/versions", fileVersionHandler.GetFileVersions, "获取文件历史版本列表")
+	userGroup.AddRoute("DELETE", "/files/:id/versions", fileVersionHandler.DeleteOldFileVersions, "删除文件旧版本")
+	userGroup.AddRoute("GET", "/files/:id/versions/:version_id/download", fileVersionHandler.DownloadFileVersion, "下载文件历史版本")
+	userGroup.AddRoute("POST", "/files/:id/versions/:version_id/restore", fileVersionHandler.RestoreFileVersion, "恢复文件历史版本")
+	userGroup.AddRoute("DELETE", "/files/:id/versions/:version_id", fileVersionHandler.DeleteFileVersion, "删除文件历史版本")
+
+	// 标签相关路由（需要用户权限）
+	userGroup.AddRoute("GET", "/tags", tagHandler.GetTags, "获取标签列表")
+	userGroup.AddRoute("POST", "/tags", tagHandler.CreateTag, "创建标签")
+	userGroup.AddRoute("PUT", "/tags/:id", tagHandler.UpdateTag, "重命名标签或修改颜色")
+	userGroup.AddRoute("DELETE", "/tags/:id", tagHandler.DeleteTag, "删除标签")
+	userGroup.AddRoute("GET", "/tags/:id/items", fileHandler.ListTagItems, "列出带有标签的文件、文件夹和URL文件")
+	userGroup.AddRoute("POST", "/tags/attach", tagHandler.AttachTags, "批量打标签")
+	userGroup.AddRoute("POST", "/tags/detach", tagHandler.DetachTags, "批量取消标签")
+	userGroup.AddRoute("GET", "/tags/assignments", tagHandler.GetItemTags, "查询对象上的标签")
+
+	// 收藏与最近访问路由（需要用户权限）
+	userGroup.AddRoute("GET", "/favorites", activityHandler.GetFavorites, "获取收藏列表")
+	userGroup.AddRoute("POST", "/favorites", activityHandler.AddFavorite, "收藏文件、文件夹或URL文件")
+	userGroup.AddRoute("DELETE", "/favorites/:type/:id", activityHandler.RemoveFavorite, "取消收藏")
+	userGroup.AddRoute("GET", "/recent", activityHandler.GetRecent, "获取最近访问列表")
+	userGroup.AddRoute("DELETE", "/recent", activityHandler.ClearRecent, "清空最近访问")
+	userGroup.AddRoute("DELETE", "/recent/:type/:id", activityHandler.RemoveRecent, "从最近访问中移除")
+	userGroup.AddRoute("GET", "/recent/settings", activityHandler.GetRecentSettings, "获取最近访问记录设置")
+	userGroup.AddRoute("PUT", "/recent/settings", activityHandler.UpdateRecentSettings, "开启或关闭最近访问记录")
+
+	// 站内通知路由（需要用户权限）
+	userGroup.AddRoute("GET", "/notifications", notificationHandler.GetNotifications, "获取站内通知")
+	userGroup.AddRoute("PUT", "/notifications/read-all", notificationHandler.MarkAllNotificationsRead, "将全部通知标记为已读")
+	userGroup.AddRoute("PUT", "/notifications/:id/read", notificationHandler.MarkNotificationRead, "将通知标记为已读")
+
+	// 应用专用密码路由（用于 WebDAV 等客户端）
+	userGroup.AddRoute("GET", "/app-tokens", appTokenHandler.GetAppTokens, "获取应用专用密码列表")
+	userGroup.AddRoute("POST", "/app-tokens", appTokenHandler.CreateAppToken, "创建应用专用密码")
+	userGroup.AddRoute("DELETE", "/app-tokens/:id", appTokenHandler.DeleteAppToken, "吊销应用专用密码")
+
+	// SSH公钥路由（SFTP 登录）
+	userGroup.AddRoute("GET", "/ssh-keys", sshKeyHandler.GetSSHKeys, "获取SSH公钥列表")
+	userGroup.AddRoute("POST", "/ssh-keys", sshKeyHandler.AddSSHKey, "登记SSH公钥")
+	userGroup.AddRoute("DELETE", "/ssh-keys/:id", sshKeyHandler.DeleteSSHKey, "删除SSH公钥")
+
+	// 变更日志路由（同步客户端增量同步）
+	userGroup.AddRoute("GET", "/changes", changeHandler.GetChanges, "获取文件和文件夹变更")
+
+	// 实时事件流路由
+	userGroup.AddRoute("GET", "/events", eventHandler.StreamEvents, "订阅实时事件（SSE）")
+	userGroup.AddRoute("GET", "/events/ws", eventHandler.StreamEventsWebSocket, "订阅实时事件（WebSocket）")
+
+	// 零知识保险库路由，内容和名称由客户端加密；列表和同步沿用文件、文件夹和变更日志接口
+	userGroup.AddRoute("GET", "/vault-devices", vaultHandler.GetVaultDevices, "获取保险库设备列表")
+	userGroup.AddRoute("POST", "/vault-devices", vaultHandler.AddVaultDevice, "登记保险库设备公钥")
+	userGroup.AddRoute("DELETE", "/vault-devices/:id", vaultHandler.DeleteVaultDevice, "删除保险库设备")
+	userGroup.AddRoute("GET", "/vaults", vaultHandler.GetVaults, "获取保险库列表")
+	userGroup.AddRoute("POST", "/vaults", vaultHandler.CreateVault, "创建保险库")
+	userGroup.AddRoute("DELETE", "/vaults/:id", vaultHandler.DeleteVault, "删除保险库")
+	userGroup.AddRoute("GET", "/vaults/:id/keys", vaultHandler.GetVaultKeys, "获取保险库密钥信封")
+	userGroup.AddRoute("PUT", "/vaults/:id/keys", vaultHandler.PutVaultKeys, "写入保险库密钥信封")
+	userGroup.AddRoute("DELETE", "/vaults/:id/keys/:device_id", vaultHandler.DeleteVaultKey, "撤销设备的保险库密钥")
+	userGroup.AddRoute("POST", "/vaults/:id/folders", vaultHandler.CreateVaultFolder, "在保险库中创建文件夹")
+	userGroup.AddRoute("PUT", "/vaults/:id/folders/:folder_id", vaultHandler.UpdateVaultFolder, "重命名或移动保险库文件夹")
+	userGroup.AddRoute("DELETE", "/vaults/:id/folders/:folder_id", vaultHandler.DeleteVaultFolder, "删除保险库文件夹")
+	userGroup.AddRoute("POST", "/vaults/:id/files", vaultHandler.UploadVaultFile, "上传加密文件到保险库")
+	userGroup.AddRoute("PUT", "/vaults/:id/files/:file_id", vaultHandler.UpdateVaultFile, "重命名、移动保险库文件或更新文件密钥")
+	userGroup.AddRoute("PUT", "/vaults/:id/files/:file_id/content", vaultHandler.ReplaceVaultFileContent, "替换保险库文件内容")
+	userGroup.AddRoute("DELETE", "/vaults/:id/files/:file_id", vaultHandler.DeleteVaultFile, "删除保险库文件")
+
+	// 分享链接管理路由（需要用户权限）
+	userGroup.AddRoute("POST", "/shares", shareHandler.CreateShare, "创建分享链接")
+	userGroup.AddRoute("GET", "/shares", shareHandler.GetShares, "获取分享链接列表")
+	userGroup.AddRoute("DELETE", "/shares/:id", shareHandler.RevokeShare, "撤销分享链接")
+	userGroup.AddRoute("GET", "/shares/:id/logs", shareHandler.GetShareLogs, "获取分享访问日志")
+
+	// 用户间共享相关路由（需要用户权限）
+	userGroup.AddRoute("POST", "/grants", grantHandler.CreateGrant, "共享文件或文件夹给用户或用户组")
+	userGroup.AddRoute("GET", "/grants", grantHandler.GetGrants, "获取共享授权列表")
+	userGroup.AddRoute("PUT", "/grants/:id", grantHandler.UpdateGrant, "修改共享权限")
+	userGroup.AddRoute("DELETE", "/grants/:id", grantHandler.DeleteGrant, "取消共享")
+	userGroup.AddRoute("GET", "/shared-with-me", grantHandler.GetSharedWithMe, "获取共享给我的文件和文件夹")
+	userGroup.AddRoute("GET", "/shared-with-me/folders/:id", grantHandler.GetSharedFolderContents, "浏览共享给我的文件夹")
+
+	// 用户组相关路由（需要用户权限）
+	userGroup.AddRoute("POST", "/groups", grantHandler.CreateGroup, "创建用户组")
+	userGroup.AddRoute("GET", "/groups", grantHandler.GetGroups, "获取用户组列表")
+	userGroup.AddRoute("DELETE", "/groups/:id", grantHandler.DeleteGroup, "删除用户组")
+	userGroup.AddRoute("GET", "/groups/:id/members", grantHandler.GetGroupMembers, "获取用户组成员")
+	userGroup.AddRoute("POST", "/groups/:id/members", grantHandler.AddGroupMember, "添加用户组成员")
+	userGroup.AddRoute("DELETE", "/groups/:id/members/:member_id", grantHandler.RemoveGroupMember, "移除用户组成员")
 
 	// URL文件相关路由（需要用户权限）
 	userGroup.AddRoute("GET", "/url-files", urlFileHandler.GetUrlFiles, "获取URL文件列表")
@@ -122,9 +243,14 @@ func (r *Router) SetupRoutes(
 
 	// 文件夹相关路由（需要用户权限）
 	userGroup.AddRoute("GET", "/folders", folderHandler.GetFolders, "获取文件夹列表")
+	userGroup.AddRoute("GET", "/folders/tree", folderHandler.GetFolderTree, "获取文件夹树及统计")
+	userGroup.AddRoute("GET", "/folders/:id/path", folderHandler.GetFolderPath, "获取文件夹祖先路径")
 	userGroup.AddRoute("POST", "/folders", folderHandler.CreateFolder, "创建文件夹")
 	userGroup.AddRoute("PUT", "/folders/:id", folderHandler.UpdateFolder, "更新文件夹")
+	userGroup.AddRoute("PATCH", "/folders/:id", folderHandler.UpdateFolderMetadata, "修改文件夹描述和自定义元数据")
 	userGroup.AddRoute("DELETE", "/folders/:id", folderHandler.DeleteFolder, "删除文件夹")
+	userGroup.AddRoute("PUT", "/folders/:id/move", folderHandler.MoveFolder, "移动文件夹")
+	userGroup.AddRoute("POST", "/folders/:id/copy", folderHandler.CopyFolder, "复制文件夹")
 	userGroup.AddRoute("GET", "/folders/:id/count", folderHandler.GetFolderFileCount, "获取文件夹文件数量")
 
 	// 存储相关路由（需要用户权限）
@@ -152,9 +278,37 @@ func (r *Router) SetupRoutes(
 	apiGroup.AddRoute("GET", "/update-logs/stats", updateLogHandler.GetUpdateLogStats, "获取更新日志统计")
 	apiGroup.AddRoute("POST", "/update-logs/validate", updateLogHandler.ValidateUpdateLogs, "验证更新日志数据完整性")
 
+	// 公开分享访问路由（无需登录）
+	shareGroup := r.RegisterGroup("share", "/s")
+	shareGroup.AddRoute("GET", "/:slug", shareHandler.GetPublicShare, "访问分享链接")
+	shareGroup.AddRoute("POST", "/:slug", shareHandler.GetPublicShare, "提交密码访问分享链接")
+	shareGroup.AddRoute("GET", "/:slug/download", shareHandler.DownloadPublicShare, "通过分享链接下载文件")
+	shareGroup.AddRoute("POST", "/:slug/download", shareHandler.DownloadPublicShare, "提交密码通过分享链接下载文件")
+	shareGroup.AddRoute("POST", "/:slug/upload", shareHandler.UploadToPublicShare, "向分享文件夹上传文件")
+
 	// 管理员清理任务路由
 	adminGroup.AddRoute("POST", "/upload/cleanup", uploadProgressHandler.CleanupOldTasks, "清理旧上传任务")
 
+	// 管理员病毒扫描路由
+	adminGroup.AddRoute("GET", "/scan/status", scanHandler.GetScanStatus, "获取病毒扫描服务状态")
+	adminGroup.AddRoute("GET", "/scan/infected", scanHandler.GetInfectedFiles, "获取被隔离的文件列表")
+	adminGroup.AddRoute("POST", "/scan/rescan", scanHandler.RescanAll, "重新扫描全部文件")
+	adminGroup.AddRoute("GET", "/encryption/status", encryptionHandler.GetEncryptionStatus, "获取存储加密状态")
+	adminGroup.AddRoute("POST", "/encryption/migrate", encryptionHandler.MigrateStorage, "加密已有的明文文件")
+	adminGroup.AddRoute("POST", "/encryption/rotate", encryptionHandler.RotateKeys, "轮换存储加密密钥")
+
+	// 上传文件预览路由（需要用户权限），按存储路径查找文件并检查查看权限
+	uploadsGroup := r.RegisterGroup("uploads", "/uploads", authHandler.CheckUserPermission())
+	uploadsGroup.AddRoute("GET", "/*filepath", fileHandler.ServeUpload, "预览上传的文件")
+	uploadsGroup.AddRoute("HEAD", "/*filepath", fileHandler.ServeUpload, "获取上传文件的信息")
+
+	// WebDAV 路由（Basic 认证），/dav/ 对应当前用户的根目录
+	davGroup := r.RegisterGroup("webdav", "/dav", webdavHandler.Authenticate())
+	for _, method := range handlers.WebDAVMethods {
+		davGroup.AddRoute(method, "", webdavHandler.ServeDAV, "WebDAV 访问网盘根目录")
+		davGroup.AddRoute(method, "/*path", webdavHandler.ServeDAV, "WebDAV 访问网盘文件和文件夹")
+	}
+
 	// 注册静态文件列表路由
 	r.registerStaticFilesRoutes()
 
@@ -218,11 +372,7 @@ func (r *Router) registerStaticRoutes() {
 		}
 	}
 
-	// 获取 backend 的绝对路径
-	backendDir, _ := os.Getwd()
-	projectRoot := filepath.Dir(backendDir)
-	uploadsPath := filepath.Join(projectRoot, "uploads")
-	r.engine.Static("/uploads", uploadsPath)
+	// 上传目录中的文件可能已加密存储，由需要登录的 /uploads 路由组按权限解密后返回
 }
 
 // registerPageRoutes 注册页面路由
@@ -776,6 +926,9 @@ func (r *Router) applyGroups() {
 				ginGroup.HEAD(route.Path, route.Handler)
 			case "OPTIONS":
 				ginGroup.OPTIONS(route.Path, route.Handler)
+			default:
+				// WebDAV 等扩展方法
+				ginGroup.Handle(route.Method, route.Path, route.Handler)
 			}
 		}
 	}
@@ -0,0 +1,222 @@
+/**
+ * 文件内容索引服务
+ *
+ * 在后台为文件建立全文检索索引：
+ * - 定期扫描没有索引或内容已变化（存储路径、校验和不一致）的文件
+ * - 上传、替换等操作后可立即唤醒扫描，无需等待下一轮
+ * - 提取文本后写入 file_contents 表的全文索引
+ *
+ * 同时维护文件、文件夹、URL文件名称的拼音索引（name_pinyin 表），供模糊搜索使用
+ *
+ * 文件删除时内容索引记录由仓库层在同一事务中清理，拼音索引在每轮扫描时清理
+ */
+
+package services
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/config"
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+)
+
+// ContentIndexService 文件内容与名称拼音索引服务
+type ContentIndexService struct {
+	contentRepo database.FileContentRepositoryInterface
+	nameRepo    database.NamePinyinRepositoryInterface
+	config      *config.SearchConfig
+
+	notify   chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewContentIndexService 创建文件内容索引服务
+func NewContentIndexService(contentRepo database.FileContentRepositoryInterface, nameRepo database.NamePinyinRepositoryInterface, cfg *config.SearchConfig) *ContentIndexService {
+	return &ContentIndexService{
+		contentRepo: contentRepo,
+		nameRepo:    nameRepo,
+		config:      cfg,
+		notify:      make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Start 启动后台索引协程，启动后立即执行一轮扫描
+func (s *ContentIndexService) Start() {
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.config.IndexInterval)
+		defer ticker.Stop()
+
+		s.runOnce()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.runOnce()
+			case <-s.notify:
+				s.runOnce()
+			}
+		}
+	}()
+}
+
+// Stop 停止后台索引协程，等待当前文件处理完成
+func (s *ContentIndexService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+}
+
+// Notify 唤醒后台索引协程处理新上传、替换或重命名的对象，不会阻塞调用方
+func (s *ContentIndexService) Notify() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// runOnce 执行一轮扫描：先同步名称拼音（开销小），再提取文件内容
+func (s *ContentIndexService) runOnce() {
+	s.indexNames()
+	s.indexPending()
+}
+
+// indexNames 为新建或重命名的对象生成拼音索引，并清理已删除对象的索引
+func (s *ContentIndexService) indexNames() {
+	if _, err := s.nameRepo.DeleteOrphanNames(); err != nil {
+		log.Printf("⚠️ 清理名称拼音索引失败: %v", err)
+	}
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		sources, err := s.nameRepo.GetNamesToIndex(s.config.NameIndexBatchSize)
+		if err != nil {
+			log.Printf("⚠️ 查询待建立拼音索引的名称失败: %v", err)
+			return
+		}
+
+		names := make([]models.NamePinyin, 0, len(sources))
+		for _, source := range sources {
+			full, initials := utils.NamePinyin(source.Name)
+			names = append(names, models.NamePinyin{
+				ItemType:   source.ItemType,
+				ItemID:     source.ItemID,
+				UserID:     source.UserID,
+				Name:       source.Name,
+				FullPinyin: strings.Join(full, " "),
+				Initials:   strings.Join(initials, " "),
+				UpdatedAt:  time.Now(),
+			})
+		}
+		if err := s.nameRepo.UpsertNames(names); err != nil {
+			log.Printf("⚠️ 写入名称拼音索引失败: %v", err)
+			return
+		}
+
+		if len(sources) < s.config.NameIndexBatchSize {
+			return
+		}
+	}
+}
+
+// indexPending 分批处理待索引的文件，直到没有待处理文件或收到停止信号
+func (s *ContentIndexService) indexPending() {
+	for {
+		files, err := s.contentRepo.GetFilesToIndex(s.config.IndexBatchSize)
+		if err != nil {
+			log.Printf("⚠️ 查询待索引文件失败: %v", err)
+			return
+		}
+
+		for _, file := range files {
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+			if err := s.IndexFile(file); err != nil {
+				log.Printf("⚠️ 写入文件 %d 的内容索引失败: %v", file.ID, err)
+				return
+			}
+		}
+
+		if len(files) < s.config.IndexBatchSize {
+			return
+		}
+	}
+}
+
+// IndexFile 提取单个文件的文本并写入索引。
+// 不支持的类型和提取失败同样记录状态，文件内容变化前不会重复处理
+func (s *ContentIndexService) IndexFile(file models.File) error {
+	content := &models.FileContent{
+		FileID:         file.ID,
+		UserID:         file.UserID,
+		SourcePath:     file.Path,
+		SourceChecksum: file.Checksum,
+		Status:         models.ContentIndexed,
+		IndexedAt:      time.Now(),
+	}
+
+	switch {
+	case !utils.IsTextExtractable(file.Name):
+		content.Status = models.ContentUnsupported
+	case file.Size > s.config.MaxIndexFileSize:
+		content.Status = models.ContentUnsupported
+		content.Error = "文件超过内容索引大小限制"
+	default:
+		absolutePath := utils.GetFileAbsolutePath(file.Path)
+		text, err := utils.ExtractText(absolutePath, file.EncryptionKeyID, file.Name, s.config.MaxIndexFileSize, s.config.MaxContentLength)
+		switch {
+		case errors.Is(err, utils.ErrUnsupportedContent):
+			content.Status = models.ContentUnsupported
+		case os.IsNotExist(err):
+			content.Status = models.ContentFailed
+			content.Error = "文件不存在"
+		case err != nil:
+			content.Status = models.ContentFailed
+			content.Error = truncateIndexError(err.Error())
+		default:
+			content.Content = text
+		}
+	}
+
+	if err := s.contentRepo.UpsertContent(content); err != nil {
+		if content.Content == "" {
+			return err
+		}
+		// 文本写入失败（如超出数据包大小）时记录失败状态，避免每轮重复处理同一文件
+		content.Status = models.ContentFailed
+		content.Content = ""
+		content.Error = truncateIndexError("写入索引失败: " + err.Error())
+		return s.contentRepo.UpsertContent(content)
+	}
+	return nil
+}
+
+// truncateIndexError 截断错误信息以适应字段长度
+func truncateIndexError(message string) string {
+	runes := []rune(message)
+	if len(runes) > 200 {
+		return string(runes[:200])
+	}
+	return message
+}
@@ -0,0 +1,422 @@
+/**
+ * 实时事件推送服务
+ *
+ * 为每个在线用户维护事件订阅，通过事件流接口（SSE 或 WebSocket）推送：
+ * - 上传、打包等任务的进度（由 UploadQueueManager 回调）
+ * - 文件、文件夹和URL文件的变更（后台协程跟踪变更日志）
+ * - 存储空间使用情况的变化
+ * - 新的站内通知
+ *
+ * 变更和通知从数据库读取，多个后端进程部署时同样能收到其他进程产生的变更。
+ * 每个用户保留最近的事件用于断线重连时按 Last-Event-ID 补发，补发不了时推送 reset 事件
+ */
+
+package services
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+)
+
+const (
+	eventPollInterval     = time.Second      // 检查变更日志和通知的间隔
+	eventQuotaInterval    = 30 * time.Second // 没有变更时重新统计存储空间的间隔，覆盖删除历史版本、调整配额等不产生变更的操作
+	eventReplaySize       = 256              // 每个用户保留用于补发的事件数量
+	eventReplayGrace      = 5 * time.Minute  // 最后一个连接断开后继续保留事件的时间，覆盖刷新页面、网络切换等短暂断线
+	eventSubscriberBuffer = 64               // 每个连接的待发送事件数量，写满说明客户端过慢，断开后由客户端重连补发
+	eventNotificationMax  = 100              // 每轮推送的通知数量上限
+)
+
+// EventHub 实时事件推送服务
+type EventHub struct {
+	changeRepo       database.ChangeRepositoryInterface
+	notificationRepo database.NotificationRepositoryInterface
+	userRepo         database.UserRepositoryInterface
+	fileRepo         database.FileRepositoryInterface
+	urlFileRepo      database.UrlFileRepositoryInterface
+
+	// epoch 区分进程，重启后之前的事件ID无法补发
+	epoch string
+
+	mu                 sync.Mutex
+	lastID             uint64
+	users              map[string]*eventUser
+	lastNotificationID uint
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// eventUser 单个用户的订阅和推送状态
+type eventUser struct {
+	subscribers map[*EventSubscription]struct{}
+	firstID     uint64         // 创建状态后的第一个事件ID，更早的事件没有保留
+	evictedID   uint64         // 已从补发队列中移除的最大事件ID
+	recent      []models.Event // 最近的事件，不含上传进度
+	idleSince   time.Time      // 最后一个连接断开的时间
+
+	changeSeq    int64
+	quota        *models.StorageInfo
+	quotaChecked time.Time
+	quotaDirty   bool
+}
+
+// EventSubscription 一个事件流连接的订阅，Events 在订阅结束或客户端过慢时关闭
+type EventSubscription struct {
+	Events chan models.Event
+
+	hub    *EventHub
+	userID string
+	closed bool
+}
+
+// NewEventHub 创建实时事件推送服务
+func NewEventHub(changeRepo database.ChangeRepositoryInterface, notificationRepo database.NotificationRepositoryInterface, userRepo database.UserRepositoryInterface, fileRepo database.FileRepositoryInterface, urlFileRepo database.UrlFileRepositoryInterface) *EventHub {
+	return &EventHub{
+		changeRepo:       changeRepo,
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+		fileRepo:         fileRepo,
+		urlFileRepo:      urlFileRepo,
+		epoch:            strconv.FormatInt(time.Now().UnixNano(), 36),
+		users:            make(map[string]*eventUser),
+		stop:             make(chan struct{}),
+		done:             make(chan struct{}),
+	}
+}
+
+// Start 启动后台协程跟踪变更日志、通知和存储空间
+func (h *EventHub) Start() {
+	go func() {
+		defer close(h.done)
+		ticker := time.NewTicker(eventPollInterval)
+		defer ticker.Stop()
+
+		// 只推送启动之后的通知
+		latest, err := h.notificationRepo.GetLatestNotificationID()
+		if err != nil {
+			log.Printf("⚠️ 获取最新通知ID失败: %v", err)
+		}
+		h.mu.Lock()
+		h.lastNotificationID = latest
+		h.mu.Unlock()
+
+		for {
+			select {
+			case <-h.stop:
+				return
+			case <-ticker.C:
+				h.poll()
+			}
+		}
+	}()
+}
+
+// Stop 停止后台协程并关闭所有订阅
+func (h *EventHub) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.stop)
+		<-h.done
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		for _, state := range h.users {
+			for sub := range state.subscribers {
+				sub.close()
+			}
+		}
+	})
+}
+
+// Subscribe 订阅用户的事件。lastEventID 为客户端收到的最后一个事件ID（新连接为空），
+// 返回需要先发送的事件：可以补发时为断线期间的事件加一个 ready 事件，否则为一个 reset 事件。
+// 两者都带有新的事件ID和变更日志游标，客户端即使之后没有收到其他事件，重连时也能补发
+func (h *EventHub) Subscribe(userID, lastEventID string) (*EventSubscription, []models.Event, error) {
+	latest, err := h.changeRepo.GetLatestChangeSeq(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state, exists := h.users[userID]
+	if !exists {
+		state = &eventUser{
+			subscribers: make(map[*EventSubscription]struct{}),
+			firstID:     h.lastID + 1,
+			changeSeq:   latest,
+			quotaDirty:  true,
+		}
+		h.users[userID] = state
+	}
+
+	var initial []models.Event
+	initialType := models.EventReady
+	if lastEventID != "" {
+		replay, ok := state.replayAfter(h.epoch, lastEventID, h.lastID)
+		if ok {
+			initial = replay
+		} else {
+			initialType = models.EventReset
+		}
+	}
+	h.lastID++
+	initial = append(initial, models.Event{
+		ID:   h.eventID(h.lastID),
+		Type: initialType,
+		Data: map[string]string{"cursor": strconv.FormatInt(state.changeSeq, 10)},
+	})
+
+	sub := &EventSubscription{
+		Events: make(chan models.Event, eventSubscriberBuffer),
+		hub:    h,
+		userID: userID,
+	}
+	state.subscribers[sub] = struct{}{}
+	// 新连接需要当前的存储空间，下一轮统计时推送给所有连接
+	state.quota = nil
+	state.quotaDirty = true
+	return sub, initial, nil
+}
+
+// Unsubscribe 结束订阅，用户状态在宽限期内保留用于补发
+func (s *EventSubscription) Unsubscribe() {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s.close()
+	if state, exists := h.users[s.userID]; exists {
+		delete(state.subscribers, s)
+		if len(state.subscribers) == 0 {
+			state.idleSince = time.Now()
+		}
+	}
+}
+
+// close 关闭事件通道，调用方需持有锁
+func (s *EventSubscription) close() {
+	if !s.closed {
+		s.closed = true
+		close(s.Events)
+	}
+}
+
+// PublishUploadTask 推送任务进度，作为 UploadQueueManager 的监听函数
+func (h *EventHub) PublishUploadTask(task utils.UploadTask) {
+	h.publish(task.UserID, models.EventUpload, task)
+}
+
+// publish 向用户的所有连接推送事件，用户没有订阅状态时丢弃
+func (h *EventHub) publish(userID, eventType string, data interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state, exists := h.users[userID]
+	if !exists {
+		return
+	}
+
+	h.lastID++
+	event := models.Event{ID: h.eventID(h.lastID), Type: eventType, Data: data}
+	// 上传进度更新频繁且只反映当前状态，不占用补发队列，重连后通过任务列表接口获取
+	if eventType != models.EventUpload {
+		state.recent = append(state.recent, event)
+		if len(state.recent) > eventReplaySize {
+			evicted := state.recent[0]
+			state.evictedID, _ = parseEventSeq(h.epoch, evicted.ID)
+			state.recent = state.recent[1:]
+		}
+	}
+
+	for sub := range state.subscribers {
+		select {
+		case sub.Events <- event:
+		default:
+			// 客户端处理过慢，断开连接，客户端重连后按 Last-Event-ID 补发
+			sub.close()
+			delete(state.subscribers, sub)
+			if len(state.subscribers) == 0 {
+				state.idleSince = time.Now()
+			}
+		}
+	}
+}
+
+// eventID 生成事件ID：进程标识加递增序号
+func (h *EventHub) eventID(seq uint64) string {
+	return h.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// replayAfter 返回 lastEventID 之后保留的事件，事件已被移除、状态曾被清理或ID不属于当前进程时返回 false
+func (u *eventUser) replayAfter(epoch, lastEventID string, lastID uint64) ([]models.Event, bool) {
+	seq, ok := parseEventSeq(epoch, lastEventID)
+	if !ok || seq > lastID || seq < u.firstID || seq < u.evictedID {
+		return nil, false
+	}
+	replay := []models.Event{}
+	for _, event := range u.recent {
+		if eventSeq, _ := parseEventSeq(epoch, event.ID); eventSeq > seq {
+			replay = append(replay, event)
+		}
+	}
+	return replay, true
+}
+
+// parseEventSeq 解析当前进程生成的事件ID中的序号
+func parseEventSeq(epoch, id string) (uint64, bool) {
+	prefix, seq, found := strings.Cut(id, "-")
+	if !found || prefix != epoch {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
+
+// poll 清理过期的用户状态，推送新的变更、通知和存储空间变化
+func (h *EventHub) poll() {
+	h.mu.Lock()
+	userIDs := make([]string, 0, len(h.users))
+	for userID, state := range h.users {
+		if len(state.subscribers) == 0 && time.Since(state.idleSince) > eventReplayGrace {
+			delete(h.users, userID)
+			continue
+		}
+		userIDs = append(userIDs, userID)
+	}
+	h.mu.Unlock()
+	if len(userIDs) == 0 {
+		return
+	}
+
+	h.pollChanges(userIDs)
+	h.pollNotifications(userIDs)
+	h.pollQuota(userIDs)
+}
+
+// pollChanges 推送变更日志中的新记录
+func (h *EventHub) pollChanges(userIDs []string) {
+	seqs, err := h.changeRepo.GetLatestChangeSeqs(userIDs)
+	if err != nil {
+		log.Printf("⚠️ 获取变更序号失败: %v", err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		h.mu.Lock()
+		state, exists := h.users[userID]
+		var cursor int64
+		if exists {
+			cursor = state.changeSeq
+		}
+		h.mu.Unlock()
+		if !exists || seqs[userID] <= cursor {
+			continue
+		}
+
+		changes, latest, err := h.changeRepo.GetChangesSince(userID, cursor, models.MaxChangeLimit)
+		if errors.Is(err, models.ErrChangeCursorExpired) {
+			h.setChangeSeq(userID, latest)
+			h.publish(userID, models.EventReset, map[string]string{"cursor": strconv.FormatInt(latest, 10)})
+			continue
+		}
+		if err != nil {
+			log.Printf("⚠️ 获取用户 %s 的变更失败: %v", userID, err)
+			continue
+		}
+		for _, change := range changes {
+			h.publish(userID, models.EventChange, change)
+		}
+		if len(changes) > 0 {
+			h.setChangeSeq(userID, changes[len(changes)-1].Seq)
+		}
+	}
+}
+
+// setChangeSeq 记录已推送的变更序号，并在下一次统计时更新存储空间
+func (h *EventHub) setChangeSeq(userID string, seq int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if state, exists := h.users[userID]; exists {
+		state.changeSeq = seq
+		state.quotaDirty = true
+	}
+}
+
+// pollNotifications 推送新的站内通知
+func (h *EventHub) pollNotifications(userIDs []string) {
+	h.mu.Lock()
+	afterID := h.lastNotificationID
+	h.mu.Unlock()
+
+	// 先取最大ID再查询，查询期间新增的通知留到下一轮，避免跳过
+	latest, err := h.notificationRepo.GetLatestNotificationID()
+	if err != nil {
+		log.Printf("⚠️ 获取最新通知ID失败: %v", err)
+		return
+	}
+	if latest <= afterID {
+		return
+	}
+	notifications, err := h.notificationRepo.GetNotificationsAfter(afterID, userIDs, eventNotificationMax)
+	if err != nil {
+		log.Printf("⚠️ 获取新通知失败: %v", err)
+		return
+	}
+	// 达到数量上限时只推进到已推送的位置，其余下一轮继续；其他用户的通知不需要推送，直接跳过
+	if len(notifications) == eventNotificationMax {
+		latest = min(latest, notifications[len(notifications)-1].ID)
+	}
+	for _, notification := range notifications {
+		if notification.ID <= latest {
+			h.publish(notification.UserID, models.EventNotification, notification)
+		}
+	}
+
+	h.mu.Lock()
+	h.lastNotificationID = latest
+	h.mu.Unlock()
+}
+
+// pollQuota 为有连接的用户统计存储空间，发生变化时推送
+func (h *EventHub) pollQuota(userIDs []string) {
+	for _, userID := range userIDs {
+		h.mu.Lock()
+		state, exists := h.users[userID]
+		due := exists && len(state.subscribers) > 0 &&
+			(state.quotaDirty || time.Since(state.quotaChecked) > eventQuotaInterval)
+		h.mu.Unlock()
+		if !due {
+			continue
+		}
+
+		info, err := LoadStorageInfo(h.userRepo, h.fileRepo, h.urlFileRepo, userID)
+		if err != nil {
+			log.Printf("⚠️ 统计用户 %s 的存储空间失败: %v", userID, err)
+			continue
+		}
+
+		h.mu.Lock()
+		state, exists = h.users[userID]
+		changed := exists && (state.quota == nil || *state.quota != *info)
+		if exists {
+			state.quota = info
+			state.quotaChecked = time.Now()
+			state.quotaDirty = false
+		}
+		h.mu.Unlock()
+		if changed {
+			h.publish(userID, models.EventQuota, info)
+		}
+	}
+}
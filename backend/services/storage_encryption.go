@@ -0,0 +1,450 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"backend/config"
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+)
+
+// encryptionBatchSize 加密迁移和密钥轮换每批处理的记录数
+const encryptionBatchSize = 200
+
+// ErrEncryptionNotConfigured 未配置主密钥
+var ErrEncryptionNotConfigured = errors.New("未配置存储加密主密钥")
+
+// ErrEncryptionJobRunning 已有存储加密后台任务在进行
+var ErrEncryptionJobRunning = errors.New("已有存储加密任务正在进行")
+
+// masterKey 主密钥及其指纹，指纹记录在数据密钥上，用于选择解密用的主密钥
+type masterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+func newMasterKey(key []byte) (*masterKey, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(key)
+	return &masterKey{id: hex.EncodeToString(sum[:8]), aead: aead}, nil
+}
+
+// wrap 加密数据密钥，附加数据绑定所属用户，防止数据密钥记录被挪给其他用户
+func (m *masterKey) wrap(userID string, dataKey []byte) ([]byte, error) {
+	nonce := make([]byte, m.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return m.aead.Seal(nonce, nonce, dataKey, []byte("user-key:"+userID)), nil
+}
+
+func (m *masterKey) unwrap(userID string, wrapped []byte) ([]byte, error) {
+	size := m.aead.NonceSize()
+	if len(wrapped) < size {
+		return nil, errors.New("数据密钥格式错误")
+	}
+	return m.aead.Open(nil, wrapped[:size], wrapped[size:], []byte("user-key:"+userID))
+}
+
+// StorageEncryptionService 存储加密服务：管理用户数据密钥，实现 utils.StorageKeyring，并执行加密迁移和密钥轮换
+type StorageEncryptionService struct {
+	repo    database.UserKeyRepositoryInterface
+	enabled bool
+	masters []*masterKey // 第一个为当前主密钥
+
+	// 已解开的数据密钥缓存，active 记录各用户使用中的数据密钥ID
+	mu     sync.Mutex
+	keys   map[uint64]*utils.DataKey
+	active map[string]uint64
+
+	jobMu sync.Mutex
+	job   *models.EncryptionJobProgress
+}
+
+// NewStorageEncryptionService 创建存储加密服务，主密钥配置错误时返回错误
+func NewStorageEncryptionService(cfg config.EncryptionConfig, repo database.UserKeyRepositoryInterface) (*StorageEncryptionService, error) {
+	s := &StorageEncryptionService{
+		repo:   repo,
+		keys:   make(map[uint64]*utils.DataKey),
+		active: make(map[string]uint64),
+	}
+	rawKeys, err := cfg.LoadMasterKeys()
+	if err != nil {
+		return s, err
+	}
+	for _, raw := range rawKeys {
+		master, err := newMasterKey(raw)
+		if err != nil {
+			return s, err
+		}
+		s.masters = append(s.masters, master)
+	}
+	s.enabled = cfg.Enabled && len(s.masters) > 0
+	return s, nil
+}
+
+// Configured 是否配置了主密钥，未配置时已加密的文件无法读取
+func (s *StorageEncryptionService) Configured() bool {
+	return len(s.masters) > 0
+}
+
+// Enabled 是否加密新写入的文件
+func (s *StorageEncryptionService) Enabled() bool {
+	return s.enabled
+}
+
+// ActiveKey 获取用户使用中的数据密钥，没有时生成新密钥；未启用加密时返回 nil
+func (s *StorageEncryptionService) ActiveKey(userID string) (*utils.DataKey, error) {
+	if !s.enabled {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, ok := s.active[userID]; ok {
+		return s.keys[id], nil
+	}
+	record, err := s.repo.GetActiveUserKey(userID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return s.createKeyLocked(userID, false)
+	}
+	key, err := s.unwrapLocked(record)
+	if err != nil {
+		return nil, err
+	}
+	s.active[userID] = key.ID
+	return key, nil
+}
+
+// KeyByID 按ID获取数据密钥，用于解密
+func (s *StorageEncryptionService) KeyByID(keyID uint64) (*utils.DataKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, ok := s.keys[keyID]; ok {
+		return key, nil
+	}
+	record, err := s.repo.GetUserKey(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("数据密钥 %d 不存在", keyID)
+	}
+	return s.unwrapLocked(record)
+}
+
+// unwrapLocked 以记录上的主密钥解开数据密钥并缓存，调用方需持有 mu
+func (s *StorageEncryptionService) unwrapLocked(record *models.UserKey) (*utils.DataKey, error) {
+	master := s.master(record.MasterKeyID)
+	if master == nil {
+		return nil, fmt.Errorf("数据密钥 %d 的主密钥 %s 未配置", record.ID, record.MasterKeyID)
+	}
+	raw, err := master.unwrap(record.UserID, record.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("解开数据密钥 %d 失败: %v", record.ID, err)
+	}
+	key := &utils.DataKey{ID: record.ID, Key: raw}
+	s.keys[key.ID] = key
+	return key, nil
+}
+
+// createKeyLocked 为用户生成新的数据密钥，replace 为 true 时停用原有密钥，调用方需持有 mu
+func (s *StorageEncryptionService) createKeyLocked(userID string, replace bool) (*utils.DataKey, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	current := s.masters[0]
+	wrapped, err := current.wrap(userID, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &models.UserKey{
+		UserID:      userID,
+		WrappedKey:  wrapped,
+		MasterKeyID: current.id,
+		Status:      models.UserKeyActive,
+	}
+	if replace {
+		err = s.repo.ReplaceActiveUserKey(record)
+	} else {
+		err = s.repo.CreateUserKey(record)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &utils.DataKey{ID: record.ID, Key: raw}
+	s.keys[key.ID] = key
+	s.active[userID] = key.ID
+	return key, nil
+}
+
+func (s *StorageEncryptionService) master(id string) *masterKey {
+	for _, master := range s.masters {
+		if master.id == id {
+			return master
+		}
+	}
+	return nil
+}
+
+// Status 获取加密配置、数据密钥统计和正在进行的后台任务
+func (s *StorageEncryptionService) Status() (*models.EncryptionStatusResponse, error) {
+	response := &models.EncryptionStatusResponse{
+		Success:    true,
+		Enabled:    s.enabled,
+		Configured: s.Configured(),
+		Job:        s.JobProgress(),
+	}
+	if !s.Configured() {
+		return response, nil
+	}
+	response.MasterKeyID = s.masters[0].id
+
+	active, retired, toRewrap, err := s.repo.CountUserKeys(response.MasterKeyID)
+	if err != nil {
+		return nil, err
+	}
+	response.ActiveKeys = active
+	response.RetiredKeys = retired
+	response.KeysToRewrap = toRewrap
+	return response, nil
+}
+
+// BeginJob 登记一个后台任务，已有任务在进行时返回 false
+func (s *StorageEncryptionService) BeginJob(taskID, jobType string) bool {
+	s.jobMu.Lock()
+	defer s.jobMu.Unlock()
+	if s.job != nil {
+		return false
+	}
+	s.job = &models.EncryptionJobProgress{TaskID: taskID, Type: jobType}
+	return true
+}
+
+// EndJob 结束登记的后台任务
+func (s *StorageEncryptionService) EndJob() {
+	s.jobMu.Lock()
+	s.job = nil
+	s.jobMu.Unlock()
+}
+
+// JobProgress 获取正在进行的后台任务的进度，没有时返回 nil
+func (s *StorageEncryptionService) JobProgress() *models.EncryptionJobProgress {
+	s.jobMu.Lock()
+	defer s.jobMu.Unlock()
+	if s.job == nil {
+		return nil
+	}
+	progress := *s.job
+	return &progress
+}
+
+func (s *StorageEncryptionService) updateJob(update func(progress *models.EncryptionJobProgress)) models.EncryptionJobProgress {
+	s.jobMu.Lock()
+	defer s.jobMu.Unlock()
+	if s.job == nil {
+		return models.EncryptionJobProgress{}
+	}
+	update(s.job)
+	return *s.job
+}
+
+// RunMigration 将已有的明文文件和历史版本原地加密，已加密的跳过；结束时自动调用 EndJob
+func (s *StorageEncryptionService) RunMigration(onProgress func(models.EncryptionJobProgress)) error {
+	defer s.EndJob()
+	if !s.enabled {
+		return errors.New("未启用存储加密")
+	}
+	return s.rewriteContent(onProgress, func(ref models.StoredContentRef) (bool, error) {
+		return ref.KeyID == 0, nil
+	})
+}
+
+// RunRotation 以当前主密钥重新加密全部数据密钥，之后即可从配置中移除旧主密钥。
+// reencrypt 为 true 时还为每个用户生成新的数据密钥，并用新密钥重新加密全部文件内容；
+// 旧数据密钥标记为已停用后保留，确保未能重写的文件仍可读取。结束时自动调用 EndJob
+func (s *StorageEncryptionService) RunRotation(reencrypt bool, onProgress func(models.EncryptionJobProgress)) error {
+	defer s.EndJob()
+	if !s.Configured() {
+		return ErrEncryptionNotConfigured
+	}
+	if reencrypt && !s.enabled {
+		return errors.New("未启用存储加密，无法生成新的数据密钥")
+	}
+
+	if err := s.rewrapKeys(reencrypt); err != nil {
+		return err
+	}
+	if !reencrypt {
+		return nil
+	}
+
+	return s.rewriteContent(onProgress, func(ref models.StoredContentRef) (bool, error) {
+		if ref.KeyID == 0 {
+			return false, nil
+		}
+		active, err := s.ActiveKey(ref.UserID)
+		if err != nil {
+			return false, err
+		}
+		return active.ID != ref.KeyID, nil
+	})
+}
+
+// rewrapKeys 将不是由当前主密钥加密的数据密钥重新加密，rotate 为 true 时为每个使用中的密钥生成替代密钥
+func (s *StorageEncryptionService) rewrapKeys(rotate bool) error {
+	current := s.masters[0]
+	// 本次新生成的密钥ID更大，会在之后的批次中再次读到，不能再次轮换
+	created := make(map[uint64]bool)
+	var afterID uint64
+	for {
+		records, err := s.repo.GetUserKeysAfterID(afterID, encryptionBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for i := range records {
+			record := &records[i]
+			afterID = record.ID
+
+			if record.MasterKeyID != current.id {
+				s.mu.Lock()
+				key, err := s.unwrapLocked(record)
+				s.mu.Unlock()
+				if err != nil {
+					return err
+				}
+				wrapped, err := current.wrap(record.UserID, key.Key)
+				if err != nil {
+					return err
+				}
+				if err := s.repo.UpdateWrappedKey(record.ID, wrapped, current.id); err != nil {
+					return err
+				}
+			}
+
+			if rotate && record.Status == models.UserKeyActive && !created[record.ID] {
+				s.mu.Lock()
+				key, err := s.createKeyLocked(record.UserID, true)
+				s.mu.Unlock()
+				if err != nil {
+					return err
+				}
+				created[key.ID] = true
+			}
+		}
+
+		if len(records) < encryptionBatchSize {
+			return nil
+		}
+	}
+}
+
+// rewriteContent 遍历全部当前文件和历史版本，needsRewrite 根据记录中的数据密钥ID判断，返回 true 的内容以所有者使用中的数据密钥重新写入。
+// 单个文件失败只记录日志并计入失败数，不中断任务
+func (s *StorageEncryptionService) rewriteContent(onProgress func(models.EncryptionJobProgress), needsRewrite func(ref models.StoredContentRef) (bool, error)) error {
+	total, err := s.repo.CountStoredContent()
+	if err != nil {
+		return err
+	}
+	s.updateJob(func(progress *models.EncryptionJobProgress) { progress.Total = int(total) })
+
+	process := func(ref models.StoredContentRef, update func(ref models.StoredContentRef, path string, keyID uint64) (bool, error)) {
+		rewritten, err := s.rewriteOne(ref, needsRewrite, update)
+		if err != nil {
+			log.Printf("⚠️ 加密文件 %s 失败: %v", ref.Path, err)
+		}
+		current := s.updateJob(func(progress *models.EncryptionJobProgress) {
+			progress.Processed++
+			if rewritten {
+				progress.Rewritten++
+			}
+			if err != nil {
+				progress.Failed++
+			}
+		})
+		onProgress(current)
+	}
+
+	sources := []struct {
+		list   func(afterID uint, limit int) ([]models.StoredContentRef, error)
+		update func(ref models.StoredContentRef, path string, keyID uint64) (bool, error)
+	}{
+		{s.repo.GetFileContentAfterID, s.repo.UpdateFileContentKey},
+		{s.repo.GetVersionContentAfterID, s.repo.UpdateVersionContentKey},
+	}
+	for _, source := range sources {
+		var afterID uint
+		for {
+			refs, err := source.list(afterID, encryptionBatchSize)
+			if err != nil {
+				return err
+			}
+			for _, ref := range refs {
+				afterID = ref.ID
+				process(ref, source.update)
+			}
+			if len(refs) < encryptionBatchSize {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// rewriteOne 将内容重新加密到同目录的新文件，记录仍指向原内容时改为指向新文件并删除原文件；
+// 期间记录被修改或删除时放弃新文件，原内容由修改方处理
+func (s *StorageEncryptionService) rewriteOne(ref models.StoredContentRef, needsRewrite func(ref models.StoredContentRef) (bool, error), update func(ref models.StoredContentRef, path string, keyID uint64) (bool, error)) (bool, error) {
+	rewrite, err := needsRewrite(ref)
+	if err != nil || !rewrite {
+		return false, err
+	}
+
+	absolutePath := utils.GetFileAbsolutePath(ref.Path)
+	if ref.Quarantined {
+		absolutePath = utils.GetQuarantinePath(ref.Path)
+	}
+	newAbsolutePath, keyID, err := utils.ReencryptStoredFile(absolutePath, ref.KeyID, ref.UserID)
+	if os.IsNotExist(err) {
+		// 文件在遍历期间被删除或移动
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	newPath := path.Join(path.Dir(ref.Path), filepath.Base(newAbsolutePath))
+	updated, err := update(ref, newPath, keyID)
+	if err != nil || !updated {
+		os.Remove(newAbsolutePath)
+		return false, err
+	}
+	os.Remove(absolutePath)
+	return true, nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+)
+
+// LoadStorageInfo 统计用户的存储使用情况：普通文件和历史版本按实际大小计算，
+// URL文件不占用实际存储空间，每个计为1字节以便在统计中体现
+func LoadStorageInfo(userRepo database.UserRepositoryInterface, fileRepo database.FileRepositoryInterface, urlFileRepo database.UrlFileRepositoryInterface, userID string) (*models.StorageInfo, error) {
+	// 获取用户存储限制
+	_, storageLimit, err := userRepo.GetUserStorageInfo(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 获取用户实际使用的存储空间（普通文件）
+	fileUsedSpace, err := fileRepo.GetUserTotalStorage(userID)
+	if err != nil {
+		// 如果获取已使用空间失败，使用默认值0
+		fileUsedSpace = 0
+	}
+
+	// 获取用户URL文件数量（URL文件不占用实际存储空间，但计入总数）
+	urlFileCount, err := urlFileRepo.GetUserTotalUrlFileCount(userID)
+	if err != nil {
+		// 如果获取URL文件数量失败，使用默认值0
+		urlFileCount = 0
+	}
+
+	// 计算总使用空间（普通文件大小 + URL文件计数）
+	storageInfo := &models.StorageInfo{
+		UsedSpace:  fileUsedSpace + int64(urlFileCount),
+		TotalSpace: storageLimit,
+	}
+
+	// 防御性处理，只在真正异常时才重置
+	if storageInfo.UsedSpace < 0 {
+		storageInfo.UsedSpace = 0
+	}
+	if storageInfo.TotalSpace < 0 {
+		storageInfo.TotalSpace = 0
+	}
+
+	// 格式化存储大小
+	storageInfo.UsedSpaceStr = utils.FormatStorageSize(storageInfo.UsedSpace)
+	storageInfo.TotalSpaceStr = utils.FormatStorageSize(storageInfo.TotalSpace)
+	return storageInfo, nil
+}
@@ -0,0 +1,405 @@
+/**
+ * 上传文件病毒扫描服务
+ *
+ * 通过可替换的扫描器（默认 clamd INSTREAM）检查上传的文件：
+ * - 小文件在上传请求中同步扫描，大文件标记为待扫描后由后台协程处理
+ * - 发现病毒的文件移入隔离目录（不在公开的上传目录中），记录状态后禁止下载
+ * - 新发现的感染文件会通知文件所有者和管理员
+ * - 管理员可以发起全量重新扫描，特征库更新后被判定为安全的隔离文件会被恢复
+ *
+ * 扫描器连接失败时文件保持待扫描状态，由后台协程在下一轮重试
+ */
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"backend/config"
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+)
+
+// ErrRescanFailed 全量重新扫描因扫描器不可用而中断
+var ErrRescanFailed = errors.New("扫描服务不可用，重新扫描已中断")
+
+// VirusScanService 上传文件病毒扫描服务
+type VirusScanService struct {
+	scanner          utils.VirusScanner // 未配置扫描服务时为 nil
+	scanRepo         database.FileScanRepositoryInterface
+	notificationRepo database.NotificationRepositoryInterface
+	userRepo         database.UserRepositoryInterface
+	config           *config.ScanConfig
+
+	notify   chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	rescanMu sync.Mutex
+	rescan   *models.RescanProgress
+}
+
+// NewVirusScanService 创建病毒扫描服务，scanner 为 nil 时不扫描上传的文件
+func NewVirusScanService(scanner utils.VirusScanner, scanRepo database.FileScanRepositoryInterface, notificationRepo database.NotificationRepositoryInterface, userRepo database.UserRepositoryInterface, cfg *config.ScanConfig) *VirusScanService {
+	return &VirusScanService{
+		scanner:          scanner,
+		scanRepo:         scanRepo,
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+		config:           cfg,
+		notify:           make(chan struct{}, 1),
+		stop:             make(chan struct{}),
+		done:             make(chan struct{}),
+	}
+}
+
+// Enabled 是否启用了病毒扫描
+func (s *VirusScanService) Enabled() bool {
+	return s != nil && s.scanner != nil
+}
+
+// Start 启动后台扫描协程，启动后立即处理一轮待扫描文件
+func (s *VirusScanService) Start() {
+	if !s.Enabled() {
+		close(s.done)
+		return
+	}
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+
+		s.scanPending()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.scanPending()
+			case <-s.notify:
+				s.scanPending()
+			}
+		}
+	}()
+}
+
+// Stop 停止后台扫描协程，等待当前文件处理完成
+func (s *VirusScanService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+}
+
+// Notify 唤醒后台扫描协程处理新的待扫描文件，不会阻塞调用方
+func (s *VirusScanService) Notify() {
+	if !s.Enabled() {
+		return
+	}
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Ping 检查扫描器是否可以连接
+func (s *VirusScanService) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+	return s.scanner.Ping(ctx)
+}
+
+// ResetScanState 重置文件的扫描状态，在写入新内容的文件记录保存前调用
+func (s *VirusScanService) ResetScanState(file *models.File) {
+	file.ScanStatus = ""
+	if s.Enabled() {
+		file.ScanStatus = models.ScanStatusPending
+	}
+	file.ScanResult = ""
+	file.ScannedAt = nil
+}
+
+// ScanUpload 扫描刚保存的文件：不超过同步扫描大小的文件立即扫描，
+// 发现病毒时返回 models.ErrFileInfected；更大的文件或扫描器暂时不可用时交给后台处理
+func (s *VirusScanService) ScanUpload(file *models.File) error {
+	if !s.Enabled() || file.ScanStatus != models.ScanStatusPending {
+		return nil
+	}
+	if file.Size > s.config.SyncMaxSize {
+		s.Notify()
+		return nil
+	}
+	if err := s.ScanFile(file); err != nil {
+		log.Printf("⚠️ 同步扫描文件 %d 失败，转为后台扫描: %v", file.ID, err)
+		s.Notify()
+		return nil
+	}
+	if file.IsInfected() {
+		return models.ErrFileInfected
+	}
+	return nil
+}
+
+// ScanFile 扫描单个文件并保存结果，扫描结果会同步写回 file。
+// 返回错误表示扫描器暂时不可用或文件正在被修改，文件保持原状态等待重试
+func (s *VirusScanService) ScanFile(file *models.File) error {
+	if file.IsVault() {
+		_, err := s.saveResult(file, models.ScanStatusSkipped, "保险库文件由客户端加密，无法扫描")
+		return err
+	}
+	if file.Size > s.config.MaxScanSize {
+		_, err := s.saveResult(file, models.ScanStatusSkipped, "文件超过扫描大小限制")
+		return err
+	}
+
+	wasInfected := file.IsInfected()
+	storedPath := utils.GetFileAbsolutePath(file.Path)
+	if wasInfected {
+		storedPath = utils.GetQuarantinePath(file.Path)
+	}
+
+	reader, err := utils.OpenStoredContent(storedPath, file.EncryptionKeyID)
+	if os.IsNotExist(err) {
+		_, err = s.saveResult(file, models.ScanStatusError, "文件不存在")
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	result, err := s.scan(reader)
+	reader.Close()
+
+	var rejected *utils.ScanRejectedError
+	switch {
+	case errors.As(err, &rejected):
+		_, err = s.saveResult(file, models.ScanStatusError, rejected.Message)
+		return err
+	case err != nil:
+		return err
+	}
+
+	if !result.Infected {
+		if !wasInfected {
+			_, err = s.saveResult(file, models.ScanStatusClean, "")
+			return err
+		}
+		// 先恢复文件再更新状态，状态未更新时重新隔离
+		if err := utils.ReleaseQuarantinedFile(file.Path); err != nil {
+			return err
+		}
+		if updated, err := s.saveResult(file, models.ScanStatusClean, ""); err != nil || !updated {
+			if quarantineErr := utils.QuarantineStoredFile(file.Path); quarantineErr != nil {
+				log.Printf("⚠️ 重新隔离文件 %d 失败: %v", file.ID, quarantineErr)
+			}
+			return err
+		}
+		return nil
+	}
+
+	if wasInfected {
+		_, err = s.saveResult(file, models.ScanStatusInfected, result.Signature)
+		return err
+	}
+	// 先移出公开目录再更新状态；扫描期间内容被替换或重命名时撤销隔离，由后续扫描处理新内容
+	if err := utils.QuarantineStoredFile(file.Path); err != nil {
+		return err
+	}
+	updated, err := s.saveResult(file, models.ScanStatusInfected, result.Signature)
+	if err != nil || !updated {
+		if releaseErr := utils.ReleaseQuarantinedFile(file.Path); releaseErr != nil {
+			log.Printf("⚠️ 撤销隔离文件 %d 失败: %v", file.ID, releaseErr)
+		}
+		return err
+	}
+	log.Printf("🦠 文件 %d (%s) 检测到病毒 %s，已隔离", file.ID, file.Name, result.Signature)
+	s.notifyInfected(file)
+	return nil
+}
+
+// scan 在超时时间内将内容发送给扫描器
+func (s *VirusScanService) scan(reader io.Reader) (*utils.VirusScanResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+	return s.scanner.Scan(ctx, reader)
+}
+
+// saveResult 保存扫描结果，文件的存储路径已变化时不更新并返回 false
+func (s *VirusScanService) saveResult(file *models.File, status, result string) (bool, error) {
+	result = truncateScanResult(result)
+	updated, err := s.scanRepo.UpdateScanResult(file.ID, file.Path, status, result)
+	if err != nil || !updated {
+		return false, err
+	}
+	now := time.Now()
+	file.ScanStatus = status
+	file.ScanResult = result
+	file.ScannedAt = &now
+	return true, nil
+}
+
+// notifyInfected 通知文件所有者和管理员文件已被隔离
+func (s *VirusScanService) notifyInfected(file *models.File) {
+	itemID := file.ID
+	content := fmt.Sprintf("文件「%s」中检测到病毒 %s，已被隔离，无法下载", file.Name, file.ScanResult)
+	notifications := []models.Notification{{
+		UserID:   file.UserID,
+		Type:     models.NotificationFileInfected,
+		Title:    "文件已被隔离",
+		Content:  content,
+		ItemType: "file",
+		ItemID:   &itemID,
+	}}
+
+	// 同时通知管理员（Mose）
+	if admin, err := s.userRepo.GetUserByUsername("Mose"); err == nil && admin.UUID != file.UserID {
+		owner := file.UserID
+		if user, err := s.userRepo.GetUserByUUID(file.UserID); err == nil {
+			owner = user.Username
+		}
+		notifications = append(notifications, models.Notification{
+			UserID:   admin.UUID,
+			Type:     models.NotificationFileInfected,
+			Title:    "用户文件已被隔离",
+			Content:  fmt.Sprintf("用户 %s 的%s", owner, content),
+			ItemType: "file",
+			ItemID:   &itemID,
+		})
+	}
+
+	if err := s.notificationRepo.CreateNotifications(notifications); err != nil {
+		log.Printf("⚠️ 创建文件 %d 的隔离通知失败: %v", file.ID, err)
+	}
+}
+
+// scanPending 分批处理待扫描的文件，直到没有待处理文件、扫描器不可用或收到停止信号
+func (s *VirusScanService) scanPending() {
+	for {
+		files, err := s.scanRepo.GetFilesToScan(s.config.BatchSize)
+		if err != nil {
+			log.Printf("⚠️ 查询待扫描文件失败: %v", err)
+			return
+		}
+
+		for i := range files {
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+			if err := s.ScanFile(&files[i]); err != nil {
+				log.Printf("⚠️ 扫描文件 %d 失败: %v", files[i].ID, err)
+				return
+			}
+		}
+
+		if len(files) < s.config.BatchSize {
+			return
+		}
+	}
+}
+
+// BeginRescan 登记一次全量重新扫描，已有重新扫描在进行时返回 false
+func (s *VirusScanService) BeginRescan(taskID string) bool {
+	s.rescanMu.Lock()
+	defer s.rescanMu.Unlock()
+	if s.rescan != nil {
+		return false
+	}
+	s.rescan = &models.RescanProgress{TaskID: taskID}
+	return true
+}
+
+// EndRescan 结束登记的全量重新扫描
+func (s *VirusScanService) EndRescan() {
+	s.rescanMu.Lock()
+	s.rescan = nil
+	s.rescanMu.Unlock()
+}
+
+// RescanProgress 获取正在进行的全量重新扫描的进度，没有时返回 nil
+func (s *VirusScanService) RescanProgress() *models.RescanProgress {
+	s.rescanMu.Lock()
+	defer s.rescanMu.Unlock()
+	if s.rescan == nil {
+		return nil
+	}
+	progress := *s.rescan
+	return &progress
+}
+
+// RunRescan 按ID顺序重新扫描全部文件，每处理一个文件调用一次 onProgress，结束时自动调用 EndRescan
+func (s *VirusScanService) RunRescan(onProgress func(models.RescanProgress)) error {
+	defer s.EndRescan()
+
+	total, err := s.scanRepo.CountFiles()
+	if err != nil {
+		return err
+	}
+	s.updateRescan(func(progress *models.RescanProgress) { progress.Total = int(total) })
+
+	var afterID uint
+	for {
+		files, err := s.scanRepo.GetFilesAfterID(afterID, s.config.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		for i := range files {
+			select {
+			case <-s.stop:
+				return ErrRescanFailed
+			default:
+			}
+			file := &files[i]
+			afterID = file.ID
+			if err := s.ScanFile(file); err != nil {
+				// 文件在扫描期间被修改时跳过，扫描器不可用时中断
+				if pingErr := s.Ping(); pingErr != nil {
+					log.Printf("⚠️ 重新扫描在文件 %d 处中断: %v", file.ID, err)
+					return ErrRescanFailed
+				}
+				log.Printf("⚠️ 重新扫描文件 %d 失败: %v", file.ID, err)
+			}
+			var current models.RescanProgress
+			s.updateRescan(func(progress *models.RescanProgress) {
+				progress.Scanned++
+				if file.IsInfected() {
+					progress.Infected++
+				}
+				current = *progress
+			})
+			onProgress(current)
+		}
+
+		if len(files) < s.config.BatchSize {
+			return nil
+		}
+	}
+}
+
+// updateRescan 在锁内修改重新扫描进度
+func (s *VirusScanService) updateRescan(update func(progress *models.RescanProgress)) {
+	s.rescanMu.Lock()
+	defer s.rescanMu.Unlock()
+	if s.rescan != nil {
+		update(s.rescan)
+	}
+}
+
+// truncateScanResult 截断扫描结果以适应字段长度
+func truncateScanResult(result string) string {
+	runes := []rune(result)
+	if len(runes) > 255 {
+		return string(runes[:255])
+	}
+	return result
+}
@@ -0,0 +1,476 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ArchiveEntry 压缩包中的一个条目
+type ArchiveEntry struct {
+	Name         string // 压缩包内路径（使用/分隔），目录条目以/结尾
+	AbsolutePath string // 磁盘绝对路径，目录条目为空
+	KeyID        uint64 // AbsolutePath 的存储加密数据密钥ID，明文为0
+	Data         []byte // 在内存中生成的内容，非空时代替 AbsolutePath
+	Size         int64
+	Modified     time.Time
+}
+
+// IsDir 是否为目录条目
+func (e ArchiveEntry) IsDir() bool {
+	return strings.HasSuffix(e.Name, "/")
+}
+
+// ArchiveNameSet 记录已使用的压缩包路径，用于处理同名冲突
+type ArchiveNameSet struct {
+	used map[string]bool
+}
+
+// NewArchiveNameSet 创建压缩包路径集合
+func NewArchiveNameSet() *ArchiveNameSet {
+	return &ArchiveNameSet{used: make(map[string]bool)}
+}
+
+// Reserve 占用一个路径，冲突时在文件名后追加 " (n)" 并返回实际使用的路径
+func (s *ArchiveNameSet) Reserve(entryPath string) string {
+	isDir := strings.HasSuffix(entryPath, "/")
+	trimmed := strings.TrimSuffix(entryPath, "/")
+
+	candidate := trimmed
+	dir, base := path.Split(trimmed)
+	ext := ""
+	if !isDir {
+		ext = path.Ext(base)
+	}
+	nameWithoutExt := strings.TrimSuffix(base, ext)
+
+	for counter := 1; s.used[strings.ToLower(candidate)]; counter++ {
+		candidate = fmt.Sprintf("%s%s (%d)%s", dir, nameWithoutExt, counter, ext)
+	}
+	s.used[strings.ToLower(candidate)] = true
+
+	if isDir {
+		return candidate + "/"
+	}
+	return candidate
+}
+
+// SanitizeArchiveName 清理单级名称中的路径分隔符，避免在压缩包中产生意外的层级
+func SanitizeArchiveName(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(strings.TrimSpace(name))
+	if name == "" || name == "." || name == ".." {
+		return "unnamed"
+	}
+	return name
+}
+
+// getArchiveJobRoot 异步打包任务结果的根目录，每个用户一个子目录
+func getArchiveJobRoot() string {
+	return filepath.Join(os.TempDir(), "star-cloud", "archives")
+}
+
+// GetArchiveJobDir 获取异步打包任务结果的存放目录（不在公开的上传目录中）
+func GetArchiveJobDir(userID string) string {
+	return filepath.Join(getArchiveJobRoot(), userID)
+}
+
+// CleanupArchiveJobs 删除修改时间早于 ttl 的打包结果（包括中断时遗留的临时文件）和清空后的用户目录，返回删除的文件数
+func CleanupArchiveJobs(ttl time.Duration) (int, error) {
+	root := getArchiveJobRoot()
+	userDirs, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-ttl)
+	removed := 0
+	for _, userDir := range userDirs {
+		if !userDir.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, userDir.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		remaining := len(entries)
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil || !info.Mode().IsRegular() || info.ModTime().After(cutoff) {
+				continue
+			}
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err == nil {
+				removed++
+				remaining--
+			}
+		}
+		if remaining == 0 {
+			// 目录非空（期间有新任务写入）时删除失败，忽略即可
+			os.Remove(dir)
+		}
+	}
+	return removed, nil
+}
+
+// SweepArchiveJobs 启动时清理一次过期的打包结果，之后每隔 interval 清理一次。
+// 结果文件还会在生成后由定时器删除，这里兜底处理服务重启后丢失的定时器
+func SweepArchiveJobs(ttl, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if removed, err := CleanupArchiveJobs(ttl); err != nil {
+			log.Printf("⚠️ 清理过期打包结果失败: %v", err)
+		} else if removed > 0 {
+			log.Printf("✅ 已清理 %d 个过期打包结果", removed)
+		}
+		<-ticker.C
+	}
+}
+
+// progressWriter 统计写入字节数并回调进度
+type progressWriter struct {
+	writer     io.Writer
+	written    int64
+	onProgress func(written int64)
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.writer.Write(p)
+	pw.written += int64(n)
+	if pw.onProgress != nil {
+		pw.onProgress(pw.written)
+	}
+	return n, err
+}
+
+// WriteZipArchive 按顺序将条目写入zip流，不产生临时文件；
+// onProgress 在写入过程中回调已读取的原始文件字节数，可为nil
+func WriteZipArchive(w io.Writer, entries []ArchiveEntry, onProgress func(written int64)) error {
+	zipWriter := zip.NewWriter(w)
+
+	var completed int64
+	for _, entry := range entries {
+		header := &zip.FileHeader{
+			Name:     entry.Name,
+			Modified: entry.Modified,
+		}
+
+		if entry.IsDir() {
+			if _, err := zipWriter.CreateHeader(header); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if entry.Data != nil {
+			header.Method = zip.Deflate
+			dst, err := zipWriter.CreateHeader(header)
+			if err != nil {
+				return err
+			}
+			if _, err := dst.Write(entry.Data); err != nil {
+				return err
+			}
+			continue
+		}
+
+		src, err := OpenStoredContent(entry.AbsolutePath, entry.KeyID)
+		if err != nil {
+			// 磁盘文件丢失时跳过该条目，不中断整个压缩包
+			continue
+		}
+
+		header.Method = zip.Deflate
+		dst, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			src.Close()
+			return err
+		}
+
+		base := completed
+		writer := &progressWriter{writer: dst, onProgress: func(written int64) {
+			if onProgress != nil {
+				onProgress(base + written)
+			}
+		}}
+
+		buffer := make([]byte, 32*1024) // 32KB buffer
+		copied, err := io.CopyBuffer(writer, src, buffer)
+		src.Close()
+		if err != nil {
+			return err
+		}
+		completed += copied
+	}
+
+	return zipWriter.Close()
+}
+
+// 支持解压的压缩格式
+const (
+	ArchiveFormatZip   = "zip"
+	ArchiveFormatTar   = "tar"
+	ArchiveFormatTarGz = "tar.gz"
+)
+
+// ratioCheckMinSize 单个条目超过该大小才检查压缩比，避免小文件误判
+const ratioCheckMinSize = 1024 * 1024
+
+// DetectArchiveFormat 根据文件名判断压缩格式，不支持时返回空字符串
+func DetectArchiveFormat(fileName string) string {
+	lower := strings.ToLower(fileName)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return ArchiveFormatZip
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return ArchiveFormatTarGz
+	case strings.HasSuffix(lower, ".tar"):
+		return ArchiveFormatTar
+	}
+	return ""
+}
+
+// TrimArchiveExt 去掉压缩包扩展名，用作解压目标文件夹名称
+func TrimArchiveExt(fileName string) string {
+	lower := strings.ToLower(fileName)
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar", ".zip"} {
+		if strings.HasSuffix(lower, ext) {
+			return fileName[:len(fileName)-len(ext)]
+		}
+	}
+	return fileName
+}
+
+// ArchiveLimits 解压限制
+type ArchiveLimits struct {
+	MaxTotalSize int64 // 解压后总大小
+	MaxEntries   int   // 最大条目数
+	MaxFileSize  int64 // 单个文件最大大小
+	MaxRatio     int64 // 最大压缩比
+}
+
+// ArchiveSummary 压缩包扫描结果
+type ArchiveSummary struct {
+	FileCount int   `json:"file_count"`
+	DirCount  int   `json:"dir_count"`
+	TotalSize int64 `json:"total_size"`
+}
+
+// ExtractedEntry 压缩包中的一个条目（路径已清理，使用/分隔，不以/结尾）
+type ExtractedEntry struct {
+	Path     string
+	IsDir    bool
+	Size     int64
+	Modified time.Time
+}
+
+// CleanArchiveEntryPath 清理压缩包条目路径，拒绝绝对路径和跳出目标目录的路径（zip-slip）
+func CleanArchiveEntryPath(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || (len(name) >= 2 && name[1] == ':') {
+		return "", fmt.Errorf("压缩包包含绝对路径: %s", name)
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("压缩包包含非法路径: %s", name)
+		}
+	}
+
+	cleaned := strings.Trim(path.Clean("/"+name), "/")
+	if cleaned == "" || cleaned == "." {
+		return "", nil
+	}
+	return cleaned, nil
+}
+
+// walkArchive 遍历压缩包中的目录和普通文件，跳过符号链接等特殊条目
+func walkArchive(archivePath string, keyID uint64, format string, fn func(entry ExtractedEntry, compressedSize int64, open func() (io.ReadCloser, error)) error) error {
+	file, err := OpenStoredContent(archivePath, keyID)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if format == ArchiveFormatZip {
+		reader, err := zip.NewReader(file, file.Size())
+		if err != nil {
+			return fmt.Errorf("无法读取zip文件: %v", err)
+		}
+
+		for _, f := range reader.File {
+			mode := f.Mode()
+			if mode&os.ModeSymlink != 0 || (!mode.IsDir() && !mode.IsRegular()) {
+				continue
+			}
+
+			entryPath, err := CleanArchiveEntryPath(f.Name)
+			if err != nil {
+				return err
+			}
+			if entryPath == "" {
+				continue
+			}
+
+			entry := ExtractedEntry{
+				Path:     entryPath,
+				IsDir:    mode.IsDir(),
+				Size:     int64(f.UncompressedSize64),
+				Modified: f.Modified,
+			}
+			if err := fn(entry, int64(f.CompressedSize64), f.Open); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var source io.Reader = file
+	if format == ArchiveFormatTarGz {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("无法读取gzip文件: %v", err)
+		}
+		defer gzipReader.Close()
+		source = gzipReader
+	}
+
+	tarReader := tar.NewReader(source)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("无法读取tar文件: %v", err)
+		}
+
+		if header.Typeflag != tar.TypeDir && header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		entryPath, err := CleanArchiveEntryPath(header.Name)
+		if err != nil {
+			return err
+		}
+		if entryPath == "" {
+			continue
+		}
+
+		entry := ExtractedEntry{
+			Path:     entryPath,
+			IsDir:    header.Typeflag == tar.TypeDir,
+			Size:     header.Size,
+			Modified: header.ModTime,
+		}
+		open := func() (io.ReadCloser, error) { return io.NopCloser(tarReader), nil }
+		if err := fn(entry, 0, open); err != nil {
+			return err
+		}
+	}
+}
+
+// checkEntryLimits 检查单个条目及累计值是否超出限制
+func checkEntryLimits(entry ExtractedEntry, compressedSize int64, summary *ArchiveSummary, limits ArchiveLimits) error {
+	if summary.FileCount+summary.DirCount > limits.MaxEntries {
+		return fmt.Errorf("压缩包条目数量超过限制(%d)", limits.MaxEntries)
+	}
+	if entry.IsDir {
+		return nil
+	}
+	if entry.Size < 0 || entry.Size > limits.MaxFileSize {
+		return fmt.Errorf("文件 %s 解压后超过单文件大小限制(%s)", entry.Path, FormatStorageSize(limits.MaxFileSize))
+	}
+	if summary.TotalSize > limits.MaxTotalSize {
+		return fmt.Errorf("压缩包解压后超过总大小限制(%s)", FormatStorageSize(limits.MaxTotalSize))
+	}
+	if compressedSize > 0 && entry.Size > ratioCheckMinSize && entry.Size/compressedSize > limits.MaxRatio {
+		return fmt.Errorf("文件 %s 压缩比异常，疑似压缩炸弹", entry.Path)
+	}
+	return nil
+}
+
+// ScanArchive 扫描压缩包（keyID 为其存储加密数据密钥ID），校验路径、条目数量、大小和压缩比，返回解压后的统计信息
+func ScanArchive(archivePath string, keyID uint64, format string, limits ArchiveLimits) (*ArchiveSummary, error) {
+	archiveInfo, err := os.Stat(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &ArchiveSummary{}
+	err = walkArchive(archivePath, keyID, format, func(entry ExtractedEntry, compressedSize int64, open func() (io.ReadCloser, error)) error {
+		if entry.IsDir {
+			summary.DirCount++
+		} else {
+			summary.FileCount++
+			summary.TotalSize += entry.Size
+		}
+		return checkEntryLimits(entry, compressedSize, summary, limits)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 整体压缩比检查（tar.gz 无法获得单个条目的压缩大小）
+	if archiveInfo.Size() > 0 && summary.TotalSize > ratioCheckMinSize && summary.TotalSize/archiveInfo.Size() > limits.MaxRatio {
+		return nil, fmt.Errorf("压缩包压缩比异常，疑似压缩炸弹")
+	}
+
+	return summary, nil
+}
+
+// boundedReader 读取超过声明大小时返回错误，防止条目头信息与实际内容不符
+type boundedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (br *boundedReader) Read(p []byte) (int, error) {
+	n, err := br.reader.Read(p)
+	br.remaining -= int64(n)
+	if br.remaining < 0 {
+		return n, fmt.Errorf("条目实际大小超过声明大小")
+	}
+	return n, err
+}
+
+// ExtractArchiveEntries 依次解压条目并交给 handle 处理（目录条目的 reader 为nil），
+// 解压过程中再次执行限制检查，实际内容超出声明大小时中止
+func ExtractArchiveEntries(archivePath string, keyID uint64, format string, limits ArchiveLimits, handle func(entry ExtractedEntry, reader io.Reader) error) error {
+	summary := &ArchiveSummary{}
+	return walkArchive(archivePath, keyID, format, func(entry ExtractedEntry, compressedSize int64, open func() (io.ReadCloser, error)) error {
+		if entry.IsDir {
+			summary.DirCount++
+		} else {
+			summary.FileCount++
+			summary.TotalSize += entry.Size
+		}
+		if err := checkEntryLimits(entry, compressedSize, summary, limits); err != nil {
+			return err
+		}
+
+		if entry.IsDir {
+			return handle(entry, nil)
+		}
+
+		reader, err := open()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		return handle(entry, &boundedReader{reader: reader, remaining: entry.Size})
+	})
+}
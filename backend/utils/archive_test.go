@@ -0,0 +1,378 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testArchiveFile 测试用压缩包条目，名称以/结尾表示目录
+type testArchiveFile struct {
+	name    string
+	content []byte
+}
+
+// writeTestZip 在临时目录中生成 zip 文件
+func writeTestZip(t *testing.T, files []testArchiveFile) string {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for _, f := range files {
+		w, err := writer.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			t.Fatalf("写入zip条目失败: %v", err)
+		}
+		if _, err := w.Write(f.content); err != nil {
+			t.Fatalf("写入zip内容失败: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("生成zip失败: %v", err)
+	}
+	return writeTestArchive(t, "test.zip", buffer.Bytes())
+}
+
+// writeTestTarGz 在临时目录中生成 tar.gz 文件
+func writeTestTarGz(t *testing.T, files []testArchiveFile) string {
+	t.Helper()
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	writer := tar.NewWriter(gzipWriter)
+	for _, f := range files {
+		header := &tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.content)), Typeflag: tar.TypeReg, ModTime: time.Now()}
+		if strings.HasSuffix(f.name, "/") {
+			header.Typeflag, header.Mode, header.Size = tar.TypeDir, 0755, 0
+		}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatalf("写入tar条目失败: %v", err)
+		}
+		if _, err := writer.Write(f.content); err != nil {
+			t.Fatalf("写入tar内容失败: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("生成tar失败: %v", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatalf("生成gzip失败: %v", err)
+	}
+	return writeTestArchive(t, "test.tar.gz", buffer.Bytes())
+}
+
+func writeTestArchive(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	archivePath := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(archivePath, data, 0600); err != nil {
+		t.Fatalf("写入压缩包失败: %v", err)
+	}
+	return archivePath
+}
+
+// testArchiveLimits 足够宽松的限制，各用例只收紧需要验证的一项
+func testArchiveLimits() ArchiveLimits {
+	return ArchiveLimits{
+		MaxTotalSize: 64 * 1024 * 1024,
+		MaxEntries:   100,
+		MaxFileSize:  32 * 1024 * 1024,
+		MaxRatio:     100,
+	}
+}
+
+func TestCleanArchiveEntryPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "docs/readme.txt", want: "docs/readme.txt"},
+		{name: "docs/", want: "docs"},
+		{name: "./docs//a.txt", want: "docs/a.txt"},
+		{name: "docs\\sub\\a.txt", want: "docs/sub/a.txt"},
+		{name: ".", want: ""},
+		{name: "../evil.txt", wantErr: true},
+		{name: "docs/../../evil.txt", wantErr: true},
+		{name: "docs/../a.txt", wantErr: true},
+		{name: "..\\evil.txt", wantErr: true},
+		{name: "/etc/passwd", wantErr: true},
+		{name: "\\windows\\system32", wantErr: true},
+		{name: "C:\\Windows\\evil.dll", wantErr: true},
+		{name: "c:/evil.txt", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CleanArchiveEntryPath(tt.name)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("期望拒绝，实际返回 %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("清理失败: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("清理结果为 %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanArchiveRejectsZipSlip(t *testing.T) {
+	writers := map[string]func(t *testing.T, files []testArchiveFile) string{
+		ArchiveFormatZip:   writeTestZip,
+		ArchiveFormatTarGz: writeTestTarGz,
+	}
+	names := []string{"../evil.txt", "docs/../../evil.txt", "/etc/cron.d/evil", "..\\evil.txt"}
+
+	for format, write := range writers {
+		for _, name := range names {
+			t.Run(format+" "+name, func(t *testing.T) {
+				archivePath := write(t, []testArchiveFile{
+					{name: "ok.txt", content: []byte("ok")},
+					{name: name, content: []byte("evil")},
+				})
+
+				if _, err := ScanArchive(archivePath, 0, format, testArchiveLimits()); err == nil {
+					t.Error("扫描应拒绝跳出目标目录的条目")
+				}
+
+				handled := 0
+				err := ExtractArchiveEntries(archivePath, 0, format, testArchiveLimits(), func(entry ExtractedEntry, reader io.Reader) error {
+					if strings.Contains(entry.Path, "..") || strings.HasPrefix(entry.Path, "/") {
+						t.Errorf("非法条目被交给处理函数: %s", entry.Path)
+					}
+					handled++
+					return nil
+				})
+				if err == nil {
+					t.Error("解压应拒绝跳出目标目录的条目")
+				}
+				if handled > 1 {
+					t.Errorf("处理了 %d 个条目", handled)
+				}
+			})
+		}
+	}
+}
+
+func TestScanArchiveLimits(t *testing.T) {
+	// 高度可压缩的内容，用于触发压缩比检查
+	zeros := make([]byte, 4*1024*1024)
+	many := make([]testArchiveFile, 11)
+	for i := range many {
+		many[i] = testArchiveFile{name: string(rune('a'+i)) + ".txt", content: []byte("x")}
+	}
+
+	tests := []struct {
+		name    string
+		format  string
+		files   []testArchiveFile
+		limits  func(limits *ArchiveLimits)
+		wantErr string
+	}{
+		{
+			name:   "未超出限制",
+			format: ArchiveFormatZip,
+			files:  []testArchiveFile{{name: "docs/"}, {name: "docs/a.txt", content: []byte("hello")}},
+		},
+		{
+			name:    "条目数量",
+			format:  ArchiveFormatZip,
+			files:   many,
+			limits:  func(limits *ArchiveLimits) { limits.MaxEntries = 10 },
+			wantErr: "条目数量超过限制",
+		},
+		{
+			name:    "单文件大小",
+			format:  ArchiveFormatZip,
+			files:   []testArchiveFile{{name: "big.bin", content: bytes.Repeat([]byte("0123456789"), 1000)}},
+			limits:  func(limits *ArchiveLimits) { limits.MaxFileSize = 4096 },
+			wantErr: "超过单文件大小限制",
+		},
+		{
+			name:   "总大小",
+			format: ArchiveFormatTarGz,
+			files: []testArchiveFile{
+				{name: "a.bin", content: make([]byte, 3000)},
+				{name: "b.bin", content: make([]byte, 3000)},
+			},
+			limits:  func(limits *ArchiveLimits) { limits.MaxTotalSize = 5000 },
+			wantErr: "超过总大小限制",
+		},
+		{
+			name:    "zip条目压缩比",
+			format:  ArchiveFormatZip,
+			files:   []testArchiveFile{{name: "bomb.bin", content: zeros}},
+			wantErr: "疑似压缩炸弹",
+		},
+		{
+			name:    "tar.gz整体压缩比",
+			format:  ArchiveFormatTarGz,
+			files:   []testArchiveFile{{name: "bomb.bin", content: zeros}},
+			wantErr: "疑似压缩炸弹",
+		},
+		{
+			name:   "小文件不检查压缩比",
+			format: ArchiveFormatZip,
+			files:  []testArchiveFile{{name: "small.bin", content: make([]byte, 512*1024)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var archivePath string
+			if tt.format == ArchiveFormatZip {
+				archivePath = writeTestZip(t, tt.files)
+			} else {
+				archivePath = writeTestTarGz(t, tt.files)
+			}
+			limits := testArchiveLimits()
+			if tt.limits != nil {
+				tt.limits(&limits)
+			}
+
+			summary, err := ScanArchive(archivePath, 0, tt.format, limits)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("扫描失败: %v", err)
+				}
+				if summary.FileCount+summary.DirCount != len(tt.files) {
+					t.Errorf("统计到 %d 个条目，期望 %d", summary.FileCount+summary.DirCount, len(tt.files))
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("期望包含 %q 的错误，实际为 %v", tt.wantErr, err)
+			}
+
+			// 跳过扫描直接解压时同样中止（tar.gz 整体压缩比只在扫描时检查）
+			if tt.format == ArchiveFormatTarGz && tt.wantErr == "疑似压缩炸弹" {
+				return
+			}
+			err = ExtractArchiveEntries(archivePath, 0, tt.format, limits, func(entry ExtractedEntry, reader io.Reader) error {
+				if reader != nil {
+					_, err := io.Copy(io.Discard, reader)
+					return err
+				}
+				return nil
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("解压期望包含 %q 的错误，实际为 %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestExtractArchiveEntries(t *testing.T) {
+	files := []testArchiveFile{
+		{name: "docs/"},
+		{name: "docs/a.txt", content: []byte("hello")},
+		{name: "./docs/sub/b.txt", content: []byte("world")},
+	}
+	want := map[string]string{"docs": "", "docs/a.txt": "hello", "docs/sub/b.txt": "world"}
+
+	for _, format := range []string{ArchiveFormatZip, ArchiveFormatTarGz} {
+		t.Run(format, func(t *testing.T) {
+			archivePath := writeTestZip(t, files)
+			if format == ArchiveFormatTarGz {
+				archivePath = writeTestTarGz(t, files)
+			}
+
+			got := make(map[string]string)
+			err := ExtractArchiveEntries(archivePath, 0, format, testArchiveLimits(), func(entry ExtractedEntry, reader io.Reader) error {
+				if entry.IsDir {
+					got[entry.Path] = ""
+					return nil
+				}
+				data, err := io.ReadAll(reader)
+				got[entry.Path] = string(data)
+				return err
+			})
+			if err != nil {
+				t.Fatalf("解压失败: %v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("解压得到 %v，期望 %v", got, want)
+			}
+			for name, content := range want {
+				if got[name] != content {
+					t.Errorf("%s 内容为 %q，期望 %q", name, got[name], content)
+				}
+			}
+		})
+	}
+}
+
+func TestBoundedReader(t *testing.T) {
+	reader := &boundedReader{reader: strings.NewReader("0123456789"), remaining: 4}
+	if _, err := io.ReadAll(reader); err == nil {
+		t.Error("实际内容超过声明大小时应返回错误")
+	}
+
+	reader = &boundedReader{reader: strings.NewReader("0123"), remaining: 4}
+	data, err := io.ReadAll(reader)
+	if err != nil || string(data) != "0123" {
+		t.Errorf("读取结果为 %q (%v)", data, err)
+	}
+}
+
+func TestCleanupArchiveJobs(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	old := time.Now().Add(-2 * time.Hour)
+
+	files := []struct {
+		userID  string
+		name    string
+		modTime time.Time
+		kept    bool
+	}{
+		{userID: "alice", name: "task-1.0.zip", modTime: old},
+		{userID: "alice", name: "task-2.zip.tmp", modTime: old},
+		{userID: "alice", name: "task-3.1.zip", modTime: time.Now(), kept: true},
+		{userID: "bob", name: "task-4.0.zip", modTime: old},
+	}
+	for _, f := range files {
+		dir := GetArchiveJobDir(f.userID)
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatalf("创建目录失败: %v", err)
+		}
+		filePath := filepath.Join(dir, f.name)
+		if err := os.WriteFile(filePath, []byte("zip"), 0600); err != nil {
+			t.Fatalf("写入文件失败: %v", err)
+		}
+		if err := os.Chtimes(filePath, f.modTime, f.modTime); err != nil {
+			t.Fatalf("修改时间失败: %v", err)
+		}
+	}
+
+	removed, err := CleanupArchiveJobs(time.Hour)
+	if err != nil {
+		t.Fatalf("清理失败: %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("删除了 %d 个文件，期望 3", removed)
+	}
+	for _, f := range files {
+		_, err := os.Stat(filepath.Join(GetArchiveJobDir(f.userID), f.name))
+		if f.kept != (err == nil) {
+			t.Errorf("%s/%s 保留状态错误 (%v)", f.userID, f.name, err)
+		}
+	}
+	if _, err := os.Stat(GetArchiveJobDir("bob")); !os.IsNotExist(err) {
+		t.Errorf("清空后的用户目录应被删除 (%v)", err)
+	}
+
+	// 目录不存在时不报错
+	t.Setenv("TMPDIR", filepath.Join(t.TempDir(), "missing"))
+	if removed, err := CleanupArchiveJobs(time.Hour); err != nil || removed != 0 {
+		t.Errorf("目录不存在时返回 %d, %v", removed, err)
+	}
+}
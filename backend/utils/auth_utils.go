@@ -15,13 +15,18 @@ package utils
 
 import (
 	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"math/big"
 	"regexp"
 	"strings"
 	"unicode"
 
 	"backend/models"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 // PasswordValidator 密码验证器
@@ -164,6 +169,44 @@ func VerifyPassword(password, hashedPassword string) bool {
 	return HashPassword(password) == hashedPassword
 }
 
+// HashSecret 使用bcrypt哈希访问密码（用于分享链接等场景）
+func HashSecret(secret string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// VerifySecret 校验bcrypt哈希的访问密码
+func VerifySecret(secret, hashedSecret string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hashedSecret), []byte(secret)) == nil
+}
+
+// HashAppToken 计算应用专用密码的 SHA-256 摘要。密码为高熵随机串，无需 bcrypt，
+// 按摘要即可直接查找，避免每个 WebDAV 请求都进行一次慢哈希
+func HashAppToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// slugAlphabet 随机标识使用的字符集
+const slugAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GenerateRandomSlug 生成指定长度的随机标识（去除了易混淆字符）
+func GenerateRandomSlug(length int) (string, error) {
+	max := big.NewInt(int64(len(slugAlphabet)))
+	slug := make([]byte, length)
+	for i := range slug {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		slug[i] = slugAlphabet[n.Int64()]
+	}
+	return string(slug), nil
+}
+
 // IsAdminUser 检查是否为管理员用户
 func IsAdminUser(username string) bool {
 	return username == "Mose"
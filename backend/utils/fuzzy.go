@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 模糊匹配命中的字段
+const (
+	FuzzyFieldName     = "name"
+	FuzzyFieldPinyin   = "pinyin"
+	FuzzyFieldInitials = "initials"
+)
+
+// FuzzyMatch 名称模糊匹配结果
+type FuzzyMatch struct {
+	Distance int    // 关键词与名称中最接近片段的编辑距离，0 表示包含关键词
+	Field    string // 命中的字段：name、pinyin、initials
+	Prefix   bool   // 是否从开头命中
+}
+
+// Better 当前结果是否优于另一个：编辑距离小的优先，其次是原名称、全拼、首字母，最后是前缀命中
+func (m FuzzyMatch) Better(other FuzzyMatch) bool {
+	if m.Distance != other.Distance {
+		return m.Distance < other.Distance
+	}
+	if rank, otherRank := fuzzyFieldRank(m.Field), fuzzyFieldRank(other.Field); rank != otherRank {
+		return rank < otherRank
+	}
+	return m.Prefix && !other.Prefix
+}
+
+func fuzzyFieldRank(field string) int {
+	switch field {
+	case FuzzyFieldName:
+		return 0
+	case FuzzyFieldPinyin:
+		return 1
+	}
+	return 2
+}
+
+// NormalizeFuzzyText 转为小写并去掉空白字符
+func NormalizeFuzzyText(text string) string {
+	var builder strings.Builder
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			builder.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return builder.String()
+}
+
+// MaxFuzzyDistance 按关键词长度允许的最大编辑距离
+func MaxFuzzyDistance(query []rune) int {
+	switch {
+	case len(query) <= 2:
+		return 0
+	case len(query) <= 5:
+		return 1
+	}
+	return 2
+}
+
+// FuzzyMatchName 将已规范化的关键词与名称、拼音全拼、拼音首字母进行匹配。
+// 名称和全拼允许少量错字，首字母只接受精确包含；
+// 关键词含汉字时转为拼音再与全拼匹配，用于找到同音错字（如"课见"匹配"课件"）
+func FuzzyMatchName(query, name string, fullPinyin, initials []string) (FuzzyMatch, bool) {
+	queryRunes := []rune(query)
+	if len(queryRunes) == 0 {
+		return FuzzyMatch{}, false
+	}
+	maxDistance := MaxFuzzyDistance(queryRunes)
+
+	best := FuzzyMatch{Distance: maxDistance + 1}
+	found := false
+	consider := func(target, field string, allowed int) {
+		distance, prefix := fuzzySubstringDistance(queryRunes, []rune(target))
+		if distance > allowed {
+			return
+		}
+		match := FuzzyMatch{Distance: distance, Field: field, Prefix: prefix}
+		if !found || match.Better(best) {
+			best = match
+			found = true
+		}
+	}
+
+	consider(NormalizeFuzzyText(name), FuzzyFieldName, maxDistance)
+	if isASCIIText(query) {
+		for _, variant := range fullPinyin {
+			consider(variant, FuzzyFieldPinyin, maxDistance)
+		}
+		for _, variant := range initials {
+			consider(variant, FuzzyFieldInitials, 0)
+		}
+		return best, found
+	}
+
+	queryPinyin, _ := NamePinyin(query)
+	for _, spelled := range queryPinyin {
+		spelledRunes := []rune(spelled)
+		allowed := MaxFuzzyDistance(spelledRunes)
+		for _, variant := range fullPinyin {
+			distance, prefix := fuzzySubstringDistance(spelledRunes, []rune(variant))
+			if distance > allowed {
+				continue
+			}
+			// 读音相同但字不同，至少按一处差异计算，排在名称直接命中之后
+			match := FuzzyMatch{Distance: max(distance, 1), Field: FuzzyFieldPinyin, Prefix: prefix}
+			if !found || match.Better(best) {
+				best = match
+				found = true
+			}
+		}
+	}
+	return best, found
+}
+
+// fuzzySubstringDistance 计算关键词与目标中任意子串的最小编辑距离（Sellers 算法），
+// 同时返回最佳匹配是否可以从目标开头开始
+func fuzzySubstringDistance(query, target []rune) (int, bool) {
+	if len(target) == 0 {
+		return len(query), false
+	}
+
+	// previous[j]、current[j] 为关键词前缀与以 target[j-1] 结尾的子串的最小距离，
+	// older 为再上一行，用于把相邻字符交换按一次编辑计算；
+	// prefix* 记录对应路径是否从目标开头开始
+	width := len(target) + 1
+	older, previous, current := make([]int, width), make([]int, width), make([]int, width)
+	prefixOlder, prefixPrev, prefixCur := make([]bool, width), make([]bool, width), make([]bool, width)
+	prefixPrev[0] = true
+
+	for i := 1; i <= len(query); i++ {
+		current[0] = i
+		prefixCur[0] = true
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if query[i-1] == target[j-1] {
+				cost = 0
+			}
+			value, prefix := previous[j-1]+cost, prefixPrev[j-1]
+			if previous[j]+1 < value {
+				value, prefix = previous[j]+1, prefixPrev[j]
+			}
+			if current[j-1]+1 < value {
+				value, prefix = current[j-1]+1, prefixCur[j-1]
+			}
+			if i > 1 && j > 1 && query[i-1] == target[j-2] && query[i-2] == target[j-1] && older[j-2]+1 < value {
+				value, prefix = older[j-2]+1, prefixOlder[j-2]
+			}
+			current[j], prefixCur[j] = value, prefix
+		}
+		older, previous, current = previous, current, older
+		prefixOlder, prefixPrev, prefixCur = prefixPrev, prefixCur, prefixOlder
+	}
+
+	best, bestPrefix := previous[0], prefixPrev[0]
+	for j := 1; j <= len(target); j++ {
+		if previous[j] < best || (previous[j] == best && prefixPrev[j] && !bestPrefix) {
+			best, bestPrefix = previous[j], prefixPrev[j]
+		}
+	}
+	return best, bestPrefix
+}
+
+// isASCIIText 是否只包含ASCII字符
+func isASCIIText(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"sort"
+	"testing"
+)
+
+// fuzzyMatchNamed 按搜索接口的方式计算名称的拼音后进行匹配
+func fuzzyMatchNamed(query, name string) (FuzzyMatch, bool) {
+	fullPinyin, initials := NamePinyin(name)
+	return FuzzyMatchName(NormalizeFuzzyText(query), name, fullPinyin, initials)
+}
+
+func TestFuzzyMatchName(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		target  string
+		want    FuzzyMatch
+		matched bool
+	}{
+		{name: "名称包含", query: "课件", target: "物理课件.pptx", want: FuzzyMatch{Distance: 0, Field: FuzzyFieldName}, matched: true},
+		{name: "名称前缀", query: "Report", target: "report final.docx", want: FuzzyMatch{Distance: 0, Field: FuzzyFieldName, Prefix: true}, matched: true},
+		{name: "忽略空格", query: "final report", target: "FinalReport.pdf", want: FuzzyMatch{Distance: 0, Field: FuzzyFieldName, Prefix: true}, matched: true},
+		{name: "全拼", query: "kejian", target: "课件.pptx", want: FuzzyMatch{Distance: 0, Field: FuzzyFieldPinyin, Prefix: true}, matched: true},
+		{name: "全拼错字", query: "kejain", target: "课件.pptx", want: FuzzyMatch{Distance: 1, Field: FuzzyFieldPinyin, Prefix: true}, matched: true},
+		{name: "首字母", query: "kj", target: "物理课件", want: FuzzyMatch{Distance: 0, Field: FuzzyFieldInitials}, matched: true},
+		{name: "同音字", query: "课见", target: "课件.pptx", want: FuzzyMatch{Distance: 1, Field: FuzzyFieldPinyin, Prefix: true}, matched: true},
+		{name: "多音字", query: "yinhang", target: "银行流水", want: FuzzyMatch{Distance: 0, Field: FuzzyFieldPinyin, Prefix: true}, matched: true},
+		{name: "ü输入u", query: "luse", target: "绿色主题", want: FuzzyMatch{Distance: 0, Field: FuzzyFieldPinyin, Prefix: true}, matched: true},
+		{name: "中英混合全拼", query: "q3baogao", target: "2024年Q3报告.pdf", want: FuzzyMatch{Distance: 0, Field: FuzzyFieldPinyin}, matched: true},
+		{name: "中英混合首字母", query: "nq3bg", target: "2024年Q3报告.pdf", want: FuzzyMatch{Distance: 0, Field: FuzzyFieldInitials}, matched: true},
+		{name: "中英混合名称", query: "q3报告", target: "2024年Q3报告.pdf", want: FuzzyMatch{Distance: 0, Field: FuzzyFieldName}, matched: true},
+		{name: "短关键词要求精确", query: "kx", target: "课件"},
+		{name: "首字母不容错", query: "wlkx", target: "物理课件"},
+		{name: "差异过多", query: "budget", target: "report.xlsx"},
+		{name: "空关键词", query: "", target: "课件"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, matched := fuzzyMatchNamed(tt.query, tt.target)
+			if matched != tt.matched {
+				t.Fatalf("FuzzyMatchName(%q, %q) 命中 = %v，期望 %v（%+v）", tt.query, tt.target, matched, tt.matched, got)
+			}
+			if matched && got != tt.want {
+				t.Errorf("FuzzyMatchName(%q, %q) = %+v，期望 %+v", tt.query, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxFuzzyDistance(t *testing.T) {
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{query: "kj", want: 0},
+		{query: "课件", want: 0},
+		{query: "abc", want: 1},
+		{query: "kejia", want: 1},
+		{query: "kejian", want: 2},
+	}
+
+	for _, tt := range tests {
+		if got := MaxFuzzyDistance([]rune(tt.query)); got != tt.want {
+			t.Errorf("MaxFuzzyDistance(%q) = %d，期望 %d", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzySubstringDistance(t *testing.T) {
+	tests := []struct {
+		query, target string
+		want          int
+		prefix        bool
+	}{
+		{query: "report", target: "report", want: 0, prefix: true},
+		{query: "port", target: "report", want: 0},
+		{query: "rpeort", target: "report", want: 1, prefix: true}, // 相邻字符颠倒算1处
+		{query: "reprt", target: "report", want: 1, prefix: true},
+		{query: "abc", target: "", want: 3},
+	}
+
+	for _, tt := range tests {
+		distance, prefix := fuzzySubstringDistance([]rune(tt.query), []rune(tt.target))
+		if distance != tt.want || prefix != tt.prefix {
+			t.Errorf("fuzzySubstringDistance(%q, %q) = (%d, %v)，期望 (%d, %v)", tt.query, tt.target, distance, prefix, tt.want, tt.prefix)
+		}
+	}
+}
+
+func TestFuzzyMatchOrdering(t *testing.T) {
+	// 排序依次为编辑距离、命中字段（名称、全拼、首字母）、是否从开头命中
+	names := []string{
+		"物理课件",       // 全拼非前缀
+		"报告.docx",    // 不命中
+		"kejain笔记",   // 名称错字
+		"课件.pptx",    // 全拼前缀
+		"my kejian",  // 名称非前缀
+		"kejian.txt", // 名称前缀
+	}
+	want := []string{"kejian.txt", "my kejian", "课件.pptx", "物理课件", "kejain笔记"}
+
+	type ranked struct {
+		name  string
+		match FuzzyMatch
+	}
+	var results []ranked
+	for _, name := range names {
+		if match, ok := fuzzyMatchNamed("kejian", name); ok {
+			results = append(results, ranked{name, match})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].match.Better(results[j].match)
+	})
+
+	if len(results) != len(want) {
+		t.Fatalf("命中 %d 个，期望 %d 个: %+v", len(results), len(want), results)
+	}
+	for i, result := range results {
+		if result.name != want[i] {
+			t.Errorf("第 %d 个结果为 %q（%+v），期望 %q", i, result.name, result.match, want[i])
+		}
+	}
+}
+
+func TestFuzzyMatchBetter(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  FuzzyMatch
+		aWins bool
+	}{
+		{name: "距离优先于字段", a: FuzzyMatch{Distance: 0, Field: FuzzyFieldInitials}, b: FuzzyMatch{Distance: 1, Field: FuzzyFieldName, Prefix: true}, aWins: true},
+		{name: "名称优先于全拼", a: FuzzyMatch{Field: FuzzyFieldName}, b: FuzzyMatch{Field: FuzzyFieldPinyin, Prefix: true}, aWins: true},
+		{name: "全拼优先于首字母", a: FuzzyMatch{Field: FuzzyFieldPinyin}, b: FuzzyMatch{Field: FuzzyFieldInitials, Prefix: true}, aWins: true},
+		{name: "前缀优先", a: FuzzyMatch{Field: FuzzyFieldName, Prefix: true}, b: FuzzyMatch{Field: FuzzyFieldName}, aWins: true},
+		{name: "相同不优于", a: FuzzyMatch{Field: FuzzyFieldPinyin}, b: FuzzyMatch{Field: FuzzyFieldPinyin}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Better(tt.b); got != tt.aWins {
+				t.Errorf("Better = %v，期望 %v", got, tt.aWins)
+			}
+			if tt.aWins && tt.b.Better(tt.a) {
+				t.Errorf("反向比较也返回 true")
+			}
+		})
+	}
+}
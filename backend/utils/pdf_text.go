@@ -0,0 +1,634 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// PDF文本提取只覆盖常见情况：未压缩或 FlateDecode 压缩的内容流、Tj/TJ 文本操作符，
+// 以及通过 ToUnicode CMap 映射的字形编码（中文PDF通常依赖它）。
+// 扫描件、加密文档以及使用其他压缩方式的内容流无法提取，按提取结果为空处理
+
+// pdfMaxStreamSize 单个内容流解压后的大小上限
+const pdfMaxStreamSize = 64 * 1024 * 1024
+
+// pdfUnsupportedFilters 不支持的流压缩方式，与 FlateDecode 组合使用时同样跳过
+var pdfUnsupportedFilters = []string{
+	"/ASCIIHexDecode", "/ASCII85Decode", "/LZWDecode", "/RunLengthDecode",
+	"/CCITTFaxDecode", "/JBIG2Decode", "/DCTDecode", "/JPXDecode", "/Crypt",
+}
+
+// pdfCMap ToUnicode 映射，按编码字节宽度分别保存
+type pdfCMap map[int]map[uint32]string
+
+// extractPDFFile 读取PDF文件并提取文本
+func extractPDFFile(absolutePath string, keyID uint64, maxBytes int64) (string, error) {
+	file, err := OpenStoredContent(absolutePath, keyID)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes))
+	if err != nil {
+		return "", err
+	}
+	return ExtractPDFText(data, maxBytes)
+}
+
+// ExtractPDFText 从PDF数据中提取文本，maxBytes 限制解压后内容流的总大小
+func ExtractPDFText(data []byte, maxBytes int64) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF")) {
+		return "", fmt.Errorf("不是有效的PDF文件")
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return "", fmt.Errorf("PDF文件已加密")
+	}
+
+	cmap := pdfCMap{}
+	var contents [][]byte
+	remaining := maxBytes
+
+	for _, stream := range pdfStreams(data) {
+		if remaining <= 0 {
+			break
+		}
+		limit := remaining
+		if limit > pdfMaxStreamSize {
+			limit = pdfMaxStreamSize
+		}
+		decoded, ok := decodePDFStream(stream.dict, stream.body, limit)
+		if !ok {
+			continue
+		}
+		remaining -= int64(len(decoded))
+
+		if bytes.Contains(decoded, []byte("begincmap")) {
+			parsePDFCMap(decoded, cmap)
+		} else if bytes.Contains(decoded, []byte("BT")) {
+			contents = append(contents, decoded)
+		}
+	}
+
+	var builder strings.Builder
+	for _, content := range contents {
+		extractPDFContentText(content, cmap, &builder)
+		builder.WriteByte('\n')
+	}
+	return builder.String(), nil
+}
+
+// pdfStream 原始流对象：字典和未解码的数据
+type pdfStream struct {
+	dict []byte
+	body []byte
+}
+
+// pdfStreams 扫描文件中的全部 stream ... endstream 数据块
+func pdfStreams(data []byte) []pdfStream {
+	var streams []pdfStream
+	keyword := []byte("stream")
+	pos := 0
+	for pos < len(data) {
+		idx := bytes.Index(data[pos:], keyword)
+		if idx < 0 {
+			break
+		}
+		start := pos + idx
+		pos = start + len(keyword)
+		// 跳过 endstream 关键字
+		if start >= 3 && string(data[start-3:start]) == "end" {
+			continue
+		}
+
+		bodyStart := pos
+		if bodyStart < len(data) && data[bodyStart] == '\r' {
+			bodyStart++
+		}
+		if bodyStart < len(data) && data[bodyStart] == '\n' {
+			bodyStart++
+		}
+		end := bytes.Index(data[bodyStart:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		bodyEnd := bodyStart + end
+
+		// 流字典位于 stream 关键字之前，从最近的 obj 关键字开始
+		dictStart := start - 4096
+		if dictStart < 0 {
+			dictStart = 0
+		}
+		if objIdx := bytes.LastIndex(data[dictStart:start], []byte("obj")); objIdx >= 0 {
+			dictStart += objIdx
+		}
+
+		streams = append(streams, pdfStream{dict: data[dictStart:start], body: data[bodyStart:bodyEnd]})
+		pos = bodyEnd + len("endstream")
+	}
+	return streams
+}
+
+// decodePDFStream 解码流数据，只处理未压缩和 FlateDecode，图片等流直接跳过
+func decodePDFStream(dict, body []byte, limit int64) ([]byte, bool) {
+	compact := bytes.ReplaceAll(dict, []byte(" "), nil)
+	if bytes.Contains(compact, []byte("/Subtype/Image")) {
+		return nil, false
+	}
+	if !bytes.Contains(dict, []byte("/Filter")) {
+		return body, true
+	}
+
+	if !bytes.Contains(dict, []byte("/FlateDecode")) {
+		return nil, false
+	}
+	for _, filter := range pdfUnsupportedFilters {
+		if bytes.Contains(dict, []byte(filter)) {
+			return nil, false
+		}
+	}
+
+	reader, err := zlib.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, false
+	}
+	defer reader.Close()
+
+	decoded, err := io.ReadAll(io.LimitReader(reader, limit))
+	if err != nil && len(decoded) == 0 {
+		return nil, false
+	}
+	return decoded, true
+}
+
+// parsePDFCMap 解析 ToUnicode CMap 中的 bfchar 和 bfrange 映射
+func parsePDFCMap(data []byte, cmap pdfCMap) {
+	for _, section := range pdfSections(data, "beginbfchar", "endbfchar") {
+		tokens := pdfHexTokens(section)
+		for i := 0; i+1 < len(tokens); i += 2 {
+			cmap.set(tokens[i], pdfUTF16(tokens[i+1]))
+		}
+	}
+
+	for _, section := range pdfSections(data, "beginbfrange", "endbfrange") {
+		lexer := &pdfLexer{data: section}
+		for {
+			lo, ok := lexer.nextHex()
+			if !ok {
+				break
+			}
+			hi, ok := lexer.nextHex()
+			if !ok {
+				break
+			}
+			low, high := pdfCode(lo), pdfCode(hi)
+			if high < low || high-low > 0xFFFF {
+				continue
+			}
+
+			lexer.skipSpace()
+			if lexer.peek() == '[' {
+				lexer.pos++
+				for code := low; code <= high; code++ {
+					dst, ok := lexer.nextHex()
+					if !ok {
+						break
+					}
+					cmap.setCode(len(lo), code, pdfUTF16(dst))
+				}
+				lexer.skipUntil(']')
+				continue
+			}
+
+			dst, ok := lexer.nextHex()
+			if !ok {
+				break
+			}
+			base := []rune(pdfUTF16(dst))
+			if len(base) == 0 {
+				continue
+			}
+			for code := low; code <= high; code++ {
+				mapped := append([]rune{}, base...)
+				mapped[len(mapped)-1] += rune(code - low)
+				cmap.setCode(len(lo), code, string(mapped))
+			}
+		}
+	}
+}
+
+// set 按源编码的字节宽度保存映射
+func (m pdfCMap) set(src []byte, dst string) {
+	m.setCode(len(src), pdfCode(src), dst)
+}
+
+func (m pdfCMap) setCode(width int, code uint32, dst string) {
+	if width < 1 || width > 4 {
+		return
+	}
+	if m[width] == nil {
+		m[width] = make(map[uint32]string)
+	}
+	m[width][code] = dst
+}
+
+// decode 使用映射解码字符串；2字节编码需要全部命中，否则按单字节编码处理
+func (m pdfCMap) decode(raw []byte) string {
+	if codes := m[2]; len(codes) > 0 && len(raw)%2 == 0 && len(raw) > 0 {
+		var builder strings.Builder
+		complete := true
+		for i := 0; i < len(raw); i += 2 {
+			text, ok := codes[uint32(raw[i])<<8|uint32(raw[i+1])]
+			if !ok {
+				complete = false
+				break
+			}
+			builder.WriteString(text)
+		}
+		if complete {
+			return builder.String()
+		}
+	}
+
+	var builder strings.Builder
+	for _, b := range raw {
+		if text, ok := m[1][uint32(b)]; ok {
+			builder.WriteString(text)
+			continue
+		}
+		switch {
+		case b == '\t' || b == '\n' || b == '\r':
+			builder.WriteByte(' ')
+		case b >= 0x20 && b < 0x7F:
+			builder.WriteByte(b)
+		case b >= 0xA0:
+			builder.WriteRune(rune(b))
+		}
+	}
+	return builder.String()
+}
+
+// extractPDFContentText 解析内容流中的文本操作符
+func extractPDFContentText(content []byte, cmap pdfCMap, builder *strings.Builder) {
+	lexer := &pdfLexer{data: content}
+	var operands [][]byte
+	inArray := false
+	var arrayParts []string
+	separate := false
+
+	emit := func(text string) {
+		if text == "" {
+			return
+		}
+		if separate && builder.Len() > 0 && needsPDFSeparator(builder.String(), text) {
+			builder.WriteByte(' ')
+		}
+		separate = false
+		builder.WriteString(text)
+	}
+
+	for {
+		token, kind, ok := lexer.next()
+		if !ok {
+			return
+		}
+		switch kind {
+		case pdfTokenString:
+			if inArray {
+				arrayParts = append(arrayParts, cmap.decode(token))
+			} else {
+				operands = append(operands, token)
+			}
+		case pdfTokenArrayStart:
+			inArray = true
+			arrayParts = nil
+		case pdfTokenArrayEnd:
+			inArray = false
+		case pdfTokenNumber:
+			// TJ 数组中较大的负偏移通常表示单词间距
+			if inArray {
+				if value, err := strconv.ParseFloat(string(token), 64); err == nil && value < -200 {
+					arrayParts = append(arrayParts, " ")
+				}
+			}
+		case pdfTokenOperator:
+			switch string(token) {
+			case "Tj", "'", "\"":
+				if string(token) != "Tj" {
+					separate = true
+				}
+				for _, operand := range operands {
+					emit(cmap.decode(operand))
+				}
+			case "TJ":
+				emit(strings.Join(arrayParts, ""))
+				arrayParts = nil
+			case "Td", "TD", "Tm", "T*", "ET":
+				separate = true
+			}
+			operands = nil
+		}
+	}
+}
+
+// needsPDFSeparator 两段文本之间是否需要插入空格；中日韩文字之间不插入，以免破坏短语检索
+func needsPDFSeparator(previous, next string) bool {
+	last := []rune(previous[max(0, len(previous)-4):])
+	first := []rune(next)
+	if len(last) == 0 || len(first) == 0 {
+		return false
+	}
+	a, b := last[len(last)-1], first[0]
+	if unicode.IsSpace(a) || unicode.IsSpace(b) {
+		return false
+	}
+	return !isCJKRune(a) && !isCJKRune(b)
+}
+
+// isCJKRune 是否为中日韩文字或全角标点
+func isCJKRune(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r) || (r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF)
+}
+
+// pdfSections 返回 begin 与 end 关键字之间的全部片段
+func pdfSections(data []byte, begin, end string) [][]byte {
+	var sections [][]byte
+	for {
+		start := bytes.Index(data, []byte(begin))
+		if start < 0 {
+			return sections
+		}
+		data = data[start+len(begin):]
+		stop := bytes.Index(data, []byte(end))
+		if stop < 0 {
+			return sections
+		}
+		sections = append(sections, data[:stop])
+		data = data[stop+len(end):]
+	}
+}
+
+// pdfHexTokens 依次读取片段中的十六进制字符串
+func pdfHexTokens(section []byte) [][]byte {
+	lexer := &pdfLexer{data: section}
+	var tokens [][]byte
+	for {
+		token, ok := lexer.nextHex()
+		if !ok {
+			return tokens
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+// pdfCode 将编码字节转为整数
+func pdfCode(raw []byte) uint32 {
+	var code uint32
+	for _, b := range raw {
+		code = code<<8 | uint32(b)
+	}
+	return code
+}
+
+// pdfUTF16 将 UTF-16BE 字节解码为字符串
+func pdfUTF16(raw []byte) string {
+	units := make([]uint16, 0, len(raw)/2)
+	for i := 0; i+1 < len(raw); i += 2 {
+		units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+	}
+	if len(raw)%2 == 1 {
+		units = append(units, uint16(raw[len(raw)-1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// PDF内容流的词法单元类型
+const (
+	pdfTokenString = iota
+	pdfTokenNumber
+	pdfTokenOperator
+	pdfTokenArrayStart
+	pdfTokenArrayEnd
+	pdfTokenOther
+)
+
+// pdfLexer 内容流和CMap的简易词法分析器
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func (l *pdfLexer) peek() byte {
+	if l.pos < len(l.data) {
+		return l.data[l.pos]
+	}
+	return 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		switch c := l.data[l.pos]; {
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		case isPDFSpace(c):
+			l.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (l *pdfLexer) skipUntil(c byte) {
+	for l.pos < len(l.data) && l.data[l.pos] != c {
+		l.pos++
+	}
+	if l.pos < len(l.data) {
+		l.pos++
+	}
+}
+
+// nextHex 跳过其他内容，读取下一个十六进制字符串
+func (l *pdfLexer) nextHex() ([]byte, bool) {
+	for l.pos < len(l.data) {
+		if l.data[l.pos] == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] != '<' {
+			l.pos++
+			return l.readHexString(), true
+		}
+		l.pos++
+	}
+	return nil, false
+}
+
+// next 读取下一个词法单元
+func (l *pdfLexer) next() ([]byte, int, bool) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, 0, false
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '(':
+		l.pos++
+		return l.readLiteralString(), pdfTokenString, true
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return nil, pdfTokenOther, true
+		}
+		l.pos++
+		return l.readHexString(), pdfTokenString, true
+	case c == '>':
+		l.pos++
+		if l.peek() == '>' {
+			l.pos++
+		}
+		return nil, pdfTokenOther, true
+	case c == '[':
+		l.pos++
+		return nil, pdfTokenArrayStart, true
+	case c == ']':
+		l.pos++
+		return nil, pdfTokenArrayEnd, true
+	case c == '{' || c == '}' || c == ')':
+		l.pos++
+		return nil, pdfTokenOther, true
+	case c == '/':
+		l.pos++
+		l.readRegular()
+		return nil, pdfTokenOther, true
+	}
+
+	token := l.readRegular()
+	if len(token) == 0 {
+		l.pos++
+		return nil, pdfTokenOther, true
+	}
+	if first := token[0]; (first >= '0' && first <= '9') || first == '-' || first == '+' || first == '.' {
+		return token, pdfTokenNumber, true
+	}
+	return token, pdfTokenOperator, true
+}
+
+func (l *pdfLexer) readRegular() []byte {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return l.data[start:l.pos]
+}
+
+// readLiteralString 读取括号字符串，处理嵌套括号和转义
+func (l *pdfLexer) readLiteralString() []byte {
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+			out = append(out, c)
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				if l.peek() == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					value := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						value = value*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(value))
+				} else {
+					out = append(out, e)
+				}
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// readHexString 读取十六进制字符串，奇数位时末尾补0
+func (l *pdfLexer) readHexString() []byte {
+	var out []byte
+	var high byte
+	half := false
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		if c == '>' {
+			break
+		}
+		value, ok := hexValue(c)
+		if !ok {
+			continue
+		}
+		if half {
+			out = append(out, high<<4|value)
+		} else {
+			high = value
+		}
+		half = !half
+	}
+	if half {
+		out = append(out, high<<4)
+	}
+	return out
+}
+
+func hexValue(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
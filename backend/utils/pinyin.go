@@ -0,0 +1,140 @@
+package utils
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// pinyinTableData 汉字拼音表，每行为 "拼音 汉字..."，拼音不带声调，ü 写作 v。
+// 覆盖基本区和扩展A区汉字，每个字取最常用的读音
+//
+//go:embed pinyin_table.txt
+var pinyinTableData string
+
+// pinyinAlternates 文件名中常见多音字的其他读音，与拼音表中的读音一起参与匹配
+var pinyinAlternates = map[rune][]string{
+	'长': {"chang"}, '行': {"hang"}, '重': {"chong"}, '乐': {"yue"}, '调': {"tiao"},
+	'会': {"kuai"}, '还': {"huan"}, '都': {"du"}, '了': {"liao"}, '朝': {"zhao"},
+	'传': {"zhuan"}, '单': {"shan", "chan"}, '和': {"huo", "hu"}, '着': {"zhao", "zhuo"}, '数': {"shuo"},
+	'藏': {"zang"}, '种': {"chong"}, '参': {"shen", "cen"}, '角': {"jue"}, '率': {"shuai"},
+	'差': {"chai", "ci"}, '便': {"pian"}, '大': {"dai"}, '校': {"jiao"}, '降': {"xiang"},
+	'系': {"ji"}, '曾': {"zeng"}, '模': {"mu"}, '解': {"xie"}, '区': {"ou"},
+	'省': {"xing"}, '翟': {"zhai"}, '柏': {"bo"}, '仇': {"qiu"}, '查': {"zha"},
+	'尉': {"yu"}, '厦': {"xia"}, '给': {"ji"}, '得': {"dei"}, '地': {"de"},
+	'没': {"mo"}, '薄': {"bao"}, '露': {"lou"}, '血': {"xie"}, '强': {"jiang"},
+	'弹': {"tan"}, '折': {"she"}, '塞': {"se"}, '提': {"di"}, '奇': {"ji"},
+	'盛': {"cheng"}, '属': {"zhu"}, '伯': {"bai"}, '卡': {"qia"}, '似': {"shi"},
+	'仔': {"zai"}, '分': {"fen"}, '称': {"chen"}, '恶': {"wu"}, '宿': {"xiu"},
+}
+
+// 单个名称最多生成的拼音组合数，避免多音字过多时组合爆炸
+const maxPinyinVariants = 8
+
+var (
+	pinyinOnce  sync.Once
+	pinyinTable map[rune]string
+)
+
+// loadPinyinTable 首次使用时解析拼音表
+func loadPinyinTable() {
+	pinyinTable = make(map[rune]string, 27000)
+	for _, line := range strings.Split(pinyinTableData, "\n") {
+		syllable, chars, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		for _, r := range chars {
+			pinyinTable[r] = syllable
+		}
+	}
+}
+
+// PinyinReadings 返回汉字的全部拼音读音，常用读音在前；非汉字返回 nil
+func PinyinReadings(r rune) []string {
+	pinyinOnce.Do(loadPinyinTable)
+	primary, ok := pinyinTable[r]
+	if !ok {
+		return nil
+	}
+	readings := []string{primary}
+	for _, alternate := range pinyinAlternates[r] {
+		if alternate != primary {
+			readings = append(readings, alternate)
+		}
+	}
+	// ü 同时接受 v 和 u 两种输入方式
+	if strings.Contains(primary, "v") {
+		readings = append(readings, strings.ReplaceAll(primary, "v", "u"))
+	}
+	return readings
+}
+
+// NamePinyin 生成名称的拼音全拼和首字母，多音字产生多个组合（最多 maxPinyinVariants 个）。
+// 汉字转为拼音，字母和数字转为小写保留，空格和标点被忽略；不含汉字时返回 nil
+func NamePinyin(name string) (full []string, initials []string) {
+	type segment struct {
+		readings []string
+		literal  string
+	}
+
+	var segments []segment
+	hasHan := false
+	for _, r := range name {
+		if readings := PinyinReadings(r); readings != nil {
+			segments = append(segments, segment{readings: readings})
+			hasHan = true
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			segments = append(segments, segment{literal: string(unicode.ToLower(r))})
+		}
+	}
+	if !hasHan {
+		return nil, nil
+	}
+
+	fullVariants := []string{""}
+	initialVariants := []string{""}
+	for _, seg := range segments {
+		if seg.readings == nil {
+			for i := range fullVariants {
+				fullVariants[i] += seg.literal
+			}
+			for i := range initialVariants {
+				initialVariants[i] += seg.literal
+			}
+			continue
+		}
+		fullVariants = expandPinyinVariants(fullVariants, seg.readings, false)
+		initialVariants = expandPinyinVariants(initialVariants, seg.readings, true)
+	}
+	return fullVariants, initialVariants
+}
+
+// expandPinyinVariants 将当前组合与汉字的各个读音相乘，超过上限后只追加常用读音
+func expandPinyinVariants(variants, readings []string, initialsOnly bool) []string {
+	options := make([]string, 0, len(readings))
+	seen := make(map[string]bool, len(readings))
+	for _, reading := range readings {
+		if initialsOnly {
+			reading = reading[:1]
+		}
+		if !seen[reading] {
+			seen[reading] = true
+			options = append(options, reading)
+		}
+	}
+
+	if len(variants)*len(options) > maxPinyinVariants {
+		options = options[:1]
+	}
+	expanded := make([]string, 0, len(variants)*len(options))
+	for _, variant := range variants {
+		for _, option := range options {
+			expanded = append(expanded, variant+option)
+		}
+	}
+	return expanded
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNamePinyin(t *testing.T) {
+	tests := []struct {
+		name         string
+		wantFull     []string
+		wantInitials []string
+	}{
+		{name: "课件", wantFull: []string{"kejian"}, wantInitials: []string{"kj"}},
+		{name: "项目 计划书", wantFull: []string{"xiangmujihuashu"}, wantInitials: []string{"xmjhs"}},
+		{name: "绿色", wantFull: []string{"lvse", "luse"}, wantInitials: []string{"ls"}}, // ü 同时接受 v 和 u
+		{name: "银行", wantFull: []string{"yinxing", "yinhang"}, wantInitials: []string{"yx", "yh"}},
+		// 字母和数字保留并转为小写，标点被忽略
+		{name: "2024年Q3报告.pdf", wantFull: []string{"2024nianq3baogaopdf"}, wantInitials: []string{"2024nq3bgpdf"}},
+		{name: "PPT模板_v2", wantFull: []string{"pptmobanv2", "pptmubanv2"}, wantInitials: []string{"pptmbv2"}},
+		{name: "report-final.docx"},
+		{name: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			full, initials := NamePinyin(tt.name)
+			if !slices.Equal(full, tt.wantFull) {
+				t.Errorf("全拼 = %q，期望 %q", full, tt.wantFull)
+			}
+			if !slices.Equal(initials, tt.wantInitials) {
+				t.Errorf("首字母 = %q，期望 %q", initials, tt.wantInitials)
+			}
+		})
+	}
+}
+
+func TestNamePinyinLimitsVariants(t *testing.T) {
+	// 每个字都是多音字，组合数不超过上限且常用读音组合排在最前
+	full, initials := NamePinyin("长行重乐调会")
+	if len(full) > maxPinyinVariants || len(initials) > maxPinyinVariants {
+		t.Fatalf("组合数超过上限: 全拼 %d 个，首字母 %d 个", len(full), len(initials))
+	}
+	var primary string
+	for _, r := range "长行重乐调会" {
+		primary += PinyinReadings(r)[0]
+	}
+	if full[0] != primary {
+		t.Errorf("第一个组合 = %q，期望常用读音 %q", full[0], primary)
+	}
+}
+
+func TestPinyinReadings(t *testing.T) {
+	tests := []struct {
+		char rune
+		want []string
+	}{
+		{char: '课', want: []string{"ke"}},
+		{char: '行', want: []string{"xing", "hang"}},
+		{char: '女', want: []string{"nv", "nu"}},
+		{char: 'a'},
+		{char: '1'},
+		{char: '，'},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.char), func(t *testing.T) {
+			if got := PinyinReadings(tt.char); !slices.Equal(got, tt.want) {
+				t.Errorf("PinyinReadings(%q) = %q，期望 %q", tt.char, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// snippetMatch 命中位置（按字符计）
+type snippetMatch struct {
+	start, end int
+}
+
+// HighlightSnippets 在文本中查找关键词并生成高亮片段。
+// 优先按完整短语匹配，找不到时按空格拆分的各个词匹配，匹配不区分大小写；
+// 片段内容经过HTML转义，命中部分用 <mark></mark> 包裹，context 为命中前后保留的字符数。
+// 没有任何命中时返回文本开头作为片段
+func HighlightSnippets(text, query string, maxSnippets, context int) []string {
+	runes := []rune(text)
+	if len(runes) == 0 || maxSnippets <= 0 {
+		return []string{}
+	}
+
+	lowered := make([]rune, len(runes))
+	for i, r := range runes {
+		lowered[i] = unicode.ToLower(r)
+	}
+
+	matches := findSnippetMatches(lowered, []string{query})
+	if len(matches) == 0 {
+		matches = findSnippetMatches(lowered, strings.Fields(query))
+	}
+	if len(matches) == 0 {
+		end := min(len(runes), context*2)
+		snippet := html.EscapeString(string(runes[:end]))
+		if end < len(runes) {
+			snippet += "…"
+		}
+		return []string{snippet}
+	}
+
+	snippets := []string{}
+	for i := 0; i < len(matches) && len(snippets) < maxSnippets; {
+		start := max(0, matches[i].start-context)
+		end := min(len(runes), matches[i].end+context)
+
+		// 窗口内的后续命中合并到同一片段
+		j := i
+		for j+1 < len(matches) && matches[j+1].end <= end {
+			j++
+		}
+
+		var builder strings.Builder
+		if start > 0 {
+			builder.WriteString("…")
+		}
+		cursor := start
+		for _, match := range matches[i : j+1] {
+			builder.WriteString(html.EscapeString(string(runes[cursor:match.start])))
+			builder.WriteString("<mark>")
+			builder.WriteString(html.EscapeString(string(runes[match.start:match.end])))
+			builder.WriteString("</mark>")
+			cursor = match.end
+		}
+		builder.WriteString(html.EscapeString(string(runes[cursor:end])))
+		if end < len(runes) {
+			builder.WriteString("…")
+		}
+		snippets = append(snippets, builder.String())
+		i = j + 1
+	}
+	return snippets
+}
+
+// findSnippetMatches 查找所有关键词的不重叠命中位置，按出现顺序返回
+func findSnippetMatches(lowered []rune, terms []string) []snippetMatch {
+	var matches []snippetMatch
+	for _, term := range terms {
+		needle := []rune(strings.TrimSpace(term))
+		if len(needle) == 0 {
+			continue
+		}
+		for i, r := range needle {
+			needle[i] = unicode.ToLower(r)
+		}
+		for i := 0; i+len(needle) <= len(lowered); i++ {
+			if runesEqual(lowered[i:i+len(needle)], needle) {
+				matches = append(matches, snippetMatch{start: i, end: i + len(needle)})
+				i += len(needle) - 1
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].start < matches[j].start
+	})
+	// 去掉与前一个命中重叠的位置
+	result := matches[:0]
+	for _, match := range matches {
+		if len(result) > 0 && match.start < result[len(result)-1].end {
+			continue
+		}
+		result = append(result, match)
+	}
+	return result
+}
+
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
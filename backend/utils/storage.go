@@ -1,11 +1,18 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"backend/models"
+
+	"github.com/google/uuid"
 )
 
 // FormatStorageSize 格式化存储大小
@@ -157,3 +164,213 @@ func GetFileAbsolutePath(relativePath string) string {
 func ValidateFileSize(fileSize, storageLimit, usedSpace int64) bool {
 	return usedSpace+fileSize <= storageLimit
 }
+
+// GetVersionUploadDir 获取用户历史版本存储目录
+func GetVersionUploadDir(userID string) string {
+	return filepath.Join(GetUploadDir(), "versions", userID)
+}
+
+// GetVersionPath 生成历史版本的存储路径
+func GetVersionPath(userID, fileName string) string {
+	return fmt.Sprintf("/uploads/versions/%s/%s", userID, fileName)
+}
+
+// GenerateUniqueFileName 在目录中生成不重复的文件名（重名时添加数字后缀）
+func GenerateUniqueFileName(dir, fileName string) (string, error) {
+	ext := filepath.Ext(fileName)
+	nameWithoutExt := strings.TrimSuffix(fileName, ext)
+
+	candidate := fileName
+	for counter := 1; counter <= 1000; counter++ {
+		if _, err := os.Stat(filepath.Join(dir, candidate)); os.IsNotExist(err) {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s_%d%s", nameWithoutExt, counter, ext)
+	}
+	return "", fmt.Errorf("无法生成唯一文件名: %s", fileName)
+}
+
+// CalculateFileChecksum 计算文件的SHA-256校验和，keyID 非0的加密文件按解密后的内容计算
+func CalculateFileChecksum(path string, keyID uint64) (string, error) {
+	f, err := OpenStoredContent(path, keyID)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// StoredFile 已写入上传目录的文件信息
+type StoredFile struct {
+	FileName     string // 去重后的实际文件名
+	FileType     string // 文件类型
+	Path         string // 相对路径（/uploads/...）
+	AbsolutePath string // 磁盘绝对路径
+	Size         int64
+	Checksum     string
+	KeyID        uint64 // 存储加密使用的数据密钥ID，明文为0
+}
+
+// StoreFileContent 将内容写入对应类型的上传目录，自动处理重名并计算校验和。
+// 启用存储加密时以所有者的数据密钥加密写入，大小和校验和均按明文计算
+func StoreFileContent(reader io.Reader, originalName, ownerID string) (*StoredFile, error) {
+	return storeContent(reader, GetFileType(originalName), filepath.Base(originalName), ownerID)
+}
+
+// StoreVaultBlob 将保险库文件的密文写入 vault 上传目录。文件名随机生成，
+// 不使用客户端加密的名称；密文在服务端仍按存储加密配置再加密一层
+func StoreVaultBlob(reader io.Reader, ownerID string) (*StoredFile, error) {
+	return storeContent(reader, models.VaultFileType, uuid.NewString()+".bin", ownerID)
+}
+
+// storeContent 将内容写入 fileType 对应的上传目录，baseName 重名时自动添加序号
+func storeContent(reader io.Reader, fileType, baseName, ownerID string) (*StoredFile, error) {
+	uploadDir := GetFileUploadDir(fileType)
+	if err := os.MkdirAll(uploadDir, 0755); err != nil {
+		return nil, err
+	}
+
+	fileName, err := GenerateUniqueFileName(uploadDir, baseName)
+	if err != nil {
+		return nil, err
+	}
+
+	absolutePath := filepath.Join(uploadDir, fileName)
+	dst, err := os.Create(absolutePath)
+	if err != nil {
+		return nil, err
+	}
+
+	writer, err := NewStoredContentWriter(dst, ownerID)
+	if err != nil {
+		dst.Close()
+		os.Remove(absolutePath)
+		return nil, err
+	}
+
+	hasher := sha256.New()
+	buffer := make([]byte, 32*1024) // 32KB buffer
+	written, err := io.CopyBuffer(io.MultiWriter(writer, hasher), reader, buffer)
+	if err == nil {
+		err = writer.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// 删除部分写入的文件
+		os.Remove(absolutePath)
+		return nil, err
+	}
+
+	return &StoredFile{
+		FileName:     fileName,
+		FileType:     fileType,
+		Path:         GetUploadPath(fileName, fileType),
+		AbsolutePath: absolutePath,
+		Size:         written,
+		Checksum:     hex.EncodeToString(hasher.Sum(nil)),
+		KeyID:        writer.KeyID(),
+	}, nil
+}
+
+// GetUploadTempDir 获取上传临时目录，位于公开的上传目录之外
+func GetUploadTempDir() string {
+	return filepath.Join(GetUploadDir(), "..", "upload-tmp")
+}
+
+// StoreTempFile 将已写完的临时文件移入上传目录，返回的存储信息与 StoreFileContent 一致。
+// 启用存储加密时加密写入上传目录后删除临时文件
+func StoreTempFile(tempPath, originalName, ownerID string) (*StoredFile, error) {
+	if storageEncryptionActive(ownerID) {
+		temp, err := os.Open(tempPath)
+		if err != nil {
+			return nil, err
+		}
+		stored, err := StoreFileContent(temp, originalName, ownerID)
+		temp.Close()
+		if err != nil {
+			return nil, err
+		}
+		os.Remove(tempPath)
+		return stored, nil
+	}
+
+	info, err := os.Stat(tempPath)
+	if err != nil {
+		return nil, err
+	}
+	checksum, err := CalculateFileChecksum(tempPath, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fileType := GetFileType(originalName)
+	uploadDir := GetFileUploadDir(fileType)
+	if err := os.MkdirAll(uploadDir, 0755); err != nil {
+		return nil, err
+	}
+
+	fileName, err := GenerateUniqueFileName(uploadDir, filepath.Base(originalName))
+	if err != nil {
+		return nil, err
+	}
+
+	absolutePath := filepath.Join(uploadDir, fileName)
+	if err := moveFile(tempPath, absolutePath); err != nil {
+		return nil, err
+	}
+	// 临时文件以 0600 创建，与普通上传的文件权限保持一致
+	os.Chmod(absolutePath, 0644)
+
+	return &StoredFile{
+		FileName:     fileName,
+		FileType:     fileType,
+		Path:         GetUploadPath(fileName, fileType),
+		AbsolutePath: absolutePath,
+		Size:         info.Size(),
+		Checksum:     checksum,
+	}, nil
+}
+
+// CopyStoredFile 复制已存储的文件（keyID 为源文件记录中的数据密钥ID），生成独立的物理副本，副本按新所有者的数据密钥加密
+func CopyStoredFile(relativePath string, keyID uint64, newName, ownerID string) (*StoredFile, error) {
+	src, err := OpenStoredContent(GetFileAbsolutePath(relativePath), keyID)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	return StoreFileContent(src, newName, ownerID)
+}
+
+// RenameStoredFile 按新文件名重命名已存储的文件，文件类型变化时移动到对应类型目录
+func RenameStoredFile(relativePath, newName string) (*StoredFile, error) {
+	fileType := GetFileType(newName)
+	uploadDir := GetFileUploadDir(fileType)
+	if err := os.MkdirAll(uploadDir, 0755); err != nil {
+		return nil, err
+	}
+
+	fileName, err := GenerateUniqueFileName(uploadDir, filepath.Base(newName))
+	if err != nil {
+		return nil, err
+	}
+
+	absolutePath := filepath.Join(uploadDir, fileName)
+	if err := os.Rename(GetFileAbsolutePath(relativePath), absolutePath); err != nil {
+		return nil, err
+	}
+
+	return &StoredFile{
+		FileName:     fileName,
+		FileType:     fileType,
+		Path:         GetUploadPath(fileName, fileType),
+		AbsolutePath: absolutePath,
+	}, nil
+}
@@ -0,0 +1,502 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+/*
+ * 存储加密格式
+ *
+ * 文件头（36字节）：魔数 "SCENC\x00\x01\x00"（8）| 数据密钥ID（8，大端）| 明文分块大小（4，大端）| 随机盐（16）
+ * 之后是依次排列的密文分块，每块为 AES-256-GCM 加密的明文分块加16字节认证标签。
+ * 除最后一块外每块都是完整的分块大小，最后一块不足一个分块（可以为空），因此密文大小唯一确定明文大小，
+ * 任意明文偏移都能直接定位到所在分块，支持随机读取和 Range 下载。
+ *
+ * 每个文件使用 HMAC-SHA256(数据密钥, 盐) 派生独立的文件密钥，随机数为分块序号；
+ * 附加数据为文件头加上是否为最后一块的标记，文件头被篡改、分块被调换或截断都会导致认证失败。
+ *
+ * 内容是否加密、使用哪个数据密钥由文件和历史版本记录的 encryption_key_id 决定（0 表示明文），
+ * 读取时不根据内容猜测格式，以魔数开头的明文文件不会被误当作密文
+ */
+
+const (
+	storageHeaderSize = 36
+	storageChunkSize  = 64 * 1024
+	storageTagSize    = 16
+)
+
+// storageMagic 加密文件的魔数，末尾两个字节为格式版本和保留位
+var storageMagic = []byte("SCENC\x00\x01\x00")
+
+// ErrStorageKeyUnavailable 文件已加密，但没有配置能解开其数据密钥的主密钥
+var ErrStorageKeyUnavailable = errors.New("加密文件的数据密钥不可用")
+
+// ErrStorageCorrupted 加密文件格式错误、认证失败，或文件头中的数据密钥与记录不一致
+var ErrStorageCorrupted = errors.New("加密文件已损坏")
+
+// DataKey 用户数据密钥
+type DataKey struct {
+	ID  uint64
+	Key []byte // 32字节 AES-256 密钥
+}
+
+// StorageKeyring 提供存储加密使用的用户数据密钥，由加密服务实现并在启动时注册
+type StorageKeyring interface {
+	// ActiveKey 返回加密用户新写入内容使用的数据密钥，未启用加密时返回 nil
+	ActiveKey(userID string) (*DataKey, error)
+	// KeyByID 按ID获取数据密钥，用于解密
+	KeyByID(keyID uint64) (*DataKey, error)
+}
+
+var (
+	storageKeyringMu sync.RWMutex
+	storageKeyring   StorageKeyring
+)
+
+// SetStorageKeyring 注册存储加密的密钥来源，为 nil 时新内容以明文写入、已加密的内容无法读取
+func SetStorageKeyring(keyring StorageKeyring) {
+	storageKeyringMu.Lock()
+	defer storageKeyringMu.Unlock()
+	storageKeyring = keyring
+}
+
+func currentStorageKeyring() StorageKeyring {
+	storageKeyringMu.RLock()
+	defer storageKeyringMu.RUnlock()
+	return storageKeyring
+}
+
+// storageHeader 加密文件头
+type storageHeader struct {
+	keyID     uint64
+	chunkSize int
+	salt      [16]byte
+	raw       [storageHeaderSize]byte
+}
+
+func parseStorageHeader(raw []byte) (*storageHeader, bool) {
+	if len(raw) < storageHeaderSize || !bytes.Equal(raw[:len(storageMagic)], storageMagic) {
+		return nil, false
+	}
+	header := &storageHeader{
+		keyID:     binary.BigEndian.Uint64(raw[8:16]),
+		chunkSize: int(binary.BigEndian.Uint32(raw[16:20])),
+	}
+	copy(header.salt[:], raw[20:36])
+	copy(header.raw[:], raw[:storageHeaderSize])
+	return header, header.chunkSize > 0
+}
+
+// newFileAEAD 由数据密钥和文件的盐派生文件密钥
+func newFileAEAD(key []byte, salt []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("star-cloud storage file key"))
+	mac.Write(salt)
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(index uint64) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[4:], index)
+	return nonce
+}
+
+func chunkAdditionalData(header *storageHeader, final bool) []byte {
+	ad := make([]byte, storageHeaderSize+1)
+	copy(ad, header.raw[:])
+	if final {
+		ad[storageHeaderSize] = 1
+	}
+	return ad
+}
+
+// encryptWriter 分块加密写入器，Close 时写入最后一块，不关闭底层写入器
+type encryptWriter struct {
+	dst    io.Writer
+	header *storageHeader
+	aead   cipher.AEAD
+	buffer []byte
+	index  uint64
+	closed bool
+}
+
+// StoredContentWriter 存储内容写入器，KeyID 为写入所用的数据密钥ID（明文为0），需保存到文件记录中
+type StoredContentWriter interface {
+	io.WriteCloser
+	KeyID() uint64
+}
+
+// NewEncryptWriter 以数据密钥创建加密写入器，立即写入文件头
+func NewEncryptWriter(dst io.Writer, key *DataKey) (StoredContentWriter, error) {
+	header := &storageHeader{keyID: key.ID, chunkSize: storageChunkSize}
+	if _, err := rand.Read(header.salt[:]); err != nil {
+		return nil, err
+	}
+	copy(header.raw[:], storageMagic)
+	binary.BigEndian.PutUint64(header.raw[8:16], header.keyID)
+	binary.BigEndian.PutUint32(header.raw[16:20], uint32(header.chunkSize))
+	copy(header.raw[20:36], header.salt[:])
+
+	aead, err := newFileAEAD(key.Key, header.salt[:])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dst.Write(header.raw[:]); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{
+		dst:    dst,
+		header: header,
+		aead:   aead,
+		buffer: make([]byte, 0, header.chunkSize),
+	}, nil
+}
+
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), w.header.chunkSize-len(w.buffer))
+		w.buffer = append(w.buffer, p[:n]...)
+		p = p[n:]
+		written += n
+		// 写满的分块一定不是最后一块，最后一块总是不足一个分块
+		if len(w.buffer) == w.header.chunkSize {
+			if err := w.flush(false); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (w *encryptWriter) flush(final bool) error {
+	sealed := w.aead.Seal(nil, chunkNonce(w.index), w.buffer, chunkAdditionalData(w.header, final))
+	w.index++
+	w.buffer = w.buffer[:0]
+	_, err := w.dst.Write(sealed)
+	return err
+}
+
+func (w *encryptWriter) KeyID() uint64 {
+	return w.header.keyID
+}
+
+func (w *encryptWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.flush(true)
+}
+
+// nopWriteCloser 未启用加密时直接写入明文
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func (nopWriteCloser) KeyID() uint64 { return 0 }
+
+// NewStoredContentWriter 返回写入用户文件内容的写入器：启用加密时以所有者的数据密钥加密，否则直接写入明文。
+// 写完后必须调用 Close 写入最后一个分块，Close 不关闭 dst
+func NewStoredContentWriter(dst io.Writer, ownerID string) (StoredContentWriter, error) {
+	keyring := currentStorageKeyring()
+	if keyring == nil {
+		return nopWriteCloser{dst}, nil
+	}
+	key, err := keyring.ActiveKey(ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("获取数据密钥失败: %w", err)
+	}
+	if key == nil {
+		return nopWriteCloser{dst}, nil
+	}
+	return NewEncryptWriter(dst, key)
+}
+
+// storageEncryptionActive 新写入的内容是否需要加密
+func storageEncryptionActive(ownerID string) bool {
+	keyring := currentStorageKeyring()
+	if keyring == nil {
+		return false
+	}
+	key, err := keyring.ActiveKey(ownerID)
+	// 获取密钥失败时同样按需要加密处理，由写入时返回错误，避免静默写入明文
+	return err != nil || key != nil
+}
+
+// StoredContent 打开的存储内容，加密文件透明解密，支持顺序读取、Seek 和随机读取
+type StoredContent struct {
+	file   *os.File
+	size   int64
+	offset int64
+
+	// 以下仅加密文件使用
+	header   *storageHeader
+	aead     cipher.AEAD
+	chunks   uint64 // 分块数量，最后一块序号为 chunks-1
+	mu       sync.Mutex
+	cached   []byte
+	cachedAt uint64
+	hasCache bool
+}
+
+// OpenStoredContent 打开磁盘上的用户文件内容。keyID 为记录中的数据密钥ID：
+// 为0时按明文读取，否则文件头必须是该密钥加密的格式，按其解密
+func OpenStoredContent(absolutePath string, keyID uint64) (*StoredContent, error) {
+	file, err := os.Open(absolutePath)
+	if err != nil {
+		return nil, err
+	}
+	content, err := newStoredContent(file, keyID)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return content, nil
+}
+
+func newStoredContent(file *os.File, keyID uint64) (*StoredContent, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if keyID == 0 {
+		return &StoredContent{file: file, size: info.Size()}, nil
+	}
+
+	raw := make([]byte, storageHeaderSize)
+	if _, err := file.ReadAt(raw, 0); err != nil {
+		if err == io.EOF {
+			return nil, ErrStorageCorrupted
+		}
+		return nil, err
+	}
+	header, ok := parseStorageHeader(raw)
+	if !ok || header.keyID != keyID {
+		return nil, ErrStorageCorrupted
+	}
+
+	keyring := currentStorageKeyring()
+	if keyring == nil {
+		return nil, ErrStorageKeyUnavailable
+	}
+	key, err := keyring.KeyByID(header.keyID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageKeyUnavailable, err)
+	}
+	aead, err := newFileAEAD(key.Key, header.salt[:])
+	if err != nil {
+		return nil, err
+	}
+
+	// 最后一块不足一个分块，余下的长度至少包含认证标签
+	sealedChunk := int64(header.chunkSize + storageTagSize)
+	body := info.Size() - storageHeaderSize
+	full, rest := body/sealedChunk, body%sealedChunk
+	if rest < storageTagSize {
+		return nil, ErrStorageCorrupted
+	}
+	content := &StoredContent{
+		file:   file,
+		size:   full*int64(header.chunkSize) + rest - storageTagSize,
+		header: header,
+		aead:   aead,
+		chunks: uint64(full) + 1,
+	}
+	// 打开时先验证最后一块：它带有结束标记，验证通过说明文件没有被截断，明文大小可信
+	if _, err := content.chunk(content.chunks - 1); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// Size 明文大小
+func (c *StoredContent) Size() int64 {
+	return c.size
+}
+
+// Encrypted 内容是否已加密
+func (c *StoredContent) Encrypted() bool {
+	return c.header != nil
+}
+
+// KeyID 加密使用的数据密钥ID，未加密时为0
+func (c *StoredContent) KeyID() uint64 {
+	if c.header == nil {
+		return 0
+	}
+	return c.header.keyID
+}
+
+// chunk 解密指定分块，结果在下次调用前有效，调用方需持有锁
+func (c *StoredContent) chunk(index uint64) ([]byte, error) {
+	if c.hasCache && c.cachedAt == index {
+		return c.cached, nil
+	}
+	chunkSize := int64(c.header.chunkSize)
+	sealedChunk := chunkSize + storageTagSize
+	offset := storageHeaderSize + int64(index)*sealedChunk
+	length := sealedChunk
+	final := index == c.chunks-1
+	if final {
+		length = c.size - int64(index)*chunkSize + storageTagSize
+	}
+
+	sealed := make([]byte, length)
+	if _, err := c.file.ReadAt(sealed, offset); err != nil {
+		if err == io.EOF {
+			return nil, ErrStorageCorrupted
+		}
+		return nil, err
+	}
+	plain, err := c.aead.Open(sealed[:0], chunkNonce(index), sealed, chunkAdditionalData(c.header, final))
+	if err != nil {
+		return nil, ErrStorageCorrupted
+	}
+	c.cached, c.cachedAt, c.hasCache = plain, index, true
+	return plain, nil
+}
+
+// ReadAt 实现 io.ReaderAt，读取明文的任意位置
+func (c *StoredContent) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+	if c.header == nil {
+		return c.file.ReadAt(p, off)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	chunkSize := int64(c.header.chunkSize)
+	read := 0
+	for read < len(p) && off < c.size {
+		plain, err := c.chunk(uint64(off / chunkSize))
+		if err != nil {
+			return read, err
+		}
+		n := copy(p[read:], plain[off%chunkSize:])
+		read += n
+		off += int64(n)
+	}
+	if read < len(p) {
+		return read, io.EOF
+	}
+	return read, nil
+}
+
+// Read 实现 io.Reader
+func (c *StoredContent) Read(p []byte) (int, error) {
+	if c.offset >= c.size {
+		return 0, io.EOF
+	}
+	if remaining := c.size - c.offset; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := c.ReadAt(p, c.offset)
+	c.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek 实现 io.Seeker，偏移按明文计算
+func (c *StoredContent) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += c.offset
+	case io.SeekEnd:
+		offset += c.size
+	default:
+		return 0, os.ErrInvalid
+	}
+	if offset < 0 {
+		return 0, os.ErrInvalid
+	}
+	c.offset = offset
+	return offset, nil
+}
+
+// Close 关闭文件
+func (c *StoredContent) Close() error {
+	return c.file.Close()
+}
+
+// ReencryptStoredFile 以所有者当前的数据密钥重新写入 keyID 加密（0为明文）的文件内容。
+// 新内容写入同目录下的新文件并同步到磁盘，原文件保持不变，返回新文件的路径和所用的数据密钥ID；
+// 调用方更新记录后删除原文件，更新失败时删除新文件。未启用加密时返回错误
+func ReencryptStoredFile(absolutePath string, keyID uint64, ownerID string) (string, uint64, error) {
+	keyring := currentStorageKeyring()
+	if keyring == nil {
+		return "", 0, ErrStorageKeyUnavailable
+	}
+	key, err := keyring.ActiveKey(ownerID)
+	if err != nil {
+		return "", 0, err
+	}
+	if key == nil {
+		return "", 0, errors.New("未启用存储加密")
+	}
+
+	source, err := OpenStoredContent(absolutePath, keyID)
+	if err != nil {
+		return "", 0, err
+	}
+	defer source.Close()
+	info, err := source.file.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+
+	dir := filepath.Dir(absolutePath)
+	name, err := GenerateUniqueFileName(dir, filepath.Base(absolutePath))
+	if err != nil {
+		return "", 0, err
+	}
+	targetPath := filepath.Join(dir, name)
+	target, err := os.OpenFile(targetPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return "", 0, err
+	}
+	writer, err := NewEncryptWriter(target, key)
+	if err == nil {
+		_, err = io.Copy(writer, io.NewSectionReader(source, 0, source.Size()))
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		err = target.Sync()
+	}
+	if closeErr := target.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(targetPath)
+		return "", 0, err
+	}
+	return targetPath, key.ID, nil
+}
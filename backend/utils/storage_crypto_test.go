@@ -0,0 +1,340 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testKeyring 内存中的密钥来源，active 为各用户当前使用的数据密钥ID
+type testKeyring struct {
+	keys   map[uint64]*DataKey
+	active map[string]uint64
+}
+
+func (k *testKeyring) ActiveKey(userID string) (*DataKey, error) {
+	id, ok := k.active[userID]
+	if !ok {
+		return nil, nil
+	}
+	return k.keys[id], nil
+}
+
+func (k *testKeyring) KeyByID(keyID uint64) (*DataKey, error) {
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, errors.New("数据密钥不存在")
+	}
+	return key, nil
+}
+
+// useTestKeyring 注册包含ID为 1、2 两个数据密钥的密钥来源，用户 alice 当前使用密钥1，测试结束后恢复
+func useTestKeyring(t *testing.T) *testKeyring {
+	t.Helper()
+	keyring := &testKeyring{keys: map[uint64]*DataKey{}, active: map[string]uint64{"alice": 1}}
+	for _, id := range []uint64{1, 2} {
+		key := &DataKey{ID: id, Key: make([]byte, 32)}
+		if _, err := rand.Read(key.Key); err != nil {
+			t.Fatalf("生成密钥失败: %v", err)
+		}
+		keyring.keys[id] = key
+	}
+	SetStorageKeyring(keyring)
+	t.Cleanup(func() { SetStorageKeyring(nil) })
+	return keyring
+}
+
+func randomContent(t *testing.T, size int) []byte {
+	t.Helper()
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("生成内容失败: %v", err)
+	}
+	return data
+}
+
+// writeStoredContent 以 ownerID 当前的数据密钥写入内容，返回文件路径和密钥ID
+func writeStoredContent(t *testing.T, ownerID string, data []byte) (string, uint64) {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer, err := NewStoredContentWriter(&buffer, ownerID)
+	if err != nil {
+		t.Fatalf("创建写入器失败: %v", err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("关闭写入器失败: %v", err)
+	}
+	filePath := filepath.Join(t.TempDir(), "content.bin")
+	if err := os.WriteFile(filePath, buffer.Bytes(), 0600); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	return filePath, writer.KeyID()
+}
+
+// readStoredContent 打开并读出全部明文，返回第一个遇到的错误
+func readStoredContent(filePath string, keyID uint64) ([]byte, error) {
+	content, err := OpenStoredContent(filePath, keyID)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+	return io.ReadAll(content)
+}
+
+func TestStoredContentRoundTrip(t *testing.T) {
+	useTestKeyring(t)
+	sizes := []int{0, 1, storageChunkSize - 1, storageChunkSize, storageChunkSize + 1, 3*storageChunkSize + 17}
+
+	for _, size := range sizes {
+		data := randomContent(t, size)
+		filePath, keyID := writeStoredContent(t, "alice", data)
+		if keyID != 1 {
+			t.Fatalf("大小 %d: 密钥ID为 %d，期望 1", size, keyID)
+		}
+		// 过短的内容可能碰巧出现在密文中，只检查足够长的内容
+		raw, _ := os.ReadFile(filePath)
+		if size >= 16 && bytes.Contains(raw, data) {
+			t.Errorf("大小 %d: 磁盘上出现了明文", size)
+		}
+
+		content, err := OpenStoredContent(filePath, keyID)
+		if err != nil {
+			t.Fatalf("大小 %d: 打开失败: %v", size, err)
+		}
+		if content.Size() != int64(size) || !content.Encrypted() || content.KeyID() != keyID {
+			t.Errorf("大小 %d: Size=%d Encrypted=%v KeyID=%d", size, content.Size(), content.Encrypted(), content.KeyID())
+		}
+		got, err := io.ReadAll(content)
+		content.Close()
+		if err != nil {
+			t.Fatalf("大小 %d: 读取失败: %v", size, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("大小 %d: 解密结果不一致", size)
+		}
+	}
+}
+
+func TestStoredContentPlaintextWithoutKeyring(t *testing.T) {
+	data := []byte("plain content")
+	filePath, keyID := writeStoredContent(t, "alice", data)
+	if keyID != 0 {
+		t.Fatalf("未注册密钥来源时密钥ID为 %d，期望 0", keyID)
+	}
+	if raw, _ := os.ReadFile(filePath); !bytes.Equal(raw, data) {
+		t.Errorf("未启用加密时应写入明文，实际为 %q", raw)
+	}
+
+	useTestKeyring(t)
+	if _, keyID := writeStoredContent(t, "bob", data); keyID != 0 {
+		t.Errorf("没有数据密钥的用户密钥ID为 %d，期望 0", keyID)
+	}
+}
+
+func TestStoredContentReadAtAndSeek(t *testing.T) {
+	useTestKeyring(t)
+	data := randomContent(t, 3*storageChunkSize+100)
+	filePath, keyID := writeStoredContent(t, "alice", data)
+
+	content, err := OpenStoredContent(filePath, keyID)
+	if err != nil {
+		t.Fatalf("打开失败: %v", err)
+	}
+	defer content.Close()
+
+	// 跨越分块边界的随机读取
+	ranges := []struct{ off, length int }{
+		{0, 10},
+		{storageChunkSize - 5, 10},
+		{storageChunkSize, storageChunkSize},
+		{storageChunkSize / 2, 2 * storageChunkSize},
+		{len(data) - 50, 50},
+	}
+	for _, r := range ranges {
+		buffer := make([]byte, r.length)
+		if _, err := content.ReadAt(buffer, int64(r.off)); err != nil {
+			t.Fatalf("ReadAt(%d, %d) 失败: %v", r.off, r.length, err)
+		}
+		if !bytes.Equal(buffer, data[r.off:r.off+r.length]) {
+			t.Errorf("ReadAt(%d, %d) 内容不一致", r.off, r.length)
+		}
+	}
+
+	// 读到末尾之后返回 io.EOF
+	buffer := make([]byte, 100)
+	n, err := content.ReadAt(buffer, int64(len(data)-40))
+	if n != 40 || err != io.EOF {
+		t.Errorf("读取末尾返回 %d, %v，期望 40, EOF", n, err)
+	}
+
+	if offset, err := content.Seek(-30, io.SeekEnd); err != nil || offset != int64(len(data)-30) {
+		t.Fatalf("Seek 返回 %d, %v", offset, err)
+	}
+	tail, err := io.ReadAll(content)
+	if err != nil || !bytes.Equal(tail, data[len(data)-30:]) {
+		t.Errorf("Seek 后读取的内容不一致 (%v)", err)
+	}
+	if _, err := content.Seek(-1, io.SeekStart); err == nil {
+		t.Error("负偏移应返回错误")
+	}
+}
+
+func TestStoredContentDetectsTampering(t *testing.T) {
+	useTestKeyring(t)
+	// 两个完整分块加一个不完整的最后一块
+	data := randomContent(t, 2*storageChunkSize+10)
+	sealedChunk := storageChunkSize + storageTagSize
+
+	tests := []struct {
+		name   string
+		tamper func(raw []byte) []byte
+	}{
+		{name: "截掉最后一个字节", tamper: func(raw []byte) []byte { return raw[:len(raw)-1] }},
+		{name: "截掉最后一块", tamper: func(raw []byte) []byte { return raw[:storageHeaderSize+2*sealedChunk] }},
+		{name: "截断到不完整的分块", tamper: func(raw []byte) []byte { return raw[:storageHeaderSize+2*sealedChunk-1] }},
+		{name: "只剩文件头", tamper: func(raw []byte) []byte { return raw[:storageHeaderSize] }},
+		{name: "文件头不完整", tamper: func(raw []byte) []byte { return raw[:storageHeaderSize-1] }},
+		{name: "末尾追加内容", tamper: func(raw []byte) []byte { return append(raw, 0) }},
+		{
+			name: "调换前两个分块",
+			tamper: func(raw []byte) []byte {
+				first := storageHeaderSize
+				second := first + sealedChunk
+				swapped := append([]byte(nil), raw[:first]...)
+				swapped = append(swapped, raw[second:second+sealedChunk]...)
+				swapped = append(swapped, raw[first:second]...)
+				return append(swapped, raw[second+sealedChunk:]...)
+			},
+		},
+		{name: "修改分块内容", tamper: func(raw []byte) []byte { raw[storageHeaderSize+100] ^= 1; return raw }},
+		{name: "修改认证标签", tamper: func(raw []byte) []byte { raw[storageHeaderSize+sealedChunk-1] ^= 1; return raw }},
+		{name: "修改文件头中的盐", tamper: func(raw []byte) []byte { raw[storageHeaderSize-1] ^= 1; return raw }},
+		{name: "修改文件头中的分块大小", tamper: func(raw []byte) []byte { raw[18] ^= 1; return raw }},
+		{name: "修改魔数", tamper: func(raw []byte) []byte { raw[0] = 'X'; return raw }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath, keyID := writeStoredContent(t, "alice", data)
+			raw, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatalf("读取文件失败: %v", err)
+			}
+			if err := os.WriteFile(filePath, tt.tamper(raw), 0600); err != nil {
+				t.Fatalf("写入文件失败: %v", err)
+			}
+
+			got, err := readStoredContent(filePath, keyID)
+			if !errors.Is(err, ErrStorageCorrupted) {
+				t.Errorf("期望 ErrStorageCorrupted，实际读出 %d 字节，错误为 %v", len(got), err)
+			}
+		})
+	}
+}
+
+func TestOpenStoredContentUsesRecordedKeyID(t *testing.T) {
+	keyring := useTestKeyring(t)
+	data := []byte("secret content")
+	encryptedPath, keyID := writeStoredContent(t, "alice", data)
+
+	// 以魔数开头的明文文件按记录（密钥ID为0）原样读取，不会被当作密文
+	plain := append(append([]byte(nil), storageMagic...), randomContent(t, storageHeaderSize+storageTagSize)...)
+	plainPath := filepath.Join(t.TempDir(), "plain.bin")
+	if err := os.WriteFile(plainPath, plain, 0600); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	if got, err := readStoredContent(plainPath, 0); err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("以魔数开头的明文读取结果不一致 (%v)", err)
+	}
+	if _, err := readStoredContent(plainPath, keyID); !errors.Is(err, ErrStorageCorrupted) {
+		t.Errorf("记录为加密但内容不是密文时期望 ErrStorageCorrupted，实际为 %v", err)
+	}
+
+	// 记录为明文时不解密，读出的是磁盘上的原始内容
+	raw, _ := os.ReadFile(encryptedPath)
+	if got, err := readStoredContent(encryptedPath, 0); err != nil || !bytes.Equal(got, raw) {
+		t.Errorf("密钥ID为0时应按明文读取 (%v)", err)
+	}
+
+	// 记录的密钥ID与文件头不一致
+	if _, err := readStoredContent(encryptedPath, 2); !errors.Is(err, ErrStorageCorrupted) {
+		t.Errorf("密钥ID不一致时期望 ErrStorageCorrupted，实际为 %v", err)
+	}
+
+	// 数据密钥不可用
+	delete(keyring.keys, keyID)
+	if _, err := readStoredContent(encryptedPath, keyID); !errors.Is(err, ErrStorageKeyUnavailable) {
+		t.Errorf("缺少数据密钥时期望 ErrStorageKeyUnavailable，实际为 %v", err)
+	}
+	SetStorageKeyring(nil)
+	if _, err := readStoredContent(encryptedPath, keyID); !errors.Is(err, ErrStorageKeyUnavailable) {
+		t.Errorf("未注册密钥来源时期望 ErrStorageKeyUnavailable，实际为 %v", err)
+	}
+}
+
+func TestReencryptStoredFile(t *testing.T) {
+	data := randomContent(t, storageChunkSize+123)
+	plainPath := filepath.Join(t.TempDir(), "report.bin")
+	if err := os.WriteFile(plainPath, data, 0600); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+
+	if _, _, err := ReencryptStoredFile(plainPath, 0, "alice"); err == nil {
+		t.Fatal("未启用加密时应返回错误")
+	}
+
+	keyring := useTestKeyring(t)
+	if _, _, err := ReencryptStoredFile(plainPath, 0, "bob"); err == nil {
+		t.Error("没有数据密钥的用户应返回错误")
+	}
+
+	// 加密明文文件：写入新文件，原文件保持不变
+	encryptedPath, keyID, err := ReencryptStoredFile(plainPath, 0, "alice")
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	if encryptedPath == plainPath || filepath.Dir(encryptedPath) != filepath.Dir(plainPath) || keyID != 1 {
+		t.Fatalf("返回 %s (密钥 %d)，期望同目录下的新文件和密钥 1", encryptedPath, keyID)
+	}
+	if raw, _ := os.ReadFile(plainPath); !bytes.Equal(raw, data) {
+		t.Error("原文件被修改")
+	}
+	if got, err := readStoredContent(encryptedPath, keyID); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("加密后的内容不一致 (%v)", err)
+	}
+
+	// 轮换到新的数据密钥
+	keyring.active["alice"] = 2
+	rotatedPath, rotatedKeyID, err := ReencryptStoredFile(encryptedPath, keyID, "alice")
+	if err != nil {
+		t.Fatalf("轮换失败: %v", err)
+	}
+	if rotatedPath == encryptedPath || rotatedKeyID != 2 {
+		t.Fatalf("返回 %s (密钥 %d)，期望新文件和密钥 2", rotatedPath, rotatedKeyID)
+	}
+	if got, err := readStoredContent(rotatedPath, rotatedKeyID); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("轮换后的内容不一致 (%v)", err)
+	}
+	if got, err := readStoredContent(encryptedPath, keyID); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("轮换后原文件应仍可读取 (%v)", err)
+	}
+
+	// 源文件损坏时不留下新文件
+	raw, _ := os.ReadFile(rotatedPath)
+	raw[storageHeaderSize] ^= 1
+	os.WriteFile(rotatedPath, raw, 0600)
+	entries, _ := os.ReadDir(filepath.Dir(rotatedPath))
+	if _, _, err := ReencryptStoredFile(rotatedPath, rotatedKeyID, "alice"); !errors.Is(err, ErrStorageCorrupted) {
+		t.Errorf("期望 ErrStorageCorrupted，实际为 %v", err)
+	}
+	if after, _ := os.ReadDir(filepath.Dir(rotatedPath)); len(after) != len(entries) {
+		t.Errorf("失败后目录中有 %d 个文件，期望 %d", len(after), len(entries))
+	}
+}
@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// ErrUnsupportedContent 文件类型不支持提取文本
+var ErrUnsupportedContent = errors.New("不支持提取该类型文件的文本")
+
+// plainTextExtensions 按纯文本读取的文件扩展名：文档、数据文件和常见源代码
+var plainTextExtensions = map[string]bool{
+	".txt": true, ".md": true, ".markdown": true, ".csv": true, ".tsv": true, ".json": true, ".log": true,
+	".xml": true, ".yaml": true, ".yml": true, ".toml": true, ".ini": true, ".conf": true, ".cfg": true,
+	".properties": true, ".html": true, ".htm": true, ".css": true, ".scss": true, ".less": true,
+	".js": true, ".mjs": true, ".ts": true, ".jsx": true, ".tsx": true, ".vue": true,
+	".go": true, ".py": true, ".java": true, ".kt": true, ".scala": true, ".c": true, ".h": true,
+	".cpp": true, ".cc": true, ".hpp": true, ".cs": true, ".rb": true, ".php": true, ".rs": true,
+	".swift": true, ".m": true, ".r": true, ".lua": true, ".pl": true, ".sh": true, ".bash": true,
+	".bat": true, ".ps1": true, ".sql": true, ".proto": true, ".gradle": true,
+}
+
+// IsTextExtractable 根据文件名判断是否支持提取文本
+func IsTextExtractable(fileName string) bool {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	switch ext {
+	case ".pdf", ".docx", ".xlsx", ".pptx":
+		return true
+	}
+	return plainTextExtensions[ext]
+}
+
+// ExtractText 从磁盘文件中提取可检索的文本，keyID 为文件记录中的存储加密数据密钥ID。
+// maxBytes 限制读取或解压的原始数据量，maxRunes 限制返回文本的字符数；
+// 返回的文本已合并空白字符，不支持的类型返回 ErrUnsupportedContent
+func ExtractText(absolutePath string, keyID uint64, fileName string, maxBytes int64, maxRunes int) (string, error) {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	var text string
+	var err error
+
+	switch {
+	case plainTextExtensions[ext]:
+		text, err = extractPlainText(absolutePath, keyID, maxBytes)
+	case ext == ".docx":
+		text, err = extractOfficeXMLText(absolutePath, keyID, maxBytes, func(name string) bool {
+			return name == "word/document.xml" || strings.HasPrefix(name, "word/header") || strings.HasPrefix(name, "word/footer") ||
+				name == "word/footnotes.xml" || name == "word/endnotes.xml"
+		}, "t", []string{"p", "tab", "br"})
+	case ext == ".xlsx":
+		text, err = extractOfficeXMLText(absolutePath, keyID, maxBytes, func(name string) bool {
+			return name == "xl/sharedStrings.xml" || strings.HasPrefix(name, "xl/worksheets/sheet")
+		}, "t", []string{"si", "c", "row"})
+	case ext == ".pptx":
+		text, err = extractOfficeXMLText(absolutePath, keyID, maxBytes, func(name string) bool {
+			return strings.HasPrefix(name, "ppt/slides/slide") || strings.HasPrefix(name, "ppt/notesSlides/notesSlide")
+		}, "t", []string{"p", "br"})
+	case ext == ".pdf":
+		text, err = extractPDFFile(absolutePath, keyID, maxBytes)
+	default:
+		return "", ErrUnsupportedContent
+	}
+	if err != nil {
+		return "", err
+	}
+	return NormalizeExtractedText(text, maxRunes), nil
+}
+
+// NormalizeExtractedText 修正无效UTF-8、将控制字符和连续空白合并为单个空格，并按字符数截断
+func NormalizeExtractedText(text string, maxRunes int) string {
+	text = strings.ToValidUTF8(text, " ")
+
+	var builder strings.Builder
+	builder.Grow(len(text))
+	count := 0
+	pendingSpace := false
+	for _, r := range text {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || r == utf8.RuneError {
+			pendingSpace = count > 0
+			continue
+		}
+		if maxRunes > 0 && count >= maxRunes {
+			break
+		}
+		if pendingSpace {
+			builder.WriteByte(' ')
+			count++
+			pendingSpace = false
+		}
+		builder.WriteRune(r)
+		count++
+	}
+	return builder.String()
+}
+
+// extractPlainText 读取纯文本文件，非UTF-8内容按GB18030解码
+func extractPlainText(absolutePath string, keyID uint64, maxBytes int64) (string, error) {
+	file, err := OpenStoredContent(absolutePath, keyID)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes))
+	if err != nil {
+		return "", err
+	}
+
+	// 带BOM的UTF-16文本
+	if bytes.HasPrefix(data, []byte{0xFF, 0xFE}) {
+		return decodeUTF16(data[2:], false), nil
+	}
+	if bytes.HasPrefix(data, []byte{0xFE, 0xFF}) {
+		return decodeUTF16(data[2:], true), nil
+	}
+
+	// 含有NUL字节视为二进制文件
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", ErrUnsupportedContent
+	}
+
+	data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+	if utf8.Valid(data) || utf8.Valid(trimIncompleteRune(data)) {
+		return string(data), nil
+	}
+	if decoded, err := simplifiedchinese.GB18030.NewDecoder().Bytes(data); err == nil {
+		return string(decoded), nil
+	}
+	return string(data), nil
+}
+
+// decodeUTF16 解码UTF-16文本，bigEndian 指定字节序
+func decodeUTF16(data []byte, bigEndian bool) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+		} else {
+			units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// trimIncompleteRune 去掉因截断读取而不完整的末尾UTF-8字符
+func trimIncompleteRune(data []byte) []byte {
+	for i := 0; i < utf8.UTFMax && len(data) > 0; i++ {
+		r, size := utf8.DecodeLastRune(data)
+		if r != utf8.RuneError || size > 1 {
+			break
+		}
+		data = data[:len(data)-1]
+	}
+	return data
+}
+
+// extractOfficeXMLText 从 OOXML（docx/xlsx/pptx）压缩包中提取文本。
+// include 选择要读取的XML部件，textElement 为文本元素的本地名称，breakElements 结束时插入分隔符
+func extractOfficeXMLText(absolutePath string, keyID uint64, maxBytes int64, include func(name string) bool, textElement string, breakElements []string) (string, error) {
+	file, err := OpenStoredContent(absolutePath, keyID)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	reader, err := zip.NewReader(file, file.Size())
+	if err != nil {
+		return "", fmt.Errorf("无法打开文档: %v", err)
+	}
+
+	var parts []*zip.File
+	for _, entry := range reader.File {
+		if include(path.Clean(entry.Name)) {
+			parts = append(parts, entry)
+		}
+	}
+	// 按名称自然顺序读取，使 sheet2 排在 sheet10 之前
+	sort.Slice(parts, func(i, j int) bool {
+		if len(parts[i].Name) != len(parts[j].Name) {
+			return len(parts[i].Name) < len(parts[j].Name)
+		}
+		return parts[i].Name < parts[j].Name
+	})
+
+	breaks := make(map[string]bool, len(breakElements))
+	for _, name := range breakElements {
+		breaks[name] = true
+	}
+
+	var builder strings.Builder
+	remaining := maxBytes
+	for _, part := range parts {
+		if remaining <= 0 {
+			break
+		}
+		rc, err := part.Open()
+		if err != nil {
+			return "", err
+		}
+		limited := &io.LimitedReader{R: rc, N: remaining}
+		err = collectXMLText(limited, textElement, breaks, &builder)
+		rc.Close()
+		remaining = limited.N
+		if err != nil && remaining > 0 {
+			return "", fmt.Errorf("解析文档内容失败: %v", err)
+		}
+	}
+	return builder.String(), nil
+}
+
+// collectXMLText 收集指定元素内的字符数据
+func collectXMLText(r io.Reader, textElement string, breaks map[string]bool, builder *strings.Builder) error {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == textElement {
+				depth++
+			}
+		case xml.EndElement:
+			if t.Name.Local == textElement && depth > 0 {
+				depth--
+			}
+			if breaks[t.Name.Local] {
+				builder.WriteByte('\n')
+			}
+		case xml.CharData:
+			if depth > 0 {
+				builder.Write(t)
+			}
+		}
+	}
+}
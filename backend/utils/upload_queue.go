@@ -1,125 +1,441 @@
 package utils
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"sort"
 	"sync"
 	"time"
+
+	"backend/models"
+)
+
+const (
+	taskNotifyInterval = 500 * time.Millisecond // 字节进度推送给监听函数的最小间隔
+	taskSaveInterval   = 2 * time.Second        // 字节进度写入数据库的最小间隔，状态变化立即写入
+	taskSpeedWindow    = time.Second            // 统计写入速度的时间窗口
+	taskMemoryTTL      = time.Hour              // 已结束的任务在内存中保留的时间，之后只能从数据库查询
+	taskSweepInterval  = 10 * time.Minute       // 创建任务时顺带清理过期任务的最小间隔
 )
 
+// ErrTaskNotCancellable 任务已结束，或已过了可以中止的阶段
+var ErrTaskNotCancellable = errors.New("任务已结束或当前阶段无法取消")
+
+// errTaskCancelled 取消任务时作为 context 的取消原因
+var errTaskCancelled = errors.New("任务已取消")
+
+// errTaskInterrupted 服务重启时未结束的任务记录的失败原因
+const errTaskInterrupted = "服务重启，任务已中断"
+
 // UploadTask 上传任务
-type UploadTask struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`
-	FileName  string    `json:"file_name"`
-	FileSize  int64     `json:"file_size"`
-	Progress  int       `json:"progress"`
-	Status    string    `json:"status"` // pending, uploading, completed, failed
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Error     string    `json:"error,omitempty"`
+type UploadTask = models.UploadTask
+
+// UploadTaskStore 任务的持久化存储
+type UploadTaskStore interface {
+	SaveUploadTask(task *models.UploadTask) error
+	GetUploadTask(taskID string) (*models.UploadTask, error)
+	GetUploadTasksByUser(userID string, limit int) ([]models.UploadTask, error)
+	DeleteUploadTasksBefore(before time.Time) error
+}
+
+// taskState 任务在内存中的状态
+type taskState struct {
+	task   UploadTask
+	cancel context.CancelCauseFunc // 正在写入内容时可中止，写入结束后为 nil
+
+	version      int64 // 每次修改递增，避免并发保存时旧快照覆盖新快照
+	savedVersion int64
+	lastSaved    time.Time
+	lastNotified time.Time
+	speedBytes   int64 // 速度统计窗口开始时已写入的字节数
+	speedAt      time.Time
 }
 
 // UploadQueueManager 上传队列管理器
 type UploadQueueManager struct {
-	tasks map[string]*UploadTask
-	mutex sync.RWMutex
+	tasks     map[string]*taskState
+	mutex     sync.RWMutex
+	listener  func(task UploadTask)
+	store     UploadTaskStore
+	saveMutex sync.Mutex
+	lastSweep time.Time
 }
 
 // NewUploadQueueManager 创建上传队列管理器
 func NewUploadQueueManager() *UploadQueueManager {
 	return &UploadQueueManager{
-		tasks: make(map[string]*UploadTask),
+		tasks:     make(map[string]*taskState),
+		lastSweep: time.Now(),
 	}
 }
 
-// CreateTask 创建上传任务
-func (q *UploadQueueManager) CreateTask(userID, fileName string, fileSize int64) *UploadTask {
+// SetListener 设置任务变化的监听函数，任务创建或更新后以任务快照调用，用于向客户端推送进度。
+// 监听函数在持有锁之外调用，不能阻塞
+func (q *UploadQueueManager) SetListener(listener func(task UploadTask)) {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
+	q.listener = listener
+}
 
-	taskID := fmt.Sprintf("%s_%d", userID, time.Now().UnixNano())
-	task := &UploadTask{
-		ID:        taskID,
-		UserID:    userID,
-		FileName:  fileName,
-		FileSize:  fileSize,
-		Progress:  0,
-		Status:    "pending",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+// SetStore 设置任务的持久化存储，设置后任务写入数据库，内存中没有的任务从数据库查询
+func (q *UploadQueueManager) SetStore(store UploadTaskStore) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.store = store
+}
+
+// publish 以任务快照调用监听函数并按需写入数据库，调用方需已释放锁
+func (q *UploadQueueManager) publish(taskID string, notify, save bool) {
+	q.mutex.RLock()
+	state, exists := q.tasks[taskID]
+	listener := q.listener
+	store := q.store
+	var snapshot UploadTask
+	var version int64
+	if exists {
+		snapshot = state.task
+		version = state.version
 	}
+	q.mutex.RUnlock()
 
-	q.tasks[taskID] = task
-	return task
+	if !exists {
+		return
+	}
+	if notify && listener != nil {
+		listener(snapshot)
+	}
+	if save && store != nil {
+		q.save(store, state, &snapshot, version)
+	}
 }
 
-// GetTask 获取任务
+// save 写入任务快照，已经写入过更新的快照时跳过
+func (q *UploadQueueManager) save(store UploadTaskStore, state *taskState, snapshot *UploadTask, version int64) {
+	q.saveMutex.Lock()
+	defer q.saveMutex.Unlock()
+
+	q.mutex.RLock()
+	stale := version <= state.savedVersion
+	q.mutex.RUnlock()
+	if stale {
+		return
+	}
+	if err := store.SaveUploadTask(snapshot); err != nil {
+		log.Printf("⚠️ 保存任务 %s 失败: %v", snapshot.ID, err)
+		return
+	}
+
+	q.mutex.Lock()
+	state.savedVersion = version
+	state.lastSaved = time.Now()
+	q.mutex.Unlock()
+}
+
+// CreateTask 创建任务，taskType 为空表示普通上传
+func (q *UploadQueueManager) CreateTask(userID, taskType, fileName string, fileSize int64) *UploadTask {
+	q.sweep()
+
+	now := time.Now()
+	q.mutex.Lock()
+	taskID := fmt.Sprintf("%s_%d", userID, now.UnixNano())
+	state := &taskState{
+		task: UploadTask{
+			ID:        taskID,
+			UserID:    userID,
+			FileName:  fileName,
+			FileSize:  fileSize,
+			Progress:  0,
+			Status:    models.UploadTaskPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+			Type:      taskType,
+		},
+		version: 1,
+		speedAt: now,
+	}
+	q.tasks[taskID] = state
+	snapshot := state.task
+	q.mutex.Unlock()
+
+	q.publish(taskID, true, true)
+	return &snapshot
+}
+
+// GetTask 获取任务快照，内存中没有时从数据库查询，不存在时返回 nil
 func (q *UploadQueueManager) GetTask(taskID string) *UploadTask {
 	q.mutex.RLock()
-	defer q.mutex.RUnlock()
+	state, exists := q.tasks[taskID]
+	var snapshot UploadTask
+	if exists {
+		snapshot = state.task
+	}
+	store := q.store
+	q.mutex.RUnlock()
+
+	if exists {
+		return &snapshot
+	}
+	if store == nil {
+		return nil
+	}
+	task, err := store.GetUploadTask(taskID)
+	if err != nil {
+		log.Printf("⚠️ 查询任务 %s 失败: %v", taskID, err)
+		return nil
+	}
+	if task != nil {
+		q.markInterrupted(store, task)
+	}
+	return task
+}
+
+// markInterrupted 数据库中未结束、内存中又没有的任务是服务重启前留下的，记为失败
+func (q *UploadQueueManager) markInterrupted(store UploadTaskStore, task *UploadTask) {
+	if task.Finished() {
+		return
+	}
+	task.Status = models.UploadTaskFailed
+	task.Error = errTaskInterrupted
+	task.Speed = 0
+	task.UpdatedAt = time.Now()
+	if err := store.SaveUploadTask(task); err != nil {
+		log.Printf("⚠️ 保存任务 %s 失败: %v", task.ID, err)
+	}
+}
+
+// updateTask 在锁内修改任务，释放锁后通知监听函数并写入数据库，throttle 为 true 时按时间间隔写入数据库。
+// 已取消的任务不再接受修改，执行方随后报告的失败不会覆盖取消状态
+func (q *UploadQueueManager) updateTask(taskID string, throttle bool, update func(task *UploadTask)) {
+	now := time.Now()
+	q.mutex.Lock()
+	state, exists := q.tasks[taskID]
+	if exists && state.task.Status == models.UploadTaskCancelled {
+		exists = false
+	}
+	save := false
+	if exists {
+		update(&state.task)
+		state.task.UpdatedAt = now
+		state.version++
+		if state.task.Finished() {
+			state.task.Speed = 0
+		}
+		save = !throttle || now.Sub(state.lastSaved) >= taskSaveInterval
+	}
+	q.mutex.Unlock()
+
+	if exists {
+		q.publish(taskID, true, save)
+	}
+}
 
-	return q.tasks[taskID]
+// StartTask 将等待中的任务标记为执行中，任务已被取消时返回 false，执行方应直接放弃
+func (q *UploadQueueManager) StartTask(taskID string) bool {
+	started := false
+	q.updateTask(taskID, false, func(task *UploadTask) {
+		task.Status = models.UploadTaskProcessing
+		started = true
+	})
+	return started
 }
 
 // UpdateTaskProgress 更新任务进度
 func (q *UploadQueueManager) UpdateTaskProgress(taskID string, progress int) {
+	q.updateTask(taskID, true, func(task *UploadTask) {
+		task.Progress = progress
+	})
+}
+
+// UpdateTaskBytes 更新已写入的字节数，同时计算进度和写入速度。total 为总字节数，未知时传 0。
+// 每个读取的数据块都会调用，推送和写入数据库按时间间隔节流
+func (q *UploadQueueManager) UpdateTaskBytes(taskID string, done, total int64) {
+	now := time.Now()
 	q.mutex.Lock()
-	defer q.mutex.Unlock()
+	state, exists := q.tasks[taskID]
+	if !exists || state.task.Finished() {
+		q.mutex.Unlock()
+		return
+	}
+	task := &state.task
+	task.BytesDone = done
+	if total > 0 {
+		task.FileSize = total
+		task.Progress = int(done * 100 / total)
+		// 写入完成后还要登记文件，留到任务完成时再显示 100
+		if task.Progress >= 100 {
+			task.Progress = 99
+		}
+	}
+	if elapsed := now.Sub(state.speedAt); elapsed >= taskSpeedWindow {
+		task.Speed = int64(float64(done-state.speedBytes) / elapsed.Seconds())
+		state.speedBytes = done
+		state.speedAt = now
+	}
+	task.UpdatedAt = now
+	state.version++
 
-	if task, exists := q.tasks[taskID]; exists {
-		task.Progress = progress
-		task.UpdatedAt = time.Now()
+	notify := now.Sub(state.lastNotified) >= taskNotifyInterval
+	if notify {
+		state.lastNotified = now
+	}
+	save := now.Sub(state.lastSaved) >= taskSaveInterval
+	q.mutex.Unlock()
+
+	if notify || save {
+		q.publish(taskID, notify, save)
 	}
 }
 
 // UpdateTaskStatus 更新任务状态
 func (q *UploadQueueManager) UpdateTaskStatus(taskID, status string) {
-	q.mutex.Lock()
-	defer q.mutex.Unlock()
-
-	if task, exists := q.tasks[taskID]; exists {
+	q.updateTask(taskID, false, func(task *UploadTask) {
 		task.Status = status
-		task.UpdatedAt = time.Now()
-	}
+	})
 }
 
 // UpdateTaskError 更新任务错误
 func (q *UploadQueueManager) UpdateTaskError(taskID, error string) {
+	q.updateTask(taskID, false, func(task *UploadTask) {
+		task.Error = error
+		task.Status = models.UploadTaskFailed
+	})
+}
+
+// UpdateTaskResult 标记任务完成并记录结果下载地址
+func (q *UploadQueueManager) UpdateTaskResult(taskID, resultURL string) {
+	q.updateTask(taskID, false, func(task *UploadTask) {
+		task.ResultURL = resultURL
+		task.Progress = 100
+		if task.FileSize > 0 {
+			task.BytesDone = task.FileSize
+		}
+		task.Status = models.UploadTaskCompleted
+	})
+}
+
+// TaskContext 返回写入任务内容时使用的 context，任务被取消时 context 随之取消。
+// 写入结束后必须调用 release，之后任务无法再取消；release 之后用 TaskCancelled 判断写入期间是否被取消
+func (q *UploadQueueManager) TaskContext(parent context.Context, taskID string) (ctx context.Context, release func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+
 	q.mutex.Lock()
-	defer q.mutex.Unlock()
+	if state, exists := q.tasks[taskID]; exists && state.task.Status != models.UploadTaskCancelled {
+		state.cancel = cancel
+	} else {
+		cancel(errTaskCancelled)
+	}
+	q.mutex.Unlock()
 
-	if task, exists := q.tasks[taskID]; exists {
-		task.Error = error
-		task.Status = "failed"
-		task.UpdatedAt = time.Now()
+	return ctx, func() {
+		q.mutex.Lock()
+		if state, exists := q.tasks[taskID]; exists {
+			state.cancel = nil
+		}
+		q.mutex.Unlock()
 	}
 }
 
-// GetUserTasks 获取用户的所有任务
+// TaskCancelled 判断 TaskContext 返回的 context 是否因为任务被取消而结束
+func TaskCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errTaskCancelled)
+}
+
+// CancelTask 取消任务：等待中的任务不再执行，正在写入的任务中止写入并由执行方删除已写入的部分。
+// 写入已经结束、正在登记结果的任务无法取消
+func (q *UploadQueueManager) CancelTask(taskID string) error {
+	q.mutex.Lock()
+	state, exists := q.tasks[taskID]
+	if !exists || (state.task.Status != models.UploadTaskPending && state.cancel == nil) {
+		q.mutex.Unlock()
+		return ErrTaskNotCancellable
+	}
+	state.task.Status = models.UploadTaskCancelled
+	state.task.Error = errTaskCancelled.Error()
+	state.task.Speed = 0
+	state.task.UpdatedAt = time.Now()
+	state.version++
+	if state.cancel != nil {
+		state.cancel(errTaskCancelled)
+		state.cancel = nil
+	}
+	q.mutex.Unlock()
+
+	q.publish(taskID, true, true)
+	return nil
+}
+
+// GetUserTasks 获取用户的任务，包括数据库中保留的已结束任务，按创建时间倒序
 func (q *UploadQueueManager) GetUserTasks(userID string) []*UploadTask {
 	q.mutex.RLock()
-	defer q.mutex.RUnlock()
+	userTasks := []*UploadTask{}
+	seen := make(map[string]bool)
+	for _, state := range q.tasks {
+		if state.task.UserID == userID {
+			snapshot := state.task
+			userTasks = append(userTasks, &snapshot)
+			seen[snapshot.ID] = true
+		}
+	}
+	store := q.store
+	q.mutex.RUnlock()
 
-	var userTasks []*UploadTask
-	for _, task := range q.tasks {
-		if task.UserID == userID {
+	if store != nil {
+		stored, err := store.GetUploadTasksByUser(userID, models.MaxUploadTaskList)
+		if err != nil {
+			log.Printf("⚠️ 查询用户任务失败: %v", err)
+		}
+		for i := range stored {
+			task := &stored[i]
+			if seen[task.ID] {
+				continue
+			}
+			q.markInterrupted(store, task)
 			userTasks = append(userTasks, task)
 		}
 	}
+
+	sort.Slice(userTasks, func(i, j int) bool {
+		return userTasks[i].CreatedAt.After(userTasks[j].CreatedAt)
+	})
+	if len(userTasks) > models.MaxUploadTaskList {
+		userTasks = userTasks[:models.MaxUploadTaskList]
+	}
 	return userTasks
 }
 
-// CleanupOldTasks 清理旧任务（超过1小时的任务）
-func (q *UploadQueueManager) CleanupOldTasks() {
+// sweep 距上次清理超过间隔时清理过期任务，在创建任务时调用，避免任务在内存中无限累积
+func (q *UploadQueueManager) sweep() {
 	q.mutex.Lock()
-	defer q.mutex.Unlock()
+	due := time.Since(q.lastSweep) >= taskSweepInterval
+	if due {
+		q.lastSweep = time.Now()
+	}
+	q.mutex.Unlock()
 
-	cutoff := time.Now().Add(-time.Hour)
-	for taskID, task := range q.tasks {
-		if task.UpdatedAt.Before(cutoff) {
+	if due {
+		q.CleanupOldTasks()
+	}
+}
+
+// CleanupOldTasks 清理旧任务：内存中只清理结束超过1小时的任务，数据库中清理超过保留期的任务
+func (q *UploadQueueManager) CleanupOldTasks() {
+	q.mutex.Lock()
+	cutoff := time.Now().Add(-taskMemoryTTL)
+	for taskID, state := range q.tasks {
+		if state.task.Finished() && state.task.UpdatedAt.Before(cutoff) {
 			delete(q.tasks, taskID)
 		}
 	}
+	store := q.store
+	q.mutex.Unlock()
+
+	if store != nil {
+		if err := store.DeleteUploadTasksBefore(time.Now().Add(-models.UploadTaskRetention)); err != nil {
+			log.Printf("⚠️ 清理过期任务失败: %v", err)
+		}
+	}
 }
 
 // GetQueueStats 获取队列统计信息
@@ -128,23 +444,29 @@ func (q *UploadQueueManager) GetQueueStats() map[string]interface{} {
 	defer q.mutex.RUnlock()
 
 	stats := map[string]interface{}{
-		"total_tasks":     len(q.tasks),
-		"pending_tasks":   0,
-		"uploading_tasks": 0,
-		"completed_tasks": 0,
-		"failed_tasks":    0,
+		"total_tasks":      len(q.tasks),
+		"pending_tasks":    0,
+		"uploading_tasks":  0,
+		"processing_tasks": 0,
+		"completed_tasks":  0,
+		"failed_tasks":     0,
+		"cancelled_tasks":  0,
 	}
 
-	for _, task := range q.tasks {
-		switch task.Status {
-		case "pending":
+	for _, state := range q.tasks {
+		switch state.task.Status {
+		case models.UploadTaskPending:
 			stats["pending_tasks"] = stats["pending_tasks"].(int) + 1
-		case "uploading":
+		case models.UploadTaskUploading:
 			stats["uploading_tasks"] = stats["uploading_tasks"].(int) + 1
-		case "completed":
+		case models.UploadTaskProcessing:
+			stats["processing_tasks"] = stats["processing_tasks"].(int) + 1
+		case models.UploadTaskCompleted:
 			stats["completed_tasks"] = stats["completed_tasks"].(int) + 1
-		case "failed":
+		case models.UploadTaskFailed:
 			stats["failed_tasks"] = stats["failed_tasks"].(int) + 1
+		case models.UploadTaskCancelled:
+			stats["cancelled_tasks"] = stats["cancelled_tasks"].(int) + 1
 		}
 	}
 
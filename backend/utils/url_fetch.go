@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress 目标地址属于内网、本机或保留地址段，禁止服务端访问
+var ErrBlockedAddress = errors.New("不允许访问内网、本机或保留地址")
+
+// blockedPrefixes netip 的 IsPrivate/IsLoopback 等方法未覆盖的保留地址段
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // 本网络
+	netip.MustParsePrefix("100.64.0.0/10"),   // 运营商级 NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF 协议分配
+	netip.MustParsePrefix("192.0.2.0/24"),    // 文档示例
+	netip.MustParsePrefix("198.18.0.0/15"),   // 基准测试
+	netip.MustParsePrefix("198.51.100.0/24"), // 文档示例
+	netip.MustParsePrefix("203.0.113.0/24"),  // 文档示例
+	netip.MustParsePrefix("240.0.0.0/4"),     // 保留及广播
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64，可映射到内网 IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // 本地 NAT64
+	netip.MustParsePrefix("2001::/32"),       // Teredo
+	netip.MustParsePrefix("2001:db8::/32"),   // 文档示例
+	netip.MustParsePrefix("2002::/16"),       // 6to4，可嵌入内网 IPv4
+}
+
+// IsPublicAddress 判断地址是否为可以从服务端访问的公网地址
+func IsPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateFetchURL 校验服务端抓取的URL：协议在允许列表中（仅支持 http/https），
+// 不包含用户名密码，主机为IP时必须是公网地址
+func ValidateFetchURL(rawURL string, allowedSchemes []string) (*url.URL, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || parsed.Host == "" {
+		return nil, errors.New("无效的URL")
+	}
+	scheme := strings.ToLower(parsed.Scheme)
+	allowed := false
+	for _, candidate := range allowedSchemes {
+		if candidate == scheme && (scheme == "http" || scheme == "https") {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, fmt.Errorf("不支持的URL协议: %s", parsed.Scheme)
+	}
+	if parsed.User != nil {
+		return nil, errors.New("URL中不能包含用户名或密码")
+	}
+
+	host := parsed.Hostname()
+	if host == "" {
+		return nil, errors.New("无效的URL")
+	}
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return nil, ErrBlockedAddress
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !IsPublicAddress(addr) {
+		return nil, ErrBlockedAddress
+	}
+	return parsed, nil
+}
+
+// blockPrivateAddress 在建立连接前校验实际连接的IP，防止DNS重绑定和重定向到内网
+func blockPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !IsPublicAddress(addr) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// NewSafeHTTPClient 创建只能访问公网地址的HTTP客户端：不使用代理，
+// 每次连接（包括重定向）都校验目标IP，重定向的协议同样受允许列表限制
+func NewSafeHTTPClient(timeout time.Duration, maxRedirects int, allowedSchemes []string) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: blockPrivateAddress,
+	}
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   15 * time.Second,
+		ResponseHeaderTimeout: 60 * time.Second,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       60 * time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf("重定向次数超过%d次", maxRedirects)
+			}
+			_, err := ValidateFetchURL(req.URL.String(), allowedSchemes)
+			return err
+		},
+	}
+}
+
+// RemoteFileName 推断远程文件的名称：优先使用 Content-Disposition 中的文件名，其次是URL路径的最后一段
+func RemoteFileName(response *http.Response) string {
+	if disposition := response.Header.Get("Content-Disposition"); disposition != "" {
+		if _, params, err := mime.ParseMediaType(disposition); err == nil {
+			if name := filepath.Base(strings.ReplaceAll(params["filename"], "\\", "/")); name != "" && name != "." && name != "/" {
+				return SanitizeArchiveName(name)
+			}
+		}
+	}
+	if name := path.Base(response.Request.URL.Path); name != "" && name != "." && name != "/" {
+		return SanitizeArchiveName(name)
+	}
+	return "download"
+}
@@ -0,0 +1,232 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// VirusScanner 病毒扫描器，可替换为其他实现
+type VirusScanner interface {
+	// Scan 扫描内容；扫描器拒绝处理时返回 *ScanRejectedError，连接失败等暂时性错误返回其他错误
+	Scan(ctx context.Context, reader io.Reader) (*VirusScanResult, error)
+	// Ping 检查扫描器是否可用
+	Ping(ctx context.Context) error
+}
+
+// VirusScanResult 扫描结果
+type VirusScanResult struct {
+	Infected  bool
+	Signature string // 命中的病毒特征名
+}
+
+// ScanRejectedError 扫描器收到内容但无法完成扫描（如超过大小限制），重试不会改变结果
+type ScanRejectedError struct {
+	Message string
+}
+
+func (e *ScanRejectedError) Error() string {
+	return "扫描失败: " + e.Message
+}
+
+// ClamdScanner 通过 clamd 的 INSTREAM 命令扫描内容，支持 TCP 和 unix socket
+type ClamdScanner struct {
+	network   string
+	address   string
+	timeout   time.Duration
+	chunkSize int
+}
+
+// NewClamdScanner 创建 clamd 扫描器，address 形如 tcp://host:3310、host:3310、unix:///path 或 /path
+func NewClamdScanner(address string, timeout time.Duration) (*ClamdScanner, error) {
+	scanner := &ClamdScanner{network: "tcp", timeout: timeout, chunkSize: 64 * 1024}
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		scanner.network, scanner.address = "unix", strings.TrimPrefix(address, "unix://")
+	case strings.HasPrefix(address, "tcp://"):
+		scanner.address = strings.TrimPrefix(address, "tcp://")
+	case strings.HasPrefix(address, "/"):
+		scanner.network, scanner.address = "unix", address
+	default:
+		scanner.address = address
+	}
+	if scanner.address == "" {
+		return nil, fmt.Errorf("无效的 clamd 地址: %s", address)
+	}
+	return scanner, nil
+}
+
+// dial 连接 clamd 并设置整体超时
+func (s *ClamdScanner) dial(ctx context.Context) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(s.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+	return conn, nil
+}
+
+// Ping 发送 PING 命令，期望返回 PONG
+func (s *ClamdScanner) Ping(ctx context.Context) error {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zPING\x00")); err != nil {
+		return err
+	}
+	reply, err := readClamdReply(conn)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("clamd 返回异常: %s", reply)
+	}
+	return nil
+}
+
+// Scan 以 INSTREAM 协议发送内容：每块前加4字节大端长度，以长度为0的块结束
+func (s *ClamdScanner) Scan(ctx context.Context, reader io.Reader) (*VirusScanResult, error) {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	writeErr := s.writeStream(conn, reader)
+	// 超过 StreamMaxLength 时 clamd 会先返回错误并关闭连接，写入失败后仍尝试读取回复
+	reply, readErr := readClamdReply(conn)
+	if readErr != nil || reply == "" {
+		if writeErr != nil {
+			return nil, writeErr
+		}
+		if readErr == nil {
+			readErr = errors.New("clamd 未返回结果")
+		}
+		return nil, readErr
+	}
+	return parseClamdReply(reply)
+}
+
+// writeStream 写入 INSTREAM 命令和全部内容
+func (s *ClamdScanner) writeStream(conn net.Conn, reader io.Reader) error {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return err
+	}
+	buffer := make([]byte, 4+s.chunkSize)
+	for {
+		n, err := io.ReadFull(reader, buffer[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buffer[:4], uint32(n))
+			if _, writeErr := conn.Write(buffer[:4+n]); writeErr != nil {
+				return writeErr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := conn.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// readClamdReply 读取以 \0 结尾的回复
+func readClamdReply(conn net.Conn) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(conn, 4096))
+	if err != nil && len(data) == 0 {
+		return "", err
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// parseClamdReply 解析 INSTREAM 的回复：stream: OK、stream: <特征名> FOUND 或 <原因> ERROR
+func parseClamdReply(reply string) (*VirusScanResult, error) {
+	body := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case body == "OK":
+		return &VirusScanResult{}, nil
+	case strings.HasSuffix(body, " FOUND"):
+		return &VirusScanResult{Infected: true, Signature: strings.TrimSuffix(body, " FOUND")}, nil
+	case strings.HasSuffix(body, "ERROR"):
+		return nil, &ScanRejectedError{Message: strings.TrimSpace(strings.TrimSuffix(body, "ERROR"))}
+	default:
+		return nil, fmt.Errorf("无法识别的 clamd 回复: %s", reply)
+	}
+}
+
+// GetQuarantineDir 获取隔离目录，默认为上传目录旁的 quarantine 目录（不在公开的上传目录中），
+// 可通过 SCAN_QUARANTINE_DIR 环境变量指定
+func GetQuarantineDir() string {
+	if dir := strings.TrimSpace(os.Getenv("SCAN_QUARANTINE_DIR")); dir != "" {
+		return dir
+	}
+	return filepath.Join(GetUploadDir(), "..", "quarantine")
+}
+
+// GetQuarantinePath 根据文件的相对路径（/uploads/...）获取其在隔离目录中的位置
+func GetQuarantinePath(relativePath string) string {
+	return filepath.Join(GetQuarantineDir(), strings.TrimPrefix(relativePath, "/uploads/"))
+}
+
+// QuarantineStoredFile 将已存储的文件移入隔离目录
+func QuarantineStoredFile(relativePath string) error {
+	return moveFile(GetFileAbsolutePath(relativePath), GetQuarantinePath(relativePath))
+}
+
+// ReleaseQuarantinedFile 将隔离的文件移回上传目录
+func ReleaseQuarantinedFile(relativePath string) error {
+	return moveFile(GetQuarantinePath(relativePath), GetFileAbsolutePath(relativePath))
+}
+
+// moveFile 移动文件，跨文件系统时复制后删除源文件
+func moveFile(source, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return err
+	}
+	err := os.Rename(source, target)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	src, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(target)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(target)
+		return err
+	}
+	return os.Remove(source)
+}
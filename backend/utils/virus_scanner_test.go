@@ -0,0 +1,301 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd 在本地端口上模拟 clamd，每个连接交给 handle 处理，返回监听地址
+func fakeClamd(t *testing.T, handle func(conn net.Conn)) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// readClamdCommand 读取以 \0 结尾的命令
+func readClamdCommand(reader *bufio.Reader) (string, error) {
+	command, err := reader.ReadString(0)
+	return strings.TrimSuffix(command, "\x00"), err
+}
+
+// readInstream 读取 INSTREAM 的分块内容，limit 大于0且内容超出时返回 false
+func readInstream(reader *bufio.Reader, limit int) ([]byte, bool, error) {
+	var data []byte
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return data, true, err
+		}
+		size := binary.BigEndian.Uint32(header)
+		if size == 0 {
+			return data, true, nil
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return data, true, err
+		}
+		data = append(data, chunk...)
+		if limit > 0 && len(data) > limit {
+			return data, false, nil
+		}
+	}
+}
+
+// instreamReply 返回只处理 INSTREAM 的 clamd，reply 根据收到的内容决定回复
+func instreamReply(t *testing.T, reply func(data []byte) string) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		reader := bufio.NewReader(conn)
+		command, err := readClamdCommand(reader)
+		if err != nil || command != "zINSTREAM" {
+			t.Errorf("期望 zINSTREAM 命令，实际为 %q (%v)", command, err)
+			return
+		}
+		data, _, err := readInstream(reader, 0)
+		if err != nil {
+			t.Errorf("读取内容失败: %v", err)
+			return
+		}
+		conn.Write([]byte(reply(data) + "\x00"))
+	}
+}
+
+func TestClamdScannerScan(t *testing.T) {
+	const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+	tests := []struct {
+		name          string
+		content       string
+		wantInfected  bool
+		wantSignature string
+		wantRejected  string
+	}{
+		{name: "干净内容", content: "hello world"},
+		{name: "空内容", content: ""},
+		{name: "命中特征", content: eicar, wantInfected: true, wantSignature: "Win.Test.EICAR_HDB-1"},
+		{name: "扫描器报错", content: "broken", wantRejected: "Can't allocate memory"},
+	}
+
+	address := fakeClamd(t, instreamReply(t, func(data []byte) string {
+		switch {
+		case bytes.Contains(data, []byte("EICAR-STANDARD-ANTIVIRUS-TEST-FILE")):
+			return "stream: Win.Test.EICAR_HDB-1 FOUND"
+		case string(data) == "broken":
+			return "Can't allocate memory ERROR"
+		default:
+			return "stream: OK"
+		}
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner, err := NewClamdScanner(address, time.Second)
+			if err != nil {
+				t.Fatalf("创建扫描器失败: %v", err)
+			}
+			// 使用很小的分块，覆盖多块发送
+			scanner.chunkSize = 7
+
+			result, err := scanner.Scan(context.Background(), strings.NewReader(tt.content))
+			if tt.wantRejected != "" {
+				var rejected *ScanRejectedError
+				if !errors.As(err, &rejected) {
+					t.Fatalf("期望 ScanRejectedError，实际为 %v", err)
+				}
+				if rejected.Message != tt.wantRejected {
+					t.Errorf("拒绝原因为 %q，期望 %q", rejected.Message, tt.wantRejected)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("扫描失败: %v", err)
+			}
+			if result.Infected != tt.wantInfected || result.Signature != tt.wantSignature {
+				t.Errorf("扫描结果为 %+v，期望 Infected=%v Signature=%q", result, tt.wantInfected, tt.wantSignature)
+			}
+		})
+	}
+}
+
+func TestClamdScannerSendsWholeStream(t *testing.T) {
+	content := strings.Repeat("0123456789", 1000)
+	received := make(chan []byte, 1)
+	address := fakeClamd(t, instreamReply(t, func(data []byte) string {
+		received <- data
+		return "stream: OK"
+	}))
+
+	scanner, err := NewClamdScanner("tcp://"+address, time.Second)
+	if err != nil {
+		t.Fatalf("创建扫描器失败: %v", err)
+	}
+	scanner.chunkSize = 4096
+	if _, err := scanner.Scan(context.Background(), strings.NewReader(content)); err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	if data := <-received; string(data) != content {
+		t.Errorf("clamd 收到 %d 字节，期望 %d 字节", len(data), len(content))
+	}
+}
+
+func TestClamdScannerSizeLimit(t *testing.T) {
+	const limit = 1024
+	address := fakeClamd(t, func(conn net.Conn) {
+		reader := bufio.NewReader(conn)
+		if _, err := readClamdCommand(reader); err != nil {
+			return
+		}
+		_, ok, err := readInstream(reader, limit)
+		if err != nil || ok {
+			conn.Write([]byte("stream: OK\x00"))
+			return
+		}
+		// 与 clamd 一致：超过 StreamMaxLength 时立即回复错误并停止处理，剩余内容丢弃
+		conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+		conn.(*net.TCPConn).CloseWrite()
+		io.Copy(io.Discard, conn)
+	})
+
+	scanner, err := NewClamdScanner(address, time.Second)
+	if err != nil {
+		t.Fatalf("创建扫描器失败: %v", err)
+	}
+	scanner.chunkSize = 256
+
+	_, err = scanner.Scan(context.Background(), bytes.NewReader(make([]byte, 64*1024)))
+	var rejected *ScanRejectedError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("期望 ScanRejectedError，实际为 %v", err)
+	}
+	if !strings.Contains(rejected.Message, "size limit exceeded") {
+		t.Errorf("拒绝原因为 %q", rejected.Message)
+	}
+}
+
+func TestClamdScannerTimeout(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	address := fakeClamd(t, func(conn net.Conn) {
+		// 接收内容但始终不回复
+		go io.Copy(io.Discard, conn)
+		<-release
+	})
+
+	scanner, err := NewClamdScanner(address, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("创建扫描器失败: %v", err)
+	}
+
+	start := time.Now()
+	_, err = scanner.Scan(context.Background(), strings.NewReader("hello"))
+	if err == nil {
+		t.Fatal("期望超时错误")
+	}
+	var rejected *ScanRejectedError
+	if errors.As(err, &rejected) {
+		t.Fatalf("超时应为暂时性错误，实际为 %v", err)
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("期望网络超时错误，实际为 %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("超时未生效，耗时 %v", elapsed)
+	}
+}
+
+func TestClamdScannerConnectionRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	scanner, err := NewClamdScanner(address, time.Second)
+	if err != nil {
+		t.Fatalf("创建扫描器失败: %v", err)
+	}
+	_, err = scanner.Scan(context.Background(), strings.NewReader("hello"))
+	var rejected *ScanRejectedError
+	if err == nil || errors.As(err, &rejected) {
+		t.Fatalf("连接失败应返回暂时性错误，实际为 %v", err)
+	}
+}
+
+func TestClamdScannerPing(t *testing.T) {
+	address := fakeClamd(t, func(conn net.Conn) {
+		command, err := readClamdCommand(bufio.NewReader(conn))
+		if err != nil || command != "zPING" {
+			t.Errorf("期望 zPING 命令，实际为 %q (%v)", command, err)
+			return
+		}
+		conn.Write([]byte("PONG\x00"))
+	})
+
+	scanner, err := NewClamdScanner(address, time.Second)
+	if err != nil {
+		t.Fatalf("创建扫描器失败: %v", err)
+	}
+	if err := scanner.Ping(context.Background()); err != nil {
+		t.Errorf("Ping 失败: %v", err)
+	}
+}
+
+func TestNewClamdScannerAddress(t *testing.T) {
+	tests := []struct {
+		address     string
+		wantNetwork string
+		wantAddress string
+		wantErr     bool
+	}{
+		{address: "clamav:3310", wantNetwork: "tcp", wantAddress: "clamav:3310"},
+		{address: "tcp://127.0.0.1:3310", wantNetwork: "tcp", wantAddress: "127.0.0.1:3310"},
+		{address: "unix:///run/clamav/clamd.ctl", wantNetwork: "unix", wantAddress: "/run/clamav/clamd.ctl"},
+		{address: "/var/run/clamd.sock", wantNetwork: "unix", wantAddress: "/var/run/clamd.sock"},
+		{address: "tcp://", wantErr: true},
+		{address: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			scanner, err := NewClamdScanner(tt.address, time.Second)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("期望错误，实际解析为 %s %s", scanner.network, scanner.address)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("解析失败: %v", err)
+			}
+			if scanner.network != tt.wantNetwork || scanner.address != tt.wantAddress {
+				t.Errorf("解析为 %s %s，期望 %s %s", scanner.network, scanner.address, tt.wantNetwork, tt.wantAddress)
+			}
+		})
+	}
+}
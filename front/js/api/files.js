@@ -15,7 +15,7 @@ class Files {
             return [];
         }
 
-        let url = `/api/files?user_id=${userId}`;
+        let url = `/api/files?user_id=${userId}&fields=thumbnail_data`;
         if (folderId) {
             url += `&folder_id=${folderId}`;
         }